@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// archiveDirectory writes a tar archive of the directory. The entries
+// are prefixed with the directory name, like in the Docker archives
+func archiveDirectory(dir string, output io.Writer) error {
+	archive := tar.NewWriter(output)
+	parent := filepath.Dir(dir)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(parent, path)
+		header.Name = filepath.ToSlash(name)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(archive, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
+
+// extractArchive extracts a tar archive into the directory. Entries
+// pointing outside of the directory or through symlinks are rejected.
+// The symlinks are created after the other entries, so the entries
+// cannot be written through them
+func extractArchive(dir string, input io.Reader) error {
+	archive := tar.NewReader(input)
+	root := filepath.Clean(dir)
+	symlinks := make([]*tar.Header, 0)
+
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		path, err := archivePath(root, header.Name)
+		if err != nil {
+			return err
+		}
+		mode := os.FileMode(header.Mode).Perm()
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, mode|0700); err != nil {
+				return err
+			}
+
+		case tar.TypeReg, tar.TypeRegA:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(file, archive)
+			file.Close()
+			if err != nil {
+				return err
+			}
+
+		case tar.TypeSymlink:
+			target := filepath.Join(filepath.Dir(path), header.Linkname)
+			if filepath.IsAbs(header.Linkname) || (filepath.Clean(target) != root && !insideDirectory(root, target)) {
+				return fmt.Errorf("Invalid archive symlink %s -> %s", header.Name, header.Linkname)
+			}
+			symlinks = append(symlinks, header)
+		}
+	}
+
+	for _, header := range symlinks {
+		path, err := archivePath(root, header.Name)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		os.Remove(path)
+		if err := os.Symlink(header.Linkname, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archivePath returns the path of the archive entry in the directory.
+// It fails, if the entry is outside of the directory or its parent
+// path goes through a symlink
+func archivePath(root, name string) (string, error) {
+	path := filepath.Join(root, filepath.Clean("/"+name))
+	if !insideDirectory(root, path) {
+		return "", fmt.Errorf("Invalid archive entry %s", name)
+	}
+
+	relative, _ := filepath.Rel(root, filepath.Dir(path))
+	parent := root
+	for _, component := range strings.Split(relative, string(os.PathSeparator)) {
+		if component == "." {
+			continue
+		}
+		parent = filepath.Join(parent, component)
+		info, err := os.Lstat(parent)
+		if os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("Invalid archive entry %s through a symlink", name)
+		}
+	}
+
+	return path, nil
+}
+
+// insideDirectory tells, if the path is inside of the root directory
+func insideDirectory(root, path string) bool {
+	return strings.HasPrefix(filepath.Clean(path), root+string(os.PathSeparator))
+}
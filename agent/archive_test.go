@@ -0,0 +1,78 @@
+package agent
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveDirectory(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	source := filepath.Join(dir, "source", "world")
+	os.MkdirAll(filepath.Join(source, "region"), 0755)
+	ioutil.WriteFile(filepath.Join(source, "level.dat"), []byte("level"), 0644)
+	ioutil.WriteFile(filepath.Join(source, "region", "r.0.0.mca"), []byte("region"), 0600)
+	os.Symlink("level.dat", filepath.Join(source, "latest"))
+
+	var archive bytes.Buffer
+	assert.NoError(t, archiveDirectory(source, &archive))
+
+	target := filepath.Join(dir, "target")
+	assert.NoError(t, extractArchive(target, &archive))
+
+	level, _ := ioutil.ReadFile(filepath.Join(target, "world", "level.dat"))
+	assert.Equal(t, "level", string(level))
+	region, _ := ioutil.ReadFile(filepath.Join(target, "world", "region", "r.0.0.mca"))
+	assert.Equal(t, "region", string(region))
+	info, _ := os.Stat(filepath.Join(target, "world", "region", "r.0.0.mca"))
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	link, _ := os.Readlink(filepath.Join(target, "world", "latest"))
+	assert.Equal(t, "level.dat", link)
+}
+
+func TestExtractArchiveRejectsEscapingSymlinks(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+	outside := filepath.Join(dir, "outside")
+	os.MkdirAll(outside, 0755)
+
+	symlinkArchive := func(linkname, file string) *bytes.Buffer {
+		var archive bytes.Buffer
+		writer := tar.NewWriter(&archive)
+		writer.WriteHeader(&tar.Header{Name: "data/x", Typeflag: tar.TypeSymlink, Linkname: linkname})
+		writer.WriteHeader(&tar.Header{Name: file, Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+		writer.Write([]byte("evil"))
+		writer.Close()
+		return &archive
+	}
+
+	target := filepath.Join(dir, "target")
+	assert.Error(t, extractArchive(target, symlinkArchive(outside, "data/y")))
+	assert.Error(t, extractArchive(target, symlinkArchive("../../outside", "data/y")))
+
+	// the files are written before the symlinks, so they cannot go through them
+	os.RemoveAll(target)
+	extractArchive(target, symlinkArchive("..", "data/x/evil"))
+	_, err := os.Stat(filepath.Join(target, "evil"))
+	assert.True(t, os.IsNotExist(err))
+
+	// an existing symlink is not followed either
+	os.RemoveAll(target)
+	os.MkdirAll(filepath.Join(target, "data"), 0755)
+	os.Symlink(outside, filepath.Join(target, "data", "link"))
+	var archive bytes.Buffer
+	writer := tar.NewWriter(&archive)
+	writer.WriteHeader(&tar.Header{Name: "data/link/evil", Typeflag: tar.TypeReg, Mode: 0644, Size: 4})
+	writer.Write([]byte("evil"))
+	writer.Close()
+	assert.Error(t, extractArchive(target, &archive))
+	_, err = os.Stat(filepath.Join(outside, "evil"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+// agentCertificateFile holds the PEM encoded certificate and key of the agent
+const agentCertificateFile = "agent.pem"
+
+// CertificateManager holds the client certificate of the agent, which
+// is enrolled with a bootstrap token and rotated before it expires
+type CertificateManager struct {
+	dir      string
+	hostname string
+
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+}
+
+// NewCertificateManager creates a CertificateManager storing
+// the certificate in the directory
+func NewCertificateManager(dir, hostname string) *CertificateManager {
+	return &CertificateManager{
+		dir:      dir,
+		hostname: hostname,
+	}
+}
+
+// Load loads the certificate of an already enrolled agent
+func (manager *CertificateManager) Load() error {
+	path := filepath.Join(manager.dir, agentCertificateFile)
+	certificate, err := tls.LoadX509KeyPair(path, path)
+	if err != nil {
+		return err
+	}
+	return manager.setCertificate(&certificate)
+}
+
+// Enroll gets the first certificate of the agent using a bootstrap token
+func (manager *CertificateManager) Enroll(ctx context.Context, client proto.AgentServiceClient, token string) error {
+	key, csr, err := newCertificateRequest(manager.hostname)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Enroll(ctx, &proto.EnrollRequest{
+		Hostname: manager.hostname,
+		Token:    token,
+		Csr:      csr,
+	})
+	if err != nil {
+		return err
+	}
+	return manager.store(key, res.Certificate)
+}
+
+// RotateIfNeeded renews the certificate, when two thirds
+// of its lifetime have passed
+func (manager *CertificateManager) RotateIfNeeded(ctx context.Context, client proto.AgentServiceClient) (bool, error) {
+	manager.mutex.RLock()
+	certificate := manager.certificate
+	manager.mutex.RUnlock()
+	if certificate == nil {
+		return false, errors.New("agent is not enrolled")
+	}
+
+	leaf := certificate.Leaf
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	if time.Now().Before(leaf.NotBefore.Add(lifetime * 2 / 3)) {
+		return false, nil
+	}
+
+	key, csr, err := newCertificateRequest(manager.hostname)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := client.RenewCertificate(ctx, &proto.RenewCertificateRequest{Csr: csr})
+	if err != nil {
+		return false, err
+	}
+	return true, manager.store(key, res.Certificate)
+}
+
+// NotAfter returns the expiry of the current certificate
+func (manager *CertificateManager) NotAfter() time.Time {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+
+	if manager.certificate == nil {
+		return time.Time{}
+	}
+	return manager.certificate.Leaf.NotAfter
+}
+
+// TLSConfig returns the client TLS configuration, which verifies the server
+// with the CA and presents the current certificate of the agent
+func (manager *CertificateManager) TLSConfig(caPEM []byte) (*tls.Config, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("invalid CA certificate")
+	}
+
+	return &tls.Config{
+		RootCAs:    pool,
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			manager.mutex.RLock()
+			defer manager.mutex.RUnlock()
+
+			if manager.certificate == nil {
+				return &tls.Certificate{}, nil
+			}
+			return manager.certificate, nil
+		},
+	}, nil
+}
+
+// store writes the key and the signed certificate atomically
+func (manager *CertificateManager) store(key *ecdsa.PrivateKey, certificatePEM []byte) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	certificate, err := tls.X509KeyPair(certificatePEM, keyPEM)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(manager.dir, 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(manager.dir, agentCertificateFile)
+	if err := ioutil.WriteFile(path+".tmp", append(certificatePEM, keyPEM...), 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+
+	return manager.setCertificate(&certificate)
+}
+
+func (manager *CertificateManager) setCertificate(certificate *tls.Certificate) error {
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	if err != nil {
+		return err
+	}
+	certificate.Leaf = leaf
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.certificate = certificate
+	return nil
+}
+
+func newCertificateRequest(hostname string) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: hostname},
+	}, key)
+	return key, csr, err
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server/pki"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+// fakeCertificateClient signs the certificates like the server
+type fakeCertificateClient struct {
+	proto.AgentServiceClient
+	ca       *pki.CA
+	validity time.Duration
+	renewed  int
+}
+
+func (client *fakeCertificateClient) Enroll(ctx context.Context, req *proto.EnrollRequest, opts ...grpc.CallOption) (*proto.CertificateResponse, error) {
+	certificate, err := client.ca.SignAgentCSR(req.Csr, req.Hostname, client.validity)
+	return &proto.CertificateResponse{Certificate: certificate, CaCertificate: client.ca.CertificatePEM}, err
+}
+
+func (client *fakeCertificateClient) RenewCertificate(ctx context.Context, req *proto.RenewCertificateRequest, opts ...grpc.CallOption) (*proto.CertificateResponse, error) {
+	client.renewed++
+	certificate, err := client.ca.SignAgentCSR(req.Csr, "agent1", client.validity)
+	return &proto.CertificateResponse{Certificate: certificate, CaCertificate: client.ca.CertificatePEM}, err
+}
+
+func TestEnrollAndRotateCertificate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "certificates")
+	defer os.RemoveAll(dir)
+
+	ca, err := pki.LoadOrCreateCA(dir + "/ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &fakeCertificateClient{ca: ca, validity: time.Hour}
+
+	manager := NewCertificateManager(dir+"/agent", "agent1")
+	assert.Error(t, manager.Load())
+
+	assert.NoError(t, manager.Enroll(context.Background(), client, "token"))
+	rotated, err := manager.RotateIfNeeded(context.Background(), client)
+	assert.NoError(t, err)
+	assert.False(t, rotated)
+
+	// the certificate is stored and loaded after a restart
+	restarted := NewCertificateManager(dir+"/agent", "agent1")
+	assert.NoError(t, restarted.Load())
+	assert.Equal(t, manager.NotAfter(), restarted.NotAfter())
+
+	// certificates valid for less than the signing margin need rotation
+	client.validity = time.Minute
+	assert.NoError(t, manager.Enroll(context.Background(), client, "token"))
+	client.validity = time.Hour
+	rotated, err = manager.RotateIfNeeded(context.Background(), client)
+	assert.NoError(t, err)
+	assert.True(t, rotated)
+	assert.Equal(t, 1, client.renewed)
+	assert.True(t, manager.NotAfter().After(time.Now().Add(50*time.Minute)))
+
+	config, err := manager.TLSConfig(ca.CertificatePEM)
+	assert.NoError(t, err)
+	certificate, _ := config.GetClientCertificate(nil)
+	assert.Equal(t, "agent1", certificate.Leaf.Subject.CommonName)
+}
@@ -0,0 +1,193 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// cgroupControllers are enabled for the gameserver cgroups, if available
+var cgroupControllers = []string{"memory", "cpu", "io"}
+
+// cgroupStats is the resource usage read from a cgroup v2
+type cgroupStats struct {
+	MemoryCurrent int64
+	MemoryMax     int64
+	CPUUsageUsec  int64
+	IOReadBytes   int64
+	IOWriteBytes  int64
+}
+
+// cgroupV2 manages the cgroups v2 of the gameserver processes. Every
+// gameserver gets its own cgroup under the root cgroup
+type cgroupV2 struct {
+	root string
+}
+
+// newCgroupV2 prepares the root cgroup. It fails, when cgroups v2 or
+// the memory controller are not available
+func newCgroupV2(root string) (*cgroupV2, error) {
+	parent := filepath.Dir(root)
+
+	data, err := ioutil.ReadFile(filepath.Join(parent, "cgroup.controllers"))
+	if err != nil {
+		return nil, err
+	}
+	available := strings.Fields(string(data))
+	if !containsString(available, "memory") {
+		return nil, errors.New("The memory cgroup controller is not available")
+	}
+
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	for _, controller := range cgroupControllers {
+		if !containsString(available, controller) {
+			continue
+		}
+		// The parent may already have the controller enabled or be
+		// managed by someone else, so only the root cgroup must succeed
+		writeCgroupFile(parent, "cgroup.subtree_control", "+"+controller)
+		if err := writeCgroupFile(root, "cgroup.subtree_control", "+"+controller); err != nil && controller == "memory" {
+			return nil, err
+		}
+	}
+
+	return &cgroupV2{root: root}, nil
+}
+
+func (cg *cgroupV2) path(uuid string) string {
+	return filepath.Join(cg.root, uuid)
+}
+
+// create creates the gameserver cgroup with the memory limits in bytes.
+// A zero limit means no limit
+func (cg *cgroupV2) create(uuid string, memoryMax, memoryLow int64) error {
+	path := cg.path(uuid)
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+
+	max := "max"
+	if memoryMax > 0 {
+		max = strconv.FormatInt(memoryMax, 10)
+	}
+	if err := writeCgroupFile(path, "memory.max", max); err != nil {
+		return err
+	}
+	return writeCgroupFile(path, "memory.low", strconv.FormatInt(memoryLow, 10))
+}
+
+// addProcess moves the process into the gameserver cgroup
+func (cg *cgroupV2) addProcess(uuid string, pid int) error {
+	return writeCgroupFile(cg.path(uuid), "cgroup.procs", strconv.Itoa(pid))
+}
+
+// remove removes the gameserver cgroup. It must not contain any processes
+func (cg *cgroupV2) remove(uuid string) error {
+	err := os.Remove(cg.path(uuid))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// stats reads the resource usage of the gameserver cgroup
+func (cg *cgroupV2) stats(uuid string) (*cgroupStats, error) {
+	path := cg.path(uuid)
+	stats := &cgroupStats{}
+
+	current, err := readCgroupInt(path, "memory.current")
+	if err != nil {
+		return nil, err
+	}
+	stats.MemoryCurrent = current
+	stats.MemoryMax, _ = readCgroupInt(path, "memory.max")
+
+	if data, err := ioutil.ReadFile(filepath.Join(path, "cpu.stat")); err == nil {
+		stats.CPUUsageUsec = parseCgroupKeyValues(data)["usage_usec"]
+	}
+
+	if data, err := ioutil.ReadFile(filepath.Join(path, "io.stat")); err == nil {
+		stats.IOReadBytes, stats.IOWriteBytes = parseCgroupIOStat(data)
+	}
+
+	return stats, nil
+}
+
+func writeCgroupFile(path, file, value string) error {
+	return ioutil.WriteFile(filepath.Join(path, file), []byte(value), 0644)
+}
+
+// readCgroupInt reads a single value file. "max" is returned as 0
+func readCgroupInt(path, file string) (int64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(path, file))
+	if err != nil {
+		return 0, err
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// parseCgroupKeyValues parses flat keyed files like cpu.stat
+func parseCgroupKeyValues(data []byte) map[string]int64 {
+	values := make(map[string]int64)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		values[fields[0]] = value
+	}
+	return values
+}
+
+// parseCgroupIOStat sums the read and written bytes of all devices in io.stat,
+// e.g. "8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0"
+func parseCgroupIOStat(data []byte) (int64, int64) {
+	var read, write int64
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		for _, field := range strings.Fields(scanner.Text()) {
+			parts := strings.SplitN(field, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			value, _ := strconv.ParseInt(parts[1], 10, 64)
+
+			switch parts[0] {
+			case "rbytes":
+				read += value
+			case "wbytes":
+				write += value
+			}
+		}
+	}
+	return read, write
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeCgroupfs(t *testing.T, controllers string) string {
+	dir, err := ioutil.TempDir("", "cgroupfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "cgroup.controllers"), []byte(controllers), 0644)
+	return dir
+}
+
+func TestCgroupV2(t *testing.T) {
+	dir := newFakeCgroupfs(t, "cpuset cpu io memory pids\n")
+	defer os.RemoveAll(dir)
+
+	cgroup, err := newCgroupV2(filepath.Join(dir, "chinchilla"))
+	assert.NoError(t, err)
+
+	assert.NoError(t, cgroup.create("uuid1", 1024*1024, 512*1024))
+	memoryMax, _ := ioutil.ReadFile(filepath.Join(dir, "chinchilla", "uuid1", "memory.max"))
+	assert.Equal(t, "1048576", string(memoryMax))
+
+	assert.NoError(t, cgroup.create("uuid2", 0, 0))
+	memoryMax, _ = ioutil.ReadFile(filepath.Join(dir, "chinchilla", "uuid2", "memory.max"))
+	assert.Equal(t, "max", string(memoryMax))
+
+	path := filepath.Join(dir, "chinchilla", "uuid1")
+	ioutil.WriteFile(filepath.Join(path, "memory.current"), []byte("524288\n"), 0644)
+	ioutil.WriteFile(filepath.Join(path, "cpu.stat"), []byte("usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n"), 0644)
+	ioutil.WriteFile(filepath.Join(path, "io.stat"), []byte("8:0 rbytes=1024 wbytes=2048 rios=1 wios=2\n8:16 rbytes=1 wbytes=2 rios=1 wios=1\n"), 0644)
+
+	stats, err := cgroup.stats("uuid1")
+	assert.NoError(t, err)
+	assert.Equal(t, &cgroupStats{
+		MemoryCurrent: 524288,
+		MemoryMax:     1048576,
+		CPUUsageUsec:  1500000,
+		IOReadBytes:   1025,
+		IOWriteBytes:  2050,
+	}, stats)
+}
+
+func TestCgroupV2WithoutMemoryController(t *testing.T) {
+	dir := newFakeCgroupfs(t, "cpu io pids\n")
+	defer os.RemoveAll(dir)
+
+	_, err := newCgroupV2(filepath.Join(dir, "chinchilla"))
+	assert.Error(t, err)
+
+	_, err = newCgroupV2(filepath.Join(dir, "missing", "chinchilla"))
+	assert.Error(t, err)
+}
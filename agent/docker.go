@@ -0,0 +1,406 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// DockerAPI is the part of the Docker client used by the DockerRuntime
+type DockerAPI interface {
+	client.ContainerAPIClient
+	client.ImageAPIClient
+}
+
+// DockerRuntime runs the gameservers as Docker containers
+type DockerRuntime struct {
+	api DockerAPI
+}
+
+// NewDockerRuntime creates a DockerRuntime instance
+func NewDockerRuntime(api DockerAPI) *DockerRuntime {
+	return &DockerRuntime{api: api}
+}
+
+// pullMessage is a single message of the Docker image pull JSON stream
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+	Error       string `json:"error"`
+	ErrorDetail *struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+}
+
+// List returns all gameserver containers
+func (runtime *DockerRuntime) List(ctx context.Context) ([]Container, error) {
+	args := filters.NewArgs()
+	args.Add("label", uuidLabel)
+
+	containers, err := runtime.api.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: args,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Container, 0, len(containers))
+	for _, cont := range containers {
+		health, _ := parseDockerHealthStatus(cont.Status)
+
+		var networks map[string]*network.EndpointSettings
+		if cont.NetworkSettings != nil {
+			networks = cont.NetworkSettings.Networks
+		}
+
+		result = append(result, Container{
+			ID:        cont.ID,
+			UUID:      cont.Labels[uuidLabel],
+			State:     cont.State,
+			Labels:    cont.Labels,
+			ImageID:   cont.ImageID,
+			IPAddress: networkIPAddress(networks),
+			Health:    health,
+		})
+	}
+	return result, nil
+}
+
+// Inspect returns the container with its exit status
+func (runtime *DockerRuntime) Inspect(ctx context.Context, id string) (*Container, error) {
+	containerJSON, err := runtime.api.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cont := &Container{
+		ID:      containerJSON.ID,
+		ImageID: containerJSON.Image,
+	}
+	if containerJSON.Config != nil {
+		cont.Labels = containerJSON.Config.Labels
+		cont.UUID = cont.Labels[uuidLabel]
+	}
+	if containerJSON.NetworkSettings != nil {
+		cont.IPAddress = networkIPAddress(containerJSON.NetworkSettings.Networks)
+	}
+	if state := containerJSON.State; state != nil {
+		cont.State = state.Status
+		cont.ExitCode = state.ExitCode
+		cont.FinishedAt = state.FinishedAt
+		if state.Health != nil {
+			cont.Health, _ = parseDockerHealthStatus("(" + state.Health.Status + ")")
+		}
+	}
+	return cont, nil
+}
+
+// Create creates the gameserver container
+func (runtime *DockerRuntime) Create(ctx context.Context, deployment *proto.GameserverDeployment, ipAddress string) (string, error) {
+	cont, err := runtime.api.ContainerCreate(ctx,
+		createGameserverContainerConfig(deployment, ipAddress),
+		createGameserverHostConfig(deployment, ipAddress),
+		nil,
+		deployment.UUID,
+	)
+	if err != nil {
+		return "", err
+	}
+	return cont.ID, nil
+}
+
+// Start starts the container
+func (runtime *DockerRuntime) Start(ctx context.Context, id string) error {
+	return runtime.api.ContainerStart(ctx, id, types.ContainerStartOptions{})
+}
+
+// Stop stops the container. A zero timeout uses the Docker default
+func (runtime *DockerRuntime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	if timeout == 0 {
+		return runtime.api.ContainerStop(ctx, id, nil)
+	}
+	return runtime.api.ContainerStop(ctx, id, &timeout)
+}
+
+// Kill sends the signal to the container
+func (runtime *DockerRuntime) Kill(ctx context.Context, id, signal string) error {
+	return runtime.api.ContainerKill(ctx, id, signal)
+}
+
+// SendInput writes a line to the stdin of the container. The container
+// must be created with an open stdin
+func (runtime *DockerRuntime) SendInput(ctx context.Context, id, input string) error {
+	resp, err := runtime.api.ContainerAttach(ctx, id, types.ContainerAttachOptions{
+		Stream: true,
+		Stdin:  true,
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Close()
+
+	_, err = resp.Conn.Write([]byte(input + "\n"))
+	return err
+}
+
+// Remove removes the container, even if it's running
+func (runtime *DockerRuntime) Remove(ctx context.Context, id string) error {
+	return runtime.api.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true})
+}
+
+// ExportVolume archives a directory of the container
+func (runtime *DockerRuntime) ExportVolume(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	content, _, err := runtime.api.CopyFromContainer(ctx, id, path)
+	return content, err
+}
+
+// ImportVolume extracts the archive into the parent of the directory
+func (runtime *DockerRuntime) ImportVolume(ctx context.Context, id, path string, content io.Reader) error {
+	return runtime.api.CopyToContainer(ctx, id, filepath.Dir(path), content, types.CopyToContainerOptions{})
+}
+
+// Logs returns the last lines of the container output
+func (runtime *DockerRuntime) Logs(ctx context.Context, id string, tail int) (string, error) {
+	reader, err := runtime.api.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       strconv.Itoa(tail),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, reader); err != nil {
+		return output.String(), err
+	}
+	return output.String(), nil
+}
+
+// Stats returns the resource usage of the container
+func (runtime *DockerRuntime) Stats(ctx context.Context, id string) (*proto.GameserverResourceUsage, error) {
+	stats, err := runtime.api.ContainerStats(ctx, id, false)
+	if err != nil {
+		return nil, err
+	}
+	defer stats.Body.Close()
+
+	var statsJSON types.StatsJSON
+	if err := json.NewDecoder(stats.Body).Decode(&statsJSON); err != nil {
+		return nil, err
+	}
+
+	return calculateResourceUsage(&statsJSON), nil
+}
+
+// Exec runs a command in the container and waits for it to finish
+func (runtime *DockerRuntime) Exec(ctx context.Context, id string, command []string) (*ExecResult, error) {
+	config := types.ExecConfig{
+		AttachStdout: true,
+		AttachStderr: true,
+		Cmd:          command,
+	}
+
+	exec, err := runtime.api.ContainerExecCreate(ctx, id, config)
+	if err != nil {
+		return nil, err
+	}
+
+	attach, err := runtime.api.ContainerExecAttach(ctx, exec.ID, config)
+	if err != nil {
+		return nil, err
+	}
+	defer attach.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, attach.Reader); err != nil {
+		return nil, err
+	}
+
+	inspect, err := runtime.api.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ExecResult{
+		ExitCode: inspect.ExitCode,
+		Output:   output.String(),
+	}, nil
+}
+
+// PullImage pulls the image and reports the progress of its layers
+func (runtime *DockerRuntime) PullImage(ctx context.Context, image string, credentials *RegistryCredentials, progress func(PullProgress)) error {
+	options := types.ImagePullOptions{}
+	if credentials != nil {
+		auth, err := encodeRegistryAuth(*credentials)
+		if err != nil {
+			return err
+		}
+		options.RegistryAuth = auth
+	}
+
+	reader, err := runtime.api.ImagePull(ctx, image, options)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	return readPullStream(reader, progress)
+}
+
+// InspectImage returns the local image or ErrImageNotFound
+func (runtime *DockerRuntime) InspectImage(ctx context.Context, image string) (*Image, error) {
+	inspect, _, err := runtime.api.ImageInspectWithRaw(ctx, image)
+	if client.IsErrImageNotFound(err) {
+		return nil, ErrImageNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		ID:          inspect.ID,
+		RepoDigests: inspect.RepoDigests,
+	}, nil
+}
+
+// readPullStream decodes the image pull JSON stream
+func readPullStream(reader io.Reader, progress func(PullProgress)) error {
+	decoder := json.NewDecoder(reader)
+	for {
+		var message pullMessage
+		if err := decoder.Decode(&message); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if message.ErrorDetail != nil && message.ErrorDetail.Message != "" {
+			return errors.New(message.ErrorDetail.Message)
+		}
+		if message.Error != "" {
+			return errors.New(message.Error)
+		}
+
+		progress(PullProgress{
+			Layer:   message.ID,
+			Status:  message.Status,
+			Current: message.ProgressDetail.Current,
+			Total:   message.ProgressDetail.Total,
+		})
+	}
+}
+
+func networkIPAddress(networks map[string]*network.EndpointSettings) string {
+	for _, endpoint := range networks {
+		if endpoint != nil && endpoint.IPAddress != "" {
+			return endpoint.IPAddress
+		}
+	}
+	return ""
+}
+
+func createGameserverContainerConfig(gameserverConfig *proto.GameserverDeployment, ipAddress string) *container.Config {
+	envs := make([]string, 0)
+	for _, variable := range gameserverConfig.Environment {
+		env := fmt.Sprintf("%s=%s", variable.Name, variable.Value)
+		envs = append(envs, env)
+	}
+
+	config := &container.Config{
+		Image:       deploymentImage(gameserverConfig),
+		Cmd:         gameserverConfig.Command,
+		Env:         envs,
+		Labels:      containerLabels(gameserverConfig, ipAddress),
+		Healthcheck: createDockerHealthConfig(gameserverConfig.HealthCheck),
+	}
+
+	if shutdown := gameserverConfig.Shutdown; shutdown != nil {
+		config.StopSignal = shutdown.Signal
+		if shutdown.Timeout > 0 {
+			timeout := int(shutdown.Timeout)
+			config.StopTimeout = &timeout
+		}
+		config.OpenStdin = shutdown.Command != "" && shutdown.Channel == proto.ShutdownChannel_SHUTDOWN_STDIN
+	}
+
+	return config
+}
+
+func createGameserverHostConfig(deployment *proto.GameserverDeployment, ipAddress string) *container.HostConfig {
+
+	portBindings := nat.PortMap{}
+	for _, port := range deployment.Ports {
+		var protocolName string
+		if port.Protocol == proto.NetworkProtocol_TCP {
+			protocolName = "tcp"
+		} else {
+			protocolName = "udp"
+		}
+		key, _ := nat.NewPort(protocolName, fmt.Sprintf("%d", port.ContainerPort))
+		value := []nat.PortBinding{
+			{
+				HostIP:   ipAddress,
+				HostPort: fmt.Sprintf("%d", port.ContainerPort),
+			},
+		}
+		portBindings[key] = value
+	}
+
+	return &container.HostConfig{
+		PortBindings: portBindings,
+		Resources: container.Resources{
+			Memory:            deployment.ResourceRequirements.MemoryLimit * 1024,
+			MemoryReservation: deployment.ResourceRequirements.MemoryReservation * 1024,
+		},
+	}
+}
+
+// parseDockerHealthStatus extracts the health status from the container status,
+// e.g. "Up 5 minutes (healthy)"
+func parseDockerHealthStatus(status string) (proto.HealthStatus, bool) {
+	switch {
+	case strings.HasSuffix(status, "(health: starting)"), strings.HasSuffix(status, "(starting)"):
+		return proto.HealthStatus_STARTING, true
+	case strings.HasSuffix(status, "(healthy)"):
+		return proto.HealthStatus_HEALTHY, true
+	case strings.HasSuffix(status, "(unhealthy)"):
+		return proto.HealthStatus_UNHEALTHY, true
+	}
+	return proto.HealthStatus_HEALTH_UNKNOWN, false
+}
+
+func createDockerHealthConfig(check *proto.HealthCheck) *container.HealthConfig {
+	if check == nil || check.Type != proto.HealthCheckType_HEALTHCHECK_EXEC {
+		return nil
+	}
+
+	return &container.HealthConfig{
+		Test:        append([]string{"CMD"}, check.Command...),
+		Interval:    healthCheckDuration(check.Interval, defaultHealthCheckInterval),
+		Timeout:     healthCheckDuration(check.Timeout, defaultHealthCheckTimeout),
+		StartPeriod: time.Duration(check.StartPeriod) * time.Second,
+		Retries:     int(check.Retries),
+	}
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDockerAPI struct {
+	DockerAPI
+
+	containers   []types.Container
+	images       map[string]types.ImageInspect
+	pullStream   string
+	pulledImage  string
+	registryAuth string
+}
+
+func (api *fakeDockerAPI) ContainerList(ctx context.Context, options types.ContainerListOptions) ([]types.Container, error) {
+	return api.containers, nil
+}
+
+func (api *fakeDockerAPI) ImagePull(ctx context.Context, ref string, options types.ImagePullOptions) (io.ReadCloser, error) {
+	api.pulledImage = ref
+	api.registryAuth = options.RegistryAuth
+	return ioutil.NopCloser(strings.NewReader(api.pullStream)), nil
+}
+
+func (api *fakeDockerAPI) ImageInspectWithRaw(ctx context.Context, image string) (types.ImageInspect, []byte, error) {
+	if inspect, ok := api.images[image]; ok {
+		return inspect, nil, nil
+	}
+	return types.ImageInspect{}, nil, imageNotFoundError{}
+}
+
+type imageNotFoundError struct{}
+
+func (err imageNotFoundError) Error() string {
+	return "No such image"
+}
+
+func (err imageNotFoundError) NotFound() bool {
+	return true
+}
+
+func TestDockerRuntimeList(t *testing.T) {
+	api := &fakeDockerAPI{
+		containers: []types.Container{
+			{
+				ID:      "container1",
+				State:   "running",
+				Status:  "Up 5 minutes (healthy)",
+				ImageID: "sha256:1234",
+				Labels: map[string]string{
+					uuidLabel:      "uuid1",
+					ipAddressLabel: "10.0.0.1",
+				},
+				NetworkSettings: &types.SummaryNetworkSettings{
+					Networks: map[string]*network.EndpointSettings{
+						"bridge": {IPAddress: "172.17.0.2"},
+					},
+				},
+			},
+		},
+	}
+
+	containers, err := NewDockerRuntime(api).List(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, containers, 1)
+	assert.Equal(t, "uuid1", containers[0].UUID)
+	assert.Equal(t, ContainerRunning, containers[0].State)
+	assert.Equal(t, "172.17.0.2", containers[0].IPAddress)
+	assert.Equal(t, proto.HealthStatus_HEALTHY, containers[0].Health)
+}
+
+func TestDockerRuntimePullImage(t *testing.T) {
+	api := &fakeDockerAPI{
+		pullStream: `{"status":"Pulling from library/minecraft","id":"latest"}` + "\n" +
+			`{"status":"Downloading","id":"layer1","progressDetail":{"current":10,"total":20}}` + "\n",
+	}
+	credentials := &RegistryCredentials{Host: "docker.io", Username: "user", Password: "secret"}
+
+	var updates []PullProgress
+	err := NewDockerRuntime(api).PullImage(context.Background(), "minecraft", credentials, func(progress PullProgress) {
+		updates = append(updates, progress)
+	})
+
+	auth, _ := encodeRegistryAuth(*credentials)
+	assert.NoError(t, err)
+	assert.Equal(t, auth, api.registryAuth)
+	assert.Equal(t, []PullProgress{
+		{Layer: "latest", Status: "Pulling from library/minecraft"},
+		{Layer: "layer1", Status: "Downloading", Current: 10, Total: 20},
+	}, updates)
+}
+
+func TestReadPullStreamError(t *testing.T) {
+	stream := `{"errorDetail":{"message":"manifest for minecraft:foo not found"},"error":"manifest for minecraft:foo not found"}` + "\n"
+
+	err := readPullStream(strings.NewReader(stream), func(PullProgress) {})
+
+	assert.EqualError(t, err, "manifest for minecraft:foo not found")
+}
+
+func TestDockerRuntimeInspectImage(t *testing.T) {
+	api := &fakeDockerAPI{
+		images: map[string]types.ImageInspect{
+			"minecraft": {ID: "sha256:1234", RepoDigests: []string{"minecraft@sha256:abcd"}},
+		},
+	}
+	runtime := NewDockerRuntime(api)
+
+	image, err := runtime.InspectImage(context.Background(), "minecraft")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"minecraft@sha256:abcd"}, image.RepoDigests)
+
+	_, err = runtime.InspectImage(context.Background(), "factorio")
+	assert.Equal(t, ErrImageNotFound, err)
+}
+
+func TestPodmanQualifiesImageNames(t *testing.T) {
+	api := &fakeDockerAPI{}
+
+	NewPodmanRuntime(api).PullImage(context.Background(), "itzg/minecraft-server", nil, func(PullProgress) {})
+	assert.Equal(t, "docker.io/itzg/minecraft-server", api.pulledImage)
+
+	assert.Equal(t, "docker.io/library/minecraft:1.14", qualifyImageName("minecraft:1.14"))
+	assert.Equal(t, "docker.io/itzg/minecraft-server", qualifyImageName("docker.io/itzg/minecraft-server"))
+	assert.Equal(t, "registry.example.com/minecraft", qualifyImageName("registry.example.com/minecraft"))
+	assert.Equal(t, "sha256:"+strings.Repeat("a", 64), qualifyImageName("sha256:"+strings.Repeat("a", 64)))
+}
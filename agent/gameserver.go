@@ -3,18 +3,9 @@ package agent
 import (
 	"context"
 	"errors"
-	"fmt"
-	"io"
-	"log"
 	"net"
-	"os"
 
 	"github.com/Trojan295/chinchilla/proto"
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/api/types/container"
-	"github.com/docker/docker/api/types/filters"
-	"github.com/docker/docker/client"
-	"github.com/docker/go-connections/nat"
 )
 
 // GameserverState holds the state of a game server
@@ -22,53 +13,94 @@ type GameserverState struct {
 	Running bool
 }
 
+// GameserverManagerConfig configures a GameserverManager
+type GameserverManagerConfig struct {
+	IPAddresses      []string
+	ImagePullWorkers int
+	ReconcileWorkers int
+	Registries       []RegistryCredentials
+	Transfer         VolumeTransfer
+}
+
 // GameserverManager struct
 type GameserverManager struct {
-	containers  client.ContainerAPIClient
-	image       client.ImageAPIClient
-	ipAddresses []string
+	runtime      Runtime
+	ipAddresses  []string
+	queries      *gameserverQueries
+	healthChecks *gameserverHealthChecks
+	restarts     *gameserverRestarts
+	stats        *containerStats
+	images       *imagePuller
+	locks        *gameserverLocks
+	actions      chan gameserverAction
+	results      *actionResults
+	transfer     VolumeTransfer
+	migrations   *migrationTasks
+	refused      *refusedUpdates
 }
 
 // NewGameserverManager creates a GameserverManager instance
-func NewGameserverManager(containersAPI client.ContainerAPIClient, imageAPI client.ImageAPIClient, ipAddresses []string) *GameserverManager {
-	return &GameserverManager{
-		containers:  containersAPI,
-		image:       imageAPI,
-		ipAddresses: ipAddresses,
+func NewGameserverManager(runtime Runtime, config GameserverManagerConfig) *GameserverManager {
+	manager := &GameserverManager{
+		runtime:      runtime,
+		ipAddresses:  config.IPAddresses,
+		queries:      newGameserverQueries(),
+		healthChecks: newGameserverHealthChecks(),
+		restarts:     newGameserverRestarts(),
+		stats:        newContainerStats(),
+		images:       newImagePuller(runtime, config.ImagePullWorkers, config.Registries),
+		locks:        newGameserverLocks(),
+		actions:      make(chan gameserverAction),
+		results:      &actionResults{},
+		transfer:     config.Transfer,
+		migrations:   newMigrationTasks(),
+		refused:      newRefusedUpdates(),
+	}
+
+	workers := config.ReconcileWorkers
+	if workers <= 0 {
+		workers = defaultReconcileWorkers
 	}
+	for i := 0; i < workers; i++ {
+		go manager.actionWorker()
+	}
+
+	return manager
 }
 
-// Tick func
+// Tick reconciles the containers with the deployments. The actions run
+// in the background, so Tick doesn't wait for slow creates
 func (manager *GameserverManager) Tick(deploymentConfig *proto.GetGameserverDeploymentsResponse) error {
-	deployments := deploymentConfig.Deployments
+	deployments := importDeployments(deploymentConfig.Deployments, deploymentConfig.MigrationTasks)
 
-	runningServers, _ := manager.GetGameservers()
-
-	for _, runningServer := range runningServers {
-		if isForRemoval(runningServer, deployments) {
-			serverUUID := runningServer.UUID
-			log.Printf("Gameserver %s marked for removal", serverUUID)
-			manager.RemoveGameserver(serverUUID)
-			log.Printf("Gameserver %s removed", serverUUID)
-		}
+	containers, err := manager.runtime.List(context.Background())
+	if err != nil {
+		return err
 	}
 
-	for _, server := range deployments {
-		runningServer, err := manager.GetGameserver(server.UUID)
-		if err != nil {
-			log.Printf("Error in GameserverManager::Tick: %s", err)
+	deployed := make(map[string]bool, len(deployments))
+	for _, deployment := range deployments {
+		deployed[deployment.UUID] = true
+		if !deployment.Stopped {
 			continue
 		}
 
-		if runningServer == nil {
-			log.Printf("Creating gameserver %s...", server.UUID)
-			err := manager.CreateGameserver(server)
-			if err != nil {
-				log.Printf("Error whie creating %s: %s", server.UUID, err)
-			}
-			log.Printf("Created gameserver %s", server.UUID)
+		manager.images.release(deployment.UUID)
+		if findGameserverContainer(containers, deployment.UUID) != nil {
+			manager.restarts.markStopped(deployment.UUID)
 		}
 	}
+	manager.images.retain(deployed)
+
+	for _, action := range planGameserverActions(deployments, containers) {
+		manager.dispatchAction(action)
+	}
+	manager.reportRefusedUpdates(deployments, containers)
+	manager.runMigrationTasks(deploymentConfig.MigrationTasks, containers)
+
+	manager.collectStats(containers)
+	manager.queryGameservers(deployments, containers)
+	manager.checkGameserversHealth(deployments, containers)
 	return nil
 }
 
@@ -76,26 +108,51 @@ func (manager *GameserverManager) Tick(deploymentConfig *proto.GetGameserverDepl
 func (manager *GameserverManager) GetGameservers() ([]*proto.Gameserver, error) {
 	ctx := context.Background()
 
-	args := filters.NewArgs()
-	args.Add("label", "chinchilla.gameserver.uuid")
-
-	containers, err := manager.containers.ContainerList(ctx, types.ContainerListOptions{
-		Filters: args,
-	})
+	containers, err := manager.runtime.List(ctx)
 	if err != nil {
 		return make([]*proto.Gameserver, 0), err
 	}
 
 	gameservers := make([]*proto.Gameserver, 0, len(containers))
+	containerUUIDs := make(map[string]bool, len(containers))
 
 	for _, cont := range containers {
-		gameservers = append(gameservers, &proto.Gameserver{
-			UUID:   cont.Labels["chinchilla.gameserver.uuid"],
-			Status: proto.GameserverStatus_RUNNING,
+		uuid := cont.UUID
+		restartState := manager.restarts.get(uuid)
+		status := gameserverExitStatus(restartState)
+		var resourceUsage *proto.GameserverResourceUsage
+
+		if cont.State == ContainerRunning {
+			status = proto.GameserverStatus_RUNNING
+			resourceUsage = manager.stats.get(cont.ID)
+		}
+
+		gameserver := &proto.Gameserver{
+			UUID:   uuid,
+			Status: status,
 			Endpoint: &proto.Endpoint{
-				IpAddress: cont.Labels["chinchilla.gameserver.ip_address"],
+				IpAddress: cont.Labels[ipAddressLabel],
 			},
-		})
+			ResourceUsage: resourceUsage,
+			ImageDigest:   manager.images.imageDigest(cont.ImageID),
+			Query:         manager.queries.result(uuid),
+			Health:        manager.healthChecks.status(uuid),
+		}
+
+		if restartState != nil {
+			gameserver.RestartCount = int64(restartState.restartCount)
+			gameserver.LastExitCode = int64(restartState.lastExitCode)
+			gameserver.CrashLog = restartState.crashLog
+		}
+
+		gameservers = append(gameservers, gameserver)
+		containerUUIDs[uuid] = true
+	}
+
+	for _, gameserver := range manager.images.gameservers() {
+		if !containerUUIDs[gameserver.UUID] {
+			gameservers = append(gameservers, gameserver)
+		}
 	}
 
 	return gameservers, nil
@@ -118,59 +175,32 @@ func (manager *GameserverManager) GetGameserver(UUID string) (*proto.Gameserver,
 
 // CreateGameserver creates a complete server
 func (manager *GameserverManager) CreateGameserver(gameServer *proto.GameserverDeployment) error {
-	err := manager.createGameserverContainer(gameServer)
-	return err
+	containerID, err := manager.createGameserverContainer(gameServer)
+	if err != nil {
+		return err
+	}
+	return manager.runtime.Start(context.Background(), containerID)
 }
 
-// RemoveGameserver removes a gameserver
-func (manager *GameserverManager) RemoveGameserver(uuid string) error {
-	return manager.removeGameServerContainer(uuid)
+// StopGameserver shuts down a gameserver gracefully, but keeps its container.
+// A nil policy uses the shutdown policy of the container
+func (manager *GameserverManager) StopGameserver(uuid string, policy *proto.ShutdownPolicy) (*proto.ShutdownResult, error) {
+	return manager.shutdownGameserver(uuid, policy)
 }
 
-func createGameserverContainerConfig(gameserverConfig *proto.GameserverDeployment, ipAddress string) *container.Config {
-	envs := make([]string, 0)
-	for _, variable := range gameserverConfig.Environment {
-		env := fmt.Sprintf("%s=%s", variable.Name, variable.Value)
-		envs = append(envs, env)
-	}
-
-	return &container.Config{
-		Image: gameserverConfig.Image,
-		Env:   envs,
-		Labels: map[string]string{
-			"chinchilla.gameserver.uuid":       gameserverConfig.UUID,
-			"chinchilla.gameserver.ip_address": ipAddress,
-		},
-	}
+// StartGameserver starts a stopped gameserver
+func (manager *GameserverManager) StartGameserver(uuid string) error {
+	ctx := context.Background()
+	return manager.runtime.Start(ctx, uuid)
 }
 
-func createGameserverHostConfig(deployment *proto.GameserverDeployment, ipAddress string) *container.HostConfig {
-
-	portBindings := nat.PortMap{}
-	for _, port := range deployment.Ports {
-		var protocolName string
-		if port.Protocol == proto.NetworkProtocol_TCP {
-			protocolName = "tcp"
-		} else {
-			protocolName = "udp"
-		}
-		key, _ := nat.NewPort(protocolName, fmt.Sprintf("%d", port.ContainerPort))
-		value := []nat.PortBinding{
-			{
-				HostIP:   ipAddress,
-				HostPort: fmt.Sprintf("%d", port.ContainerPort),
-			},
-		}
-		portBindings[key] = value
-	}
-
-	return &container.HostConfig{
-		PortBindings: portBindings,
-		Resources: container.Resources{
-			Memory:            deployment.ResourceRequirements.MemoryLimit * 1024,
-			MemoryReservation: deployment.ResourceRequirements.MemoryReservation * 1024,
-		},
+// RemoveGameserver shuts down a gameserver gracefully and removes it
+func (manager *GameserverManager) RemoveGameserver(uuid string) (*proto.ShutdownResult, error) {
+	result, err := manager.shutdownGameserver(uuid, nil)
+	if err != nil {
+		return result, err
 	}
+	return result, manager.removeGameServerContainer(uuid)
 }
 
 func findFreeIPAddress(ports []*proto.NetworkPort, allIPs []string) (*string, error) {
@@ -214,48 +244,16 @@ func findFreeIPAddress(ports []*proto.NetworkPort, allIPs []string) (*string, er
 	return nil, errors.New("Cannot find free IP")
 }
 
-func (manager *GameserverManager) createGameserverContainer(deployment *proto.GameserverDeployment) error {
-	ctx := context.Background()
-
-	reader, err := manager.image.ImagePull(ctx, deployment.Image, types.ImagePullOptions{})
-	if err != nil {
-		return err
-	}
-	io.Copy(os.Stdout, reader)
-
+func (manager *GameserverManager) createGameserverContainer(deployment *proto.GameserverDeployment) (string, error) {
 	ipAddress, err := findFreeIPAddress(deployment.Ports, manager.ipAddresses)
 	if err != nil {
-		return err
-	}
-
-	container, err := manager.containers.ContainerCreate(ctx,
-		createGameserverContainerConfig(deployment, *ipAddress),
-		createGameserverHostConfig(deployment, *ipAddress),
-		nil,
-		deployment.UUID,
-	)
-
-	if err != nil {
-		return err
-	}
-
-	if err := manager.containers.ContainerStart(ctx, container.ID, types.ContainerStartOptions{}); err != nil {
-		panic(err)
+		return "", err
 	}
 
-	return nil
+	return manager.runtime.Create(context.Background(), deployment, *ipAddress)
 }
 
 func (manager *GameserverManager) removeGameServerContainer(containerID string) error {
 	ctx := context.Background()
-	return manager.containers.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true})
-}
-
-func isForRemoval(server *proto.Gameserver, deployments []*proto.GameserverDeployment) bool {
-	for _, deployment := range deployments {
-		if deployment.UUID == server.UUID {
-			return false
-		}
-	}
-	return true
+	return manager.runtime.Remove(ctx, containerID)
 }
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Trojan295/chinchilla/agent/query"
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+const gameserverQueryTimeout = 3 * time.Second
+
+var queriers = map[proto.QueryProtocol]query.Querier{
+	proto.QueryProtocol_QUERY_MINECRAFT: query.MinecraftQuerier{},
+	proto.QueryProtocol_QUERY_FACTORIO:  query.FactorioQuerier{},
+	proto.QueryProtocol_QUERY_TEAMSPEAK: query.TeamspeakQuerier{},
+}
+
+// gameserverQueries holds the latest query results of the gameservers
+type gameserverQueries struct {
+	mutex    sync.Mutex
+	results  map[string]*proto.GameserverQueryResult
+	inFlight map[string]bool
+}
+
+func newGameserverQueries() *gameserverQueries {
+	return &gameserverQueries{
+		results:  make(map[string]*proto.GameserverQueryResult),
+		inFlight: make(map[string]bool),
+	}
+}
+
+func (queries *gameserverQueries) result(uuid string) *proto.GameserverQueryResult {
+	queries.mutex.Lock()
+	defer queries.mutex.Unlock()
+	return queries.results[uuid]
+}
+
+func (queries *gameserverQueries) start(uuid string) bool {
+	queries.mutex.Lock()
+	defer queries.mutex.Unlock()
+
+	if queries.inFlight[uuid] {
+		return false
+	}
+	queries.inFlight[uuid] = true
+	return true
+}
+
+func (queries *gameserverQueries) finish(uuid string, result *proto.GameserverQueryResult) {
+	queries.mutex.Lock()
+	defer queries.mutex.Unlock()
+
+	delete(queries.inFlight, uuid)
+	queries.results[uuid] = result
+}
+
+func (queries *gameserverQueries) remove(uuid string) {
+	queries.mutex.Lock()
+	defer queries.mutex.Unlock()
+	delete(queries.results, uuid)
+}
+
+// queryGameservers starts a background query of every running gameserver,
+// which declares a query protocol
+func (manager *GameserverManager) queryGameservers(deployments []*proto.GameserverDeployment, containers []Container) {
+	for _, deployment := range deployments {
+		if deployment.Query == nil || deployment.Query.Protocol == proto.QueryProtocol_QUERY_NONE {
+			continue
+		}
+
+		cont := findGameserverContainer(containers, deployment.UUID)
+		if cont == nil || cont.State != ContainerRunning {
+			manager.queries.remove(deployment.UUID)
+			continue
+		}
+
+		if !manager.queries.start(deployment.UUID) {
+			continue
+		}
+
+		go func(deployment *proto.GameserverDeployment, cont Container) {
+			result, err := manager.queryGameserver(deployment, &cont)
+			if err != nil {
+				log.Printf("Cannot query gameserver %s: %s", deployment.UUID, err)
+			}
+			manager.queries.finish(deployment.UUID, result)
+		}(deployment, *cont)
+	}
+}
+
+func (manager *GameserverManager) queryGameserver(deployment *proto.GameserverDeployment, cont *Container) (*proto.GameserverQueryResult, error) {
+	querier, ok := queriers[deployment.Query.Protocol]
+	if !ok {
+		return nil, fmt.Errorf("Unsupported query protocol %s", deployment.Query.Protocol)
+	}
+
+	password := deployment.Query.Password
+	if password == "" && deployment.Query.PasswordFile != "" {
+		var err error
+		password, err = manager.readContainerFile(cont.ID, deployment.Query.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	address := net.JoinHostPort(containerIPAddress(cont), fmt.Sprintf("%d", deployment.Query.Port))
+	status, err := querier.Query(address, query.Options{
+		Password: strings.TrimSpace(password),
+		Timeout:  gameserverQueryTimeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &proto.GameserverQueryResult{
+		PlayersOnline: int64(status.PlayersOnline),
+		PlayersMax:    int64(status.PlayersMax),
+		Motd:          status.MOTD,
+		Version:       status.Version,
+	}, nil
+}
+
+func (manager *GameserverManager) readContainerFile(containerID, path string) (string, error) {
+	result, err := manager.runtime.Exec(context.Background(), containerID, []string{"cat", path})
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("Cannot read %s: %s", path, strings.TrimSpace(result.Output))
+	}
+	if len(result.Output) == 0 {
+		return "", errors.New("Empty file")
+	}
+	return result.Output, nil
+}
+
+func containerIPAddress(cont *Container) string {
+	if cont.IPAddress != "" {
+		return cont.IPAddress
+	}
+	return cont.Labels[ipAddressLabel]
+}
+
+func findGameserverContainer(containers []Container, uuid string) *Container {
+	for i := range containers {
+		if containers[i].UUID == uuid {
+			return &containers[i]
+		}
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package agent
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/Trojan295/chinchilla/proto"
 	"github.com/docker/go-connections/nat"
@@ -35,9 +36,34 @@ func TestCreateGameserverContainerConfig(t *testing.T) {
 	if !reflect.DeepEqual(containerConfig.Labels, map[string]string{
 		"chinchilla.gameserver.uuid":       runConfig.UUID,
 		"chinchilla.gameserver.ip_address": "127.0.0.1",
+		configHashLabel:                    deploymentConfigHash(runConfig),
 	}) {
 		t.Errorf("Wrong labels: %v", containerConfig.Labels)
 	}
+
+	assert.Nil(t, containerConfig.Healthcheck)
+}
+
+func TestCreateGameserverContainerConfigWithHealthCheck(t *testing.T) {
+	runConfig := &proto.GameserverDeployment{
+		UUID:  "787a1b9d-6371-44d4-bd0b-d3c94077ad6b",
+		Image: "itzg/minecraft-server",
+		HealthCheck: &proto.HealthCheck{
+			Type:        proto.HealthCheckType_HEALTHCHECK_EXEC,
+			Command:     []string{"mc-health"},
+			Interval:    10,
+			StartPeriod: 120,
+			Retries:     5,
+		},
+	}
+
+	containerConfig := createGameserverContainerConfig(runConfig, "127.0.0.1")
+
+	assert.Equal(t, []string{"CMD", "mc-health"}, containerConfig.Healthcheck.Test)
+	assert.Equal(t, 10*time.Second, containerConfig.Healthcheck.Interval)
+	assert.Equal(t, defaultHealthCheckTimeout, containerConfig.Healthcheck.Timeout)
+	assert.Equal(t, 120*time.Second, containerConfig.Healthcheck.StartPeriod)
+	assert.Equal(t, 5, containerConfig.Healthcheck.Retries)
 }
 
 func TestCreateGameserverHostConfig(t *testing.T) {
@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+const (
+	defaultHealthCheckInterval = 30 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+	defaultHealthCheckRetries  = 3
+)
+
+// gameserverHealth holds the health check state of a single gameserver
+type gameserverHealth struct {
+	status         proto.HealthStatus
+	failingStreak  int
+	startedAt      time.Time
+	lastCheck      time.Time
+	unhealthySince time.Time
+	checking       bool
+}
+
+// update applies a probe result to the health state
+func (health *gameserverHealth) update(check *proto.HealthCheck, healthy bool, now time.Time) {
+	if healthy {
+		health.status = proto.HealthStatus_HEALTHY
+		health.failingStreak = 0
+		health.unhealthySince = time.Time{}
+		return
+	}
+
+	if health.status != proto.HealthStatus_HEALTHY &&
+		now.Sub(health.startedAt) < time.Duration(check.StartPeriod)*time.Second {
+		health.status = proto.HealthStatus_STARTING
+		return
+	}
+
+	health.failingStreak++
+
+	retries := int(check.Retries)
+	if retries == 0 {
+		retries = defaultHealthCheckRetries
+	}
+
+	if health.failingStreak >= retries && health.status != proto.HealthStatus_UNHEALTHY {
+		health.status = proto.HealthStatus_UNHEALTHY
+		health.unhealthySince = now
+	}
+}
+
+func (health *gameserverHealth) needsRestart(check *proto.HealthCheck, now time.Time) bool {
+	return health.status == proto.HealthStatus_UNHEALTHY &&
+		check.RestartAfter > 0 &&
+		now.Sub(health.unhealthySince) >= time.Duration(check.RestartAfter)*time.Second
+}
+
+// gameserverHealthChecks holds the health of all gameservers
+type gameserverHealthChecks struct {
+	mutex  sync.Mutex
+	health map[string]*gameserverHealth
+}
+
+func newGameserverHealthChecks() *gameserverHealthChecks {
+	return &gameserverHealthChecks{
+		health: make(map[string]*gameserverHealth),
+	}
+}
+
+func (checks *gameserverHealthChecks) status(uuid string) proto.HealthStatus {
+	checks.mutex.Lock()
+	defer checks.mutex.Unlock()
+
+	if health, ok := checks.health[uuid]; ok {
+		return health.status
+	}
+	return proto.HealthStatus_HEALTH_UNKNOWN
+}
+
+func (checks *gameserverHealthChecks) get(uuid string, now time.Time) *gameserverHealth {
+	health, ok := checks.health[uuid]
+	if !ok {
+		health = &gameserverHealth{
+			status:    proto.HealthStatus_STARTING,
+			startedAt: now,
+		}
+		checks.health[uuid] = health
+	}
+	return health
+}
+
+func (checks *gameserverHealthChecks) remove(uuid string) {
+	checks.mutex.Lock()
+	defer checks.mutex.Unlock()
+	delete(checks.health, uuid)
+}
+
+// checkGameserversHealth updates the health of every running gameserver,
+// which declares a health check and restarts the ones unhealthy for too long
+func (manager *GameserverManager) checkGameserversHealth(deployments []*proto.GameserverDeployment, containers []Container) {
+	for _, deployment := range deployments {
+		check := deployment.HealthCheck
+		if check == nil || check.Type == proto.HealthCheckType_HEALTHCHECK_NONE {
+			continue
+		}
+
+		cont := findGameserverContainer(containers, deployment.UUID)
+		if cont == nil || cont.State != ContainerRunning {
+			manager.healthChecks.remove(deployment.UUID)
+			continue
+		}
+
+		manager.checkGameserverHealth(deployment, cont)
+	}
+}
+
+func (manager *GameserverManager) checkGameserverHealth(deployment *proto.GameserverDeployment, cont *Container) {
+	checks := manager.healthChecks
+	check := deployment.HealthCheck
+	now := time.Now()
+
+	checks.mutex.Lock()
+	defer checks.mutex.Unlock()
+
+	health := checks.get(deployment.UUID, now)
+
+	if health.needsRestart(check, now) {
+		log.Printf("Restarting unhealthy gameserver %s", deployment.UUID)
+		delete(checks.health, deployment.UUID)
+
+		containerID := cont.ID
+		go func() {
+			ctx := context.Background()
+			timeout := healthCheckDuration(check.Timeout, defaultHealthCheckTimeout)
+			err := manager.runtime.Stop(ctx, containerID, timeout)
+			if err == nil {
+				err = manager.runtime.Start(ctx, containerID)
+			}
+			if err != nil {
+				log.Printf("Cannot restart gameserver %s: %s", deployment.UUID, err)
+			}
+		}()
+		return
+	}
+
+	if check.Type == proto.HealthCheckType_HEALTHCHECK_EXEC {
+		if status := cont.Health; status != proto.HealthStatus_HEALTH_UNKNOWN && status != health.status {
+			health.status = status
+			if status == proto.HealthStatus_UNHEALTHY {
+				health.unhealthySince = now
+			}
+		}
+		return
+	}
+
+	interval := healthCheckDuration(check.Interval, defaultHealthCheckInterval)
+	if health.checking || now.Sub(health.lastCheck) < interval {
+		return
+	}
+	health.checking = true
+	health.lastCheck = now
+
+	address := net.JoinHostPort(containerIPAddress(cont), fmt.Sprintf("%d", check.Port))
+	go func() {
+		err := probeGameserver(check, address)
+
+		checks.mutex.Lock()
+		defer checks.mutex.Unlock()
+
+		health.checking = false
+		health.update(check, err == nil, time.Now())
+	}()
+}
+
+func probeGameserver(check *proto.HealthCheck, address string) error {
+	timeout := healthCheckDuration(check.Timeout, defaultHealthCheckTimeout)
+
+	switch check.Type {
+	case proto.HealthCheckType_HEALTHCHECK_TCP:
+		return probeTCP(address, timeout)
+	case proto.HealthCheckType_HEALTHCHECK_UDP:
+		return probeUDP(address, []byte(check.Payload), timeout)
+	}
+	return fmt.Errorf("Unsupported health check type %s", check.Type)
+}
+
+func probeTCP(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func probeUDP(address string, payload []byte, timeout time.Duration) error {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := conn.Write(payload); err != nil {
+		return err
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errors.New("Empty response")
+	}
+	return nil
+}
+
+func healthCheckDuration(seconds int64, defaultDuration time.Duration) time.Duration {
+	if seconds == 0 {
+		return defaultDuration
+	}
+	return time.Duration(seconds) * time.Second
+}
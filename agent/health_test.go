@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameserverHealthUpdate(t *testing.T) {
+	check := &proto.HealthCheck{
+		StartPeriod:  60,
+		Retries:      2,
+		RestartAfter: 30,
+	}
+	startedAt := time.Now()
+	health := &gameserverHealth{
+		status:    proto.HealthStatus_STARTING,
+		startedAt: startedAt,
+	}
+
+	health.update(check, false, startedAt.Add(10*time.Second))
+	assert.Equal(t, proto.HealthStatus_STARTING, health.status)
+	assert.Equal(t, 0, health.failingStreak)
+
+	health.update(check, true, startedAt.Add(20*time.Second))
+	assert.Equal(t, proto.HealthStatus_HEALTHY, health.status)
+
+	health.update(check, false, startedAt.Add(30*time.Second))
+	assert.Equal(t, proto.HealthStatus_HEALTHY, health.status)
+	assert.Equal(t, 1, health.failingStreak)
+
+	unhealthyAt := startedAt.Add(40 * time.Second)
+	health.update(check, false, unhealthyAt)
+	assert.Equal(t, proto.HealthStatus_UNHEALTHY, health.status)
+	assert.False(t, health.needsRestart(check, unhealthyAt.Add(10*time.Second)))
+	assert.True(t, health.needsRestart(check, unhealthyAt.Add(30*time.Second)))
+}
+
+func TestParseDockerHealthStatus(t *testing.T) {
+	status, ok := parseDockerHealthStatus("Up 5 minutes (healthy)")
+	assert.True(t, ok)
+	assert.Equal(t, proto.HealthStatus_HEALTHY, status)
+
+	status, ok = parseDockerHealthStatus("Up 10 seconds (health: starting)")
+	assert.True(t, ok)
+	assert.Equal(t, proto.HealthStatus_STARTING, status)
+
+	_, ok = parseDockerHealthStatus("Up 5 minutes")
+	assert.False(t, ok)
+}
+
+func TestProbeTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := ln.Addr().String()
+
+	assert.Nil(t, probeTCP(address, time.Second))
+
+	ln.Close()
+	assert.NotNil(t, probeTCP(address, time.Second))
+}
+
+func TestProbeUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	go func() {
+		buf := make([]byte, 1500)
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		conn.WriteTo(buf[:n], addr)
+	}()
+
+	assert.Nil(t, probeUDP(conn.LocalAddr().String(), []byte("ping"), time.Second))
+}
@@ -0,0 +1,298 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+const (
+	defaultImagePullWorkers = 2
+	imagePullResultTTL      = 5 * time.Minute
+	imagePullRetryDelay     = time.Minute
+)
+
+type layerProgress struct {
+	current  int64
+	total    int64
+	complete bool
+}
+
+// imagePull holds the progress of a single image pull
+type imagePull struct {
+	image      string
+	policy     proto.ImagePullPolicy
+	layers     map[string]*layerProgress
+	done       bool
+	err        error
+	finishedAt time.Time
+}
+
+// imagePullStatus is a snapshot of an imagePull
+type imagePullStatus struct {
+	done     bool
+	progress float64
+	err      error
+}
+
+func newImagePull(image string, policy proto.ImagePullPolicy) *imagePull {
+	return &imagePull{
+		image:  image,
+		policy: policy,
+		layers: make(map[string]*layerProgress),
+	}
+}
+
+// apply updates the layer progress
+func (pull *imagePull) apply(progress PullProgress) {
+	if progress.Layer == "" {
+		return
+	}
+
+	layer, ok := pull.layers[progress.Layer]
+	if !ok {
+		layer = &layerProgress{}
+		pull.layers[progress.Layer] = layer
+	}
+
+	switch progress.Status {
+	case "Downloading":
+		layer.current = progress.Current
+		layer.total = progress.Total
+	case "Download complete", "Pull complete", "Already exists":
+		layer.complete = true
+	}
+}
+
+// progress returns the percent of downloaded bytes of all layers
+func (pull *imagePull) progress() float64 {
+	if pull.done && pull.err == nil {
+		return 100
+	}
+
+	var current, total int64
+	for _, layer := range pull.layers {
+		if layer.total == 0 {
+			continue
+		}
+		total += layer.total
+		if layer.complete {
+			current += layer.total
+		} else {
+			current += layer.current
+		}
+	}
+
+	if total == 0 {
+		return 0
+	}
+	return float64(current) * 100 / float64(total)
+}
+
+func (pull *imagePull) expired(now time.Time) bool {
+	if !pull.done {
+		return false
+	}
+	if pull.err != nil {
+		return now.Sub(pull.finishedAt) >= imagePullRetryDelay
+	}
+	return now.Sub(pull.finishedAt) >= imagePullResultTTL
+}
+
+// imagePuller pulls images in a pool of background workers. Concurrent
+// requests for the same image share a single pull
+type imagePuller struct {
+	runtime    Runtime
+	registries map[string]RegistryCredentials
+	mutex      sync.Mutex
+	pulls      map[string]*imagePull
+	waiting    map[string]string
+	digests    map[string]string
+	queue      chan *imagePull
+}
+
+func newImagePuller(runtime Runtime, workers int, registries []RegistryCredentials) *imagePuller {
+	if workers <= 0 {
+		workers = defaultImagePullWorkers
+	}
+
+	puller := &imagePuller{
+		runtime:    runtime,
+		registries: make(map[string]RegistryCredentials),
+		pulls:      make(map[string]*imagePull),
+		waiting:    make(map[string]string),
+		digests:    make(map[string]string),
+		queue:      make(chan *imagePull),
+	}
+
+	for _, credentials := range registries {
+		puller.registries[credentials.Host] = credentials
+	}
+
+	for i := 0; i < workers; i++ {
+		go puller.worker()
+	}
+	return puller
+}
+
+// request returns the pull status of the gameserver image and schedules
+// a pull, if the image isn't pulled yet
+func (puller *imagePuller) request(uuid, image string, policy proto.ImagePullPolicy) imagePullStatus {
+	puller.mutex.Lock()
+	defer puller.mutex.Unlock()
+
+	puller.waiting[uuid] = image
+
+	pull, ok := puller.pulls[image]
+	if !ok || pull.expired(time.Now()) {
+		pull = newImagePull(image, policy)
+		puller.pulls[image] = pull
+		go func() {
+			puller.queue <- pull
+		}()
+	}
+
+	return imagePullStatus{
+		done:     pull.done,
+		progress: pull.progress(),
+		err:      pull.err,
+	}
+}
+
+// isWaiting tells, if the gameserver waits for its image
+func (puller *imagePuller) isWaiting(uuid string) bool {
+	puller.mutex.Lock()
+	defer puller.mutex.Unlock()
+
+	_, ok := puller.waiting[uuid]
+	return ok
+}
+
+// release stops tracking the image of the gameserver and tells,
+// if the gameserver was waiting for it
+func (puller *imagePuller) release(uuid string) bool {
+	puller.mutex.Lock()
+	defer puller.mutex.Unlock()
+
+	_, ok := puller.waiting[uuid]
+	delete(puller.waiting, uuid)
+	return ok
+}
+
+// retain stops tracking the images of gameservers, which aren't deployed anymore
+func (puller *imagePuller) retain(uuids map[string]bool) {
+	puller.mutex.Lock()
+	defer puller.mutex.Unlock()
+
+	for uuid := range puller.waiting {
+		if !uuids[uuid] {
+			delete(puller.waiting, uuid)
+		}
+	}
+}
+
+// gameservers returns the gameservers waiting for their images
+func (puller *imagePuller) gameservers() []*proto.Gameserver {
+	puller.mutex.Lock()
+	defer puller.mutex.Unlock()
+
+	gameservers := make([]*proto.Gameserver, 0, len(puller.waiting))
+	for uuid, image := range puller.waiting {
+		pull, ok := puller.pulls[image]
+		if !ok {
+			continue
+		}
+
+		gameserver := &proto.Gameserver{
+			UUID:         uuid,
+			Status:       proto.GameserverStatus_PULLING_IMAGE,
+			PullProgress: pull.progress(),
+		}
+		if pull.err != nil {
+			gameserver.Status = proto.GameserverStatus_ERROR
+			gameserver.Info = pull.err.Error()
+		}
+		gameservers = append(gameservers, gameserver)
+	}
+	return gameservers
+}
+
+func (puller *imagePuller) worker() {
+	for pull := range puller.queue {
+		log.Printf("Pulling image %s...", pull.image)
+		err := puller.pullImage(pull)
+
+		puller.mutex.Lock()
+		pull.done = true
+		pull.err = err
+		pull.finishedAt = time.Now()
+		puller.mutex.Unlock()
+
+		if err != nil {
+			log.Printf("Cannot pull image %s: %s", pull.image, err)
+		} else {
+			log.Printf("Pulled image %s", pull.image)
+		}
+	}
+}
+
+func (puller *imagePuller) pullImage(pull *imagePull) error {
+	ctx := context.Background()
+
+	if pull.policy != proto.ImagePullPolicy_PULL_ALWAYS {
+		_, err := puller.runtime.InspectImage(ctx, pull.image)
+		if err == nil {
+			return nil
+		}
+		if err != ErrImageNotFound {
+			return err
+		}
+		if pull.policy == proto.ImagePullPolicy_PULL_NEVER {
+			return fmt.Errorf("Image %s is not present and the pull policy is Never", pull.image)
+		}
+	}
+
+	var credentials *RegistryCredentials
+	if registry, ok := puller.registries[imageRegistry(pull.image)]; ok {
+		credentials = &registry
+	}
+
+	return puller.runtime.PullImage(ctx, pull.image, credentials, func(progress PullProgress) {
+		puller.mutex.Lock()
+		defer puller.mutex.Unlock()
+		pull.apply(progress)
+	})
+}
+
+// imageDigest returns the repository digest of a local image
+func (puller *imagePuller) imageDigest(imageID string) string {
+	puller.mutex.Lock()
+	digest, ok := puller.digests[imageID]
+	puller.mutex.Unlock()
+	if ok {
+		return digest
+	}
+
+	image, err := puller.runtime.InspectImage(context.Background(), imageID)
+	if err != nil {
+		log.Printf("Cannot inspect image %s: %s", imageID, err)
+		return ""
+	}
+
+	for _, repoDigest := range image.RepoDigests {
+		if i := strings.Index(repoDigest, "@"); i >= 0 {
+			digest = repoDigest[i+1:]
+			break
+		}
+	}
+
+	puller.mutex.Lock()
+	puller.digests[imageID] = digest
+	puller.mutex.Unlock()
+	return digest
+}
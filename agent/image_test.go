@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func waitForPull(t *testing.T, puller *imagePuller, uuid, image string) imagePullStatus {
+	return waitForPullWithPolicy(t, puller, uuid, image, proto.ImagePullPolicy_PULL_ALWAYS)
+}
+
+func waitForPullWithPolicy(t *testing.T, puller *imagePuller, uuid, image string, policy proto.ImagePullPolicy) imagePullStatus {
+	for i := 0; i < 100; i++ {
+		status := puller.request(uuid, image, policy)
+		if status.done {
+			return status
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("image pull did not finish")
+	return imagePullStatus{}
+}
+
+func TestImagePullProgress(t *testing.T) {
+	pull := newImagePull("minecraft", proto.ImagePullPolicy_PULL_ALWAYS)
+
+	updates := []PullProgress{
+		{Layer: "layer1", Status: "Pulling fs layer"},
+		{Layer: "layer2", Status: "Pulling fs layer"},
+		{Layer: "layer1", Status: "Downloading", Current: 50, Total: 200},
+		{Layer: "layer2", Status: "Download complete"},
+	}
+	for _, update := range updates {
+		pull.apply(update)
+	}
+	assert.Equal(t, 25.0, pull.progress())
+
+	pull.apply(PullProgress{Layer: "layer1", Status: "Downloading", Current: 150, Total: 200})
+	assert.Equal(t, 75.0, pull.progress())
+}
+
+func TestImagePullDeduplication(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.pullBlock = make(chan struct{})
+	runtime.pullProgress = []PullProgress{
+		{Layer: "layer1", Status: "Downloading", Current: 10, Total: 10},
+	}
+	puller := newImagePuller(runtime, 2, nil)
+
+	first := puller.request("gs1", "minecraft", proto.ImagePullPolicy_PULL_ALWAYS)
+	second := puller.request("gs2", "minecraft", proto.ImagePullPolicy_PULL_ALWAYS)
+	assert.False(t, first.done)
+	assert.False(t, second.done)
+
+	gameservers := puller.gameservers()
+	assert.Len(t, gameservers, 2)
+	assert.Equal(t, proto.GameserverStatus_PULLING_IMAGE, gameservers[0].Status)
+
+	close(runtime.pullBlock)
+	status := waitForPull(t, puller, "gs1", "minecraft")
+
+	assert.NoError(t, status.err)
+	assert.Equal(t, 100.0, status.progress)
+	assert.Equal(t, []string{"minecraft"}, runtime.pulls)
+
+	assert.True(t, puller.release("gs1"))
+	assert.False(t, puller.release("gs1"))
+}
+
+func TestImagePullError(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.pullErr = errors.New("manifest for minecraft:foo not found")
+	puller := newImagePuller(runtime, 1, nil)
+
+	status := waitForPull(t, puller, "gs1", "minecraft:foo")
+	assert.EqualError(t, status.err, "manifest for minecraft:foo not found")
+
+	gameservers := puller.gameservers()
+	assert.Len(t, gameservers, 1)
+	assert.Equal(t, proto.GameserverStatus_ERROR, gameservers[0].Status)
+	assert.Equal(t, "manifest for minecraft:foo not found", gameservers[0].Info)
+}
+
+func TestImagePullPolicyIfNotPresent(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.images["minecraft"] = &Image{ID: "sha256:1234"}
+	puller := newImagePuller(runtime, 1, nil)
+
+	status := waitForPullWithPolicy(t, puller, "gs1", "minecraft", proto.ImagePullPolicy_PULL_IF_NOT_PRESENT)
+	assert.NoError(t, status.err)
+	assert.Empty(t, runtime.pulls)
+
+	status = waitForPullWithPolicy(t, puller, "gs2", "factorio", proto.ImagePullPolicy_PULL_IF_NOT_PRESENT)
+	assert.NoError(t, status.err)
+	assert.Equal(t, []string{"factorio"}, runtime.pulls)
+}
+
+func TestImagePullPolicyNever(t *testing.T) {
+	runtime := newFakeRuntime()
+	puller := newImagePuller(runtime, 1, nil)
+
+	status := waitForPullWithPolicy(t, puller, "gs1", "minecraft", proto.ImagePullPolicy_PULL_NEVER)
+	assert.EqualError(t, status.err, "Image minecraft is not present and the pull policy is Never")
+	assert.Empty(t, runtime.pulls)
+}
+
+func TestImagePullRegistryCredentials(t *testing.T) {
+	runtime := newFakeRuntime()
+	credentials := RegistryCredentials{Host: "registry.example.com", Username: "user", Password: "secret"}
+	puller := newImagePuller(runtime, 1, []RegistryCredentials{credentials})
+
+	waitForPull(t, puller, "gs1", "registry.example.com/games/minecraft:1.14")
+	assert.Equal(t, &credentials, runtime.pullAuth)
+
+	waitForPull(t, puller, "gs2", "itzg/minecraft-server")
+	assert.Nil(t, runtime.pullAuth)
+}
+
+func TestImageDigest(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.images["sha256:1234"] = &Image{
+		ID:          "sha256:1234",
+		RepoDigests: []string{"itzg/minecraft-server@sha256:abcd"},
+	}
+	puller := newImagePuller(runtime, 1, nil)
+
+	assert.Equal(t, "sha256:abcd", puller.imageDigest("sha256:1234"))
+}
@@ -0,0 +1,258 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+const volumeChunkSize = 1 << 20
+
+// VolumeTransfer moves the volume archives of migrated gameservers
+// between the agents and reports the migration tasks
+type VolumeTransfer interface {
+	Upload(operationID, volume string, content io.Reader) error
+	Download(operationID, volume string, content io.Writer) error
+	Report(result *proto.MigrationTaskResult) error
+}
+
+// grpcVolumeTransfer streams the volumes through the server
+type grpcVolumeTransfer struct {
+	client proto.AgentServiceClient
+}
+
+// NewGRPCVolumeTransfer creates a VolumeTransfer using the server gRPC API
+func NewGRPCVolumeTransfer(client proto.AgentServiceClient) VolumeTransfer {
+	return &grpcVolumeTransfer{client}
+}
+
+func (transfer *grpcVolumeTransfer) Upload(operationID, volume string, content io.Reader) error {
+	stream, err := transfer.client.UploadVolume(context.Background())
+	if err != nil {
+		return err
+	}
+
+	buffer := make([]byte, volumeChunkSize)
+	for {
+		n, err := content.Read(buffer)
+		if n > 0 {
+			chunk := &proto.VolumeChunk{
+				OperationId: operationID,
+				Volume:      volume,
+				Data:        buffer[:n],
+			}
+			if err := stream.Send(chunk); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			stream.CloseSend()
+			return err
+		}
+	}
+
+	_, err = stream.CloseAndRecv()
+	return err
+}
+
+func (transfer *grpcVolumeTransfer) Download(operationID, volume string, content io.Writer) error {
+	stream, err := transfer.client.DownloadVolume(context.Background(), &proto.VolumeRequest{
+		OperationId: operationID,
+		Volume:      volume,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if _, err := content.Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+}
+
+func (transfer *grpcVolumeTransfer) Report(result *proto.MigrationTaskResult) error {
+	_, err := transfer.client.ReportMigrationTask(context.Background(), result)
+	return err
+}
+
+// migrationTasks remembers the tasks already started, so they run once
+type migrationTasks struct {
+	mutex   sync.Mutex
+	started map[string]bool
+}
+
+func newMigrationTasks() *migrationTasks {
+	return &migrationTasks{started: make(map[string]bool)}
+}
+
+func migrationTaskKey(task *proto.MigrationTask) string {
+	return fmt.Sprintf("%s/%s", task.OperationId, task.Type)
+}
+
+func (tasks *migrationTasks) start(task *proto.MigrationTask) bool {
+	tasks.mutex.Lock()
+	defer tasks.mutex.Unlock()
+
+	key := migrationTaskKey(task)
+	if tasks.started[key] {
+		return false
+	}
+	tasks.started[key] = true
+	return true
+}
+
+// retain forgets the tasks, which are not requested by the server anymore
+func (tasks *migrationTasks) retain(current []*proto.MigrationTask) {
+	tasks.mutex.Lock()
+	defer tasks.mutex.Unlock()
+
+	keys := make(map[string]bool, len(current))
+	for _, task := range current {
+		keys[migrationTaskKey(task)] = true
+	}
+	for key := range tasks.started {
+		if !keys[key] {
+			delete(tasks.started, key)
+		}
+	}
+}
+
+// importDeployments returns the deployments of the imported gameservers,
+// as stopped deployments, so their containers are not removed
+// before the migration switches them to this agent
+func importDeployments(deployments []*proto.GameserverDeployment, tasks []*proto.MigrationTask) []*proto.GameserverDeployment {
+	deployed := make(map[string]bool, len(deployments))
+	for _, deployment := range deployments {
+		deployed[deployment.UUID] = true
+	}
+
+	for _, task := range tasks {
+		if task.Type != proto.MigrationTaskType_MIGRATION_IMPORT || deployed[task.Deployment.UUID] {
+			continue
+		}
+		deployment := *task.Deployment
+		deployment.Stopped = true
+		deployments = append(deployments, &deployment)
+	}
+	return deployments
+}
+
+// runMigrationTasks starts the migration tasks in the background.
+// Imports wait for the image of the gameserver to be pulled
+func (manager *GameserverManager) runMigrationTasks(tasks []*proto.MigrationTask, containers []Container) {
+	manager.migrations.retain(tasks)
+	if manager.transfer == nil {
+		return
+	}
+
+	for _, task := range tasks {
+		uuid := task.Deployment.UUID
+		importing := task.Type == proto.MigrationTaskType_MIGRATION_IMPORT
+		if importing && findGameserverContainer(containers, uuid) == nil && !manager.imageReady(task.Deployment) {
+			continue
+		}
+
+		if !manager.locks.tryLock(uuid) {
+			continue
+		}
+		if !manager.migrations.start(task) {
+			manager.locks.unlock(uuid)
+			continue
+		}
+
+		go manager.runMigrationTask(task)
+	}
+}
+
+func (manager *GameserverManager) runMigrationTask(task *proto.MigrationTask) {
+	uuid := task.Deployment.UUID
+	defer manager.locks.unlock(uuid)
+
+	var err error
+	if task.Type == proto.MigrationTaskType_MIGRATION_EXPORT {
+		log.Printf("Exporting volumes of gameserver %s...", uuid)
+		err = manager.exportVolumes(task)
+	} else {
+		log.Printf("Importing volumes of gameserver %s...", uuid)
+		err = manager.importVolumes(task)
+	}
+
+	result := &proto.MigrationTaskResult{
+		OperationId: task.OperationId,
+		Type:        task.Type,
+		Success:     err == nil,
+	}
+	if err != nil {
+		log.Printf("Migration task %s of gameserver %s failed: %v", task.Type, uuid, err)
+		result.Error = err.Error()
+	}
+
+	if err := manager.transfer.Report(result); err != nil {
+		log.Printf("Cannot report migration task of gameserver %s: %v", uuid, err)
+	}
+}
+
+func (manager *GameserverManager) exportVolumes(task *proto.MigrationTask) error {
+	ctx := context.Background()
+	uuid := task.Deployment.UUID
+
+	cont, err := manager.runtime.Inspect(ctx, uuid)
+	if err != nil {
+		return err
+	}
+	if cont.State == ContainerRunning {
+		return fmt.Errorf("Gameserver %s is still running", uuid)
+	}
+
+	for _, volume := range task.Deployment.Volumes {
+		content, err := manager.runtime.ExportVolume(ctx, uuid, volume.Path)
+		if err != nil {
+			return err
+		}
+		err = manager.transfer.Upload(task.OperationId, volume.Name, content)
+		content.Close()
+		if err != nil {
+			return fmt.Errorf("Cannot upload volume %s: %v", volume.Name, err)
+		}
+	}
+	return nil
+}
+
+func (manager *GameserverManager) importVolumes(task *proto.MigrationTask) error {
+	ctx := context.Background()
+	uuid := task.Deployment.UUID
+
+	if _, err := manager.runtime.Inspect(ctx, uuid); err != nil {
+		defer manager.images.release(uuid)
+		if _, err := manager.createGameserverContainer(task.Deployment); err != nil {
+			return err
+		}
+	}
+
+	for _, volume := range task.Deployment.Volumes {
+		reader, writer := io.Pipe()
+		go func(name string) {
+			writer.CloseWithError(manager.transfer.Download(task.OperationId, name, writer))
+		}(volume.Name)
+
+		err := manager.runtime.ImportVolume(ctx, uuid, volume.Path, reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("Cannot import volume %s: %v", volume.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeVolumeTransfer keeps the uploaded volumes in memory
+type fakeVolumeTransfer struct {
+	mutex   sync.Mutex
+	volumes map[string][]byte
+	results []*proto.MigrationTaskResult
+}
+
+func newFakeVolumeTransfer() *fakeVolumeTransfer {
+	return &fakeVolumeTransfer{volumes: make(map[string][]byte)}
+}
+
+func (transfer *fakeVolumeTransfer) Upload(operationID, volume string, content io.Reader) error {
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	transfer.mutex.Lock()
+	defer transfer.mutex.Unlock()
+	transfer.volumes[operationID+"/"+volume] = data
+	return nil
+}
+
+func (transfer *fakeVolumeTransfer) Download(operationID, volume string, content io.Writer) error {
+	transfer.mutex.Lock()
+	data := transfer.volumes[operationID+"/"+volume]
+	transfer.mutex.Unlock()
+
+	_, err := io.Copy(content, bytes.NewReader(data))
+	return err
+}
+
+func (transfer *fakeVolumeTransfer) Report(result *proto.MigrationTaskResult) error {
+	transfer.mutex.Lock()
+	defer transfer.mutex.Unlock()
+	transfer.results = append(transfer.results, result)
+	return nil
+}
+
+func (transfer *fakeVolumeTransfer) reported() []*proto.MigrationTaskResult {
+	transfer.mutex.Lock()
+	defer transfer.mutex.Unlock()
+	return transfer.results
+}
+
+func TestMigrateVolumesBetweenAgents(t *testing.T) {
+	transfer := newFakeVolumeTransfer()
+	deployment := &proto.GameserverDeployment{
+		UUID:                 "uuid1",
+		Image:                "minecraft",
+		Stopped:              true,
+		ResourceRequirements: &proto.ResourceRequirements{},
+		Volumes:              []*proto.Volume{{Name: "data", Path: "/data"}},
+	}
+
+	sourceRuntime := newFakeRuntime()
+	runningFakeGameserver(t, sourceRuntime, deployment)
+	sourceRuntime.exit("uuid1", 0)
+	sourceRuntime.volumes["uuid1"] = map[string]string{"/data": "world"}
+	source := NewGameserverManager(sourceRuntime, GameserverManagerConfig{Transfer: transfer})
+
+	source.Tick(&proto.GetGameserverDeploymentsResponse{
+		Deployments: []*proto.GameserverDeployment{deployment},
+		MigrationTasks: []*proto.MigrationTask{
+			{OperationId: "op1", Type: proto.MigrationTaskType_MIGRATION_EXPORT, Deployment: deployment},
+		},
+	})
+	waitForCondition(t, func() bool { return len(transfer.reported()) == 1 })
+	assert.True(t, transfer.reported()[0].Success)
+
+	targetRuntime := newFakeRuntime()
+	target := NewGameserverManager(targetRuntime, GameserverManagerConfig{
+		IPAddresses: []string{"127.0.0.1"},
+		Transfer:    transfer,
+	})
+	config := &proto.GetGameserverDeploymentsResponse{
+		MigrationTasks: []*proto.MigrationTask{
+			{OperationId: "op1", Type: proto.MigrationTaskType_MIGRATION_IMPORT, Deployment: deployment},
+		},
+	}
+
+	waitForCondition(t, func() bool {
+		target.Tick(config)
+		return len(transfer.reported()) == 2
+	})
+	assert.True(t, transfer.reported()[1].Success)
+	assert.Equal(t, proto.MigrationTaskType_MIGRATION_IMPORT, transfer.reported()[1].Type)
+	assert.Equal(t, "world", targetRuntime.volumes["uuid1"]["/data"])
+
+	// the imported gameserver is kept, until the migration switches it
+	target.Tick(config)
+	cont, err := targetRuntime.Inspect(context.Background(), "uuid1")
+	assert.NoError(t, err)
+	assert.Equal(t, ContainerCreated, cont.State)
+	assert.Len(t, transfer.reported(), 2)
+}
+
+func TestExportVolumesOfRunningGameserverFails(t *testing.T) {
+	transfer := newFakeVolumeTransfer()
+	deployment := &proto.GameserverDeployment{
+		UUID:    "uuid1",
+		Volumes: []*proto.Volume{{Name: "data", Path: "/data"}},
+	}
+
+	runtime := newFakeRuntime()
+	runningFakeGameserver(t, runtime, deployment)
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{Transfer: transfer})
+
+	manager.runMigrationTasks([]*proto.MigrationTask{
+		{OperationId: "op1", Type: proto.MigrationTaskType_MIGRATION_EXPORT, Deployment: deployment},
+	}, nil)
+
+	waitForCondition(t, func() bool { return len(transfer.reported()) == 1 })
+	assert.False(t, transfer.reported()[0].Success)
+	assert.Empty(t, transfer.volumes)
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+const defaultPodmanHost = "unix:///run/podman/podman.sock"
+
+var imageIDRegexp = regexp.MustCompile(`^(sha256:)?[a-f0-9]{64}$`)
+
+// PodmanRuntime runs the gameservers with Podman through its
+// Docker compatible API. It handles the differences to Docker:
+// short image names are not resolved by Podman and the container
+// list doesn't always carry the health status
+type PodmanRuntime struct {
+	*DockerRuntime
+}
+
+// NewPodmanRuntime creates a PodmanRuntime instance
+func NewPodmanRuntime(api DockerAPI) *PodmanRuntime {
+	return &PodmanRuntime{NewDockerRuntime(api)}
+}
+
+// List returns all gameserver containers
+func (runtime *PodmanRuntime) List(ctx context.Context) ([]Container, error) {
+	containers, err := runtime.DockerRuntime.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range containers {
+		cont := &containers[i]
+		if cont.State != ContainerRunning || cont.Health != proto.HealthStatus_HEALTH_UNKNOWN {
+			continue
+		}
+
+		inspected, err := runtime.DockerRuntime.Inspect(ctx, cont.ID)
+		if err != nil {
+			return nil, err
+		}
+		cont.Health = inspected.Health
+	}
+	return containers, nil
+}
+
+// Create creates the gameserver container with a fully qualified image name
+func (runtime *PodmanRuntime) Create(ctx context.Context, deployment *proto.GameserverDeployment, ipAddress string) (string, error) {
+	config := createGameserverContainerConfig(deployment, ipAddress)
+	config.Image = qualifyImageName(config.Image)
+
+	cont, err := runtime.api.ContainerCreate(ctx,
+		config,
+		createGameserverHostConfig(deployment, ipAddress),
+		nil,
+		deployment.UUID,
+	)
+	if err != nil {
+		return "", err
+	}
+	return cont.ID, nil
+}
+
+// PullImage pulls the image using its fully qualified name
+func (runtime *PodmanRuntime) PullImage(ctx context.Context, image string, credentials *RegistryCredentials, progress func(PullProgress)) error {
+	return runtime.DockerRuntime.PullImage(ctx, qualifyImageName(image), credentials, progress)
+}
+
+// InspectImage returns the local image or ErrImageNotFound
+func (runtime *PodmanRuntime) InspectImage(ctx context.Context, image string) (*Image, error) {
+	return runtime.DockerRuntime.InspectImage(ctx, qualifyImageName(image))
+}
+
+// qualifyImageName prefixes Docker Hub images with the registry,
+// e.g. minecraft becomes docker.io/library/minecraft
+func qualifyImageName(image string) string {
+	if imageIDRegexp.MatchString(image) || imageRegistry(image) != defaultRegistry ||
+		strings.HasPrefix(image, defaultRegistry+"/") {
+		return image
+	}
+
+	if !strings.Contains(image, "/") {
+		return defaultRegistry + "/library/" + image
+	}
+	return defaultRegistry + "/" + image
+}
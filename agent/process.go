@@ -0,0 +1,843 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+// Defaults of the process runtime
+const (
+	defaultProcessDir         = "/var/lib/chinchilla/gameservers"
+	defaultCgroupRoot         = "/sys/fs/cgroup/chinchilla"
+	defaultProcessStopTimeout = 10 * time.Second
+)
+
+const (
+	processStateFile   = "state.json"
+	processLogFile     = "output.log"
+	processStdinFile   = "stdin"
+	processWorkDir     = "data"
+	processLogMaxSize  = 10 * 1024 * 1024
+	processLogMaxFiles = 3
+
+	// unknownExitCode is reported for adopted processes, which exited
+	// on their own, as only the parent can read the exit status
+	unknownExitCode = -1
+
+	// clockTicks is the USER_HZ used in /proc/<pid>/stat
+	clockTicks = 100
+)
+
+var errProcessNotFound = errors.New("No such gameserver process")
+
+// processState is persisted in the gameserver directory, so the processes
+// can be adopted again after an agent restart
+type processState struct {
+	UUID       string            `json:"uuid"`
+	Command    []string          `json:"command"`
+	Env        []string          `json:"env"`
+	Labels     map[string]string `json:"labels"`
+	IPAddress  string            `json:"ipAddress"`
+	MemoryMax  int64             `json:"memoryMax"`
+	MemoryLow  int64             `json:"memoryLow"`
+	State      string            `json:"state"`
+	PID        int               `json:"pid"`
+	StartTime  uint64            `json:"startTime"`
+	ExitCode   int               `json:"exitCode"`
+	FinishedAt string            `json:"finishedAt"`
+}
+
+type cpuSample struct {
+	usage time.Duration
+	time  time.Time
+}
+
+// ProcessRuntime runs the gameservers as plain processes on the host.
+// Every gameserver has its own directory with the state file, the output
+// logs and the working directory of the process
+type ProcessRuntime struct {
+	dir    string
+	cgroup *cgroupV2
+
+	mutex     sync.Mutex
+	processes map[string]*processState
+	exited    map[string]chan struct{}
+	samples   map[string]cpuSample
+}
+
+// NewProcessRuntime creates a ProcessRuntime instance and adopts the processes
+// started before the agent restart. Memory limits are applied only,
+// when cgroups v2 are available
+func NewProcessRuntime(dir, cgroupRoot string) (*ProcessRuntime, error) {
+	if dir == "" {
+		dir = defaultProcessDir
+	}
+	if cgroupRoot == "" {
+		cgroupRoot = defaultCgroupRoot
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	cgroup, err := newCgroupV2(cgroupRoot)
+	if err != nil {
+		log.Printf("Cgroups v2 are not available, memory limits are disabled: %v", err)
+	}
+
+	runtime := &ProcessRuntime{
+		dir:       dir,
+		cgroup:    cgroup,
+		processes: make(map[string]*processState),
+		exited:    make(map[string]chan struct{}),
+		samples:   make(map[string]cpuSample),
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		state, err := runtime.loadState(entry.Name())
+		if err != nil {
+			log.Printf("Cannot load the state of gameserver %s: %v", entry.Name(), err)
+			continue
+		}
+		runtime.processes[state.UUID] = state
+	}
+
+	return runtime, nil
+}
+
+func (runtime *ProcessRuntime) path(id string, elem ...string) string {
+	return filepath.Join(append([]string{runtime.dir, id}, elem...)...)
+}
+
+func (runtime *ProcessRuntime) loadState(id string) (*processState, error) {
+	data, err := ioutil.ReadFile(runtime.path(id, processStateFile))
+	if err != nil {
+		return nil, err
+	}
+
+	state := &processState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// saveState writes the state file atomically
+func (runtime *ProcessRuntime) saveState(state *processState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	path := runtime.path(state.UUID, processStateFile)
+	if err := ioutil.WriteFile(path+".tmp", data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// refresh detects, if an adopted process has exited. Supervised
+// processes are updated by their wait goroutine
+func (runtime *ProcessRuntime) refresh(state *processState) {
+	if state.State != ContainerRunning {
+		return
+	}
+	if _, supervised := runtime.exited[state.UUID]; supervised {
+		return
+	}
+	if processAlive(state.PID, state.StartTime) {
+		return
+	}
+
+	runtime.markExited(state, unknownExitCode)
+}
+
+func (runtime *ProcessRuntime) markExited(state *processState, exitCode int) {
+	state.State = ContainerExited
+	state.ExitCode = exitCode
+	state.FinishedAt = time.Now().Format(time.RFC3339Nano)
+
+	if runtime.cgroup != nil {
+		if err := runtime.cgroup.remove(state.UUID); err != nil {
+			log.Printf("Cannot remove the cgroup of gameserver %s: %v", state.UUID, err)
+		}
+	}
+	if err := runtime.saveState(state); err != nil {
+		log.Printf("Cannot save the state of gameserver %s: %v", state.UUID, err)
+	}
+}
+
+func (runtime *ProcessRuntime) container(state *processState) Container {
+	return Container{
+		ID:         state.UUID,
+		UUID:       state.UUID,
+		State:      state.State,
+		Labels:     state.Labels,
+		IPAddress:  state.IPAddress,
+		ExitCode:   state.ExitCode,
+		FinishedAt: state.FinishedAt,
+	}
+}
+
+// List returns all gameserver processes and rotates their logs
+func (runtime *ProcessRuntime) List(ctx context.Context) ([]Container, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	containers := make([]Container, 0, len(runtime.processes))
+	for _, state := range runtime.processes {
+		runtime.refresh(state)
+
+		if state.State == ContainerRunning {
+			if err := rotateLogFile(runtime.path(state.UUID, processLogFile), processLogMaxSize, processLogMaxFiles); err != nil {
+				log.Printf("Cannot rotate the logs of gameserver %s: %v", state.UUID, err)
+			}
+		}
+
+		containers = append(containers, runtime.container(state))
+	}
+	return containers, nil
+}
+
+// Inspect returns the gameserver process with its exit status
+func (runtime *ProcessRuntime) Inspect(ctx context.Context, id string) (*Container, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	state, ok := runtime.processes[id]
+	if !ok {
+		return nil, errProcessNotFound
+	}
+	runtime.refresh(state)
+
+	cont := runtime.container(state)
+	return &cont, nil
+}
+
+// Create prepares the gameserver directory. The process is started with
+// the command of the deployment in its working directory
+func (runtime *ProcessRuntime) Create(ctx context.Context, deployment *proto.GameserverDeployment, ipAddress string) (string, error) {
+	if len(deployment.Command) == 0 {
+		return "", errors.New("The process runtime requires a command in the deployment")
+	}
+
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if _, ok := runtime.processes[deployment.UUID]; ok {
+		return "", fmt.Errorf("Gameserver %s already exists", deployment.UUID)
+	}
+
+	workDir := runtime.path(deployment.UUID, processWorkDir)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "", err
+	}
+
+	env := []string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + workDir,
+		"CHINCHILLA_GAMESERVER_UUID=" + deployment.UUID,
+		"CHINCHILLA_IP_ADDRESS=" + ipAddress,
+	}
+	for _, variable := range deployment.Environment {
+		env = append(env, fmt.Sprintf("%s=%s", variable.Name, variable.Value))
+	}
+
+	state := &processState{
+		UUID:      deployment.UUID,
+		Command:   deployment.Command,
+		Env:       env,
+		Labels:    containerLabels(deployment, ipAddress),
+		IPAddress: ipAddress,
+		State:     ContainerCreated,
+	}
+	if requirements := deployment.ResourceRequirements; requirements != nil {
+		state.MemoryMax = requirements.MemoryLimit * 1024
+		state.MemoryLow = requirements.MemoryReservation * 1024
+	}
+
+	if err := runtime.saveState(state); err != nil {
+		return "", err
+	}
+	runtime.processes[state.UUID] = state
+	return state.UUID, nil
+}
+
+// Start starts the gameserver process in its own process group. The output
+// goes directly to the log file, so the process doesn't depend on the agent
+func (runtime *ProcessRuntime) Start(ctx context.Context, id string) error {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	state, ok := runtime.processes[id]
+	if !ok {
+		return errProcessNotFound
+	}
+	runtime.refresh(state)
+	if state.State == ContainerRunning {
+		return nil
+	}
+
+	logFile, err := os.OpenFile(runtime.path(id, processLogFile), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer logFile.Close()
+
+	stdin, err := openProcessStdin(runtime.path(id, processStdinFile))
+	if err != nil {
+		return err
+	}
+	defer stdin.Close()
+
+	cmd := exec.Command(state.Command[0], state.Command[1:]...)
+	cmd.Dir = runtime.path(id, processWorkDir)
+	cmd.Env = state.Env
+	cmd.Stdin = stdin
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if runtime.cgroup != nil {
+		if err := runtime.cgroup.create(id, state.MemoryMax, state.MemoryLow); err != nil {
+			return err
+		}
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	pid := cmd.Process.Pid
+	if runtime.cgroup != nil {
+		if err := runtime.cgroup.addProcess(id, pid); err != nil {
+			log.Printf("Cannot move gameserver %s to its cgroup: %v", id, err)
+		}
+	}
+
+	state.State = ContainerRunning
+	state.PID = pid
+	state.StartTime, _ = processStartTime(pid)
+	state.ExitCode = 0
+	state.FinishedAt = ""
+	if err := runtime.saveState(state); err != nil {
+		log.Printf("Cannot save the state of gameserver %s: %v", id, err)
+	}
+
+	exited := make(chan struct{})
+	runtime.exited[id] = exited
+	go runtime.wait(id, cmd, exited)
+
+	return nil
+}
+
+// wait reaps the supervised process and records its exit code
+func (runtime *ProcessRuntime) wait(id string, cmd *exec.Cmd, exited chan struct{}) {
+	cmd.Wait()
+
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if state, ok := runtime.processes[id]; ok && state.PID == cmd.Process.Pid {
+		runtime.markExited(state, processExitCode(cmd.ProcessState))
+	}
+	if runtime.exited[id] == exited {
+		delete(runtime.exited, id)
+	}
+	close(exited)
+}
+
+// Stop sends SIGTERM to the process group and SIGKILL after the timeout.
+// A zero timeout uses the default of 10 seconds
+func (runtime *ProcessRuntime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = defaultProcessStopTimeout
+	}
+
+	runtime.mutex.Lock()
+	state, ok := runtime.processes[id]
+	if !ok {
+		runtime.mutex.Unlock()
+		return errProcessNotFound
+	}
+	runtime.refresh(state)
+	if state.State != ContainerRunning {
+		runtime.mutex.Unlock()
+		return nil
+	}
+	pid, startTime, exited := state.PID, state.StartTime, runtime.exited[id]
+	runtime.mutex.Unlock()
+
+	signal := syscall.SIGTERM
+	syscall.Kill(-pid, signal)
+	if !waitForProcessExit(pid, startTime, exited, timeout) {
+		signal = syscall.SIGKILL
+		syscall.Kill(-pid, signal)
+		if !waitForProcessExit(pid, startTime, exited, timeout) {
+			return fmt.Errorf("Gameserver %s did not stop", id)
+		}
+	}
+
+	if exited == nil {
+		runtime.mutex.Lock()
+		if state.State == ContainerRunning && state.PID == pid {
+			runtime.markExited(state, 128+int(signal))
+		}
+		runtime.mutex.Unlock()
+	}
+	return nil
+}
+
+// Kill sends the signal to the process group
+func (runtime *ProcessRuntime) Kill(ctx context.Context, id, signal string) error {
+	sig, err := parseSignal(signal)
+	if err != nil {
+		return err
+	}
+
+	pid, err := runtime.runningPID(id)
+	if err != nil {
+		return err
+	}
+	return syscall.Kill(-pid, sig)
+}
+
+// SendInput writes a line to the stdin FIFO of the process
+func (runtime *ProcessRuntime) SendInput(ctx context.Context, id, input string) error {
+	if _, err := runtime.runningPID(id); err != nil {
+		return err
+	}
+
+	fifo, err := os.OpenFile(runtime.path(id, processStdinFile), os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	defer fifo.Close()
+
+	_, err = fifo.WriteString(input + "\n")
+	return err
+}
+
+func (runtime *ProcessRuntime) runningPID(id string) (int, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	state, ok := runtime.processes[id]
+	if !ok {
+		return 0, errProcessNotFound
+	}
+	runtime.refresh(state)
+	if state.State != ContainerRunning {
+		return 0, fmt.Errorf("Gameserver %s is not running", id)
+	}
+	return state.PID, nil
+}
+
+// Remove stops the process and removes the gameserver directory
+func (runtime *ProcessRuntime) Remove(ctx context.Context, id string) error {
+	if err := runtime.Stop(ctx, id, 0); err != nil {
+		return err
+	}
+
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	delete(runtime.processes, id)
+	delete(runtime.samples, id)
+	if runtime.cgroup != nil {
+		if err := runtime.cgroup.remove(id); err != nil {
+			log.Printf("Cannot remove the cgroup of gameserver %s: %v", id, err)
+		}
+	}
+	return os.RemoveAll(runtime.path(id))
+}
+
+// Logs returns the last lines of the process output, including
+// the most recently rotated log file
+func (runtime *ProcessRuntime) Logs(ctx context.Context, id string, tail int) (string, error) {
+	runtime.mutex.Lock()
+	_, ok := runtime.processes[id]
+	runtime.mutex.Unlock()
+	if !ok {
+		return "", errProcessNotFound
+	}
+
+	logPath := runtime.path(id, processLogFile)
+
+	var output []byte
+	for _, path := range []string{logPath + ".1", logPath} {
+		data, err := ioutil.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		output = append(output, data...)
+	}
+
+	return tailLines(string(output), tail), nil
+}
+
+// Stats returns the resource usage from the cgroup of the gameserver
+// or from /proc, when cgroups v2 are not available
+func (runtime *ProcessRuntime) Stats(ctx context.Context, id string) (*proto.GameserverResourceUsage, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	state, ok := runtime.processes[id]
+	if !ok {
+		return nil, errProcessNotFound
+	}
+
+	usage := &proto.GameserverResourceUsage{
+		MemoryLimit: state.MemoryMax / 1024,
+	}
+
+	var cpuUsage time.Duration
+	if stats, err := runtime.cgroupStats(id); err == nil {
+		usage.MemoryUsage = stats.MemoryCurrent / 1024
+		usage.MemoryLimit = stats.MemoryMax / 1024
+		usage.BlockReadBytes = stats.IOReadBytes
+		usage.BlockWriteBytes = stats.IOWriteBytes
+		cpuUsage = time.Duration(stats.CPUUsageUsec) * time.Microsecond
+	} else {
+		stats, err := readProcessStats(state.PID)
+		if err != nil {
+			return nil, err
+		}
+		usage.MemoryUsage = stats.MemoryRSS
+		usage.BlockReadBytes = stats.ReadBytes
+		usage.BlockWriteBytes = stats.WriteBytes
+		cpuUsage = stats.CPUTime
+	}
+
+	now := time.Now()
+	if previous, ok := runtime.samples[id]; ok && now.After(previous.time) && cpuUsage >= previous.usage {
+		usage.CpuPercent = float64(cpuUsage-previous.usage) / float64(now.Sub(previous.time)) * 100
+	}
+	runtime.samples[id] = cpuSample{usage: cpuUsage, time: now}
+
+	return usage, nil
+}
+
+func (runtime *ProcessRuntime) cgroupStats(id string) (*cgroupStats, error) {
+	if runtime.cgroup == nil {
+		return nil, errors.New("Cgroups v2 are not available")
+	}
+	return runtime.cgroup.stats(id)
+}
+
+// Exec runs a command in the working directory of the gameserver
+// with its environment
+func (runtime *ProcessRuntime) Exec(ctx context.Context, id string, command []string) (*ExecResult, error) {
+	if len(command) == 0 {
+		return nil, errors.New("Empty command")
+	}
+
+	runtime.mutex.Lock()
+	state, ok := runtime.processes[id]
+	if !ok {
+		runtime.mutex.Unlock()
+		return nil, errProcessNotFound
+	}
+	env := state.Env
+	runtime.mutex.Unlock()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = runtime.path(id, processWorkDir)
+	cmd.Env = env
+
+	output, err := cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return &ExecResult{ExitCode: processExitCode(exitErr.ProcessState), Output: string(output)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &ExecResult{Output: string(output)}, nil
+}
+
+// volumePath returns the volume directory. Volume paths are relative
+// to the working directory of the process
+func (runtime *ProcessRuntime) volumePath(id, path string) (string, error) {
+	if _, err := runtime.Inspect(context.Background(), id); err != nil {
+		return "", err
+	}
+
+	volumePath := filepath.Join(runtime.path(id, processWorkDir), filepath.Clean("/"+path))
+	if volumePath == runtime.path(id, processWorkDir) {
+		return "", fmt.Errorf("Invalid volume path %s", path)
+	}
+	return volumePath, nil
+}
+
+// ExportVolume archives a volume directory of the process
+func (runtime *ProcessRuntime) ExportVolume(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	volumePath, err := runtime.volumePath(id, path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(volumePath); err != nil {
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(archiveDirectory(volumePath, writer))
+	}()
+	return reader, nil
+}
+
+// ImportVolume extracts the archive into the parent of the volume directory
+func (runtime *ProcessRuntime) ImportVolume(ctx context.Context, id, path string, content io.Reader) error {
+	volumePath, err := runtime.volumePath(id, path)
+	if err != nil {
+		return err
+	}
+	return extractArchive(filepath.Dir(volumePath), content)
+}
+
+// PullImage does nothing, the process runtime doesn't use images
+func (runtime *ProcessRuntime) PullImage(ctx context.Context, image string, credentials *RegistryCredentials, progress func(PullProgress)) error {
+	return nil
+}
+
+// InspectImage reports every image as present, the process runtime
+// doesn't use images
+func (runtime *ProcessRuntime) InspectImage(ctx context.Context, image string) (*Image, error) {
+	return &Image{ID: image}, nil
+}
+
+// waitForProcessExit waits for the process to exit. Supervised processes
+// are waited for using their exited channel, adopted ones are polled
+func waitForProcessExit(pid int, startTime uint64, exited chan struct{}, timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	if exited != nil {
+		select {
+		case <-exited:
+			return true
+		case <-timer.C:
+			return false
+		}
+	}
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for processAlive(pid, startTime) {
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			return false
+		}
+	}
+	return true
+}
+
+// openProcessStdin opens the FIFO used as the stdin of the process. It's
+// opened for reading and writing, so the process never reads EOF
+// and the FIFO can be written to after an agent restart
+func openProcessStdin(path string) (*os.File, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := syscall.Mkfifo(path, 0600); err != nil {
+			return nil, err
+		}
+	}
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
+
+var signals = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// parseSignal parses signal names like SIGTERM or TERM and signal numbers
+func parseSignal(name string) (syscall.Signal, error) {
+	if number, err := strconv.Atoi(name); err == nil && number > 0 {
+		return syscall.Signal(number), nil
+	}
+
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+
+	signal, ok := signals[name]
+	if !ok {
+		return 0, fmt.Errorf("Unsupported signal %s", name)
+	}
+	return signal, nil
+}
+
+func processExitCode(state *os.ProcessState) int {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+	return state.ExitCode()
+}
+
+// readProcStat returns the fields of /proc/<pid>/stat following
+// the command name, so the process state has index 0
+func readProcStat(pid int) ([]string, error) {
+	data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return nil, err
+	}
+	return parseProcStat(string(data))
+}
+
+func parseProcStat(stat string) ([]string, error) {
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return nil, errors.New("Invalid process stat")
+	}
+
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 20 {
+		return nil, errors.New("Invalid process stat")
+	}
+	return fields, nil
+}
+
+// processStartTime returns the start time of the process in clock ticks
+// after boot. It's used to detect reused PIDs
+func processStartTime(pid int) (uint64, error) {
+	fields, err := readProcStat(pid)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(fields[19], 10, 64)
+}
+
+// processAlive checks, if the process with the PID is still the one started
+// by the runtime. Zombies are considered dead
+func processAlive(pid int, startTime uint64) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	fields, err := readProcStat(pid)
+	if err != nil || fields[0] == "Z" || fields[0] == "X" {
+		return false
+	}
+	if startTime == 0 {
+		return true
+	}
+
+	current, err := strconv.ParseUint(fields[19], 10, 64)
+	return err == nil && current == startTime
+}
+
+// processStats is the resource usage of a single process from /proc
+type processStats struct {
+	MemoryRSS  int64
+	CPUTime    time.Duration
+	ReadBytes  int64
+	WriteBytes int64
+}
+
+func readProcessStats(pid int) (*processStats, error) {
+	fields, err := readProcStat(pid)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &processStats{}
+	utime, _ := strconv.ParseInt(fields[11], 10, 64)
+	stime, _ := strconv.ParseInt(fields[12], 10, 64)
+	stats.CPUTime = time.Duration(utime+stime) * time.Second / clockTicks
+
+	if data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/status", pid)); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if strings.HasPrefix(line, "VmRSS:") {
+				if cols := strings.Fields(line); len(cols) >= 2 {
+					stats.MemoryRSS, _ = strconv.ParseInt(cols[1], 10, 64)
+				}
+			}
+		}
+	}
+
+	if data, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/io", pid)); err == nil {
+		values := parseCgroupKeyValues([]byte(strings.Replace(string(data), ":", "", -1)))
+		stats.ReadBytes = values["read_bytes"]
+		stats.WriteBytes = values["write_bytes"]
+	}
+
+	return stats, nil
+}
+
+// rotateLogFile copies the log file to path.1 and truncates it, when it's
+// bigger than maxSize. The process keeps writing to the same file descriptor,
+// so the file can't be renamed
+func rotateLogFile(path string, maxSize int64, maxFiles int) error {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.Size() < maxSize {
+		return nil
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", path, maxFiles))
+	for i := maxFiles - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(path + ".1")
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		return err
+	}
+	return os.Truncate(path, 0)
+}
+
+// tailLines returns the last lines of the text
+func tailLines(text string, tail int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,308 @@
+package agent
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/gameservers"
+	"github.com/Trojan295/chinchilla/server/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestProcessRuntime(t *testing.T, dir string) *ProcessRuntime {
+	runtime, err := NewProcessRuntime(dir, filepath.Join(dir, "no-cgroups", "chinchilla"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return runtime
+}
+
+func startTestProcess(t *testing.T, runtime *ProcessRuntime, uuid, script string) {
+	ctx := context.Background()
+	deployment := &proto.GameserverDeployment{
+		UUID:    uuid,
+		Command: []string{"sh", "-c", script},
+		Environment: []*proto.EnvironmentVariable{
+			{Name: "GREETING", Value: "world"},
+		},
+	}
+
+	if _, err := runtime.Create(ctx, deployment, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.Start(ctx, uuid); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessRuntimeLifecycle(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	runtime := newTestProcessRuntime(t, dir)
+	startTestProcess(t, runtime, "uuid1", "echo hello $GREETING; echo secret > password; sleep 30")
+
+	waitForCondition(t, func() bool {
+		logs, _ := runtime.Logs(ctx, "uuid1", 10)
+		return logs == "hello world"
+	})
+
+	containers, err := runtime.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, containers, 1)
+	assert.Equal(t, ContainerRunning, containers[0].State)
+	assert.Equal(t, "127.0.0.1", containers[0].IPAddress)
+	assert.Equal(t, "uuid1", containers[0].Labels[uuidLabel])
+
+	result, err := runtime.Exec(ctx, "uuid1", []string{"cat", "password"})
+	assert.NoError(t, err)
+	assert.Equal(t, &ExecResult{Output: "secret\n"}, result)
+
+	usage, err := runtime.Stats(ctx, "uuid1")
+	assert.NoError(t, err)
+	assert.True(t, usage.MemoryUsage > 0)
+
+	assert.NoError(t, runtime.Stop(ctx, "uuid1", time.Second))
+	cont, err := runtime.Inspect(ctx, "uuid1")
+	assert.NoError(t, err)
+	assert.Equal(t, ContainerExited, cont.State)
+	assert.Equal(t, 128+int(syscall.SIGTERM), cont.ExitCode)
+
+	assert.NoError(t, runtime.Remove(ctx, "uuid1"))
+	_, err = os.Stat(filepath.Join(dir, "uuid1"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestProcessRuntimeExitCode(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	runtime := newTestProcessRuntime(t, dir)
+	startTestProcess(t, runtime, "uuid1", "exit 3")
+
+	waitForCondition(t, func() bool {
+		cont, _ := runtime.Inspect(context.Background(), "uuid1")
+		return cont.State == ContainerExited
+	})
+
+	cont, _ := runtime.Inspect(context.Background(), "uuid1")
+	assert.Equal(t, 3, cont.ExitCode)
+	assert.NotEmpty(t, cont.FinishedAt)
+}
+
+func TestProcessRuntimeRequiresCommand(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	_, err := newTestProcessRuntime(t, dir).Create(context.Background(), &proto.GameserverDeployment{UUID: "uuid1"}, "127.0.0.1")
+	assert.Error(t, err)
+}
+
+func TestProcessRuntimeAdoptsProcesses(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	previous := newTestProcessRuntime(t, dir)
+	startTestProcess(t, previous, "uuid1", "sleep 30")
+	startTestProcess(t, previous, "uuid2", "sleep 30")
+
+	runtime := newTestProcessRuntime(t, dir)
+	containers, err := runtime.List(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, containers, 2)
+	for _, cont := range containers {
+		assert.Equal(t, ContainerRunning, cont.State)
+	}
+
+	assert.NoError(t, runtime.Stop(ctx, "uuid1", time.Second))
+	cont, _ := runtime.Inspect(ctx, "uuid1")
+	assert.Equal(t, ContainerExited, cont.State)
+	assert.Equal(t, 128+int(syscall.SIGTERM), cont.ExitCode)
+
+	previous.mutex.Lock()
+	pid := previous.processes["uuid2"].PID
+	previous.mutex.Unlock()
+	syscall.Kill(-pid, syscall.SIGKILL)
+
+	waitForCondition(t, func() bool {
+		cont, _ := runtime.Inspect(ctx, "uuid2")
+		return cont.State == ContainerExited
+	})
+	cont, _ = runtime.Inspect(ctx, "uuid2")
+	assert.Equal(t, unknownExitCode, cont.ExitCode)
+}
+
+func TestRotateLogFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "output.log")
+	for _, content := range []string{"first\n", "second\n", "third\n"} {
+		ioutil.WriteFile(path, []byte(content), 0644)
+		assert.NoError(t, rotateLogFile(path, 4, 2))
+	}
+
+	current, _ := ioutil.ReadFile(path)
+	rotated, _ := ioutil.ReadFile(path + ".1")
+	oldest, _ := ioutil.ReadFile(path + ".2")
+	assert.Empty(t, current)
+	assert.Equal(t, "third\n", string(rotated))
+	assert.Equal(t, "second\n", string(oldest))
+	_, err := os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestParseProcStat(t *testing.T) {
+	stat := "1234 (my (game) server) S 1 1234 1234 0 -1 4194560 1000 0 0 0 250 50 0 0 20 0 1 0 98765 10000 500"
+
+	fields, err := parseProcStat(stat)
+	assert.NoError(t, err)
+	assert.Equal(t, "S", fields[0])
+	assert.Equal(t, "250", fields[11])
+	assert.Equal(t, "50", fields[12])
+	assert.Equal(t, "98765", fields[19])
+
+	_, err = parseProcStat("1234 (broken")
+	assert.Error(t, err)
+}
+
+func TestTailLines(t *testing.T) {
+	assert.Equal(t, "b\nc", tailLines("a\nb\nc\n", 2))
+	assert.Equal(t, "a", tailLines("a", 5))
+	assert.Equal(t, "y", tailLines("x\ny", 1))
+}
+
+func TestProcessRuntimeSendInput(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	runtime := newTestProcessRuntime(t, dir)
+	startTestProcess(t, runtime, "uuid1", `read command; echo "received $command"`)
+
+	assert.NoError(t, runtime.SendInput(ctx, "uuid1", "stop"))
+	waitForCondition(t, func() bool {
+		cont, _ := runtime.Inspect(ctx, "uuid1")
+		return cont.State == ContainerExited
+	})
+
+	logs, _ := runtime.Logs(ctx, "uuid1", 10)
+	assert.Equal(t, "received stop", logs)
+}
+
+func TestParseSignal(t *testing.T) {
+	for _, name := range []string{"SIGINT", "int", "2"} {
+		signal, err := parseSignal(name)
+		assert.NoError(t, err)
+		assert.Equal(t, syscall.SIGINT, signal)
+	}
+
+	_, err := parseSignal("SIGFOO")
+	assert.Error(t, err)
+}
+
+func TestProcessRuntimeVolumes(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	runtime := newTestProcessRuntime(t, dir)
+	startTestProcess(t, runtime, "uuid1", "mkdir world; echo saved > world/level.dat")
+	startTestProcess(t, runtime, "uuid2", "true")
+
+	waitForCondition(t, func() bool {
+		cont, _ := runtime.Inspect(ctx, "uuid1")
+		return cont.State == ContainerExited
+	})
+
+	content, err := runtime.ExportVolume(ctx, "uuid1", "/world")
+	assert.NoError(t, err)
+	assert.NoError(t, runtime.ImportVolume(ctx, "uuid2", "/world", content))
+	content.Close()
+
+	level, _ := ioutil.ReadFile(filepath.Join(dir, "uuid2", processWorkDir, "world", "level.dat"))
+	assert.Equal(t, "saved\n", string(level))
+
+	_, err = runtime.ExportVolume(ctx, "uuid1", "/")
+	assert.Error(t, err)
+}
+
+func TestProcessRuntimeRunsGameserverCreatedByAPI(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var created *server.Gameserver
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{}, nil).AnyTimes()
+	gameserverStore.EXPECT().
+		CreateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) { created = gs }).
+		Return(nil).
+		Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().AddGameserverEvent(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	quotaStore := mocks.NewMockQuotaStore(ctrl)
+	quotaStore.EXPECT().GetQuota(gomock.Any()).Return(nil, nil).AnyTimes()
+	quotaStore.EXPECT().LockQuota(gomock.Any()).Return(func() {}, nil).AnyTimes()
+
+	router := utils.SetupRouter()
+	gameservers.MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, eventStore,
+		mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), quotaStore)
+
+	body := `{
+		"name": "My server",
+		"game": "Teamspeak",
+		"version": "latest",
+		"parameters": {},
+		"command": ["sh", "-c", "echo started > started; sleep 30"]
+	}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", strings.NewReader(body))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 202, w.Code)
+	if created == nil {
+		t.Fatal("gameserver not created")
+	}
+
+	ctx := context.Background()
+	uuid := created.Definition.UUID
+	runtime := newTestProcessRuntime(t, dir)
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{
+		IPAddresses: []string{"127.0.0.1"},
+	})
+	config := &proto.GetGameserverDeploymentsResponse{
+		Deployments: []*proto.GameserverDeployment{created.Deployment},
+	}
+
+	waitForCondition(t, func() bool {
+		manager.Tick(config)
+		result, err := runtime.Exec(ctx, uuid, []string{"cat", "started"})
+		return err == nil && result.Output == "started\n"
+	})
+
+	cont, err := runtime.Inspect(ctx, uuid)
+	assert.NoError(t, err)
+	assert.Equal(t, ContainerRunning, cont.State)
+	assert.NoError(t, runtime.Stop(ctx, uuid, time.Second))
+}
@@ -0,0 +1,42 @@
+package query
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var factorioPlayersRegexp = regexp.MustCompile(`\((\d+)\)`)
+
+// FactorioQuerier queries a Factorio server over RCON
+type FactorioQuerier struct{}
+
+// Query returns the online players count and the server version
+func (querier FactorioQuerier) Query(address string, options Options) (*Status, error) {
+	client, err := dialRcon(address, options)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	players, err := client.Execute("/players online count")
+	if err != nil {
+		return nil, err
+	}
+
+	match := factorioPlayersRegexp.FindStringSubmatch(players)
+	if match == nil {
+		return nil, ErrUnexpectedResponse
+	}
+	online, _ := strconv.Atoi(match[1])
+
+	version, err := client.Execute("/version")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Status{
+		PlayersOnline: online,
+		Version:       strings.TrimSpace(version),
+	}, nil
+}
@@ -0,0 +1,69 @@
+package query
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeRconServer(t *testing.T, password string, responses map[string]string) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		server := &rconClient{conn: conn}
+		for {
+			packet, err := server.read()
+			if err != nil {
+				return
+			}
+
+			if packet.Type == rconTypeAuth {
+				if packet.Body == password {
+					server.nextID = packet.ID
+				} else {
+					server.nextID = -1
+				}
+				server.send(rconTypeAuthResponse, "")
+				continue
+			}
+
+			server.nextID = packet.ID
+			server.send(rconTypeResponseValue, responses[packet.Body])
+		}
+	}()
+
+	return ln
+}
+
+func TestFactorioQuery(t *testing.T) {
+	ln := fakeRconServer(t, "secret", map[string]string{
+		"/players online count": "Online players (2):",
+		"/version":              "0.17.63\n",
+	})
+	defer ln.Close()
+
+	status, err := FactorioQuerier{}.Query(ln.Addr().String(), Options{Password: "secret"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, status.PlayersOnline)
+	assert.Equal(t, "0.17.63", status.Version)
+}
+
+func TestFactorioQueryWrongPassword(t *testing.T) {
+	ln := fakeRconServer(t, "secret", map[string]string{})
+	defer ln.Close()
+
+	_, err := FactorioQuerier{}.Query(ln.Addr().String(), Options{Password: "wrong"})
+
+	assert.Equal(t, ErrAuthenticationFailed, err)
+}
@@ -0,0 +1,174 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const minecraftProtocolVersion = 47
+
+// maxStringLength bounds the length of the strings sent by the server,
+// as the protocol allows 32767 characters of up to 4 bytes
+const maxStringLength = 32767 * 4
+
+// MinecraftQuerier implements the Minecraft Server List Ping protocol
+type MinecraftQuerier struct{}
+
+type minecraftDescription struct {
+	Text  string                 `json:"text"`
+	Extra []minecraftDescription `json:"extra"`
+}
+
+func (desc *minecraftDescription) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		desc.Text = text
+		return nil
+	}
+
+	type plainDescription minecraftDescription
+	return json.Unmarshal(data, (*plainDescription)(desc))
+}
+
+func (desc *minecraftDescription) String() string {
+	text := desc.Text
+	for _, extra := range desc.Extra {
+		text += extra.String()
+	}
+	return text
+}
+
+type minecraftStatusResponse struct {
+	Version struct {
+		Name string `json:"name"`
+	} `json:"version"`
+	Players struct {
+		Max    int `json:"max"`
+		Online int `json:"online"`
+	} `json:"players"`
+	Description minecraftDescription `json:"description"`
+}
+
+// Query performs a Server List Ping
+func (querier MinecraftQuerier) Query(address string, options Options) (*Status, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := dial("tcp", address, options)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	handshake := &bytes.Buffer{}
+	writeVarInt(handshake, 0x00)
+	writeVarInt(handshake, minecraftProtocolVersion)
+	writeString(handshake, host)
+	binary.Write(handshake, binary.BigEndian, uint16(port))
+	writeVarInt(handshake, 1)
+
+	if err := writePacket(conn, handshake.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writePacket(conn, []byte{0x00}); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	if _, err := readVarInt(reader); err != nil {
+		return nil, err
+	}
+	packetID, err := readVarInt(reader)
+	if err != nil {
+		return nil, err
+	}
+	if packetID != 0x00 {
+		return nil, ErrUnexpectedResponse
+	}
+
+	payload, err := readString(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var response minecraftStatusResponse
+	if err := json.Unmarshal([]byte(payload), &response); err != nil {
+		return nil, err
+	}
+
+	return &Status{
+		PlayersOnline: response.Players.Online,
+		PlayersMax:    response.Players.Max,
+		MOTD:          strings.TrimSpace(response.Description.String()),
+		Version:       response.Version.Name,
+	}, nil
+}
+
+func writePacket(w io.Writer, data []byte) error {
+	packet := &bytes.Buffer{}
+	writeVarInt(packet, len(data))
+	packet.Write(data)
+	_, err := w.Write(packet.Bytes())
+	return err
+}
+
+func writeVarInt(buf *bytes.Buffer, value int) {
+	uvalue := uint32(value)
+	for {
+		if uvalue&^0x7F == 0 {
+			buf.WriteByte(byte(uvalue))
+			return
+		}
+		buf.WriteByte(byte(uvalue&0x7F | 0x80))
+		uvalue >>= 7
+	}
+}
+
+func writeString(buf *bytes.Buffer, value string) {
+	writeVarInt(buf, len(value))
+	buf.WriteString(value)
+}
+
+func readVarInt(r io.ByteReader) (int, error) {
+	var value uint32
+	for i := uint(0); i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int(int32(value)), nil
+		}
+	}
+	return 0, errors.New("VarInt is too big")
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || length > maxStringLength {
+		return "", ErrUnexpectedResponse
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,81 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeMinecraftServer(t *testing.T, response string) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			length, err := readVarInt(reader)
+			if err != nil {
+				return
+			}
+			reader.Discard(length)
+		}
+
+		payload := &bytes.Buffer{}
+		writeVarInt(payload, 0x00)
+		writeString(payload, response)
+		writePacket(conn, payload.Bytes())
+	}()
+
+	return ln
+}
+
+func TestMinecraftQuery(t *testing.T) {
+	ln := fakeMinecraftServer(t, `{
+		"version": {"name": "1.14.1", "protocol": 480},
+		"players": {"max": 20, "online": 3},
+		"description": {"text": "Hello ", "extra": [{"text": "world"}]}
+	}`)
+	defer ln.Close()
+
+	status, err := MinecraftQuerier{}.Query(ln.Addr().String(), Options{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, status.PlayersOnline)
+	assert.Equal(t, 20, status.PlayersMax)
+	assert.Equal(t, "Hello world", status.MOTD)
+	assert.Equal(t, "1.14.1", status.Version)
+}
+
+func TestMinecraftQueryWithStringDescription(t *testing.T) {
+	ln := fakeMinecraftServer(t, `{
+		"version": {"name": "1.13.2"},
+		"players": {"max": 10, "online": 0},
+		"description": "A Minecraft Server"
+	}`)
+	defer ln.Close()
+
+	status, err := MinecraftQuerier{}.Query(ln.Addr().String(), Options{})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "A Minecraft Server", status.MOTD)
+}
+
+func TestReadStringRejectsHugeLength(t *testing.T) {
+	payload := &bytes.Buffer{}
+	writeVarInt(payload, 1<<30)
+
+	_, err := readString(bufio.NewReader(payload))
+
+	assert.Equal(t, ErrUnexpectedResponse, err)
+}
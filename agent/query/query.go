@@ -0,0 +1,44 @@
+package query
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// Status is the result of a gameserver query
+type Status struct {
+	PlayersOnline int
+	PlayersMax    int
+	MOTD          string
+	Version       string
+}
+
+// Options holds the protocol specific query settings
+type Options struct {
+	Password string
+	Timeout  time.Duration
+}
+
+// Querier queries a running gameserver for its status
+type Querier interface {
+	Query(address string, options Options) (*Status, error)
+}
+
+// ErrUnexpectedResponse is returned, when the gameserver response cannot be parsed
+var ErrUnexpectedResponse = errors.New("Unexpected query response")
+
+func dial(network, address string, options Options) (net.Conn, error) {
+	timeout := options.Timeout
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
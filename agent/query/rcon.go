@@ -0,0 +1,139 @@
+package query
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+)
+
+const (
+	rconTypeResponseValue = 0
+	rconTypeExecCommand   = 2
+	rconTypeAuthResponse  = 2
+	rconTypeAuth          = 3
+
+	rconMaxPacketSize = 64 * 1024
+)
+
+// ErrAuthenticationFailed is returned, when the gameserver rejects the credentials
+var ErrAuthenticationFailed = errors.New("Authentication failed")
+
+type rconPacket struct {
+	ID   int32
+	Type int32
+	Body string
+}
+
+// rconClient is a minimal Source RCON client
+type rconClient struct {
+	conn   net.Conn
+	nextID int32
+}
+
+func dialRcon(address string, options Options) (*rconClient, error) {
+	conn, err := dial("tcp", address, options)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &rconClient{conn: conn, nextID: 1}
+	if err := client.authenticate(options.Password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+func (client *rconClient) Close() error {
+	return client.conn.Close()
+}
+
+func (client *rconClient) authenticate(password string) error {
+	id, err := client.send(rconTypeAuth, password)
+	if err != nil {
+		return err
+	}
+
+	for {
+		packet, err := client.read()
+		if err != nil {
+			return err
+		}
+
+		if packet.Type != rconTypeAuthResponse {
+			continue
+		}
+		if packet.ID != id {
+			return ErrAuthenticationFailed
+		}
+		return nil
+	}
+}
+
+// Execute runs a command and returns its output
+func (client *rconClient) Execute(command string) (string, error) {
+	id, err := client.send(rconTypeExecCommand, command)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		packet, err := client.read()
+		if err != nil {
+			return "", err
+		}
+
+		if packet.ID == id && packet.Type == rconTypeResponseValue {
+			return packet.Body, nil
+		}
+	}
+}
+
+func (client *rconClient) send(packetType int32, body string) (int32, error) {
+	id := client.nextID
+	client.nextID++
+
+	buf := &bytes.Buffer{}
+	binary.Write(buf, binary.LittleEndian, int32(len(body)+10))
+	binary.Write(buf, binary.LittleEndian, id)
+	binary.Write(buf, binary.LittleEndian, packetType)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+
+	_, err := client.conn.Write(buf.Bytes())
+	return id, err
+}
+
+func (client *rconClient) read() (*rconPacket, error) {
+	var size int32
+	if err := binary.Read(client.conn, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if size < 10 || size > rconMaxPacketSize {
+		return nil, ErrUnexpectedResponse
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(client.conn, data); err != nil {
+		return nil, err
+	}
+
+	return &rconPacket{
+		ID:   int32(binary.LittleEndian.Uint32(data[0:4])),
+		Type: int32(binary.LittleEndian.Uint32(data[4:8])),
+		Body: string(bytes.TrimRight(data[8:], "\x00")),
+	}, nil
+}
+
+// ExecuteRcon runs a single command on the gameserver over RCON
+func ExecuteRcon(address, command string, options Options) (string, error) {
+	client, err := dialRcon(address, options)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	return client.Execute(command)
+}
@@ -0,0 +1,154 @@
+package query
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// TeamspeakQuerier queries a Teamspeak 3 server over ServerQuery
+type TeamspeakQuerier struct {
+	ServerID int
+}
+
+// Query returns the virtual server info
+func (querier TeamspeakQuerier) Query(address string, options Options) (*Status, error) {
+	conn, err := dial("tcp", address, options)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	greeting, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "TS3") {
+		return nil, ErrUnexpectedResponse
+	}
+	if _, err := reader.ReadString('\n'); err != nil {
+		return nil, err
+	}
+
+	if options.Password != "" {
+		login := fmt.Sprintf("login client_login_name=serveradmin client_login_password=%s", escapeTeamspeak(options.Password))
+		if _, err := teamspeakCommand(conn, reader, login); err != nil {
+			return nil, err
+		}
+	}
+
+	serverID := querier.ServerID
+	if serverID == 0 {
+		serverID = 1
+	}
+	if _, err := teamspeakCommand(conn, reader, fmt.Sprintf("use sid=%d", serverID)); err != nil {
+		return nil, err
+	}
+
+	info, err := teamspeakCommand(conn, reader, "serverinfo")
+	if err != nil {
+		return nil, err
+	}
+
+	props := parseTeamspeakProperties(info)
+	clientsOnline, _ := strconv.Atoi(props["virtualserver_clientsonline"])
+	queryClientsOnline, _ := strconv.Atoi(props["virtualserver_queryclientsonline"])
+	maxClients, _ := strconv.Atoi(props["virtualserver_maxclients"])
+
+	teamspeakCommand(conn, reader, "quit")
+
+	return &Status{
+		PlayersOnline: clientsOnline - queryClientsOnline,
+		PlayersMax:    maxClients,
+		MOTD:          props["virtualserver_welcomemessage"],
+		Version:       props["virtualserver_version"],
+	}, nil
+}
+
+func teamspeakCommand(conn net.Conn, reader *bufio.Reader, command string) (string, error) {
+	if _, err := fmt.Fprintf(conn, "%s\n", command); err != nil {
+		return "", err
+	}
+
+	lines := make([]string, 0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		line = strings.Trim(line, "\r\n")
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "error ") {
+			props := parseTeamspeakProperties(line)
+			if props["id"] != "0" {
+				return "", fmt.Errorf("ServerQuery error %s: %s", props["id"], props["msg"])
+			}
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+	}
+}
+
+func parseTeamspeakProperties(line string) map[string]string {
+	props := make(map[string]string)
+	for _, field := range strings.Fields(line) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) == 2 {
+			props[kv[0]] = unescapeTeamspeak(kv[1])
+		} else {
+			props[kv[0]] = ""
+		}
+	}
+	return props
+}
+
+var teamspeakEscapes = [][2]string{
+	{`\`, `\\`},
+	{`/`, `\/`},
+	{` `, `\s`},
+	{`|`, `\p`},
+	{"\n", `\n`},
+	{"\r", `\r`},
+	{"\t", `\t`},
+}
+
+func escapeTeamspeak(value string) string {
+	for _, escape := range teamspeakEscapes {
+		value = strings.Replace(value, escape[0], escape[1], -1)
+	}
+	return value
+}
+
+func unescapeTeamspeak(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i+1 == len(value) {
+			builder.WriteByte(value[i])
+			continue
+		}
+
+		i++
+		switch value[i] {
+		case 's':
+			builder.WriteByte(' ')
+		case 'p':
+			builder.WriteByte('|')
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		case 't':
+			builder.WriteByte('\t')
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
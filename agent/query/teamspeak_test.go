@@ -0,0 +1,79 @@
+package query
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func fakeTeamspeakServer(t *testing.T, password string) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(conn, "TS3\n\rWelcome to the TeamSpeak 3 ServerQuery interface.\n\r")
+
+		loggedIn := false
+		reader := bufio.NewReader(conn)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			command := strings.TrimSpace(line)
+			switch {
+			case strings.HasPrefix(command, "login "):
+				props := parseTeamspeakProperties(command)
+				if props["client_login_password"] != password {
+					fmt.Fprint(conn, "error id=520 msg=invalid\\sloginname\\sor\\spassword\n\r")
+					continue
+				}
+				loggedIn = true
+			case command == "serverinfo":
+				if !loggedIn {
+					fmt.Fprint(conn, "error id=2568 msg=insufficient\\sclient\\spermissions\n\r")
+					continue
+				}
+				fmt.Fprint(conn, "virtualserver_name=My\\sServer virtualserver_welcomemessage=Hello\\sthere virtualserver_clientsonline=4 virtualserver_queryclientsonline=1 virtualserver_maxclients=32 virtualserver_version=3.9.1\\s[Build:\\s1564054246]\n\r")
+			}
+			fmt.Fprint(conn, "error id=0 msg=ok\n\r")
+		}
+	}()
+
+	return ln
+}
+
+func TestTeamspeakQuery(t *testing.T) {
+	ln := fakeTeamspeakServer(t, "pass word")
+	defer ln.Close()
+
+	status, err := TeamspeakQuerier{}.Query(ln.Addr().String(), Options{Password: "pass word"})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, status.PlayersOnline)
+	assert.Equal(t, 32, status.PlayersMax)
+	assert.Equal(t, "Hello there", status.MOTD)
+	assert.Equal(t, "3.9.1 [Build: 1564054246]", status.Version)
+}
+
+func TestTeamspeakQueryWrongPassword(t *testing.T) {
+	ln := fakeTeamspeakServer(t, "secret")
+	defer ln.Close()
+
+	_, err := TeamspeakQuerier{}.Query(ln.Addr().String(), Options{Password: "wrong"})
+
+	assert.NotNil(t, err)
+}
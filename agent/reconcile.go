@@ -0,0 +1,407 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+const (
+	defaultReconcileWorkers = 4
+	maxActionResults        = 100
+	configHashLabel         = "chinchilla.gameserver.config_hash"
+)
+
+var errDestructiveUpdate = errors.New("Gameserver has no volumes, recreating its container would discard its data")
+
+// gameserverAction is a single reconciliation step planned by Tick
+type gameserverAction struct {
+	uuid       string
+	action     proto.GameserverAction
+	deployment *proto.GameserverDeployment
+}
+
+// planGameserverActions compares the deployments with a snapshot of the
+// containers and returns the actions needed to reconcile them
+func planGameserverActions(deployments []*proto.GameserverDeployment, containers []Container) []gameserverAction {
+	containersByUUID := make(map[string]*Container, len(containers))
+	for i := range containers {
+		containersByUUID[containers[i].UUID] = &containers[i]
+	}
+
+	actions := make([]gameserverAction, 0)
+	deployed := make(map[string]bool, len(deployments))
+
+	for _, deployment := range deployments {
+		deployed[deployment.UUID] = true
+		cont, ok := containersByUUID[deployment.UUID]
+
+		action := gameserverAction{
+			uuid:       deployment.UUID,
+			deployment: deployment,
+		}
+
+		switch {
+		case !ok && deployment.Stopped:
+			continue
+		case !ok:
+			action.action = proto.GameserverAction_ACTION_CREATE
+		case deployment.Stopped && cont.State == ContainerRunning:
+			action.action = proto.GameserverAction_ACTION_STOP
+		case deployment.Stopped:
+			continue
+		case isOutdated(cont, deployment) && len(deployment.Volumes) > 0:
+			action.action = proto.GameserverAction_ACTION_UPDATE
+		case cont.State != ContainerRunning:
+			action.action = proto.GameserverAction_ACTION_START
+		default:
+			continue
+		}
+
+		actions = append(actions, action)
+	}
+
+	for uuid := range containersByUUID {
+		if !deployed[uuid] {
+			actions = append(actions, gameserverAction{
+				uuid:   uuid,
+				action: proto.GameserverAction_ACTION_REMOVE,
+			})
+		}
+	}
+
+	return actions
+}
+
+// refusedUpdates remembers the config hashes of the refused updates,
+// so each of them is reported once
+type refusedUpdates struct {
+	mutex  sync.Mutex
+	hashes map[string]string
+}
+
+func newRefusedUpdates() *refusedUpdates {
+	return &refusedUpdates{hashes: make(map[string]string)}
+}
+
+// refuse records the refused update and tells, if it wasn't reported yet
+func (updates *refusedUpdates) refuse(uuid, hash string) bool {
+	updates.mutex.Lock()
+	defer updates.mutex.Unlock()
+
+	if updates.hashes[uuid] == hash {
+		return false
+	}
+	updates.hashes[uuid] = hash
+	return true
+}
+
+// retain forgets the gameservers, which aren't outdated anymore
+func (updates *refusedUpdates) retain(outdated map[string]bool) {
+	updates.mutex.Lock()
+	defer updates.mutex.Unlock()
+
+	for uuid := range updates.hashes {
+		if !outdated[uuid] {
+			delete(updates.hashes, uuid)
+		}
+	}
+}
+
+// reportRefusedUpdates reports the outdated gameservers without volumes.
+// Their data lives in the container and its anonymous volumes, so they keep
+// running with the old config instead of being recreated
+func (manager *GameserverManager) reportRefusedUpdates(deployments []*proto.GameserverDeployment, containers []Container) {
+	outdated := make(map[string]bool)
+	for _, deployment := range deployments {
+		cont := findGameserverContainer(containers, deployment.UUID)
+		if deployment.Stopped || cont == nil || len(deployment.Volumes) > 0 || !isOutdated(cont, deployment) {
+			continue
+		}
+
+		outdated[deployment.UUID] = true
+		if !manager.refused.refuse(deployment.UUID, deploymentConfigHash(deployment)) {
+			continue
+		}
+
+		log.Printf("Not recreating outdated gameserver %s: %s", deployment.UUID, errDestructiveUpdate)
+		manager.results.add(&proto.GameserverActionResult{
+			UUID:      deployment.UUID,
+			Action:    proto.GameserverAction_ACTION_UPDATE,
+			Timestamp: time.Now().Unix(),
+			Error:     errDestructiveUpdate.Error(),
+		})
+	}
+	manager.refused.retain(outdated)
+}
+
+// isOutdated tells, if the container was created from a different
+// deployment config. Containers without the config hash are left as they are
+func isOutdated(cont *Container, deployment *proto.GameserverDeployment) bool {
+	hash, ok := cont.Labels[configHashLabel]
+	return ok && hash != deploymentConfigHash(deployment)
+}
+
+// deploymentConfigHash hashes the parts of the deployment,
+// which are baked into the container
+func deploymentConfigHash(deployment *proto.GameserverDeployment) string {
+	// The disk reservation is used only for scheduling
+	var requirements *proto.ResourceRequirements
+	if deployment.ResourceRequirements != nil {
+		requirements = &proto.ResourceRequirements{
+			CpuReservation:    deployment.ResourceRequirements.CpuReservation,
+			CpuLimit:          deployment.ResourceRequirements.CpuLimit,
+			MemoryReservation: deployment.ResourceRequirements.MemoryReservation,
+			MemoryLimit:       deployment.ResourceRequirements.MemoryLimit,
+		}
+	}
+
+	data, _ := protobuf.Marshal(&proto.GameserverDeployment{
+		Image:                deployment.Image,
+		ImageDigest:          deployment.ImageDigest,
+		ResourceRequirements: requirements,
+		Ports:                deployment.Ports,
+		Environment:          deployment.Environment,
+		HealthCheck:          deployment.HealthCheck,
+		Command:              deployment.Command,
+	})
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// gameserverLocks makes sure only one action runs for a gameserver at a time
+type gameserverLocks struct {
+	mutex  sync.Mutex
+	locked map[string]bool
+}
+
+func newGameserverLocks() *gameserverLocks {
+	return &gameserverLocks{
+		locked: make(map[string]bool),
+	}
+}
+
+func (locks *gameserverLocks) tryLock(uuid string) bool {
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+
+	if locks.locked[uuid] {
+		return false
+	}
+	locks.locked[uuid] = true
+	return true
+}
+
+func (locks *gameserverLocks) unlock(uuid string) {
+	locks.mutex.Lock()
+	defer locks.mutex.Unlock()
+	delete(locks.locked, uuid)
+}
+
+// actionResults buffers the action results until they are sent to the server
+type actionResults struct {
+	mutex   sync.Mutex
+	results []*proto.GameserverActionResult
+}
+
+func (results *actionResults) add(result *proto.GameserverActionResult) {
+	results.mutex.Lock()
+	defer results.mutex.Unlock()
+
+	results.results = append(results.results, result)
+	if len(results.results) > maxActionResults {
+		results.results = results.results[len(results.results)-maxActionResults:]
+	}
+}
+
+func (results *actionResults) drain() []*proto.GameserverActionResult {
+	results.mutex.Lock()
+	defer results.mutex.Unlock()
+
+	drained := results.results
+	results.results = nil
+	return drained
+}
+
+// ActionResults returns the results of the actions finished since the last call
+func (manager *GameserverManager) ActionResults() []*proto.GameserverActionResult {
+	return manager.results.drain()
+}
+
+// dispatchAction queues the action, unless another action
+// for the same gameserver is still running
+func (manager *GameserverManager) dispatchAction(action gameserverAction) {
+	if !manager.locks.tryLock(action.uuid) {
+		return
+	}
+
+	go func() {
+		manager.actions <- action
+	}()
+}
+
+func (manager *GameserverManager) actionWorker() {
+	for action := range manager.actions {
+		manager.runAction(action)
+	}
+}
+
+func (manager *GameserverManager) runAction(action gameserverAction) {
+	defer manager.locks.unlock(action.uuid)
+
+	start := time.Now()
+	performed, shutdown, err := manager.performAction(action)
+	if !performed && err == nil {
+		return
+	}
+
+	result := &proto.GameserverActionResult{
+		UUID:      action.uuid,
+		Action:    action.action,
+		Success:   err == nil,
+		Timestamp: start.Unix(),
+		Duration:  int64(time.Since(start) / time.Millisecond),
+		Shutdown:  shutdown,
+	}
+	if err != nil {
+		log.Printf("Error while running %s for %s: %s", action.action, action.uuid, err)
+		result.Error = err.Error()
+	}
+	manager.results.add(result)
+}
+
+// performAction runs the action and tells, if anything was done. Actions
+// stopping a running gameserver return how it was shut down
+func (manager *GameserverManager) performAction(action gameserverAction) (bool, *proto.ShutdownResult, error) {
+	uuid := action.uuid
+	deployment := action.deployment
+
+	switch action.action {
+	case proto.GameserverAction_ACTION_CREATE:
+		if !manager.imageReady(deployment) {
+			return false, nil, nil
+		}
+		defer manager.images.release(uuid)
+
+		log.Printf("Creating gameserver %s...", uuid)
+		return true, nil, manager.CreateGameserver(deployment)
+
+	case proto.GameserverAction_ACTION_UPDATE:
+		if !manager.imageReady(deployment) {
+			return false, nil, nil
+		}
+		defer manager.images.release(uuid)
+
+		log.Printf("Recreating outdated gameserver %s...", uuid)
+		shutdown, err := manager.StopGameserver(uuid, nil)
+		if err != nil {
+			return true, shutdown, err
+		}
+		manager.restarts.remove(uuid)
+		return true, shutdown, manager.recreateGameserver(deployment)
+
+	case proto.GameserverAction_ACTION_START:
+		performed, err := manager.handleExitedGameserver(deployment)
+		return performed, nil, err
+
+	case proto.GameserverAction_ACTION_STOP:
+		log.Printf("Stopping gameserver %s...", uuid)
+		shutdown, err := manager.StopGameserver(uuid, deployment.Shutdown)
+		return true, shutdown, err
+
+	case proto.GameserverAction_ACTION_REMOVE:
+		log.Printf("Removing gameserver %s...", uuid)
+		manager.restarts.remove(uuid)
+		manager.healthChecks.remove(uuid)
+		manager.queries.remove(uuid)
+		shutdown, err := manager.RemoveGameserver(uuid)
+		return true, shutdown, err
+	}
+
+	return false, nil, nil
+}
+
+// imageReady requests the deployment image and tells, if it's ready to use
+func (manager *GameserverManager) imageReady(deployment *proto.GameserverDeployment) bool {
+	pull := manager.images.request(deployment.UUID, deploymentImage(deployment), deployment.ImagePullPolicy)
+	return pull.done && pull.err == nil
+}
+
+// recreateGameserver replaces the container of a stopped gameserver with one
+// created from the deployment. The volumes are removed with the old container,
+// so they are saved to temporary files and imported into the new one
+func (manager *GameserverManager) recreateGameserver(deployment *proto.GameserverDeployment) error {
+	uuid := deployment.UUID
+
+	dir, err := ioutil.TempDir("", "chinchilla-"+uuid)
+	if err != nil {
+		return err
+	}
+
+	for i, volume := range deployment.Volumes {
+		if err := manager.saveVolume(uuid, volume.Path, filepath.Join(dir, strconv.Itoa(i))); err != nil {
+			os.RemoveAll(dir)
+			return fmt.Errorf("Cannot export volume %s: %v", volume.Name, err)
+		}
+	}
+
+	if err := manager.removeGameServerContainer(uuid); err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+
+	// The old container is gone, so the saved volumes are kept on errors
+	if _, err := manager.createGameserverContainer(deployment); err != nil {
+		return fmt.Errorf("Cannot create container, the volumes are saved in %s: %v", dir, err)
+	}
+	for i, volume := range deployment.Volumes {
+		if err := manager.restoreVolume(uuid, volume.Path, filepath.Join(dir, strconv.Itoa(i))); err != nil {
+			return fmt.Errorf("Cannot import volume %s, the volumes are saved in %s: %v", volume.Name, dir, err)
+		}
+	}
+
+	os.RemoveAll(dir)
+	return manager.runtime.Start(context.Background(), uuid)
+}
+
+func (manager *GameserverManager) saveVolume(uuid, path, filename string) error {
+	content, err := manager.runtime.ExportVolume(context.Background(), uuid, path)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (manager *GameserverManager) restoreVolume(uuid, path, filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return manager.runtime.ImportVolume(context.Background(), uuid, path, file)
+}
@@ -0,0 +1,228 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func gameserverContainer(uuid, state string, labels map[string]string) Container {
+	containerLabels := map[string]string{
+		"chinchilla.gameserver.uuid": uuid,
+	}
+	for key, value := range labels {
+		containerLabels[key] = value
+	}
+	return Container{
+		ID:     uuid,
+		UUID:   uuid,
+		State:  state,
+		Labels: containerLabels,
+	}
+}
+
+func TestPlanGameserverActions(t *testing.T) {
+	updated := &proto.GameserverDeployment{
+		UUID:    "updated",
+		Image:   "minecraft:1.14",
+		Volumes: []*proto.Volume{{Name: "data", Path: "/data"}},
+	}
+	outdatedHash := deploymentConfigHash(&proto.GameserverDeployment{Image: "minecraft:1.13"})
+
+	deployments := []*proto.GameserverDeployment{
+		{UUID: "new"},
+		{UUID: "new-stopped", Stopped: true},
+		{UUID: "running"},
+		{UUID: "exited"},
+		{UUID: "to-stop", Stopped: true},
+		{UUID: "stopped", Stopped: true},
+		updated,
+		{UUID: "without-volumes", Image: "minecraft:1.14"},
+		{UUID: "legacy", Image: "minecraft:1.14"},
+	}
+	containers := []Container{
+		gameserverContainer("running", "running", nil),
+		gameserverContainer("exited", "exited", nil),
+		gameserverContainer("to-stop", "running", nil),
+		gameserverContainer("stopped", "exited", nil),
+		gameserverContainer("updated", "running", map[string]string{configHashLabel: outdatedHash}),
+		gameserverContainer("without-volumes", "running", map[string]string{configHashLabel: outdatedHash}),
+		gameserverContainer("legacy", "running", nil),
+		gameserverContainer("undeployed", "running", nil),
+	}
+
+	actions := make(map[string]proto.GameserverAction)
+	for _, action := range planGameserverActions(deployments, containers) {
+		actions[action.uuid] = action.action
+	}
+
+	assert.Equal(t, map[string]proto.GameserverAction{
+		"new":        proto.GameserverAction_ACTION_CREATE,
+		"exited":     proto.GameserverAction_ACTION_START,
+		"to-stop":    proto.GameserverAction_ACTION_STOP,
+		"updated":    proto.GameserverAction_ACTION_UPDATE,
+		"undeployed": proto.GameserverAction_ACTION_REMOVE,
+	}, actions)
+}
+
+func TestDeploymentConfigHash(t *testing.T) {
+	deployment := &proto.GameserverDeployment{
+		UUID:  "uuid",
+		Image: "minecraft:1.14",
+	}
+	hash := deploymentConfigHash(deployment)
+
+	deployment.Stopped = true
+	deployment.Agent = "other-agent"
+	assert.Equal(t, hash, deploymentConfigHash(deployment))
+
+	deployment.Image = "minecraft:1.15"
+	assert.NotEqual(t, hash, deploymentConfigHash(deployment))
+}
+
+func TestGameserverLocks(t *testing.T) {
+	locks := newGameserverLocks()
+
+	assert.True(t, locks.tryLock("uuid"))
+	assert.False(t, locks.tryLock("uuid"))
+	assert.True(t, locks.tryLock("other"))
+
+	locks.unlock("uuid")
+	assert.True(t, locks.tryLock("uuid"))
+}
+
+func TestActionResults(t *testing.T) {
+	results := &actionResults{}
+	for i := 0; i < maxActionResults+10; i++ {
+		results.add(&proto.GameserverActionResult{Duration: int64(i)})
+	}
+
+	drained := results.drain()
+	assert.Len(t, drained, maxActionResults)
+	assert.Equal(t, int64(10), drained[0].Duration)
+	assert.Empty(t, results.drain())
+}
+
+func waitForCondition(t *testing.T, condition func() bool) {
+	for i := 0; i < 200; i++ {
+		if condition() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+func TestTickWithFakeRuntime(t *testing.T) {
+	runtime := newFakeRuntime()
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{
+		IPAddresses: []string{"127.0.0.1"},
+	})
+
+	deployment := &proto.GameserverDeployment{
+		UUID:                 "uuid1",
+		Image:                "minecraft",
+		ResourceRequirements: &proto.ResourceRequirements{},
+	}
+	config := &proto.GetGameserverDeploymentsResponse{
+		Deployments: []*proto.GameserverDeployment{deployment},
+	}
+
+	waitForCondition(t, func() bool {
+		manager.Tick(config)
+		gameserver, _ := manager.GetGameserver("uuid1")
+		return gameserver != nil && gameserver.Status == proto.GameserverStatus_RUNNING
+	})
+	assert.Equal(t, 1, runtime.pullCount())
+
+	results := manager.ActionResults()
+	assert.Len(t, results, 1)
+	assert.Equal(t, proto.GameserverAction_ACTION_CREATE, results[0].Action)
+	assert.True(t, results[0].Success)
+
+	deployment.Stopped = true
+	waitForCondition(t, func() bool {
+		manager.Tick(config)
+		gameserver, _ := manager.GetGameserver("uuid1")
+		return gameserver.Status == proto.GameserverStatus_STOPPED
+	})
+
+	config.Deployments = nil
+	waitForCondition(t, func() bool {
+		manager.Tick(config)
+		gameservers, _ := manager.GetGameservers()
+		return len(gameservers) == 0
+	})
+}
+
+func TestUpdateGameserverKeepsVolumes(t *testing.T) {
+	runtime := newFakeRuntime()
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{
+		IPAddresses: []string{"127.0.0.1"},
+	})
+
+	deployment := &proto.GameserverDeployment{
+		UUID:                 "uuid1",
+		Image:                "minecraft",
+		ResourceRequirements: &proto.ResourceRequirements{},
+		Volumes:              []*proto.Volume{{Name: "data", Path: "/data"}},
+	}
+	runningFakeGameserver(t, runtime, deployment)
+	runtime.volumes["uuid1"] = map[string]string{"/data": "world"}
+	config := &proto.GetGameserverDeploymentsResponse{
+		Deployments: []*proto.GameserverDeployment{deployment},
+	}
+
+	deployment.ResourceRequirements = &proto.ResourceRequirements{CpuLimit: 2000}
+	var results []*proto.GameserverActionResult
+	waitForCondition(t, func() bool {
+		manager.Tick(config)
+		results = append(results, manager.ActionResults()...)
+		return len(results) > 0
+	})
+
+	assert.Equal(t, proto.GameserverAction_ACTION_UPDATE, results[0].Action)
+	assert.True(t, results[0].Success)
+
+	cont, err := runtime.Inspect(context.Background(), "uuid1")
+	assert.NoError(t, err)
+	assert.Equal(t, ContainerRunning, cont.State)
+	assert.Equal(t, deploymentConfigHash(deployment), cont.Labels[configHashLabel])
+	assert.Equal(t, "world", runtime.volumes["uuid1"]["/data"])
+}
+
+func TestUpdateGameserverWithoutVolumesIsRefused(t *testing.T) {
+	runtime := newFakeRuntime()
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{
+		IPAddresses: []string{"127.0.0.1"},
+	})
+
+	deployment := &proto.GameserverDeployment{
+		UUID:                 "uuid1",
+		Image:                "minecraft",
+		ResourceRequirements: &proto.ResourceRequirements{},
+	}
+	runningFakeGameserver(t, runtime, deployment)
+	outdatedHash := deploymentConfigHash(deployment)
+	config := &proto.GetGameserverDeploymentsResponse{
+		Deployments: []*proto.GameserverDeployment{deployment},
+	}
+
+	deployment.ResourceRequirements = &proto.ResourceRequirements{CpuLimit: 2000}
+	manager.Tick(config)
+	manager.Tick(config)
+
+	results := manager.ActionResults()
+	assert.Len(t, results, 1)
+	assert.Equal(t, proto.GameserverAction_ACTION_UPDATE, results[0].Action)
+	assert.False(t, results[0].Success)
+	assert.Equal(t, errDestructiveUpdate.Error(), results[0].Error)
+
+	cont, err := runtime.Inspect(context.Background(), "uuid1")
+	assert.NoError(t, err)
+	assert.Equal(t, ContainerRunning, cont.State)
+	assert.Equal(t, outdatedHash, cont.Labels[configHashLabel])
+}
@@ -0,0 +1,58 @@
+package agent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/docker/docker/api/types"
+)
+
+const defaultRegistry = "docker.io"
+
+// RegistryCredentials are used to pull images from a private registry
+type RegistryCredentials struct {
+	Host     string
+	Username string
+	Password string
+}
+
+// imageRegistry returns the registry host of an image reference
+func imageRegistry(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0]
+	}
+	return defaultRegistry
+}
+
+// encodeRegistryAuth encodes the credentials for the RegistryAuth pull option
+func encodeRegistryAuth(credentials RegistryCredentials) (string, error) {
+	data, err := json.Marshal(types.AuthConfig{
+		Username:      credentials.Username,
+		Password:      credentials.Password,
+		ServerAddress: credentials.Host,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(data), nil
+}
+
+// deploymentImage returns the image reference of the deployment,
+// pinned by digest if the deployment has one
+func deploymentImage(deployment *proto.GameserverDeployment) string {
+	if deployment.ImageDigest == "" {
+		return deployment.Image
+	}
+
+	image := deployment.Image
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + "@" + deployment.ImageDigest
+}
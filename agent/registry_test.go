@@ -0,0 +1,26 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageRegistry(t *testing.T) {
+	assert.Equal(t, "docker.io", imageRegistry("minecraft"))
+	assert.Equal(t, "docker.io", imageRegistry("itzg/minecraft-server:latest"))
+	assert.Equal(t, "registry.example.com", imageRegistry("registry.example.com/games/minecraft"))
+	assert.Equal(t, "localhost:5000", imageRegistry("localhost:5000/minecraft"))
+	assert.Equal(t, "localhost", imageRegistry("localhost/minecraft"))
+}
+
+func TestDeploymentImage(t *testing.T) {
+	deployment := &proto.GameserverDeployment{
+		Image: "registry.example.com:5000/games/minecraft:1.14",
+	}
+	assert.Equal(t, "registry.example.com:5000/games/minecraft:1.14", deploymentImage(deployment))
+
+	deployment.ImageDigest = "sha256:abcd"
+	assert.Equal(t, "registry.example.com:5000/games/minecraft@sha256:abcd", deploymentImage(deployment))
+}
@@ -2,28 +2,184 @@ package agent
 
 import (
 	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
+
+	"github.com/Trojan295/chinchilla/proto"
 )
 
-// MemoryStats struct
+// AgentConditionLowDisk is raised, when the data root is running out of space
+const AgentConditionLowDisk = "LowDisk"
+
+// MemoryStats describes the memory of the machine in kB
 type MemoryStats struct {
 	Total     int
 	Available int
 }
 
-func parseMeminfo(file *os.File) *MemoryStats {
+// CPUStats describes the CPUs available to the agent. Millicores are
+// 1/1000 of a core and reflect fractional cgroup CPU quotas
+type CPUStats struct {
+	Cores      int
+	Millicores int64
+}
+
+// DiskStats describes the filesystem of the data root in bytes
+type DiskStats struct {
+	Path  string
+	Total int64
+	Free  int64
+}
+
+// NetworkInterface describes a network interface. Speed is in Mbit/s,
+// 0 when unknown
+type NetworkInterface struct {
+	Name            string
+	HardwareAddress string
+	MTU             int
+	Speed           int64
+	Addresses       []string
+}
+
+// Resources are the resources discovered on the machine
+type Resources struct {
+	CPU               CPUStats
+	Memory            MemoryStats
+	Disk              DiskStats
+	NetworkInterfaces []NetworkInterface
+}
+
+// ResourceReservations are the resources reserved for the OS, the container
+// runtime and the agent itself. Memory is in kB, CPU in millicores
+type ResourceReservations struct {
+	OSMemory      int64
+	RuntimeMemory int64
+	AgentMemory   int64
+	CPU           int64
+}
+
+// ResourceDiscovery discovers the resources of the machine from /proc and /sys.
+// The cgroup v1 or v2 limits of the agent are applied, so agents running
+// in containers report only the resources of their container
+type ResourceDiscovery struct {
+	ProcRoot string
+	SysRoot  string
+	DataRoot string
+}
+
+// NewResourceDiscovery creates a ResourceDiscovery for the host. The disk
+// capacity is reported for the filesystem of the data root
+func NewResourceDiscovery(dataRoot string) *ResourceDiscovery {
+	if dataRoot == "" {
+		dataRoot = "/"
+	}
+
+	return &ResourceDiscovery{
+		ProcRoot: "/proc",
+		SysRoot:  "/sys",
+		DataRoot: dataRoot,
+	}
+}
+
+// Discover returns the resources of the machine
+func (discovery *ResourceDiscovery) Discover() (*Resources, error) {
+	cgroups := discovery.cgroupPaths()
+
+	memory, err := discovery.memory(cgroups)
+	if err != nil {
+		return nil, err
+	}
+
+	cpu, err := discovery.cpu(cgroups)
+	if err != nil {
+		return nil, err
+	}
+
+	disk, err := discovery.disk()
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces, err := discovery.networkInterfaces()
+	if err != nil {
+		log.Printf("Cannot list the network interfaces: %v", err)
+	}
+
+	return &Resources{
+		CPU:               cpu,
+		Memory:            *memory,
+		Disk:              disk,
+		NetworkInterfaces: interfaces,
+	}, nil
+}
+
+// AgentResources subtracts the reservations from the resources and returns
+// the resources available for gameservers with their current usage
+func (resources *Resources) AgentResources(reservations ResourceReservations) (*proto.AgentResources, *proto.AgentResourceUsage) {
+	reservedMemory := reservations.OSMemory + reservations.RuntimeMemory + reservations.AgentMemory
+
+	memory := nonNegative(int64(resources.Memory.Total) - reservedMemory)
+	usedMemory := nonNegative(int64(resources.Memory.Total-resources.Memory.Available) - reservedMemory)
+	millicores := nonNegative(resources.CPU.Millicores - reservations.CPU)
+
+	agentResources := &proto.AgentResources{
+		Cpus:          millicores / 1000,
+		CpuMillicores: millicores,
+		Memory:        memory,
+		Disk:          resources.Disk.Total,
+	}
+	for _, nic := range resources.NetworkInterfaces {
+		agentResources.NetworkInterfaces = append(agentResources.NetworkInterfaces, &proto.NetworkInterface{
+			Name:            nic.Name,
+			HardwareAddress: nic.HardwareAddress,
+			Mtu:             int64(nic.MTU),
+			Speed:           nic.Speed,
+			Addresses:       nic.Addresses,
+		})
+	}
+
+	return agentResources, &proto.AgentResourceUsage{
+		Memory:   usedMemory,
+		DiskFree: resources.Disk.Free,
+	}
+}
+
+// Conditions returns the warnings about the resources. LowDisk is raised,
+// when the free disk space drops below the threshold in percent
+func (resources *Resources) Conditions(lowDiskPercent int) []*proto.AgentCondition {
+	conditions := make([]*proto.AgentCondition, 0)
+
+	disk := resources.Disk
+	if disk.Total > 0 && disk.Free*100 < disk.Total*int64(lowDiskPercent) {
+		conditions = append(conditions, &proto.AgentCondition{
+			Type: AgentConditionLowDisk,
+			Message: fmt.Sprintf("Only %d of %d bytes free on %s (below %d%%)",
+				disk.Free, disk.Total, disk.Path, lowDiskPercent),
+		})
+	}
+
+	return conditions
+}
+
+func parseMeminfo(reader io.Reader) *MemoryStats {
 	memoryStats := &MemoryStats{}
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
-		line := scanner.Text()
-		cols := strings.Split(line, " ")
-		metric := cols[0][:len(cols[0])-1]
-		value, _ := strconv.Atoi(cols[len(cols)-2])
+		cols := strings.Fields(scanner.Text())
+		if len(cols) < 2 {
+			continue
+		}
+		metric := strings.TrimSuffix(cols[0], ":")
+		value, _ := strconv.Atoi(cols[1])
 
 		if metric == "MemTotal" {
 			memoryStats.Total = value
@@ -34,35 +190,230 @@ func parseMeminfo(file *os.File) *MemoryStats {
 	return memoryStats
 }
 
-// GetMemoryStats returns MemoryStats for the machine
-func GetMemoryStats() (*MemoryStats, error) {
-	fp, err := os.Open("/proc/meminfo")
+// memory reads /proc/meminfo and limits it to the cgroup memory limit
+func (discovery *ResourceDiscovery) memory(cgroups map[string]string) (*MemoryStats, error) {
+	fp, err := os.Open(filepath.Join(discovery.ProcRoot, "meminfo"))
 	if err != nil {
-		log.Fatalf("Cannot open /proc/meminfo: %s\n", err)
 		return nil, err
 	}
 	defer fp.Close()
 
-	return parseMeminfo(fp), nil
+	memoryStats := parseMeminfo(fp)
+
+	limit, ok := discovery.cgroupValue(cgroups, "memory", "memory.max", "memory.limit_in_bytes")
+	if !ok || limit <= 0 || int(limit/1024) >= memoryStats.Total {
+		return memoryStats, nil
+	}
+	memoryStats.Total = int(limit / 1024)
+
+	if usage, ok := discovery.cgroupValue(cgroups, "memory", "memory.current", "memory.usage_in_bytes"); ok {
+		if available := memoryStats.Total - int(usage/1024); available < memoryStats.Available {
+			memoryStats.Available = available
+		}
+	}
+	if memoryStats.Available < 0 {
+		memoryStats.Available = 0
+	}
+	if memoryStats.Available > memoryStats.Total {
+		memoryStats.Available = memoryStats.Total
+	}
+
+	return memoryStats, nil
 }
 
-// CPUStats struct
-type CPUStats struct {
-	Cores int
+// cpu counts the CPUs in /proc/stat and limits them to the cgroup CPU quota
+func (discovery *ResourceDiscovery) cpu(cgroups map[string]string) (CPUStats, error) {
+	data, err := ioutil.ReadFile(filepath.Join(discovery.ProcRoot, "stat"))
+	if err != nil {
+		return CPUStats{}, err
+	}
+
+	cores := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		if len(line) > 3 && strings.HasPrefix(line, "cpu") && line[3] >= '0' && line[3] <= '9' {
+			cores++
+		}
+	}
+
+	stats := CPUStats{
+		Cores:      cores,
+		Millicores: int64(cores) * 1000,
+	}
+
+	if quota := discovery.cpuQuota(cgroups); quota > 0 && quota < stats.Millicores {
+		stats.Millicores = quota
+		stats.Cores = int(quota / 1000)
+		if stats.Cores == 0 {
+			stats.Cores = 1
+		}
+	}
+
+	return stats, nil
+}
+
+// cpuQuota returns the cgroup CPU quota in millicores, 0 when there's none
+func (discovery *ResourceDiscovery) cpuQuota(cgroups map[string]string) int64 {
+	if data, ok := discovery.readCgroupFile(cgroups, "", "cpu.max"); ok {
+		fields := strings.Fields(data)
+		if len(fields) != 2 || fields[0] == "max" {
+			return 0
+		}
+		quota, _ := strconv.ParseInt(fields[0], 10, 64)
+		period, _ := strconv.ParseInt(fields[1], 10, 64)
+		if period <= 0 {
+			return 0
+		}
+		return quota * 1000 / period
+	}
+
+	quota, ok := discovery.cgroupValue(cgroups, "cpu", "", "cpu.cfs_quota_us")
+	if !ok || quota <= 0 {
+		return 0
+	}
+	period, ok := discovery.cgroupValue(cgroups, "cpu", "", "cpu.cfs_period_us")
+	if !ok || period <= 0 {
+		return 0
+	}
+	return quota * 1000 / period
+}
+
+// disk returns the capacity of the filesystem containing the data root
+func (discovery *ResourceDiscovery) disk() (DiskStats, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(discovery.DataRoot, &stat); err != nil {
+		return DiskStats{}, err
+	}
+
+	return DiskStats{
+		Path:  discovery.DataRoot,
+		Total: int64(stat.Blocks) * int64(stat.Bsize),
+		Free:  int64(stat.Bavail) * int64(stat.Bsize),
+	}, nil
+}
+
+// networkInterfaces returns the interfaces, which are up and not loopback
+func (discovery *ResourceDiscovery) networkInterfaces() ([]NetworkInterface, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]NetworkInterface, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		nic := NetworkInterface{
+			Name:            iface.Name,
+			HardwareAddress: iface.HardwareAddr.String(),
+			MTU:             iface.MTU,
+			Speed:           discovery.interfaceSpeed(iface.Name),
+		}
+		if addrs, err := iface.Addrs(); err == nil {
+			for _, addr := range addrs {
+				nic.Addresses = append(nic.Addresses, addr.String())
+			}
+		}
+		result = append(result, nic)
+	}
+	return result, nil
 }
 
-// GetCPUStats return CpuStats for the machine
-func GetCPUStats() (CPUStats, error) {
-	cpuStats := CPUStats{}
+// interfaceSpeed reads the link speed in Mbit/s. Virtual interfaces
+// report no speed or -1
+func (discovery *ResourceDiscovery) interfaceSpeed(name string) int64 {
+	data, err := ioutil.ReadFile(filepath.Join(discovery.SysRoot, "class", "net", name, "speed"))
+	if err != nil {
+		return 0
+	}
+
+	speed, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil || speed < 0 {
+		return 0
+	}
+	return speed
+}
 
-	out, err := exec.Command("nproc").Output()
+// cgroupPaths parses /proc/self/cgroup into the cgroup paths of the agent
+// by controller. The cgroup v2 path has an empty controller name
+func (discovery *ResourceDiscovery) cgroupPaths() map[string]string {
+	data, err := ioutil.ReadFile(filepath.Join(discovery.ProcRoot, "self", "cgroup"))
 	if err != nil {
-		log.Fatalf("Cannot exec nproc command: %s\n", err)
-		return cpuStats, err
+		return map[string]string{}
+	}
+	return parseProcCgroup(string(data))
+}
+
+func parseProcCgroup(data string) map[string]string {
+	paths := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+
+		if parts[1] == "" {
+			paths[""] = parts[2]
+			continue
+		}
+		for _, controller := range strings.Split(parts[1], ",") {
+			paths[controller] = parts[2]
+		}
 	}
+	return paths
+}
 
-	cores, _ := strconv.Atoi(strings.TrimSpace(string(out)))
-	cpuStats.Cores = cores
+// readCgroupFile reads a file of the agent cgroup. The v1 controller is
+// empty for cgroup v2 files. Inside a container the cgroup filesystem
+// shows only the container cgroup, so the hierarchy root is tried as well
+func (discovery *ResourceDiscovery) readCgroupFile(cgroups map[string]string, controller, file string) (string, bool) {
+	mountpoint := filepath.Join(discovery.SysRoot, "fs", "cgroup")
+	if controller != "" {
+		mountpoint = filepath.Join(mountpoint, controller)
+	}
 
-	return cpuStats, nil
+	path, ok := cgroups[controller]
+	if !ok {
+		return "", false
+	}
+
+	for _, dir := range []string{filepath.Join(mountpoint, path), mountpoint} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, file))
+		if err == nil {
+			return strings.TrimSpace(string(data)), true
+		}
+	}
+	return "", false
+}
+
+// cgroupValue reads a numeric cgroup v2 or v1 file. "max" is returned as 0
+func (discovery *ResourceDiscovery) cgroupValue(cgroups map[string]string, controller, v2File, v1File string) (int64, bool) {
+	data, ok := "", false
+	if v2File != "" {
+		data, ok = discovery.readCgroupFile(cgroups, "", v2File)
+	}
+	if !ok {
+		data, ok = discovery.readCgroupFile(cgroups, controller, v1File)
+	}
+	if !ok {
+		return 0, false
+	}
+
+	if data == "max" {
+		return 0, true
+	}
+	value, err := strconv.ParseInt(data, 10, 64)
+	if err != nil {
+		log.Printf("Invalid cgroup value %s: %v", data, err)
+		return 0, false
+	}
+	return value, true
+}
+
+func nonNegative(value int64) int64 {
+	if value < 0 {
+		return 0
+	}
+	return value
 }
@@ -1,8 +1,11 @@
 package agent
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestParseMeminfo(t *testing.T) {
@@ -21,3 +24,119 @@ func TestParseMeminfo(t *testing.T) {
 		t.Errorf("Total mem is %d, should be %d", memStats.Total, 16330360)
 	}
 }
+
+func fixtureResourceDiscovery(fixture string) *ResourceDiscovery {
+	return &ResourceDiscovery{
+		ProcRoot: "../mockdata/resources/" + fixture + "/proc",
+		SysRoot:  "../mockdata/resources/" + fixture + "/sys",
+		DataRoot: "../mockdata",
+	}
+}
+
+func TestResourceDiscoveryCgroupV1(t *testing.T) {
+	discovery := fixtureResourceDiscovery("cgroupv1")
+	cgroups := discovery.cgroupPaths()
+
+	memory, err := discovery.memory(cgroups)
+	assert.NoError(t, err)
+	assert.Equal(t, &MemoryStats{Total: 2097152, Available: 1572864}, memory)
+
+	cpu, err := discovery.cpu(cgroups)
+	assert.NoError(t, err)
+	assert.Equal(t, CPUStats{Cores: 1, Millicores: 1500}, cpu)
+
+	assert.Equal(t, int64(1000), discovery.interfaceSpeed("eth0"))
+	assert.Equal(t, int64(0), discovery.interfaceSpeed("docker0"))
+}
+
+func TestResourceDiscoveryCgroupV2(t *testing.T) {
+	discovery := fixtureResourceDiscovery("cgroupv2")
+	cgroups := discovery.cgroupPaths()
+
+	memory, err := discovery.memory(cgroups)
+	assert.NoError(t, err)
+	assert.Equal(t, &MemoryStats{Total: 4194304, Available: 3145728}, memory)
+
+	cpu, err := discovery.cpu(cgroups)
+	assert.NoError(t, err)
+	assert.Equal(t, CPUStats{Cores: 2, Millicores: 2500}, cpu)
+}
+
+func TestResourceDiscoveryWithoutCgroupLimits(t *testing.T) {
+	discovery := fixtureResourceDiscovery("cgroupv2")
+	cgroups := map[string]string{"": "/"}
+
+	memory, err := discovery.memory(cgroups)
+	assert.NoError(t, err)
+	assert.Equal(t, &MemoryStats{Total: 16330360, Available: 13122188}, memory)
+
+	cpu, err := discovery.cpu(cgroups)
+	assert.NoError(t, err)
+	assert.Equal(t, CPUStats{Cores: 4, Millicores: 4000}, cpu)
+}
+
+func TestResourceDiscoveryDisk(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "chinchilla")
+	defer os.RemoveAll(dir)
+
+	disk, err := (&ResourceDiscovery{DataRoot: dir}).disk()
+	assert.NoError(t, err)
+	assert.Equal(t, dir, disk.Path)
+	assert.True(t, disk.Total > 0)
+	assert.True(t, disk.Free <= disk.Total)
+
+	_, err = (&ResourceDiscovery{DataRoot: dir + "/missing"}).disk()
+	assert.Error(t, err)
+}
+
+func TestParseProcCgroup(t *testing.T) {
+	paths := parseProcCgroup("12:memory:/docker/abc\n11:cpu,cpuacct:/docker/abc\n0::/system.slice/agent.service\n")
+
+	assert.Equal(t, map[string]string{
+		"memory":  "/docker/abc",
+		"cpu":     "/docker/abc",
+		"cpuacct": "/docker/abc",
+		"":        "/system.slice/agent.service",
+	}, paths)
+}
+
+func TestAgentResourcesReservations(t *testing.T) {
+	resources := &Resources{
+		CPU:    CPUStats{Cores: 4, Millicores: 4000},
+		Memory: MemoryStats{Total: 8 * 1024 * 1024, Available: 6 * 1024 * 1024},
+		Disk:   DiskStats{Total: 100 << 30, Free: 50 << 30},
+		NetworkInterfaces: []NetworkInterface{
+			{Name: "eth0", MTU: 1500, Speed: 1000, Addresses: []string{"10.0.0.1/24"}},
+		},
+	}
+
+	agentResources, usage := resources.AgentResources(ResourceReservations{
+		OSMemory:      512 * 1024,
+		RuntimeMemory: 256 * 1024,
+		AgentMemory:   256 * 1024,
+		CPU:           500,
+	})
+
+	assert.Equal(t, int64(3), agentResources.Cpus)
+	assert.Equal(t, int64(3500), agentResources.CpuMillicores)
+	assert.Equal(t, int64(7*1024*1024), agentResources.Memory)
+	assert.Equal(t, int64(100<<30), agentResources.Disk)
+	assert.Equal(t, "eth0", agentResources.NetworkInterfaces[0].Name)
+	assert.Equal(t, int64(1000), agentResources.NetworkInterfaces[0].Speed)
+	assert.Equal(t, int64(1024*1024), usage.Memory)
+
+	_, usage = resources.AgentResources(ResourceReservations{OSMemory: 4 * 1024 * 1024})
+	assert.Equal(t, int64(0), usage.Memory)
+}
+
+func TestResourceConditions(t *testing.T) {
+	resources := &Resources{Disk: DiskStats{Path: "/var/lib/docker", Total: 1000, Free: 50}}
+
+	conditions := resources.Conditions(10)
+	assert.Len(t, conditions, 1)
+	assert.Equal(t, AgentConditionLowDisk, conditions[0].Type)
+	assert.Equal(t, "Only 50 of 1000 bytes free on /var/lib/docker (below 10%)", conditions[0].Message)
+
+	resources.Disk.Free = 100
+	assert.Empty(t, resources.Conditions(10))
+}
@@ -0,0 +1,250 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+const (
+	initialRestartBackoff     = 10 * time.Second
+	maxRestartBackoff         = 5 * time.Minute
+	restartBackoffReset       = 10 * time.Minute
+	defaultCrashLoopFailures  = 5
+	defaultCrashLoopWindow    = 10 * time.Minute
+	crashLogTailLines         = 50
+	crashLogInspectionTimeout = 10 * time.Second
+)
+
+// restartState tracks the exits and restarts of a gameserver
+type restartState struct {
+	restartCount   int
+	failures       []time.Time
+	lastExitCode   int
+	lastFinishedAt string
+	lastRestartAt  time.Time
+	nextRestart    time.Time
+	backoff        time.Duration
+	crashLoop      bool
+	crashLog       string
+}
+
+// recordExit registers a container exit. The same exit, identified by
+// finishedAt, is only registered once
+func (state *restartState) recordExit(policy *proto.RestartPolicy, exitCode int, finishedAt string, now time.Time) {
+	if finishedAt == state.lastFinishedAt {
+		return
+	}
+	state.lastFinishedAt = finishedAt
+	state.lastExitCode = exitCode
+
+	if !state.lastRestartAt.IsZero() && now.Sub(state.lastRestartAt) > restartBackoffReset {
+		state.backoff = 0
+	}
+
+	if state.backoff == 0 {
+		state.backoff = initialRestartBackoff
+	} else {
+		state.backoff *= 2
+		if state.backoff > maxRestartBackoff {
+			state.backoff = maxRestartBackoff
+		}
+	}
+	state.nextRestart = now.Add(state.backoff)
+
+	if exitCode == 0 {
+		return
+	}
+
+	window := defaultCrashLoopWindow
+	threshold := defaultCrashLoopFailures
+	if policy != nil && policy.CrashLoopWindow > 0 {
+		window = time.Duration(policy.CrashLoopWindow) * time.Second
+	}
+	if policy != nil && policy.CrashLoopFailures > 0 {
+		threshold = int(policy.CrashLoopFailures)
+	}
+
+	failures := []time.Time{now}
+	for _, failure := range state.failures {
+		if now.Sub(failure) < window {
+			failures = append(failures, failure)
+		}
+	}
+	state.failures = failures
+
+	if len(state.failures) >= threshold {
+		state.crashLoop = true
+	}
+}
+
+// shouldRestart tells, if the exited container should be started now
+func (state *restartState) shouldRestart(policy *proto.RestartPolicy, now time.Time) bool {
+	if state.crashLoop {
+		return false
+	}
+
+	policyType := proto.RestartPolicyType_RESTART_ON_FAILURE
+	if policy != nil {
+		policyType = policy.Type
+	}
+
+	switch policyType {
+	case proto.RestartPolicyType_RESTART_NEVER:
+		return false
+	case proto.RestartPolicyType_RESTART_ON_FAILURE:
+		if state.lastExitCode == 0 {
+			return false
+		}
+	}
+
+	if policy != nil && policy.MaxRetries > 0 && state.restartCount >= int(policy.MaxRetries) {
+		return false
+	}
+
+	return !now.Before(state.nextRestart)
+}
+
+// gameserverRestarts tracks the restart state of all gameservers
+type gameserverRestarts struct {
+	mutex     sync.Mutex
+	states    map[string]*restartState
+	stoppedBy map[string]bool
+}
+
+func newGameserverRestarts() *gameserverRestarts {
+	return &gameserverRestarts{
+		states:    make(map[string]*restartState),
+		stoppedBy: make(map[string]bool),
+	}
+}
+
+// get returns a copy of the gameserver restart state
+func (restarts *gameserverRestarts) get(uuid string) *restartState {
+	restarts.mutex.Lock()
+	defer restarts.mutex.Unlock()
+
+	state, ok := restarts.states[uuid]
+	if !ok {
+		return nil
+	}
+	stateCopy := *state
+	return &stateCopy
+}
+
+// markStopped remembers, that the gameserver was stopped on purpose
+// and clears its crash history
+func (restarts *gameserverRestarts) markStopped(uuid string) {
+	restarts.mutex.Lock()
+	defer restarts.mutex.Unlock()
+
+	restarts.stoppedBy[uuid] = true
+	delete(restarts.states, uuid)
+}
+
+func (restarts *gameserverRestarts) clearStopped(uuid string) bool {
+	restarts.mutex.Lock()
+	defer restarts.mutex.Unlock()
+
+	stopped := restarts.stoppedBy[uuid]
+	delete(restarts.stoppedBy, uuid)
+	return stopped
+}
+
+func (restarts *gameserverRestarts) remove(uuid string) {
+	restarts.mutex.Lock()
+	defer restarts.mutex.Unlock()
+
+	delete(restarts.states, uuid)
+	delete(restarts.stoppedBy, uuid)
+}
+
+func (restarts *gameserverRestarts) update(uuid string, updateFunc func(*restartState)) {
+	restarts.mutex.Lock()
+	defer restarts.mutex.Unlock()
+
+	state, ok := restarts.states[uuid]
+	if !ok {
+		state = &restartState{}
+		restarts.states[uuid] = state
+	}
+	updateFunc(state)
+}
+
+// handleExitedGameserver applies the restart policy to an exited gameserver
+// and tells, if the gameserver was started
+func (manager *GameserverManager) handleExitedGameserver(deployment *proto.GameserverDeployment) (bool, error) {
+	ctx := context.Background()
+
+	if manager.restarts.clearStopped(deployment.UUID) {
+		log.Printf("Starting gameserver %s...", deployment.UUID)
+		return true, manager.StartGameserver(deployment.UUID)
+	}
+
+	cont, err := manager.runtime.Inspect(ctx, deployment.UUID)
+	if err != nil {
+		return false, err
+	}
+	if cont.State == ContainerRunning {
+		return false, nil
+	}
+
+	now := time.Now()
+	var state restartState
+	manager.restarts.update(deployment.UUID, func(s *restartState) {
+		wasCrashLoop := s.crashLoop
+		s.recordExit(deployment.RestartPolicy, cont.ExitCode, cont.FinishedAt, now)
+		if s.crashLoop && !wasCrashLoop {
+			log.Printf("Gameserver %s is in a crash loop, last exit code %d", deployment.UUID, s.lastExitCode)
+		}
+		state = *s
+	})
+
+	if state.crashLoop && state.crashLog == "" {
+		crashLog, err := manager.getContainerLogTail(cont.ID)
+		if err != nil {
+			log.Printf("Cannot get logs of gameserver %s: %s", deployment.UUID, err)
+		}
+		manager.restarts.update(deployment.UUID, func(s *restartState) {
+			s.crashLog = crashLog
+		})
+	}
+
+	if !state.shouldRestart(deployment.RestartPolicy, now) {
+		return false, nil
+	}
+
+	log.Printf("Restarting gameserver %s, exit code %d", deployment.UUID, state.lastExitCode)
+	if err := manager.StartGameserver(deployment.UUID); err != nil {
+		return true, err
+	}
+
+	manager.restarts.update(deployment.UUID, func(s *restartState) {
+		s.restartCount++
+		s.lastRestartAt = now
+	})
+	return true, nil
+}
+
+func (manager *GameserverManager) getContainerLogTail(containerID string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), crashLogInspectionTimeout)
+	defer cancel()
+
+	return manager.runtime.Logs(ctx, containerID, crashLogTailLines)
+}
+
+func gameserverExitStatus(state *restartState) proto.GameserverStatus {
+	if state == nil {
+		return proto.GameserverStatus_STOPPED
+	}
+	if state.crashLoop {
+		return proto.GameserverStatus_CRASH_LOOP
+	}
+	if state.lastExitCode != 0 {
+		return proto.GameserverStatus_ERROR
+	}
+	return proto.GameserverStatus_STOPPED
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestartBackoff(t *testing.T) {
+	now := time.Now()
+	state := &restartState{}
+
+	state.recordExit(nil, 1, "exit-1", now)
+	assert.False(t, state.shouldRestart(nil, now))
+	assert.True(t, state.shouldRestart(nil, now.Add(10*time.Second)))
+
+	state.restartCount++
+	state.lastRestartAt = now.Add(10 * time.Second)
+
+	now = now.Add(20 * time.Second)
+	state.recordExit(nil, 1, "exit-2", now)
+	assert.False(t, state.shouldRestart(nil, now.Add(10*time.Second)))
+	assert.True(t, state.shouldRestart(nil, now.Add(20*time.Second)))
+}
+
+func TestRecordExitIsIdempotent(t *testing.T) {
+	now := time.Now()
+	state := &restartState{}
+
+	state.recordExit(nil, 1, "exit-1", now)
+	state.recordExit(nil, 1, "exit-1", now.Add(time.Second))
+
+	assert.Equal(t, initialRestartBackoff, state.backoff)
+	assert.Len(t, state.failures, 1)
+}
+
+func TestCrashLoopDetection(t *testing.T) {
+	policy := &proto.RestartPolicy{
+		CrashLoopFailures: 3,
+		CrashLoopWindow:   60,
+	}
+	now := time.Now()
+	state := &restartState{}
+
+	state.recordExit(policy, 1, "exit-1", now)
+	state.recordExit(policy, 1, "exit-2", now.Add(10*time.Second))
+	assert.False(t, state.crashLoop)
+
+	state.recordExit(policy, 137, "exit-3", now.Add(20*time.Second))
+	assert.True(t, state.crashLoop)
+	assert.False(t, state.shouldRestart(policy, now.Add(time.Hour)))
+	assert.Equal(t, proto.GameserverStatus_CRASH_LOOP, gameserverExitStatus(state))
+}
+
+func TestCrashLoopWindow(t *testing.T) {
+	policy := &proto.RestartPolicy{
+		CrashLoopFailures: 2,
+		CrashLoopWindow:   60,
+	}
+	now := time.Now()
+	state := &restartState{}
+
+	state.recordExit(policy, 1, "exit-1", now)
+	state.recordExit(policy, 1, "exit-2", now.Add(2*time.Minute))
+
+	assert.False(t, state.crashLoop)
+	assert.Equal(t, proto.GameserverStatus_ERROR, gameserverExitStatus(state))
+}
+
+func TestRestartPolicies(t *testing.T) {
+	now := time.Now()
+
+	clean := &restartState{}
+	clean.recordExit(nil, 0, "exit-1", now)
+	later := now.Add(time.Minute)
+
+	assert.False(t, clean.shouldRestart(nil, later))
+	assert.False(t, clean.shouldRestart(&proto.RestartPolicy{Type: proto.RestartPolicyType_RESTART_ON_FAILURE}, later))
+	assert.True(t, clean.shouldRestart(&proto.RestartPolicy{Type: proto.RestartPolicyType_RESTART_ALWAYS}, later))
+
+	failed := &restartState{}
+	failed.recordExit(nil, 1, "exit-1", now)
+	assert.False(t, failed.shouldRestart(&proto.RestartPolicy{Type: proto.RestartPolicyType_RESTART_NEVER}, later))
+
+	failed.restartCount = 3
+	assert.False(t, failed.shouldRestart(&proto.RestartPolicy{MaxRetries: 3}, later))
+	assert.True(t, failed.shouldRestart(&proto.RestartPolicy{MaxRetries: 4}, later))
+}
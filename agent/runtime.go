@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/docker/docker/api"
+	"github.com/docker/docker/client"
+)
+
+// Labels of the gameserver containers
+const (
+	uuidLabel      = "chinchilla.gameserver.uuid"
+	ipAddressLabel = "chinchilla.gameserver.ip_address"
+)
+
+// States of a gameserver container
+const (
+	ContainerCreated = "created"
+	ContainerRunning = "running"
+	ContainerExited  = "exited"
+)
+
+// ErrImageNotFound is returned, when the image is not present on the host
+var ErrImageNotFound = errors.New("Image not found")
+
+// Container describes a gameserver container
+type Container struct {
+	ID         string
+	UUID       string
+	State      string
+	Labels     map[string]string
+	ImageID    string
+	IPAddress  string
+	Health     proto.HealthStatus
+	ExitCode   int
+	FinishedAt string
+}
+
+// Image describes an image present on the host
+type Image struct {
+	ID          string
+	RepoDigests []string
+}
+
+// ExecResult is the result of a command executed in a container
+type ExecResult struct {
+	ExitCode int
+	Output   string
+}
+
+// PullProgress is a progress update of a single image layer
+type PullProgress struct {
+	Layer   string
+	Status  string
+	Current int64
+	Total   int64
+}
+
+// Runtime runs the gameserver containers. Containers are named
+// and looked up by the gameserver UUID. Volumes are exported and imported
+// as tar archives with the directory name as the top level entry
+type Runtime interface {
+	List(ctx context.Context) ([]Container, error)
+	Inspect(ctx context.Context, id string) (*Container, error)
+	Create(ctx context.Context, deployment *proto.GameserverDeployment, ipAddress string) (string, error)
+	Start(ctx context.Context, id string) error
+	Stop(ctx context.Context, id string, timeout time.Duration) error
+	Kill(ctx context.Context, id, signal string) error
+	SendInput(ctx context.Context, id, input string) error
+	Remove(ctx context.Context, id string) error
+	Logs(ctx context.Context, id string, tail int) (string, error)
+	Stats(ctx context.Context, id string) (*proto.GameserverResourceUsage, error)
+	Exec(ctx context.Context, id string, command []string) (*ExecResult, error)
+	ExportVolume(ctx context.Context, id, path string) (io.ReadCloser, error)
+	ImportVolume(ctx context.Context, id, path string, content io.Reader) error
+
+	PullImage(ctx context.Context, image string, credentials *RegistryCredentials, progress func(PullProgress)) error
+	InspectImage(ctx context.Context, image string) (*Image, error)
+}
+
+// containerLabels returns the labels of a gameserver container
+func containerLabels(deployment *proto.GameserverDeployment, ipAddress string) map[string]string {
+	labels := map[string]string{
+		uuidLabel:       deployment.UUID,
+		ipAddressLabel:  ipAddress,
+		configHashLabel: deploymentConfigHash(deployment),
+	}
+	if deployment.Shutdown != nil {
+		labels[shutdownLabel] = encodeShutdownPolicy(deployment.Shutdown)
+	}
+	return labels
+}
+
+// RuntimeConfig selects and configures the gameserver runtime
+type RuntimeConfig struct {
+	Name       string
+	Host       string
+	ProcessDir string
+	CgroupRoot string
+}
+
+// RuntimeName returns the name of the runtime reported to the server
+func (config RuntimeConfig) RuntimeName() string {
+	if config.Name == "" {
+		return "docker"
+	}
+	return config.Name
+}
+
+// NewRuntime creates the runtime with the given name. An empty host
+// uses the default socket of the runtime
+func NewRuntime(config RuntimeConfig) (Runtime, error) {
+	host := config.Host
+
+	switch config.Name {
+	case "", "docker":
+		if host == "" {
+			docker, err := client.NewEnvClient()
+			if err != nil {
+				return nil, err
+			}
+			return NewDockerRuntime(docker), nil
+		}
+
+		docker, err := client.NewClient(host, api.DefaultVersion, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewDockerRuntime(docker), nil
+
+	case "podman":
+		if host == "" {
+			host = defaultPodmanHost
+		}
+
+		podman, err := client.NewClient(host, api.DefaultVersion, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewPodmanRuntime(podman), nil
+
+	case "process":
+		return NewProcessRuntime(config.ProcessDir, config.CgroupRoot)
+	}
+
+	return nil, fmt.Errorf("Unsupported runtime %s", config.Name)
+}
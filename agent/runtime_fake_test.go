@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+)
+
+var errContainerNotFound = errors.New("No such container")
+
+// fakeRuntime is an in-memory Runtime for unit tests
+type fakeRuntime struct {
+	mutex        sync.Mutex
+	containers   map[string]*Container
+	images       map[string]*Image
+	files        map[string]map[string]string
+	volumes      map[string]map[string]string
+	logs         map[string]string
+	stats        map[string]*proto.GameserverResourceUsage
+	pulls        []string
+	pullAuth     *RegistryCredentials
+	pullProgress []PullProgress
+	pullErr      error
+	pullBlock    chan struct{}
+	inputs       map[string][]string
+	signals      []string
+
+	// ignoreSignals makes the containers survive all signals except SIGKILL
+	ignoreSignals bool
+	// exitOnInput makes the containers exit after receiving an input
+	exitOnInput bool
+}
+
+func newFakeRuntime() *fakeRuntime {
+	return &fakeRuntime{
+		containers: make(map[string]*Container),
+		images:     make(map[string]*Image),
+		files:      make(map[string]map[string]string),
+		volumes:    make(map[string]map[string]string),
+		logs:       make(map[string]string),
+		stats:      make(map[string]*proto.GameserverResourceUsage),
+		inputs:     make(map[string][]string),
+	}
+}
+
+func (runtime *fakeRuntime) List(ctx context.Context) ([]Container, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	containers := make([]Container, 0, len(runtime.containers))
+	for _, cont := range runtime.containers {
+		containers = append(containers, *cont)
+	}
+	return containers, nil
+}
+
+func (runtime *fakeRuntime) Inspect(ctx context.Context, id string) (*Container, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	cont, ok := runtime.containers[id]
+	if !ok {
+		return nil, errContainerNotFound
+	}
+	contCopy := *cont
+	return &contCopy, nil
+}
+
+func (runtime *fakeRuntime) Create(ctx context.Context, deployment *proto.GameserverDeployment, ipAddress string) (string, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if _, ok := runtime.containers[deployment.UUID]; ok {
+		return "", errors.New("Container already exists")
+	}
+
+	runtime.containers[deployment.UUID] = &Container{
+		ID:      deployment.UUID,
+		UUID:    deployment.UUID,
+		State:   ContainerCreated,
+		Labels:  containerLabels(deployment, ipAddress),
+		ImageID: deploymentImage(deployment),
+	}
+	return deployment.UUID, nil
+}
+
+func (runtime *fakeRuntime) Start(ctx context.Context, id string) error {
+	return runtime.setState(id, ContainerRunning, 0)
+}
+
+func (runtime *fakeRuntime) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	return runtime.setState(id, ContainerExited, 0)
+}
+
+func (runtime *fakeRuntime) Kill(ctx context.Context, id, signal string) error {
+	runtime.mutex.Lock()
+	runtime.signals = append(runtime.signals, signal)
+	ignore := runtime.ignoreSignals
+	runtime.mutex.Unlock()
+
+	if signal == "SIGKILL" {
+		return runtime.setState(id, ContainerExited, 137)
+	} else if !ignore {
+		return runtime.setState(id, ContainerExited, 143)
+	}
+	return nil
+}
+
+func (runtime *fakeRuntime) SendInput(ctx context.Context, id, input string) error {
+	runtime.mutex.Lock()
+	runtime.inputs[id] = append(runtime.inputs[id], input)
+	exit := runtime.exitOnInput
+	runtime.mutex.Unlock()
+
+	if exit {
+		return runtime.setState(id, ContainerExited, 0)
+	}
+	return nil
+}
+
+// exit simulates a container exiting on its own
+func (runtime *fakeRuntime) exit(id string, exitCode int) error {
+	return runtime.setState(id, ContainerExited, exitCode)
+}
+
+func (runtime *fakeRuntime) setState(id, state string, exitCode int) error {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	cont, ok := runtime.containers[id]
+	if !ok {
+		return errContainerNotFound
+	}
+	cont.State = state
+	if state == ContainerExited {
+		cont.ExitCode = exitCode
+		cont.FinishedAt = time.Now().Format(time.RFC3339Nano)
+	}
+	return nil
+}
+
+func (runtime *fakeRuntime) Remove(ctx context.Context, id string) error {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if _, ok := runtime.containers[id]; !ok {
+		return errContainerNotFound
+	}
+	delete(runtime.containers, id)
+	delete(runtime.volumes, id)
+	return nil
+}
+
+func (runtime *fakeRuntime) Logs(ctx context.Context, id string, tail int) (string, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	lines := strings.Split(runtime.logs[id], "\n")
+	if len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (runtime *fakeRuntime) Stats(ctx context.Context, id string) (*proto.GameserverResourceUsage, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+	return runtime.stats[id], nil
+}
+
+// Exec supports only reading files with cat
+func (runtime *fakeRuntime) Exec(ctx context.Context, id string, command []string) (*ExecResult, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if _, ok := runtime.containers[id]; !ok {
+		return nil, errContainerNotFound
+	}
+	if len(command) != 2 || command[0] != "cat" {
+		return &ExecResult{ExitCode: 127, Output: "command not found"}, nil
+	}
+
+	content, ok := runtime.files[id][command[1]]
+	if !ok {
+		return &ExecResult{ExitCode: 1, Output: "No such file or directory"}, nil
+	}
+	return &ExecResult{Output: content}, nil
+}
+
+// ExportVolume returns the volume content as the archive
+func (runtime *fakeRuntime) ExportVolume(ctx context.Context, id, path string) (io.ReadCloser, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if _, ok := runtime.containers[id]; !ok {
+		return nil, errContainerNotFound
+	}
+	return ioutil.NopCloser(strings.NewReader(runtime.volumes[id][path])), nil
+}
+
+func (runtime *fakeRuntime) ImportVolume(ctx context.Context, id, path string, content io.Reader) error {
+	data, err := ioutil.ReadAll(content)
+	if err != nil {
+		return err
+	}
+
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if _, ok := runtime.containers[id]; !ok {
+		return errContainerNotFound
+	}
+	if runtime.volumes[id] == nil {
+		runtime.volumes[id] = make(map[string]string)
+	}
+	runtime.volumes[id][path] = string(data)
+	return nil
+}
+
+func (runtime *fakeRuntime) PullImage(ctx context.Context, image string, credentials *RegistryCredentials, progress func(PullProgress)) error {
+	runtime.mutex.Lock()
+	runtime.pulls = append(runtime.pulls, image)
+	runtime.pullAuth = credentials
+	block := runtime.pullBlock
+	runtime.mutex.Unlock()
+
+	if block != nil {
+		<-block
+	}
+
+	for _, update := range runtime.pullProgress {
+		progress(update)
+	}
+
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if runtime.pullErr != nil {
+		return runtime.pullErr
+	}
+	runtime.images[image] = &Image{ID: image}
+	return nil
+}
+
+func (runtime *fakeRuntime) InspectImage(ctx context.Context, image string) (*Image, error) {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+
+	if img, ok := runtime.images[image]; ok {
+		return img, nil
+	}
+	return nil, ErrImageNotFound
+}
+
+func (runtime *fakeRuntime) pullCount() int {
+	runtime.mutex.Lock()
+	defer runtime.mutex.Unlock()
+	return len(runtime.pulls)
+}
@@ -0,0 +1,155 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Trojan295/chinchilla/agent/query"
+	"github.com/Trojan295/chinchilla/proto"
+	protobuf "github.com/golang/protobuf/proto"
+)
+
+const (
+	shutdownLabel          = "chinchilla.gameserver.shutdown"
+	defaultStopSignal      = "SIGTERM"
+	defaultShutdownTimeout = 30 * time.Second
+	shutdownKillTimeout    = 10 * time.Second
+)
+
+var shutdownPollInterval = 200 * time.Millisecond
+
+// encodeShutdownPolicy stores the shutdown policy in a container label,
+// so gameservers removed from the deployments can be still stopped gracefully
+func encodeShutdownPolicy(policy *proto.ShutdownPolicy) string {
+	data, _ := protobuf.Marshal(policy)
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+// containerShutdownPolicy returns the shutdown policy of the container
+// or the default policy, if the container has none
+func containerShutdownPolicy(cont *Container) *proto.ShutdownPolicy {
+	policy := &proto.ShutdownPolicy{}
+
+	encoded, ok := cont.Labels[shutdownLabel]
+	if !ok {
+		return policy
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err == nil {
+		err = protobuf.Unmarshal(data, policy)
+	}
+	if err != nil {
+		log.Printf("Invalid shutdown policy of gameserver %s: %v", cont.UUID, err)
+		return &proto.ShutdownPolicy{}
+	}
+	return policy
+}
+
+// shutdownGameserver stops the gameserver gracefully using the console
+// command or the stop signal and kills it, when it doesn't exit within
+// the timeout. A nil policy uses the one stored in the container
+func (manager *GameserverManager) shutdownGameserver(uuid string, policy *proto.ShutdownPolicy) (*proto.ShutdownResult, error) {
+	ctx := context.Background()
+
+	cont, err := manager.runtime.Inspect(ctx, uuid)
+	if err != nil {
+		return nil, err
+	}
+	if cont.State != ContainerRunning {
+		return nil, nil
+	}
+	if policy == nil {
+		policy = containerShutdownPolicy(cont)
+	}
+
+	timeout := defaultShutdownTimeout
+	if policy.Timeout > 0 {
+		timeout = time.Duration(policy.Timeout) * time.Second
+	}
+
+	start := time.Now()
+	result := &proto.ShutdownResult{}
+
+	if policy.Command != "" {
+		if err := manager.sendShutdownCommand(cont, policy); err != nil {
+			log.Printf("Cannot send the shutdown command to gameserver %s: %v", uuid, err)
+		} else {
+			result.Method = fmt.Sprintf("command %q via %s", policy.Command, shutdownChannelName(policy.Channel))
+		}
+	}
+
+	if result.Method == "" {
+		signal := policy.Signal
+		if signal == "" {
+			signal = defaultStopSignal
+		}
+		if err := manager.runtime.Kill(ctx, uuid, signal); err != nil {
+			return nil, err
+		}
+		result.Method = "signal " + signal
+	}
+
+	result.Graceful = manager.waitForExit(uuid, timeout)
+	if !result.Graceful {
+		log.Printf("Gameserver %s did not shut down within %s, killing it", uuid, timeout)
+		if err := manager.runtime.Kill(ctx, uuid, "SIGKILL"); err != nil {
+			return result, err
+		}
+		if !manager.waitForExit(uuid, shutdownKillTimeout) {
+			return result, fmt.Errorf("Gameserver %s did not exit after SIGKILL", uuid)
+		}
+	}
+
+	result.Duration = int64(time.Since(start) / time.Millisecond)
+	return result, nil
+}
+
+func (manager *GameserverManager) sendShutdownCommand(cont *Container, policy *proto.ShutdownPolicy) error {
+	if policy.Channel == proto.ShutdownChannel_SHUTDOWN_STDIN {
+		return manager.runtime.SendInput(context.Background(), cont.ID, policy.Command)
+	}
+
+	password := policy.RconPassword
+	if password == "" && policy.RconPasswordFile != "" {
+		var err error
+		password, err = manager.readContainerFile(cont.ID, policy.RconPasswordFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	address := net.JoinHostPort(containerIPAddress(cont), fmt.Sprintf("%d", policy.RconPort))
+	_, err := query.ExecuteRcon(address, policy.Command, query.Options{
+		Password: strings.TrimSpace(password),
+		Timeout:  gameserverQueryTimeout,
+	})
+	return err
+}
+
+// waitForExit polls the container, until it's not running anymore
+func (manager *GameserverManager) waitForExit(uuid string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		cont, err := manager.runtime.Inspect(context.Background(), uuid)
+		if err != nil || cont.State != ContainerRunning {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(shutdownPollInterval)
+	}
+}
+
+func shutdownChannelName(channel proto.ShutdownChannel) string {
+	if channel == proto.ShutdownChannel_SHUTDOWN_RCON {
+		return "RCON"
+	}
+	return "stdin"
+}
@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/proto"
+	protobuf "github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+)
+
+func runningFakeGameserver(t *testing.T, runtime *fakeRuntime, deployment *proto.GameserverDeployment) {
+	ctx := context.Background()
+	if _, err := runtime.Create(ctx, deployment, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := runtime.Start(ctx, deployment.UUID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShutdownWithConsoleCommand(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.exitOnInput = true
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{})
+
+	policy := &proto.ShutdownPolicy{Command: "stop", Timeout: 1}
+	runningFakeGameserver(t, runtime, &proto.GameserverDeployment{UUID: "uuid1", Shutdown: policy})
+
+	result, err := manager.StopGameserver("uuid1", policy)
+
+	assert.NoError(t, err)
+	assert.True(t, result.Graceful)
+	assert.Equal(t, `command "stop" via stdin`, result.Method)
+	assert.Equal(t, []string{"stop"}, runtime.inputs["uuid1"])
+	assert.Empty(t, runtime.signals)
+}
+
+func TestShutdownWithSignalFromLabel(t *testing.T) {
+	runtime := newFakeRuntime()
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{})
+
+	runningFakeGameserver(t, runtime, &proto.GameserverDeployment{
+		UUID:     "uuid1",
+		Shutdown: &proto.ShutdownPolicy{Signal: "SIGINT"},
+	})
+
+	result, err := manager.RemoveGameserver("uuid1")
+
+	assert.NoError(t, err)
+	assert.True(t, result.Graceful)
+	assert.Equal(t, "signal SIGINT", result.Method)
+	assert.Equal(t, []string{"SIGINT"}, runtime.signals)
+	assert.Empty(t, runtime.containers)
+}
+
+func TestShutdownKillsAfterTimeout(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.ignoreSignals = true
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{})
+
+	runningFakeGameserver(t, runtime, &proto.GameserverDeployment{UUID: "uuid1"})
+
+	result, err := manager.StopGameserver("uuid1", &proto.ShutdownPolicy{Timeout: 1})
+
+	assert.NoError(t, err)
+	assert.False(t, result.Graceful)
+	assert.Equal(t, "signal SIGTERM", result.Method)
+	assert.Equal(t, []string{"SIGTERM", "SIGKILL"}, runtime.signals)
+
+	cont, _ := runtime.Inspect(context.Background(), "uuid1")
+	assert.Equal(t, ContainerExited, cont.State)
+	assert.Equal(t, 137, cont.ExitCode)
+}
+
+func TestShutdownSkipsExitedGameservers(t *testing.T) {
+	runtime := newFakeRuntime()
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{})
+
+	runningFakeGameserver(t, runtime, &proto.GameserverDeployment{UUID: "uuid1"})
+	runtime.exit("uuid1", 0)
+
+	result, err := manager.StopGameserver("uuid1", nil)
+
+	assert.NoError(t, err)
+	assert.Nil(t, result)
+	assert.Empty(t, runtime.signals)
+}
+
+func TestContainerShutdownPolicy(t *testing.T) {
+	policy := &proto.ShutdownPolicy{Command: "stop", Timeout: 60}
+	cont := &Container{Labels: containerLabels(&proto.GameserverDeployment{Shutdown: policy}, "127.0.0.1")}
+
+	assert.True(t, protobuf.Equal(policy, containerShutdownPolicy(cont)))
+	assert.True(t, protobuf.Equal(&proto.ShutdownPolicy{}, containerShutdownPolicy(&Container{})))
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/docker/docker/api/types"
+)
+
+// containerStats caches the resource usage of the containers. Collecting
+// the stats blocks for a sampling interval, so it runs in the background
+type containerStats struct {
+	mutex    sync.Mutex
+	usage    map[string]*proto.GameserverResourceUsage
+	inFlight map[string]bool
+}
+
+func newContainerStats() *containerStats {
+	return &containerStats{
+		usage:    make(map[string]*proto.GameserverResourceUsage),
+		inFlight: make(map[string]bool),
+	}
+}
+
+func (stats *containerStats) get(containerID string) *proto.GameserverResourceUsage {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return stats.usage[containerID]
+}
+
+func (stats *containerStats) start(containerID string) bool {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	if stats.inFlight[containerID] {
+		return false
+	}
+	stats.inFlight[containerID] = true
+	return true
+}
+
+func (stats *containerStats) finish(containerID string, usage *proto.GameserverResourceUsage) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	delete(stats.inFlight, containerID)
+	stats.usage[containerID] = usage
+}
+
+// retain removes the usage of the containers, which are not running anymore
+func (stats *containerStats) retain(running map[string]bool) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	for containerID := range stats.usage {
+		if !running[containerID] {
+			delete(stats.usage, containerID)
+		}
+	}
+}
+
+// collectStats starts a concurrent background collection of
+// the stats of every running container
+func (manager *GameserverManager) collectStats(containers []Container) {
+	running := make(map[string]bool, len(containers))
+	for _, cont := range containers {
+		if cont.State != ContainerRunning {
+			continue
+		}
+		running[cont.ID] = true
+
+		if !manager.stats.start(cont.ID) {
+			continue
+		}
+
+		go func(containerID string) {
+			usage, err := manager.runtime.Stats(context.Background(), containerID)
+			if err != nil {
+				log.Printf("Cannot get stats of container %s: %s", containerID, err)
+			}
+			manager.stats.finish(containerID, usage)
+		}(cont.ID)
+	}
+	manager.stats.retain(running)
+}
+
+func calculateResourceUsage(stats *types.StatsJSON) *proto.GameserverResourceUsage {
+	usage := &proto.GameserverResourceUsage{
+		CpuPercent:  calculateCPUPercent(&stats.Stats),
+		MemoryUsage: int64(calculateMemoryRSS(&stats.MemoryStats) / 1024),
+		MemoryLimit: int64(stats.MemoryStats.Limit / 1024),
+	}
+
+	for _, network := range stats.Networks {
+		usage.NetworkRxBytes += int64(network.RxBytes)
+		usage.NetworkTxBytes += int64(network.TxBytes)
+	}
+
+	for _, entry := range stats.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			usage.BlockReadBytes += int64(entry.Value)
+		case "write":
+			usage.BlockWriteBytes += int64(entry.Value)
+		}
+	}
+
+	return usage
+}
+
+func calculateCPUPercent(stats *types.Stats) float64 {
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	if onlineCPUs == 0 {
+		onlineCPUs = float64(len(stats.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	if cpuDelta <= 0 || systemDelta <= 0 {
+		return 0
+	}
+
+	return cpuDelta / systemDelta * onlineCPUs * 100
+}
+
+func calculateMemoryRSS(stats *types.MemoryStats) uint64 {
+	if rss, ok := stats.Stats["rss"]; ok {
+		return rss
+	}
+
+	cache := stats.Stats["cache"]
+	if stats.Usage < cache {
+		return 0
+	}
+	return stats.Usage - cache
+}
@@ -0,0 +1,90 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateResourceUsage(t *testing.T) {
+	stats := &types.StatsJSON{
+		Stats: types.Stats{
+			CPUStats: types.CPUStats{
+				CPUUsage: types.CPUUsage{
+					TotalUsage: 300,
+				},
+				SystemUsage: 2000,
+				OnlineCPUs:  4,
+			},
+			PreCPUStats: types.CPUStats{
+				CPUUsage: types.CPUUsage{
+					TotalUsage: 100,
+				},
+				SystemUsage: 1000,
+			},
+			MemoryStats: types.MemoryStats{
+				Usage: 4096 * 1024,
+				Limit: 8192 * 1024,
+				Stats: map[string]uint64{
+					"rss":   2048 * 1024,
+					"cache": 1024 * 1024,
+				},
+			},
+			BlkioStats: types.BlkioStats{
+				IoServiceBytesRecursive: []types.BlkioStatEntry{
+					{Op: "Read", Value: 100},
+					{Op: "Write", Value: 200},
+					{Op: "Total", Value: 300},
+				},
+			},
+		},
+		Networks: map[string]types.NetworkStats{
+			"eth0": {RxBytes: 10, TxBytes: 20},
+			"eth1": {RxBytes: 1, TxBytes: 2},
+		},
+	}
+
+	usage := calculateResourceUsage(stats)
+
+	assert.Equal(t, 80.0, usage.CpuPercent)
+	assert.Equal(t, int64(2048), usage.MemoryUsage)
+	assert.Equal(t, int64(8192), usage.MemoryLimit)
+	assert.Equal(t, int64(11), usage.NetworkRxBytes)
+	assert.Equal(t, int64(22), usage.NetworkTxBytes)
+	assert.Equal(t, int64(100), usage.BlockReadBytes)
+	assert.Equal(t, int64(200), usage.BlockWriteBytes)
+}
+
+func TestCalculateMemoryRSSWithoutRSSStat(t *testing.T) {
+	stats := &types.MemoryStats{
+		Usage: 3000,
+		Stats: map[string]uint64{
+			"cache": 1000,
+		},
+	}
+
+	assert.Equal(t, uint64(2000), calculateMemoryRSS(stats))
+}
+
+func TestCollectStatsCachesUsage(t *testing.T) {
+	runtime := newFakeRuntime()
+	runtime.containers["uuid1"] = &Container{ID: "uuid1", UUID: "uuid1", State: ContainerRunning}
+	runtime.stats["uuid1"] = &proto.GameserverResourceUsage{MemoryUsage: 1024}
+	manager := NewGameserverManager(runtime, GameserverManagerConfig{})
+
+	containers, _ := runtime.List(context.Background())
+	manager.collectStats(containers)
+	waitForCondition(t, func() bool {
+		return manager.stats.get("uuid1") != nil
+	})
+
+	gameservers, _ := manager.GetGameservers()
+	assert.Len(t, gameservers, 1)
+	assert.Equal(t, int64(1024), gameservers[0].ResourceUsage.MemoryUsage)
+
+	manager.collectStats(nil)
+	assert.Nil(t, manager.stats.get("uuid1"))
+}
@@ -2,54 +2,78 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Trojan295/chinchilla/agent"
 	"github.com/Trojan295/chinchilla/common"
 	"github.com/Trojan295/chinchilla/proto"
-	"github.com/docker/docker/client"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
-const osMemoryReservation = 512
+func getAgentState(hostname string, discovery *agent.ResourceDiscovery, reservations agent.ResourceReservations, lowDiskPercent int) (*proto.AgentState, error) {
+	resources, err := discovery.Discover()
+	if err != nil {
+		return nil, err
+	}
 
-func getAgentState(hostname string) *proto.AgentState {
-	cpuStats, err := agent.GetCPUStats()
+	conditions := resources.Conditions(lowDiskPercent)
+	for _, condition := range conditions {
+		log.Printf("WARNING %s: %s", condition.Type, condition.Message)
+	}
+
+	agentResources, resourceUsage := resources.AgentResources(reservations)
+	return &proto.AgentState{
+		Hostname:      hostname,
+		Resources:     agentResources,
+		ResourceUsage: resourceUsage,
+		Conditions:    conditions,
+	}, nil
+}
+
+// setupTLS returns the dial option for a mutual TLS connection to the
+// server. An agent without a certificate enrolls with the bootstrap token
+func setupTLS(config *common.Configuration, hostname, serverAddress string) (*agent.CertificateManager, grpc.DialOption, error) {
+	caPEM, err := ioutil.ReadFile(config.Server.CACert)
 	if err != nil {
-		log.Fatalf("cannot get cpuStats: %s\n", err)
-		return nil
+		return nil, nil, err
 	}
 
-	memoryStats, err := agent.GetMemoryStats()
+	certificates := agent.NewCertificateManager(config.Agent.CertDir, hostname)
+	tlsConfig, err := certificates.TLSConfig(caPEM)
 	if err != nil {
-		log.Fatalf("cannot get memoryStats: %s\n", err)
-		return nil
+		return nil, nil, err
 	}
+	dialOption := grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig))
 
-	totalAvailableMemory := memoryStats.Total - osMemoryReservation
-	if totalAvailableMemory < 0 {
-		totalAvailableMemory = 0
+	if err := certificates.Load(); err == nil {
+		return certificates, dialOption, nil
+	}
+	if config.Agent.BootstrapToken == "" {
+		return nil, nil, errors.New("the agent is not enrolled and has no bootstrap token")
 	}
 
-	availableMemory := memoryStats.Total - memoryStats.Available - osMemoryReservation
-	if availableMemory < 0 {
-		availableMemory = 0
+	log.Printf("Enrolling the agent using the bootstrap token")
+	conn, err := grpc.Dial(serverAddress, dialOption)
+	if err != nil {
+		return nil, nil, err
 	}
+	defer conn.Close()
 
-	return &proto.AgentState{
-		Hostname: hostname,
-		Resources: &proto.AgentResources{
-			Cpus:   int64(cpuStats.Cores),
-			Memory: int64(totalAvailableMemory),
-		},
-		ResourceUsage: &proto.AgentResourceUsage{
-			Memory: int64(availableMemory),
-		},
+	if err := certificates.Enroll(context.Background(), proto.NewAgentServiceClient(conn), config.Agent.BootstrapToken); err != nil {
+		return nil, nil, fmt.Errorf("cannot enroll: %v", err)
 	}
+	log.Printf("Agent enrolled, certificate valid until %s", certificates.NotAfter())
+
+	return certificates, dialOption, nil
 }
 
 var version string
@@ -74,7 +98,16 @@ func main() {
 	log.Printf("Using hostname: %s", hostname)
 	log.Printf("Advertising IP addresses: %s", ipAddresses)
 
-	conn, err := grpc.Dial(serverAddress, grpc.WithInsecure())
+	dialOption := grpc.WithInsecure()
+	var certificates *agent.CertificateManager
+	if config.Server.TLS {
+		certificates, dialOption, err = setupTLS(config, hostname, serverAddress)
+		if err != nil {
+			log.Fatalf("cannot set up TLS: %v", err)
+		}
+	}
+
+	conn, err := grpc.Dial(serverAddress, dialOption)
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -83,19 +116,86 @@ func main() {
 
 	ctx := context.Background()
 
-	docker, _ := client.NewEnvClient()
-	manager := agent.NewGameserverManager(docker, docker, ipAddresses)
+	registries := make([]agent.RegistryCredentials, 0, len(config.Agent.Registries))
+	for _, registry := range config.Agent.Registries {
+		registries = append(registries, agent.RegistryCredentials{
+			Host:     registry.Host,
+			Username: registry.Username,
+			Password: registry.Password,
+		})
+	}
+
+	runtimeConfig := agent.RuntimeConfig{
+		Name:       config.Agent.Runtime,
+		Host:       config.Agent.RuntimeHost,
+		ProcessDir: config.Agent.ProcessDir,
+		CgroupRoot: config.Agent.CgroupRoot,
+	}
+	runtime, err := agent.NewRuntime(runtimeConfig)
+	if err != nil {
+		log.Fatalf("cannot create the container runtime: %v", err)
+	}
+
+	manager := agent.NewGameserverManager(runtime, agent.GameserverManagerConfig{
+		IPAddresses:      ipAddresses,
+		ImagePullWorkers: config.Agent.ImagePullWorkers,
+		ReconcileWorkers: config.Agent.ReconcileWorkers,
+		Registries:       registries,
+		Transfer:         agent.NewGRPCVolumeTransfer(c),
+	})
+
+	discovery := agent.NewResourceDiscovery(config.Agent.DataRoot)
+	reservations := agent.ResourceReservations{
+		OSMemory:      config.Agent.Reservations.OSMemoryKB,
+		RuntimeMemory: config.Agent.Reservations.RuntimeMemoryKB,
+		AgentMemory:   config.Agent.Reservations.AgentMemoryKB,
+		CPU:           config.Agent.Reservations.CPUMillicores,
+	}
+
+	shutdown := make(chan os.Signal, 1)
+	if config.Agent.DrainOnShutdown {
+		signal.Notify(shutdown, syscall.SIGTERM)
+	}
+	var drainDeadline *time.Time
 
 	for {
+		select {
+		case <-shutdown:
+			log.Printf("Received SIGTERM, requesting a drain of the agent")
+			if _, err := c.RequestDrain(ctx, &proto.DrainRequest{Hostname: hostname}); err != nil {
+				log.Printf("Cannot request the drain: %v", err)
+				return
+			}
+			deadline := time.Now().Add(time.Duration(config.Agent.DrainTimeout) * time.Second)
+			drainDeadline = &deadline
+		default:
+		}
+
+		if certificates != nil {
+			if rotated, err := certificates.RotateIfNeeded(ctx, c); err != nil {
+				log.Printf("Cannot rotate the agent certificate expiring at %s: %v", certificates.NotAfter(), err)
+			} else if rotated {
+				log.Printf("Agent certificate rotated, valid until %s", certificates.NotAfter())
+			}
+		}
+
 		gameservers, err := manager.GetGameservers()
 		if err != nil {
 			log.Fatalf("Failed to get game servers")
 			continue
 		}
 
-		agentState := getAgentState(hostname)
+		agentState, err := getAgentState(hostname, discovery, reservations, config.Agent.LowDiskPercent)
+		if err != nil {
+			log.Printf("Cannot discover the agent resources: %v", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
 		agentState.Resources.IpAddresses = int64(len(ipAddresses))
+		agentState.Labels = config.Agent.Labels
+		agentState.Runtime = runtimeConfig.RuntimeName()
 		agentState.RunningGameservers = gameservers
+		agentState.ActionResults = manager.ActionResults()
 
 		c.Register(ctx, agentState)
 
@@ -109,6 +209,17 @@ func main() {
 
 		manager.Tick(targetConfig)
 
+		if drainDeadline != nil {
+			if len(targetConfig.Deployments) == 0 {
+				log.Printf("Agent drained, shutting down")
+				return
+			}
+			if time.Now().After(*drainDeadline) {
+				log.Printf("Agent not drained within %ds, shutting down", config.Agent.DrainTimeout)
+				return
+			}
+		}
+
 		time.Sleep(5 * time.Second)
 	}
 }
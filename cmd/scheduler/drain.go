@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+)
+
+// drainAgents moves the gameservers out of the agents being drained
+func (service *SchedulerService) drainAgents() error {
+	agents, err := service.agentStore.ListAgents()
+	if err != nil {
+		return err
+	}
+
+	for i := range agents {
+		agent := &agents[i]
+		if drain := agent.Maintenance.Drain; drain == nil || drain.State != server.DrainInProgress {
+			continue
+		}
+
+		if err := service.drainAgent(agent); err != nil {
+			log.Printf("ERROR Failed to drain agent %s: %s", agent.State.Hostname, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// drainAgent advances every gameserver of the agent one step: running
+// gameservers are stopped first and moved, when the agent reports them
+// as stopped. The drain is completed, when all gameservers were moved
+func (service *SchedulerService) drainAgent(agent *server.Agent) error {
+	hostname := agent.State.Hostname
+	drain := agent.Maintenance.Drain
+
+	gameservers, err := server.GetGameserversForAgent(hostname, service.gameserverStore)
+	if err != nil {
+		return err
+	}
+
+	byUUID := make(map[string]*server.Gameserver)
+	for i := range gameservers {
+		gameserver := &gameservers[i]
+		byUUID[gameserver.Definition.UUID] = gameserver
+		if findDrainedGameserver(drain, gameserver.Definition.UUID) == nil {
+			drain.Gameservers = append(drain.Gameservers, server.DrainedGameserver{
+				UUID:  gameserver.Definition.UUID,
+				Phase: server.DrainPending,
+			})
+		}
+	}
+
+	completed := true
+	for i := range drain.Gameservers {
+		entry := &drain.Gameservers[i]
+		if entry.Phase == server.DrainMigrated || entry.Phase == server.DrainRemoved {
+			continue
+		}
+
+		gameserver, ok := byUUID[entry.UUID]
+		if !ok {
+			service.forgetDrainedGameserver(entry)
+			continue
+		}
+
+		if err := service.drainGameserver(agent, entry, gameserver); err != nil {
+			entry.Error = err.Error()
+		}
+		if entry.Phase != server.DrainMigrated {
+			completed = false
+		}
+	}
+
+	if completed {
+		now := time.Now()
+		drain.State = server.DrainCompleted
+		drain.FinishedAt = &now
+		log.Printf("Drained agent %s", hostname)
+	}
+
+	return service.agentStore.UpdateAgentMaintenance(hostname, &agent.Maintenance)
+}
+
+// forgetDrainedGameserver handles gameservers, which left the agent
+// without the drain, because they were deleted or moved by a wake up
+func (service *SchedulerService) forgetDrainedGameserver(entry *server.DrainedGameserver) {
+	gameserver, err := service.gameserverStore.GetGameserver(entry.UUID)
+	if err != nil {
+		entry.Phase = server.DrainRemoved
+		return
+	}
+	entry.Phase = server.DrainMigrated
+	entry.TargetAgent = gameserver.Deployment.Agent
+}
+
+// drainGameserver advances the drain of the gameserver. It's locked and
+// read again, so the changes made by the API since it was listed are not
+// overwritten. Gameservers moved meanwhile are forgotten by the next step
+func (service *SchedulerService) drainGameserver(agent *server.Agent, entry *server.DrainedGameserver, gameserver *server.Gameserver) error {
+	hostname := agent.State.Hostname
+	UUID := gameserver.Definition.UUID
+
+	if entry.OperationID != "" {
+		return service.followDrainMigration(entry)
+	}
+
+	unlock, err := service.gameserverStore.LockGameserver(UUID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	gameserver, err = service.gameserverStore.GetGameserver(UUID)
+	if err != nil || gameserver.Deployment.Agent != hostname {
+		return nil
+	}
+
+	if entry.Phase == server.DrainPending {
+		entry.WasRunning = !gameserver.Deployment.Stopped
+		entry.Phase = server.DrainStopping
+	}
+
+	if len(gameserver.Deployment.Volumes) > 0 {
+		return service.startDrainMigration(entry, gameserver)
+	}
+
+	if entry.WasRunning && !gameserver.Deployment.Stopped {
+		gameserver.Deployment.Stopped = true
+		if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+			return err
+		}
+		server.RecordGameserverEvent(service.eventStore, UUID, server.EventDrainStopping,
+			fmt.Sprintf("Stopping for the drain of agent %s", hostname))
+		service.audit("gameserver.drain-stop", gameserver, "running on agent "+hostname, "stopped")
+		return nil
+	}
+
+	if !service.stoppedOnAgent(agent, UUID) {
+		return nil
+	}
+
+	possibleAgents, reasons, err := service.findPossibleAgents(gameserver)
+	if err != nil {
+		return err
+	}
+	if len(possibleAgents) == 0 {
+		return fmt.Errorf("No free agents: %s", strings.Join(reasons, ", "))
+	}
+
+	possibleAgents = preferRegion(possibleAgents, gameserver.Definition.Placement)
+	target := possibleAgents[rand.Intn(len(possibleAgents))]
+	gameserver.Deployment.Agent = target.hostname
+	gameserver.Deployment.Stopped = !entry.WasRunning
+	gameserver.UnschedulableReasons = nil
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+
+	entry.Phase = server.DrainMigrated
+	entry.TargetAgent = target.hostname
+	entry.Error = ""
+	server.RecordGameserverEvent(service.eventStore, UUID, server.EventDrained,
+		fmt.Sprintf("Moved from agent %s to %s by the drain", hostname, target.hostname))
+	service.audit("gameserver.drain-move", gameserver, "agent "+hostname, "agent "+target.hostname)
+	return nil
+}
+
+// startDrainMigration moves a gameserver with volumes using a migration,
+// which takes its data along
+func (service *SchedulerService) startDrainMigration(entry *server.DrainedGameserver, gameserver *server.Gameserver) error {
+	if gameserver.OperationID != "" {
+		entry.OperationID = gameserver.OperationID
+		return nil
+	}
+
+	operation := server.NewMigration(gameserver, "", "drain")
+	if err := service.operationStore.CreateOperation(operation); err != nil {
+		return err
+	}
+
+	gameserver.OperationID = operation.ID
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+	entry.OperationID = operation.ID
+	return nil
+}
+
+// followDrainMigration waits for the migration of the gameserver.
+// Rolled back migrations are retried
+func (service *SchedulerService) followDrainMigration(entry *server.DrainedGameserver) error {
+	operation, err := service.operationStore.GetOperation(entry.OperationID)
+	if err != nil {
+		return err
+	}
+	if !operation.Finished() {
+		return nil
+	}
+
+	entry.OperationID = ""
+	if operation.Phase != server.MigrationCompleted {
+		entry.Phase = server.DrainPending
+		return fmt.Errorf("Migration failed: %s", operation.Error)
+	}
+
+	entry.Phase = server.DrainMigrated
+	entry.TargetAgent = operation.TargetAgent
+	entry.Error = ""
+	return nil
+}
+
+// stoppedOnAgent checks, if the gameserver is not running on the agent.
+// Gameservers of agents, which are not contacted anymore, are considered stopped
+func (service *SchedulerService) stoppedOnAgent(agent *server.Agent, UUID string) bool {
+	if time.Now().Sub(agent.LastContact).Seconds() > float64(service.config.AgentContactDelay) {
+		return true
+	}
+
+	for _, gameserver := range agent.State.RunningGameservers {
+		if gameserver.UUID == UUID {
+			return gameserver.Status != proto.GameserverStatus_RUNNING &&
+				gameserver.Status != proto.GameserverStatus_PENDING
+		}
+	}
+	return true
+}
+
+func findDrainedGameserver(drain *server.AgentDrain, UUID string) *server.DrainedGameserver {
+	for i := range drain.Gameservers {
+		if drain.Gameservers[i].UUID == UUID {
+			return &drain.Gameservers[i]
+		}
+	}
+	return nil
+}
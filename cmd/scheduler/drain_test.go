@@ -0,0 +1,110 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Trojan295/chinchilla/common"
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDrainAgent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	draining := schedulerAgent("old", 0, 0)
+	draining.Maintenance = server.AgentMaintenance{
+		Cordoned: true,
+		Drain:    &server.AgentDrain{State: server.DrainInProgress},
+	}
+	draining.State.RunningGameservers = []*proto.Gameserver{
+		{UUID: "running", Status: proto.GameserverStatus_RUNNING},
+		{UUID: "stopped", Status: proto.GameserverStatus_STOPPED},
+	}
+
+	stopped := schedulerGameserver("stopped", "old", 0)
+	stopped.Deployment.Stopped = true
+	gameservers := map[string]server.Gameserver{
+		"running": schedulerGameserver("running", "old", 0),
+		"stopped": stopped,
+	}
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{draining, schedulerAgent("new", 0, 0)}, nil).AnyTimes()
+	agentStore.EXPECT().UpdateAgentMaintenance("old", gomock.Any()).Return(nil).AnyTimes()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().DoAndReturn(func() ([]server.Gameserver, error) {
+		list := make([]server.Gameserver, 0)
+		for _, gs := range gameservers {
+			list = append(list, gs)
+		}
+		return list, nil
+	}).AnyTimes()
+	gameserverStore.EXPECT().GetGameserver(gomock.Any()).DoAndReturn(func(UUID string) (*server.Gameserver, error) {
+		gs := gameservers[UUID]
+		return &gs, nil
+	}).AnyTimes()
+	gameserverStore.EXPECT().LockGameserver(gomock.Any()).Return(func() {}, nil).AnyTimes()
+	gameserverStore.EXPECT().UpdateGameserver(gomock.Any()).Do(func(gs *server.Gameserver) {
+		gameservers[gs.Definition.UUID] = *gs
+	}).Return(nil).AnyTimes()
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().AddGameserverEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	service := SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+		eventStore:      eventStore,
+	}
+
+	assert.NoError(t, service.drainAgent(&draining))
+	assert.True(t, gameservers["running"].Deployment.Stopped)
+	assert.Equal(t, "old", gameservers["running"].Deployment.Agent)
+	assert.Equal(t, "new", gameservers["stopped"].Deployment.Agent)
+	assert.Equal(t, server.DrainInProgress, draining.Maintenance.Drain.State)
+
+	// the agent reports the gameserver as stopped
+	draining.State.RunningGameservers[0].Status = proto.GameserverStatus_STOPPED
+	assert.NoError(t, service.drainAgent(&draining))
+
+	assert.Equal(t, "new", gameservers["running"].Deployment.Agent)
+	assert.False(t, gameservers["running"].Deployment.Stopped)
+	assert.True(t, gameservers["stopped"].Deployment.Stopped)
+	assert.Equal(t, server.DrainCompleted, draining.Maintenance.Drain.State)
+	for _, entry := range draining.Maintenance.Drain.Gameservers {
+		assert.Equal(t, server.DrainMigrated, entry.Phase)
+		assert.Equal(t, "new", entry.TargetAgent)
+	}
+}
+
+func TestFindPossibleAgentsSkipsCordoned(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("new", "", 0)
+	cordoned := schedulerAgent("cordoned", 0, 0)
+	cordoned.Maintenance.Cordoned = true
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{cordoned, schedulerAgent("free", 0, 0)}, nil)
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{gameserver}, nil).AnyTimes()
+
+	service := SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+	}
+
+	agents, reasons, err := service.findPossibleAgents(&gameserver)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"free"}, agentHostnames(agents))
+	assert.Equal(t, []string{"1 agent(s): agent cordoned"}, reasons)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/Trojan295/chinchilla/server"
+)
+
+// migrationPhaseTimeout limits how long a migration waits in a single phase
+var migrationPhaseTimeout = 15 * time.Minute
+
+// migrateGameservers advances the migrations in progress
+func (service *SchedulerService) migrateGameservers() error {
+	operations, err := service.operationStore.ListOperations()
+	if err != nil {
+		return err
+	}
+
+	for i := range operations {
+		operation := &operations[i]
+		if operation.Type != server.OperationMigrate || operation.Finished() {
+			continue
+		}
+
+		if err := service.migrateGameserver(operation); err != nil {
+			log.Printf("ERROR Failed to migrate %s: %s", operation.GameserverUUID, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// migrateGameserver runs one step of the migration: the gameserver is
+// stopped on the source agent, its volumes are exported to the server and
+// imported on the target agent, before it's switched to the target agent.
+// Failures before the switch roll the gameserver back to the source agent.
+// The gameserver is locked, so the step doesn't overwrite API changes
+func (service *SchedulerService) migrateGameserver(operation *server.Operation) error {
+	unlock, err := service.gameserverStore.LockGameserver(operation.GameserverUUID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	gameserver, err := service.gameserverStore.GetGameserver(operation.GameserverUUID)
+	if err != nil {
+		service.finishMigration(operation, server.MigrationRolledBack, "Gameserver was removed")
+		return service.operationStore.UpdateOperation(operation)
+	}
+
+	if operation.Error != "" {
+		return service.rollbackMigration(operation, gameserver, operation.Error)
+	}
+	if operation.Phase != server.MigrationPending && time.Since(operation.PhaseStartedAt) > migrationPhaseTimeout {
+		return service.rollbackMigration(operation, gameserver, fmt.Sprintf("Timed out in phase %s", operation.Phase))
+	}
+
+	switch operation.Phase {
+	case server.MigrationPending:
+		target, err := service.migrationTarget(operation, gameserver)
+		if err != nil {
+			return service.rollbackMigration(operation, gameserver, err.Error())
+		}
+
+		operation.TargetAgent = target
+		operation.WasRunning = !gameserver.Deployment.Stopped
+		gameserver.Deployment.Stopped = true
+		if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+			return err
+		}
+		setMigrationPhase(operation, server.MigrationStopping)
+		server.RecordGameserverEvent(service.eventStore, gameserver.Definition.UUID, server.EventMigrationStart,
+			fmt.Sprintf("Migrating from agent %s to %s", operation.SourceAgent, target))
+
+	case server.MigrationStopping:
+		source, err := service.agentStore.GetAgent(operation.SourceAgent)
+		if err == nil && !service.stoppedOnAgent(source, gameserver.Definition.UUID) {
+			return nil
+		}
+
+		if len(gameserver.Deployment.Volumes) == 0 {
+			return service.switchMigration(operation, gameserver)
+		}
+		setMigrationPhase(operation, server.MigrationExporting)
+
+	case server.MigrationExporting:
+		if !operation.ExportDone {
+			return nil
+		}
+		setMigrationPhase(operation, server.MigrationImporting)
+
+	case server.MigrationImporting:
+		if !operation.ImportDone {
+			return nil
+		}
+		return service.switchMigration(operation, gameserver)
+	}
+
+	return service.operationStore.UpdateOperation(operation)
+}
+
+// migrationTarget validates the requested target agent or chooses one
+func (service *SchedulerService) migrationTarget(operation *server.Operation, gameserver *server.Gameserver) (string, error) {
+	possibleAgents, reasons, err := service.findPossibleAgents(gameserver)
+	if err != nil {
+		return "", err
+	}
+
+	candidates := make([]agentInfo, 0, len(possibleAgents))
+	for _, agent := range possibleAgents {
+		if agent.hostname == operation.SourceAgent {
+			continue
+		}
+		if operation.TargetAgent == "" || agent.hostname == operation.TargetAgent {
+			candidates = append(candidates, agent)
+		}
+	}
+
+	if len(candidates) == 0 {
+		if operation.TargetAgent != "" {
+			return "", fmt.Errorf("Agent %s cannot run the gameserver", operation.TargetAgent)
+		}
+		if len(reasons) == 0 {
+			return "", errors.New("No other agents to migrate to")
+		}
+		return "", fmt.Errorf("No free agents: %s", strings.Join(reasons, ", "))
+	}
+
+	candidates = preferRegion(candidates, gameserver.Definition.Placement)
+	return candidates[rand.Intn(len(candidates))].hostname, nil
+}
+
+// switchMigration moves the gameserver to the target agent and starts it,
+// if it was running before the migration
+func (service *SchedulerService) switchMigration(operation *server.Operation, gameserver *server.Gameserver) error {
+	gameserver.Deployment.Agent = operation.TargetAgent
+	gameserver.Deployment.Stopped = !operation.WasRunning && !operation.StartOnTarget
+	gameserver.OperationID = ""
+	gameserver.UnschedulableReasons = nil
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+
+	service.finishMigration(operation, server.MigrationCompleted, "")
+	server.RecordGameserverEvent(service.eventStore, gameserver.Definition.UUID, server.EventMigrated,
+		fmt.Sprintf("Migrated from agent %s to %s", operation.SourceAgent, operation.TargetAgent))
+	service.audit("gameserver.migrate", gameserver, "agent "+operation.SourceAgent, "agent "+operation.TargetAgent)
+	return service.operationStore.UpdateOperation(operation)
+}
+
+// rollbackMigration leaves the gameserver on the source agent
+// and starts it again, if it was running before the migration
+func (service *SchedulerService) rollbackMigration(operation *server.Operation, gameserver *server.Gameserver, reason string) error {
+	if operation.WasRunning {
+		gameserver.Deployment.Stopped = false
+	}
+	gameserver.OperationID = ""
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+
+	service.finishMigration(operation, server.MigrationRolledBack, reason)
+	server.RecordGameserverEvent(service.eventStore, gameserver.Definition.UUID, server.EventMigrationFailed,
+		fmt.Sprintf("Migration to agent %s rolled back: %s", operation.TargetAgent, reason))
+	service.audit("gameserver.migrate-rollback", gameserver, "migrating to agent "+operation.TargetAgent, "agent "+operation.SourceAgent+": "+reason)
+	return service.operationStore.UpdateOperation(operation)
+}
+
+func (service *SchedulerService) finishMigration(operation *server.Operation, phase, reason string) {
+	now := time.Now()
+	setMigrationPhase(operation, phase)
+	operation.Error = reason
+	operation.FinishedAt = &now
+}
+
+func setMigrationPhase(operation *server.Operation, phase string) {
+	operation.Phase = phase
+	operation.PhaseStartedAt = time.Now()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/common"
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMigrationTestService(ctrl *gomock.Controller, gameserver *server.Gameserver, agents ...server.Agent) SchedulerService {
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return(agents, nil).AnyTimes()
+	for i := range agents {
+		agentStore.EXPECT().GetAgent(agents[i].State.Hostname).Return(&agents[i], nil).AnyTimes()
+	}
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().DoAndReturn(func() ([]server.Gameserver, error) {
+		return []server.Gameserver{*gameserver}, nil
+	}).AnyTimes()
+	gameserverStore.EXPECT().GetGameserver(gameserver.Definition.UUID).DoAndReturn(func(string) (*server.Gameserver, error) {
+		copy := *gameserver
+		return &copy, nil
+	}).AnyTimes()
+	gameserverStore.EXPECT().UpdateGameserver(gomock.Any()).Do(func(gs *server.Gameserver) {
+		*gameserver = *gs
+	}).Return(nil).AnyTimes()
+	gameserverStore.EXPECT().LockGameserver(gameserver.Definition.UUID).Return(func() {}, nil).AnyTimes()
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().AddGameserverEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	operationStore := mocks.NewMockOperationStore(ctrl)
+	operationStore.EXPECT().UpdateOperation(gomock.Any()).Return(nil).AnyTimes()
+
+	return SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+		eventStore:      eventStore,
+		operationStore:  operationStore,
+	}
+}
+
+func TestMigrateGameserverWithVolumes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("uuid1", "source", 0)
+	gameserver.Deployment.Volumes = []*proto.Volume{{Name: "data", Path: "/data"}}
+
+	source := schedulerAgent("source", 0, 0)
+	source.State.RunningGameservers = []*proto.Gameserver{{UUID: "uuid1", Status: proto.GameserverStatus_RUNNING}}
+	service := newMigrationTestService(ctrl, &gameserver, source, schedulerAgent("target", 0, 0))
+
+	operation := server.NewMigration(&gameserver, "", "user1")
+	gameserver.OperationID = operation.ID
+
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationStopping, operation.Phase)
+	assert.Equal(t, "target", operation.TargetAgent)
+	assert.True(t, gameserver.Deployment.Stopped)
+
+	// still running on the source agent
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationStopping, operation.Phase)
+
+	source.State.RunningGameservers[0].Status = proto.GameserverStatus_STOPPED
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationExporting, operation.Phase)
+
+	operation.ExportDone = true
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationImporting, operation.Phase)
+
+	operation.ImportDone = true
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationCompleted, operation.Phase)
+	assert.True(t, operation.Finished())
+	assert.Equal(t, "target", gameserver.Deployment.Agent)
+	assert.False(t, gameserver.Deployment.Stopped)
+	assert.Empty(t, gameserver.OperationID)
+}
+
+func TestMigrateGameserverRollsBack(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("uuid1", "source", 0)
+	gameserver.Deployment.Volumes = []*proto.Volume{{Name: "data", Path: "/data"}}
+	gameserver.Deployment.Stopped = true
+	service := newMigrationTestService(ctrl, &gameserver, schedulerAgent("source", 0, 0), schedulerAgent("target", 0, 0))
+
+	operation := server.NewMigration(&gameserver, "target", "user1")
+	operation.Phase = server.MigrationImporting
+	operation.TargetAgent = "target"
+	operation.WasRunning = true
+	operation.Error = "MIGRATION_IMPORT failed: no space left"
+	gameserver.OperationID = operation.ID
+
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationRolledBack, operation.Phase)
+	assert.Equal(t, "source", gameserver.Deployment.Agent)
+	assert.False(t, gameserver.Deployment.Stopped)
+	assert.Empty(t, gameserver.OperationID)
+
+	timedOut := server.NewMigration(&gameserver, "target", "user1")
+	timedOut.Phase = server.MigrationExporting
+	timedOut.PhaseStartedAt = time.Now().Add(-time.Hour)
+	assert.NoError(t, service.migrateGameserver(timedOut))
+	assert.Equal(t, server.MigrationRolledBack, timedOut.Phase)
+	assert.Equal(t, "Timed out in phase Exporting", timedOut.Error)
+}
+
+func TestMigrateGameserverToUnknownTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("uuid1", "source", 0)
+	service := newMigrationTestService(ctrl, &gameserver, schedulerAgent("source", 0, 0))
+
+	operation := server.NewMigration(&gameserver, "", "user1")
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationRolledBack, operation.Phase)
+	assert.Equal(t, "No other agents to migrate to", operation.Error)
+	assert.False(t, gameserver.Deployment.Stopped)
+}
+
+func TestMigrateGameserverStartsOnTarget(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("uuid1", "source", 0)
+	gameserver.Deployment.Stopped = true
+	service := newMigrationTestService(ctrl, &gameserver, schedulerAgent("source", 0, 0), schedulerAgent("target", 0, 0))
+
+	operation := server.NewMigration(&gameserver, "target", "scheduler")
+	operation.StartOnTarget = true
+	gameserver.OperationID = operation.ID
+
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.NoError(t, service.migrateGameserver(operation))
+	assert.Equal(t, server.MigrationCompleted, operation.Phase)
+	assert.Equal(t, "target", gameserver.Deployment.Agent)
+	assert.False(t, gameserver.Deployment.Stopped)
+}
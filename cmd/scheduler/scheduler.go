@@ -1,12 +1,15 @@
 package main
 
 import (
-	"errors"
+	"fmt"
 	"log"
 	"math/rand"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Trojan295/chinchilla/common"
+	"github.com/Trojan295/chinchilla/proto"
 	"github.com/Trojan295/chinchilla/server"
 	"github.com/Trojan295/chinchilla/server/stores"
 	"go.etcd.io/etcd/client"
@@ -19,12 +22,31 @@ type agentInfo struct {
 	lastContact time.Time
 	ipAddresses int
 	totalMemory int
+	totalDisk   int64
+	freeDisk    int64
+	labels      map[string]string
+	runtime     string
+	cordoned    bool
 }
 
 type SchedulerService struct {
 	config          common.Scheduler
 	gameserverStore server.GameserverStore
 	agentStore      server.AgentStore
+	eventStore      server.EventStore
+	operationStore  server.OperationStore
+	auditLog        *server.AuditLog
+}
+
+// audit records a scheduler decision in the audit log
+func (service *SchedulerService) audit(action string, gameserver *server.Gameserver, before, after string) {
+	service.auditLog.Record(&server.AuditEvent{
+		Actor:  "scheduler",
+		Action: action,
+		Target: gameserver.Definition.UUID,
+		Before: before,
+		After:  after,
+	})
 }
 
 func (service *SchedulerService) getAllAgentInfo() ([]agentInfo, error) {
@@ -36,24 +58,55 @@ func (service *SchedulerService) getAllAgentInfo() ([]agentInfo, error) {
 	}
 
 	for _, agent := range agents {
-		agentInfos = append(agentInfos, agentInfo{
+		info := agentInfo{
 			hostname:    agent.State.Hostname,
 			lastContact: agent.LastContact,
 			ipAddresses: int(agent.State.Resources.IpAddresses),
 			totalMemory: int(agent.State.Resources.Memory),
-		})
+			totalDisk:   agent.State.Resources.Disk,
+			labels:      agent.State.Labels,
+			runtime:     agent.State.Runtime,
+			cordoned:    agent.Maintenance.Cordoned,
+		}
+		if agent.State.ResourceUsage != nil {
+			info.freeDisk = agent.State.ResourceUsage.DiskFree
+		}
+		agentInfos = append(agentInfos, info)
 	}
 
 	return agentInfos, nil
 }
 
-func (service *SchedulerService) assignAgent(gameserver *server.Gameserver) error {
+// Reasons, why an agent cannot run the gameserver
+const (
+	reasonNotContacted  = "agent not contacted recently"
+	reasonCordoned      = "agent cordoned"
+	reasonNotRegistered = "agent not registered"
+	reasonMemory        = "insufficient memory"
+	reasonIPAddresses   = "no free IP address"
+	reasonDisk          = "insufficient disk space"
+	reasonAntiAffinity  = "anti-affinity with other gameservers of the owner"
+	reasonNodeSelector  = "node selector %s=%s not matched"
+	reasonRuntime       = "runtime %s cannot run the gameserver"
+)
+
+// processRuntime runs the command of the deployment as a process on the
+// agent host, so it cannot run the deployments without a command
+const processRuntime = "process"
+
+// canRunDeployment tells, if the runtime of the agent can run the deployment
+func canRunDeployment(runtime string, deployment *proto.GameserverDeployment) bool {
+	return runtime != processRuntime || len(deployment.Command) > 0
+}
+
+func (service *SchedulerService) findPossibleAgents(gameserver *server.Gameserver) ([]agentInfo, []string, error) {
 	agentsInfo, err := service.getAllAgentInfo()
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	possibleAgents := make([]agentInfo, 0)
+	rejections := make(map[string]int)
 
 	for _, agent := range agentsInfo {
 		agentGss, err := server.GetGameserversForAgent(agent.hostname, service.gameserverStore)
@@ -61,64 +114,407 @@ func (service *SchedulerService) assignAgent(gameserver *server.Gameserver) erro
 			continue
 		}
 
-		usedIPs := 0
-		memoryReservation := 0
+		reason := service.rejectAgent(gameserver, agent, agentGss)
+		if reason != "" {
+			rejections[reason]++
+			continue
+		}
 
-		for _, agentGs := range agentGss {
-			memoryReservation += int(agentGs.Deployment.ResourceRequirements.MemoryReservation)
+		possibleAgents = append(possibleAgents, agent)
+	}
 
-			if agentGs.Definition.Game == gameserver.Definition.Game {
-				usedIPs++
-			}
-		}
+	return possibleAgents, unschedulableReasons(rejections), nil
+}
 
-		if time.Now().Sub(agent.lastContact).Seconds() > float64(service.config.AgentContactDelay) {
+// rejectAgent returns the first constraint, which the agent doesn't
+// satisfy, or an empty string, when it can run the gameserver
+func (service *SchedulerService) rejectAgent(gameserver *server.Gameserver, agent agentInfo, agentGss []server.Gameserver) string {
+	placement := gameserver.Definition.Placement
+	if placement == nil {
+		placement = &server.Placement{}
+	}
+
+	usedIPs := 0
+	memoryReservation := 0
+	var diskReservation int64
+	sameOwner := false
+
+	for _, agentGs := range agentGss {
+		if agentGs.Definition.UUID == gameserver.Definition.UUID {
 			continue
 		}
 
-		if agent.totalMemory-memoryReservation-int(gameserver.Deployment.ResourceRequirements.MemoryReservation) <= 0 {
-			continue
+		if agentGs.Definition.Owner == gameserver.Definition.Owner {
+			sameOwner = true
 		}
 
-		if agent.ipAddresses-usedIPs <= 0 {
+		if agentGs.Definition.Game == gameserver.Definition.Game {
+			usedIPs++
+		}
+
+		// Stopped gameservers keep their data on the disk
+		diskReservation += agentGs.Deployment.ResourceRequirements.DiskReservation
+
+		if agentGs.Deployment.Stopped {
 			continue
 		}
+		memoryReservation += int(agentGs.Deployment.ResourceRequirements.MemoryReservation)
+	}
 
-		possibleAgents = append(possibleAgents, agent)
+	if time.Now().Sub(agent.lastContact).Seconds() > float64(service.config.AgentContactDelay) {
+		return reasonNotContacted
+	}
+
+	if agent.cordoned {
+		return reasonCordoned
+	}
+
+	if !canRunDeployment(agent.runtime, gameserver.Deployment) {
+		return fmt.Sprintf(reasonRuntime, agent.runtime)
+	}
+
+	keys := make([]string, 0, len(placement.NodeSelector))
+	for key := range placement.NodeSelector {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := agent.labels[key]; !ok || value != placement.NodeSelector[key] {
+			return fmt.Sprintf(reasonNodeSelector, key, placement.NodeSelector[key])
+		}
+	}
+
+	if placement.AntiAffinity && sameOwner {
+		return reasonAntiAffinity
+	}
+
+	if agent.totalMemory-memoryReservation-int(gameserver.Deployment.ResourceRequirements.MemoryReservation) <= 0 {
+		return reasonMemory
+	}
+
+	if agent.ipAddresses-usedIPs <= 0 {
+		return reasonIPAddresses
+	}
+
+	if !hasEnoughDisk(agent, diskReservation, gameserver.Deployment.ResourceRequirements.DiskReservation) {
+		return reasonDisk
+	}
+
+	return ""
+}
+
+// unschedulableReasons reports, how many agents were rejected
+// by each constraint
+func unschedulableReasons(rejections map[string]int) []string {
+	reasons := make([]string, 0, len(rejections))
+	for reason, count := range rejections {
+		reasons = append(reasons, fmt.Sprintf("%d agent(s): %s", count, reason))
+	}
+	sort.Strings(reasons)
+	return reasons
+}
+
+// hasEnoughDisk checks the disk reservations and the free disk space
+// of the agent. Agents not reporting their disk are not checked
+func hasEnoughDisk(agent agentInfo, reserved, required int64) bool {
+	if agent.totalDisk == 0 {
+		return true
+	}
+	return agent.totalDisk-reserved-required >= 0 && agent.freeDisk >= required
+}
+
+// preferRegion narrows the agents to the ones in the preferred region,
+// if there are any
+func preferRegion(agents []agentInfo, placement *server.Placement) []agentInfo {
+	if placement == nil || placement.PreferredRegion == "" {
+		return agents
+	}
+
+	preferred := make([]agentInfo, 0)
+	for _, agent := range agents {
+		if agent.labels[server.RegionLabel] == placement.PreferredRegion {
+			preferred = append(preferred, agent)
+		}
+	}
+	if len(preferred) == 0 {
+		return agents
+	}
+	return preferred
+}
+
+// markUnschedulable stores the reasons in the gameserver and records
+// an event, when they changed since the last scheduling attempt
+func (service *SchedulerService) markUnschedulable(gameserver *server.Gameserver, reasons []string) error {
+	if len(reasons) == 0 {
+		reasons = []string{"no agents registered"}
+	}
+	message := strings.Join(reasons, ", ")
+
+	if strings.Join(gameserver.UnschedulableReasons, ", ") != message {
+		gameserver.UnschedulableReasons = reasons
+		if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+			return err
+		}
+		server.RecordGameserverEvent(service.eventStore, gameserver.Definition.UUID, server.EventUnschedulable, message)
+	}
+
+	return fmt.Errorf("No free agents to assign: %s", message)
+}
+
+func (service *SchedulerService) assignAgent(gameserver *server.Gameserver) error {
+	possibleAgents, reasons, err := service.findPossibleAgents(gameserver)
+	if err != nil {
+		return err
 	}
 
 	if len(possibleAgents) == 0 {
-		return errors.New("No free agents to assign")
+		return service.markUnschedulable(gameserver, reasons)
 	}
 
+	possibleAgents = preferRegion(possibleAgents, gameserver.Definition.Placement)
 	idx := rand.Intn(len(possibleAgents))
 	agent := possibleAgents[idx]
 	gameserver.Deployment.Agent = agent.hostname
-	return service.gameserverStore.UpdateGameserver(gameserver)
+	gameserver.UnschedulableReasons = nil
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+
+	server.RecordGameserverEvent(service.eventStore, gameserver.Definition.UUID, server.EventScheduled,
+		fmt.Sprintf("Scheduled to agent %s", agent.hostname))
+	service.audit("gameserver.schedule", gameserver, "", "agent "+agent.hostname)
+	return nil
+}
+
+// wakeGameserver starts a stopped gameserver on its agent, which keeps its
+// volumes. When the agent cannot run it, the gameserver is rescheduled to
+// another agent. Gameservers with volumes are migrated there and started
+// after the migration, so they stay unschedulable, while their data is
+// not reachable on an unavailable agent
+func (service *SchedulerService) wakeGameserver(gameserver *server.Gameserver) error {
+	hostname := gameserver.Deployment.Agent
+
+	reason, err := service.rejectOwnAgent(gameserver)
+	if err != nil {
+		return err
+	}
+	if reason == "" {
+		return service.startGameserver(gameserver, fmt.Sprintf("Started on agent %s", hostname))
+	}
+	ownReason := fmt.Sprintf("agent %s: %s", hostname, reason)
+
+	hasVolumes := len(gameserver.Deployment.Volumes) > 0
+	if hasVolumes && (reason == reasonNotRegistered || reason == reasonNotContacted) {
+		return service.markUnschedulable(gameserver, []string{ownReason})
+	}
+
+	possibleAgents, reasons, err := service.findPossibleAgents(gameserver)
+	if err != nil {
+		return err
+	}
+	candidates := make([]agentInfo, 0, len(possibleAgents))
+	for _, agent := range possibleAgents {
+		if agent.hostname != hostname {
+			candidates = append(candidates, agent)
+		}
+	}
+	if len(candidates) == 0 {
+		return service.markUnschedulable(gameserver, append([]string{ownReason}, reasons...))
+	}
+
+	candidates = preferRegion(candidates, gameserver.Definition.Placement)
+	target := candidates[rand.Intn(len(candidates))].hostname
+
+	if hasVolumes {
+		return service.migrateToWake(gameserver, target)
+	}
+
+	gameserver.Deployment.Agent = target
+	return service.startGameserver(gameserver, fmt.Sprintf("Started on agent %s, as %s", target, ownReason))
+}
+
+// rejectOwnAgent checks, if the agent of the gameserver can run it
+func (service *SchedulerService) rejectOwnAgent(gameserver *server.Gameserver) (string, error) {
+	hostname := gameserver.Deployment.Agent
+
+	agentsInfo, err := service.getAllAgentInfo()
+	if err != nil {
+		return "", err
+	}
+
+	for _, agent := range agentsInfo {
+		if agent.hostname != hostname {
+			continue
+		}
+
+		agentGss, err := server.GetGameserversForAgent(hostname, service.gameserverStore)
+		if err != nil {
+			return "", err
+		}
+		return service.rejectAgent(gameserver, agent, agentGss), nil
+	}
+
+	return reasonNotRegistered, nil
+}
+
+// startGameserver starts the woken gameserver on its current agent
+func (service *SchedulerService) startGameserver(gameserver *server.Gameserver, message string) error {
+	gameserver.Deployment.Stopped = false
+	gameserver.WakeRequested = false
+	gameserver.UnschedulableReasons = nil
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+
+	server.RecordGameserverEvent(service.eventStore, gameserver.Definition.UUID, server.EventWoken, message)
+	service.audit("gameserver.start", gameserver, "stopped", message)
+	return nil
+}
+
+// migrateToWake migrates the gameserver with its volumes to the target
+// agent and starts it there
+func (service *SchedulerService) migrateToWake(gameserver *server.Gameserver, target string) error {
+	operation := server.NewMigration(gameserver, target, "scheduler")
+	operation.StartOnTarget = true
+
+	gameserver.OperationID = operation.ID
+	gameserver.WakeRequested = false
+	gameserver.UnschedulableReasons = nil
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+
+	if err := service.operationStore.CreateOperation(operation); err != nil {
+		gameserver.OperationID = ""
+		gameserver.WakeRequested = true
+		if revertErr := service.gameserverStore.UpdateGameserver(gameserver); revertErr != nil {
+			log.Printf("ERROR Failed to revert the wake of %s: %s", gameserver.Definition.UUID, revertErr.Error())
+		}
+		return err
+	}
+
+	log.Printf("Migrating gameserver %s to %s to wake it", gameserver.Definition.UUID, target)
+	return nil
+}
+
+// checkIdle stops the gameserver, when it had no players for longer
+// than allowed by its IdlePolicy
+func (service *SchedulerService) checkIdle(gameserver *server.Gameserver, runningGameserver *proto.Gameserver) error {
+	policy := gameserver.Definition.IdlePolicy
+	if policy == nil || gameserver.Deployment.Stopped {
+		return nil
+	}
+
+	if runningGameserver == nil ||
+		runningGameserver.Status != proto.GameserverStatus_RUNNING ||
+		runningGameserver.Query == nil ||
+		runningGameserver.Query.PlayersOnline > 0 {
+
+		if gameserver.IdleSince == nil {
+			return nil
+		}
+		gameserver.IdleSince = nil
+		return service.gameserverStore.UpdateGameserver(gameserver)
+	}
+
+	now := time.Now()
+	if gameserver.IdleSince == nil {
+		gameserver.IdleSince = &now
+		return service.gameserverStore.UpdateGameserver(gameserver)
+	}
+
+	if now.Sub(*gameserver.IdleSince) < time.Duration(policy.StopAfterMinutes)*time.Minute {
+		return nil
+	}
+
+	log.Printf("Stopping idle gameserver %s", gameserver.Definition.UUID)
+	gameserver.Deployment.Stopped = true
+	gameserver.IdleSince = nil
+	if err := service.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("No players online for %d minutes", policy.StopAfterMinutes)
+	server.RecordGameserverEvent(service.eventStore, gameserver.Definition.UUID, server.EventIdleStopped, message)
+	service.audit("gameserver.idle-stop", gameserver, "running", "stopped: "+message)
+	return nil
 }
 
 func (service *SchedulerService) Tick() error {
+	if err := service.drainAgents(); err != nil {
+		log.Printf("ERROR Failed to drain agents: %s", err.Error())
+	}
+	if err := service.migrateGameservers(); err != nil {
+		log.Printf("ERROR Failed to migrate gameservers: %s", err.Error())
+	}
+
 	gameservers, err := service.gameserverStore.ListGameservers()
 	if err != nil {
 		return err
 	}
 
+	runningGameservers, err := server.GetRunningGameservers(service.agentStore)
+	if err != nil {
+		return err
+	}
+
 	for _, gameserver := range gameservers {
-		if gameserver.Deployment.Agent != "" {
+		if gameserver.OperationID != "" {
 			continue
 		}
 
-		log.Printf("Scheduling gameserver %s...", gameserver.Definition.Name)
-		if err := service.assignAgent(&gameserver); err != nil {
-			log.Printf("ERROR Failed to schedule %s: %s", gameserver.Definition.UUID, err.Error())
-		} else {
-			log.Printf("Scheduled gameserver %s to %s", gameserver.Definition.Name, gameserver.Deployment.Agent)
+		UUID := gameserver.Definition.UUID
+		if err := service.scheduleGameserver(UUID, runningGameservers[UUID]); err != nil {
+			log.Printf("ERROR Failed to lock %s: %s", UUID, err.Error())
 		}
 	}
 
 	return nil
 }
 
+// scheduleGameserver wakes, stops or schedules the gameserver. It's locked
+// and read again, so the changes made by the API since the gameserver
+// was listed are not overwritten
+func (service *SchedulerService) scheduleGameserver(UUID string, runningGameserver *proto.Gameserver) error {
+	unlock, err := service.gameserverStore.LockGameserver(UUID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	gameserver, err := service.gameserverStore.GetGameserver(UUID)
+	if err != nil {
+		// removed since it was listed
+		return nil
+	}
+
+	if gameserver.OperationID != "" {
+		return nil
+	}
+
+	if gameserver.WakeRequested {
+		if err := service.wakeGameserver(gameserver); err != nil {
+			log.Printf("ERROR Failed to wake %s: %s", UUID, err.Error())
+		}
+		return nil
+	}
+
+	if gameserver.Deployment.Agent != "" {
+		if err := service.checkIdle(gameserver, runningGameserver); err != nil {
+			log.Printf("ERROR Failed to check idle state of %s: %s", UUID, err.Error())
+		}
+		return nil
+	}
+
+	log.Printf("Scheduling gameserver %s...", gameserver.Definition.Name)
+	if err := service.assignAgent(gameserver); err != nil {
+		log.Printf("ERROR Failed to schedule %s: %s", UUID, err.Error())
+	} else {
+		log.Printf("Scheduled gameserver %s to %s", gameserver.Definition.Name, gameserver.Deployment.Agent)
+	}
+	return nil
+}
+
 func main() {
 	log.Printf("Chinchilla scheduler v%s\n", version)
 
@@ -141,10 +537,19 @@ func main() {
 		panic(err)
 	}
 
+	auditRetention := time.Duration(config.Server.AuditRetentionDays) * 24 * time.Hour
+	auditLog, err := server.NewAuditLog(etcdStore, auditRetention, config.Server.AuditLogFile)
+	if err != nil {
+		panic(err)
+	}
+
 	service := SchedulerService{
 		config:          config.Scheduler,
 		gameserverStore: etcdStore,
 		agentStore:      etcdStore,
+		eventStore:      etcdStore,
+		operationStore:  etcdStore,
+		auditLog:        auditLog,
 	}
 
 	for {
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/common"
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func schedulerAgent(hostname string, disk, diskFree int64) server.Agent {
+	return server.Agent{
+		LastContact: time.Now(),
+		State: proto.AgentState{
+			Hostname: hostname,
+			Resources: &proto.AgentResources{
+				Memory:      8 * 1024 * 1024,
+				IpAddresses: 4,
+				Disk:        disk,
+			},
+			ResourceUsage: &proto.AgentResourceUsage{
+				DiskFree: diskFree,
+			},
+		},
+	}
+}
+
+func schedulerGameserver(uuid, agent string, diskReservation int64) server.Gameserver {
+	return server.Gameserver{
+		Definition: server.GameserverDefinition{UUID: uuid, Game: "Minecraft"},
+		Deployment: &proto.GameserverDeployment{
+			UUID:  uuid,
+			Agent: agent,
+			ResourceRequirements: &proto.ResourceRequirements{
+				MemoryReservation: 1024 * 1024,
+				DiskReservation:   diskReservation,
+			},
+		},
+	}
+}
+
+func TestFindPossibleAgentsChecksDisk(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("new", "", 5<<30)
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{
+		schedulerAgent("free", 100<<30, 80<<30),
+		schedulerAgent("full", 100<<30, 2<<30),
+		schedulerAgent("reserved", 20<<30, 15<<30),
+		schedulerAgent("legacy", 0, 0),
+	}, nil)
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{
+		gameserver,
+		schedulerGameserver("existing", "reserved", 16<<30),
+	}, nil).AnyTimes()
+
+	service := SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+	}
+
+	agents, reasons, err := service.findPossibleAgents(&gameserver)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"free", "legacy"}, agentHostnames(agents))
+	assert.Equal(t, []string{"2 agent(s): insufficient disk space"}, reasons)
+}
+
+func agentHostnames(agents []agentInfo) []string {
+	hostnames := make([]string, 0)
+	for _, agent := range agents {
+		hostnames = append(hostnames, agent.hostname)
+	}
+	return hostnames
+}
+
+func labeledAgent(hostname string, labels map[string]string) server.Agent {
+	agent := schedulerAgent(hostname, 0, 0)
+	agent.State.Labels = labels
+	return agent
+}
+
+func TestFindPossibleAgentsChecksPlacement(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("new", "", 0)
+	gameserver.Definition.Owner = "user1"
+	gameserver.Definition.Placement = &server.Placement{
+		NodeSelector: map[string]string{"hardware": "ssd"},
+		AntiAffinity: true,
+	}
+
+	existing := schedulerGameserver("existing", "ssd-busy", 0)
+	existing.Definition.Owner = "user1"
+	otherOwner := schedulerGameserver("other", "ssd-free", 0)
+	otherOwner.Definition.Owner = "user2"
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{
+		labeledAgent("ssd-free", map[string]string{"hardware": "ssd"}),
+		labeledAgent("ssd-busy", map[string]string{"hardware": "ssd"}),
+		labeledAgent("hdd", map[string]string{"hardware": "hdd"}),
+		labeledAgent("unlabeled", nil),
+	}, nil)
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{
+		gameserver, existing, otherOwner,
+	}, nil).AnyTimes()
+
+	service := SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+	}
+
+	agents, reasons, err := service.findPossibleAgents(&gameserver)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ssd-free"}, agentHostnames(agents))
+	assert.Equal(t, []string{
+		"1 agent(s): anti-affinity with other gameservers of the owner",
+		"2 agent(s): node selector hardware=ssd not matched",
+	}, reasons)
+}
+
+func runtimeAgent(hostname, runtime string) server.Agent {
+	agent := schedulerAgent(hostname, 0, 0)
+	agent.State.Runtime = runtime
+	return agent
+}
+
+func TestFindPossibleAgentsChecksRuntime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	image := schedulerGameserver("image", "", 0)
+	command := schedulerGameserver("command", "", 0)
+	command.Deployment.Command = []string{"./server"}
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{
+		runtimeAgent("docker", "docker"),
+		runtimeAgent("process", "process"),
+	}, nil).Times(2)
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{image, command}, nil).AnyTimes()
+
+	service := SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+	}
+
+	agents, reasons, err := service.findPossibleAgents(&image)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"docker"}, agentHostnames(agents))
+	assert.Equal(t, []string{"1 agent(s): runtime process cannot run the gameserver"}, reasons)
+
+	agents, reasons, err = service.findPossibleAgents(&command)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"docker", "process"}, agentHostnames(agents))
+	assert.Empty(t, reasons)
+}
+
+func TestPreferRegion(t *testing.T) {
+	agents := []agentInfo{
+		{hostname: "eu", labels: map[string]string{"region": "eu-west"}},
+		{hostname: "us", labels: map[string]string{"region": "us-east"}},
+	}
+
+	assert.Equal(t, []string{"eu"}, agentHostnames(preferRegion(agents, &server.Placement{PreferredRegion: "eu-west"})))
+	assert.Equal(t, []string{"eu", "us"}, agentHostnames(preferRegion(agents, &server.Placement{PreferredRegion: "ap-south"})))
+	assert.Equal(t, []string{"eu", "us"}, agentHostnames(preferRegion(agents, nil)))
+}
+
+func TestAssignAgentReportsUnschedulableReasons(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("new", "", 0)
+	gameserver.Definition.Placement = &server.Placement{
+		NodeSelector: map[string]string{"region": "eu-west"},
+	}
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{
+		labeledAgent("us", map[string]string{"region": "us-east"}),
+	}, nil).Times(2)
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{gameserver}, nil).AnyTimes()
+	gameserverStore.EXPECT().UpdateGameserver(gomock.Any()).Return(nil).Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().AddGameserverEvent("new", gomock.Any()).Return(nil).Times(1)
+
+	service := SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+		eventStore:      eventStore,
+	}
+
+	assert.Error(t, service.assignAgent(&gameserver))
+	assert.Equal(t, []string{"1 agent(s): node selector region=eu-west not matched"}, gameserver.UnschedulableReasons)
+
+	// the same reasons are not stored again
+	assert.Error(t, service.assignAgent(&gameserver))
+}
+
+func TestAssignAgentRecordsAuditEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := schedulerGameserver("new", "", 0)
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{schedulerAgent("free", 0, 0)}, nil).AnyTimes()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{gameserver}, nil).AnyTimes()
+	gameserverStore.EXPECT().UpdateGameserver(gomock.Any()).Return(nil).Times(1)
+
+	auditStore := mocks.NewMockAuditStore(ctrl)
+	auditStore.EXPECT().
+		AddAuditEvent(gomock.Any(), time.Hour).
+		Do(func(event *server.AuditEvent, retention time.Duration) {
+			assert.Equal(t, "scheduler", event.Actor)
+			assert.Equal(t, "gameserver.schedule", event.Action)
+			assert.Equal(t, "new", event.Target)
+			assert.Equal(t, "agent free", event.After)
+		}).
+		Return(nil).
+		Times(1)
+	auditLog, _ := server.NewAuditLog(auditStore, time.Hour, "")
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent("new", gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			assert.Equal(t, server.EventScheduled, event.Type)
+			assert.Equal(t, "Scheduled to agent free", event.Message)
+		}).
+		Return(nil).
+		Times(1)
+
+	service := SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+		eventStore:      eventStore,
+		auditLog:        auditLog,
+	}
+
+	assert.NoError(t, service.assignAgent(&gameserver))
+	assert.Equal(t, "free", gameserver.Deployment.Agent)
+}
+
+// newWakeTestService sets up a scheduler with the stopped gameserver on the
+// full agent, where the running gameserver reserves all the memory
+func newWakeTestService(ctrl *gomock.Controller, gameserver *server.Gameserver, agents ...server.Agent) SchedulerService {
+	running := schedulerGameserver("running", "full", 0)
+	running.Deployment.ResourceRequirements.MemoryReservation = 8 * 1024 * 1024
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return(agents, nil).AnyTimes()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{*gameserver, running}, nil).AnyTimes()
+	gameserverStore.EXPECT().UpdateGameserver(gomock.Any()).Return(nil).Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().AddGameserverEvent("stopped", gomock.Any()).Return(nil).AnyTimes()
+
+	auditStore := mocks.NewMockAuditStore(ctrl)
+	auditStore.EXPECT().AddAuditEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	auditLog, _ := server.NewAuditLog(auditStore, 0, "")
+
+	return SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+		eventStore:      eventStore,
+		operationStore:  mocks.NewMockOperationStore(ctrl),
+		auditLog:        auditLog,
+	}
+}
+
+func stoppedGameserver(volumes ...*proto.Volume) server.Gameserver {
+	gameserver := schedulerGameserver("stopped", "full", 0)
+	gameserver.Deployment.Stopped = true
+	gameserver.Deployment.Volumes = volumes
+	gameserver.WakeRequested = true
+	return gameserver
+}
+
+func TestWakeGameserverOnItsAgent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := stoppedGameserver()
+	gameserver.Deployment.Agent = "free"
+	service := newWakeTestService(ctrl, &gameserver, schedulerAgent("full", 0, 0), schedulerAgent("free", 0, 0))
+
+	assert.NoError(t, service.wakeGameserver(&gameserver))
+	assert.Equal(t, "free", gameserver.Deployment.Agent)
+	assert.False(t, gameserver.Deployment.Stopped)
+	assert.False(t, gameserver.WakeRequested)
+}
+
+func TestWakeGameserverReschedulesWithoutVolumes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := stoppedGameserver()
+	service := newWakeTestService(ctrl, &gameserver, schedulerAgent("full", 0, 0), schedulerAgent("free", 0, 0))
+
+	assert.NoError(t, service.wakeGameserver(&gameserver))
+	assert.Equal(t, "free", gameserver.Deployment.Agent)
+	assert.False(t, gameserver.Deployment.Stopped)
+	assert.False(t, gameserver.WakeRequested)
+	assert.Empty(t, gameserver.OperationID)
+}
+
+func TestWakeGameserverMigratesVolumes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := stoppedGameserver(&proto.Volume{Name: "data", Path: "/data"})
+	service := newWakeTestService(ctrl, &gameserver, schedulerAgent("full", 0, 0), schedulerAgent("free", 0, 0))
+
+	operationStore := mocks.NewMockOperationStore(ctrl)
+	operationStore.EXPECT().
+		CreateOperation(gomock.Any()).
+		Do(func(operation *server.Operation) {
+			assert.Equal(t, server.OperationMigrate, operation.Type)
+			assert.Equal(t, "full", operation.SourceAgent)
+			assert.Equal(t, "free", operation.TargetAgent)
+			assert.True(t, operation.StartOnTarget)
+		}).
+		Return(nil).
+		Times(1)
+	service.operationStore = operationStore
+
+	assert.NoError(t, service.wakeGameserver(&gameserver))
+	// the migration moves the gameserver and starts it
+	assert.Equal(t, "full", gameserver.Deployment.Agent)
+	assert.True(t, gameserver.Deployment.Stopped)
+	assert.False(t, gameserver.WakeRequested)
+	assert.NotEmpty(t, gameserver.OperationID)
+}
+
+func TestWakeGameserverWithoutFreeAgents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := stoppedGameserver()
+	service := newWakeTestService(ctrl, &gameserver, schedulerAgent("full", 0, 0))
+
+	assert.Error(t, service.wakeGameserver(&gameserver))
+	assert.Equal(t, "full", gameserver.Deployment.Agent)
+	assert.True(t, gameserver.Deployment.Stopped)
+	assert.True(t, gameserver.WakeRequested)
+	assert.Equal(t, []string{"agent full: insufficient memory", "1 agent(s): insufficient memory"}, gameserver.UnschedulableReasons)
+}
+
+func TestWakeGameserverKeepsVolumesOfUnavailableAgent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := stoppedGameserver(&proto.Volume{Name: "data", Path: "/data"})
+	gameserver.Deployment.Agent = "gone"
+	service := newWakeTestService(ctrl, &gameserver, schedulerAgent("free", 0, 0))
+
+	assert.Error(t, service.wakeGameserver(&gameserver))
+	assert.Equal(t, "gone", gameserver.Deployment.Agent)
+	assert.True(t, gameserver.Deployment.Stopped)
+	assert.Equal(t, []string{"agent gone: agent not registered"}, gameserver.UnschedulableReasons)
+}
+
+// newTickTestService sets up a scheduler, which lists the snapshot of the
+// gameserver, while the gameserver read under the lock is the current one
+func newTickTestService(ctrl *gomock.Controller, snapshot, current server.Gameserver) (SchedulerService, *mocks.MockGameserverStore) {
+	agent := schedulerAgent("agent1", 0, 0)
+	agent.State.RunningGameservers = []*proto.Gameserver{{
+		UUID:   "uuid1",
+		Status: proto.GameserverStatus_RUNNING,
+		Query:  &proto.GameserverQueryResult{PlayersOnline: 0},
+	}}
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().ListAgents().Return([]server.Agent{agent}, nil).AnyTimes()
+
+	operationStore := mocks.NewMockOperationStore(ctrl)
+	operationStore.EXPECT().ListOperations().Return(nil, nil).AnyTimes()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{snapshot}, nil).AnyTimes()
+	gomock.InOrder(
+		gameserverStore.EXPECT().LockGameserver("uuid1").Return(func() {}, nil),
+		gameserverStore.EXPECT().GetGameserver("uuid1").Return(&current, nil),
+	)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().AddGameserverEvent("uuid1", gomock.Any()).Return(nil).AnyTimes()
+
+	auditStore := mocks.NewMockAuditStore(ctrl)
+	auditStore.EXPECT().AddAuditEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	auditLog, _ := server.NewAuditLog(auditStore, 0, "")
+
+	return SchedulerService{
+		config:          common.Scheduler{AgentContactDelay: 30},
+		agentStore:      agentStore,
+		gameserverStore: gameserverStore,
+		eventStore:      eventStore,
+		operationStore:  operationStore,
+		auditLog:        auditLog,
+	}, gameserverStore
+}
+
+func TestTickKeepsMigrationRequestedDuringTick(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	idleSince := time.Now().Add(-time.Hour)
+	snapshot := schedulerGameserver("uuid1", "agent1", 0)
+	snapshot.Definition.IdlePolicy = &server.IdlePolicy{StopAfterMinutes: 10}
+	snapshot.IdleSince = &idleSince
+
+	// the API started a migration after the gameserver was listed
+	current := snapshot
+	current.OperationID = "operation1"
+
+	service, _ := newTickTestService(ctrl, snapshot, current)
+
+	// the idle gameserver is not stopped, which would drop the operation
+	assert.NoError(t, service.Tick())
+}
+
+func TestTickKeepsWakeRequestedDuringTick(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	snapshot := schedulerGameserver("uuid1", "agent1", 0)
+	snapshot.Deployment.Stopped = true
+
+	// the API requested a wake after the gameserver was listed
+	current := snapshot
+	current.WakeRequested = true
+
+	service, gameserverStore := newTickTestService(ctrl, snapshot, current)
+	gameserverStore.EXPECT().
+		UpdateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) {
+			assert.False(t, gs.Deployment.Stopped)
+			assert.False(t, gs.WakeRequested)
+		}).
+		Return(nil).
+		Times(1)
+
+	assert.NoError(t, service.Tick())
+}
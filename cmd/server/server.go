@@ -1,34 +1,69 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/Trojan295/chinchilla/common"
 	"github.com/Trojan295/chinchilla/proto"
 	"github.com/Trojan295/chinchilla/server"
 	"github.com/Trojan295/chinchilla/server/agents"
+	"github.com/Trojan295/chinchilla/server/audit"
 	"github.com/Trojan295/chinchilla/server/auth"
 	"github.com/Trojan295/chinchilla/server/gameservers"
+	"github.com/Trojan295/chinchilla/server/pki"
 	"github.com/Trojan295/chinchilla/server/stores"
+	"github.com/Trojan295/chinchilla/server/teams"
+	"github.com/Trojan295/chinchilla/server/tokens"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.etcd.io/etcd/client"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
+// updateHistorySize is the number of gameserver updates kept for
+// the clients resuming the /events stream
+const updateHistorySize = 1000
+
 // NewAgentServiceServer constructor
-func NewAgentServiceServer(etcdStore *stores.EtcdStore) agents.AgentServiceServer {
+func NewAgentServiceServer(etcdStore *stores.EtcdStore, config common.Server, ca *pki.CA, updates *server.UpdateBus) agents.AgentServiceServer {
 	return agents.AgentServiceServer{
-		AgentStore:      etcdStore,
-		GameserverStore: etcdStore,
+		AgentStore:          etcdStore,
+		GameserverStore:     etcdStore,
+		EventStore:          etcdStore,
+		OperationStore:      etcdStore,
+		Updates:             updates,
+		VolumeDir:           config.VolumeDir,
+		BootstrapTokenStore: etcdStore,
+		CA:                  ca,
+		CertificateValidity: time.Duration(config.CertValidityHours) * time.Hour,
+	}
+}
+
+// serverTLSConfig requires the agents to present a certificate signed by
+// the CA. Agents without one can only enroll
+func serverTLSConfig(config common.Server, ca *pki.CA) (*tls.Config, error) {
+	hostname, _ := os.Hostname()
+	certificate, err := ca.ServerCertificate([]string{config.Host, hostname, "localhost", "127.0.0.1"})
+	if err != nil {
+		return nil, err
 	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    ca.Pool(),
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
 }
 
-func runGrpcServer(config *common.Configuration, etcdStore *stores.EtcdStore) {
+func runGrpcServer(config *common.Configuration, etcdStore *stores.EtcdStore, updates *server.UpdateBus) {
 	port := fmt.Sprintf(":%d", config.Server.Port)
 
 	lis, err := net.Listen("tcp", port)
@@ -36,8 +71,27 @@ func runGrpcServer(config *common.Configuration, etcdStore *stores.EtcdStore) {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	proto.RegisterAgentServiceServer(s, NewAgentServiceServer(etcdStore))
+	var ca *pki.CA
+	options := []grpc.ServerOption{}
+	if config.Server.TLS {
+		ca, err = pki.LoadOrCreateCA(config.Server.PKIDir)
+		if err != nil {
+			log.Fatalf("failed to load the CA: %v", err)
+		}
+		tlsConfig, err := serverTLSConfig(config.Server, ca)
+		if err != nil {
+			log.Fatalf("failed to create the server certificate: %v", err)
+		}
+		options = append(options,
+			grpc.Creds(credentials.NewTLS(tlsConfig)),
+			grpc.UnaryInterceptor(agents.UnaryCertificateInterceptor),
+			grpc.StreamInterceptor(agents.StreamCertificateInterceptor))
+	} else {
+		log.Printf("WARNING TLS is disabled, any client can register as an agent")
+	}
+
+	s := grpc.NewServer(options...)
+	proto.RegisterAgentServiceServer(s, NewAgentServiceServer(etcdStore, config.Server, ca, updates))
 
 	log.Printf("Listening for gRPC on %s\n", port)
 	if err := s.Serve(lis); err != nil {
@@ -45,15 +99,19 @@ func runGrpcServer(config *common.Configuration, etcdStore *stores.EtcdStore) {
 	}
 }
 
-func setupRouter(r *gin.Engine, etcd *stores.EtcdStore) {
+func setupRouter(r *gin.Engine, etcd *stores.EtcdStore, updates *server.UpdateBus) {
 	r.GET("/health/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "OK"})
 	})
 
 	r.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
-	agents.MountAgentsAPI(r, etcd, etcd)
-	gameservers.MountGameserverAPI(r, etcd, etcd)
+	agents.MountAgentsAPI(r, etcd, etcd, etcd)
+	gameservers.MountGameserverAPI(r, etcd, etcd, etcd, etcd, etcd, etcd)
+	gameservers.MountUpdatesAPI(r, updates, etcd)
+	teams.MountTeamsAPI(r, etcd, etcd)
+	tokens.MountTokensAPI(r, etcd)
+	audit.MountAuditAPI(r, etcd)
 }
 
 var version string
@@ -75,11 +133,20 @@ func main() {
 	if err != nil {
 		panic(err)
 	}
-	go runGrpcServer(config, etcdStore)
-	server.StartMetrics(etcdStore)
+	updates := server.NewUpdateBus(updateHistorySize)
+	go runGrpcServer(config, etcdStore, updates)
+	server.StartMetrics(etcdStore, etcdStore)
+
+	auditRetention := time.Duration(config.Server.AuditRetentionDays) * 24 * time.Hour
+	auditLog, err := server.NewAuditLog(etcdStore, auditRetention, config.Server.AuditLogFile)
+	if err != nil {
+		panic(err)
+	}
 
-	r := gin.Default()
-	auth.SetupAuthentication(r, config.Auth)
-	setupRouter(r, etcdStore)
+	r := gin.New()
+	r.Use(auth.Logger(), gin.Recovery())
+	auth.SetupAuthentication(r, config.Auth, etcdStore)
+	r.Use(audit.UseAuditLog(auditLog))
+	setupRouter(r, etcdStore, updates)
 	r.Run(":8080")
 }
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"log"
 	"math"
+	"math/rand"
 	"strings"
 	"time"
 
@@ -76,14 +77,28 @@ func main() {
 		runningGameservers = make([]*proto.Gameserver, 0)
 		usedMemory = 0
 		for i, deployment := range deployments.Deployments {
+			if deployment.Stopped {
+				runningGameservers = append(runningGameservers, &proto.Gameserver{
+					UUID:   deployment.UUID,
+					Status: proto.GameserverStatus_STOPPED,
+				})
+				continue
+			}
+
+			gameserverMemory := int64(math.Round(float64(deployment.ResourceRequirements.MemoryReservation) * 0.6))
 			runningGameservers = append(runningGameservers, &proto.Gameserver{
 				UUID:   deployment.UUID,
 				Status: proto.GameserverStatus_RUNNING,
 				Endpoint: &proto.Endpoint{
 					IpAddress: ipAddresses[i%(len(ipAddresses))],
 				},
+				ResourceUsage: &proto.GameserverResourceUsage{
+					CpuPercent:  rand.Float64() * 100,
+					MemoryUsage: gameserverMemory,
+					MemoryLimit: deployment.ResourceRequirements.MemoryLimit,
+				},
 			})
-			usedMemory += int(math.Round(float64(deployment.ResourceRequirements.MemoryReservation) * 0.6))
+			usedMemory += int(gameserverMemory)
 		}
 
 		time.Sleep(5 * time.Second)
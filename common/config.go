@@ -6,10 +6,20 @@ import (
 	"github.com/BurntSushi/toml"
 )
 
-// Server configuration
+// Server configuration. With TLS, the server signs the agent certificates
+// with the CA in PKIDir and the agents verify the server using CACert.
+// Audit events are kept for AuditRetentionDays and also written
+// to the AuditLogFile, if set
 type Server struct {
-	Host string
-	Port int
+	Host               string
+	Port               int
+	VolumeDir          string
+	TLS                bool
+	PKIDir             string
+	CACert             string
+	CertValidityHours  int
+	AuditRetentionDays int
+	AuditLogFile       string
 }
 
 // Etcd configuration
@@ -17,9 +27,47 @@ type Etcd struct {
 	Address string
 }
 
+// Registry credentials configuration
+type Registry struct {
+	Host     string
+	Username string
+	Password string
+}
+
+// Reservations of the agent resources for the OS, the container runtime
+// and the agent itself
+type Reservations struct {
+	OSMemoryKB      int64
+	RuntimeMemoryKB int64
+	AgentMemoryKB   int64
+	CPUMillicores   int64
+}
+
+// DefaultReservations are used, when the configuration has none
+var DefaultReservations = Reservations{
+	OSMemoryKB:      512 * 1024,
+	RuntimeMemoryKB: 256 * 1024,
+	AgentMemoryKB:   64 * 1024,
+}
+
 // Agent configuration
 type Agent struct {
-	IPAddresses string
+	IPAddresses      string
+	Runtime          string
+	RuntimeHost      string
+	ProcessDir       string
+	CgroupRoot       string
+	DataRoot         string
+	LowDiskPercent   int
+	CertDir          string
+	BootstrapToken   string
+	Reservations     Reservations
+	Labels           map[string]string
+	DrainOnShutdown  bool
+	DrainTimeout     int
+	ImagePullWorkers int
+	ReconcileWorkers int
+	Registries       []Registry
 }
 
 type Scheduler struct {
@@ -43,7 +91,21 @@ func LoadConfig() (*Configuration, error) {
 		return nil, err
 	}
 
-	config := &Configuration{}
+	config := &Configuration{
+		Server: Server{
+			VolumeDir:          "/var/lib/chinchilla/volumes",
+			PKIDir:             "/var/lib/chinchilla/pki",
+			CACert:             "/var/lib/chinchilla/pki/ca.crt",
+			CertValidityHours:  30 * 24,
+			AuditRetentionDays: 90,
+		},
+		Agent: Agent{
+			CertDir:        "/var/lib/chinchilla/agent",
+			Reservations:   DefaultReservations,
+			LowDiskPercent: 10,
+			DrainTimeout:   300,
+		},
+	}
 	err = toml.Unmarshal(dat, config)
 	return config, err
 }
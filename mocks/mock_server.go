@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/Trojan295/chinchilla/server (interfaces: AgentStore,GameserverStore)
+// Source: github.com/Trojan295/chinchilla/server (interfaces: AgentStore,GameserverStore,EventStore,OperationStore,BootstrapTokenStore,TeamStore,QuotaStore,APITokenStore,AuditStore)
 
 // Package mocks is a generated GoMock package.
 package mocks
@@ -8,6 +8,7 @@ import (
 	server "github.com/Trojan295/chinchilla/server"
 	gomock "github.com/golang/mock/gomock"
 	reflect "reflect"
+	time "time"
 )
 
 // MockAgentStore is a mock of AgentStore interface
@@ -77,6 +78,20 @@ func (mr *MockAgentStoreMockRecorder) RegisterAgent(arg0 interface{}) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterAgent", reflect.TypeOf((*MockAgentStore)(nil).RegisterAgent), arg0)
 }
 
+// UpdateAgentMaintenance mocks base method
+func (m *MockAgentStore) UpdateAgentMaintenance(arg0 string, arg1 *server.AgentMaintenance) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAgentMaintenance", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAgentMaintenance indicates an expected call of UpdateAgentMaintenance
+func (mr *MockAgentStoreMockRecorder) UpdateAgentMaintenance(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAgentMaintenance", reflect.TypeOf((*MockAgentStore)(nil).UpdateAgentMaintenance), arg0, arg1)
+}
+
 // MockGameserverStore is a mock of GameserverStore interface
 type MockGameserverStore struct {
 	ctrl     *gomock.Controller
@@ -158,6 +173,21 @@ func (mr *MockGameserverStoreMockRecorder) ListGameservers() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGameservers", reflect.TypeOf((*MockGameserverStore)(nil).ListGameservers))
 }
 
+// LockGameserver mocks base method
+func (m *MockGameserverStore) LockGameserver(arg0 string) (func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockGameserver", arg0)
+	ret0, _ := ret[0].(func())
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockGameserver indicates an expected call of LockGameserver
+func (mr *MockGameserverStoreMockRecorder) LockGameserver(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockGameserver", reflect.TypeOf((*MockGameserverStore)(nil).LockGameserver), arg0)
+}
+
 // UpdateGameserver mocks base method
 func (m *MockGameserverStore) UpdateGameserver(arg0 *server.Gameserver) error {
 	m.ctrl.T.Helper()
@@ -171,3 +201,526 @@ func (mr *MockGameserverStoreMockRecorder) UpdateGameserver(arg0 interface{}) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateGameserver", reflect.TypeOf((*MockGameserverStore)(nil).UpdateGameserver), arg0)
 }
+
+// MockEventStore is a mock of EventStore interface
+type MockEventStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockEventStoreMockRecorder
+}
+
+// MockEventStoreMockRecorder is the mock recorder for MockEventStore
+type MockEventStoreMockRecorder struct {
+	mock *MockEventStore
+}
+
+// NewMockEventStore creates a new mock instance
+func NewMockEventStore(ctrl *gomock.Controller) *MockEventStore {
+	mock := &MockEventStore{ctrl: ctrl}
+	mock.recorder = &MockEventStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockEventStore) EXPECT() *MockEventStoreMockRecorder {
+	return m.recorder
+}
+
+// AddGameserverEvent mocks base method
+func (m *MockEventStore) AddGameserverEvent(arg0 string, arg1 *server.GameserverEvent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddGameserverEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddGameserverEvent indicates an expected call of AddGameserverEvent
+func (mr *MockEventStoreMockRecorder) AddGameserverEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddGameserverEvent", reflect.TypeOf((*MockEventStore)(nil).AddGameserverEvent), arg0, arg1)
+}
+
+// ListGameserverEvents mocks base method
+func (m *MockEventStore) ListGameserverEvents(arg0 string) ([]server.GameserverEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListGameserverEvents", arg0)
+	ret0, _ := ret[0].([]server.GameserverEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListGameserverEvents indicates an expected call of ListGameserverEvents
+func (mr *MockEventStoreMockRecorder) ListGameserverEvents(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListGameserverEvents", reflect.TypeOf((*MockEventStore)(nil).ListGameserverEvents), arg0)
+}
+
+// MockOperationStore is a mock of OperationStore interface
+type MockOperationStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockOperationStoreMockRecorder
+}
+
+// MockOperationStoreMockRecorder is the mock recorder for MockOperationStore
+type MockOperationStoreMockRecorder struct {
+	mock *MockOperationStore
+}
+
+// NewMockOperationStore creates a new mock instance
+func NewMockOperationStore(ctrl *gomock.Controller) *MockOperationStore {
+	mock := &MockOperationStore{ctrl: ctrl}
+	mock.recorder = &MockOperationStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOperationStore) EXPECT() *MockOperationStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateOperation mocks base method
+func (m *MockOperationStore) CreateOperation(arg0 *server.Operation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOperation", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOperation indicates an expected call of CreateOperation
+func (mr *MockOperationStoreMockRecorder) CreateOperation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOperation", reflect.TypeOf((*MockOperationStore)(nil).CreateOperation), arg0)
+}
+
+// GetOperation mocks base method
+func (m *MockOperationStore) GetOperation(arg0 string) (*server.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOperation", arg0)
+	ret0, _ := ret[0].(*server.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOperation indicates an expected call of GetOperation
+func (mr *MockOperationStoreMockRecorder) GetOperation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOperation", reflect.TypeOf((*MockOperationStore)(nil).GetOperation), arg0)
+}
+
+// ListOperations mocks base method
+func (m *MockOperationStore) ListOperations() ([]server.Operation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOperations")
+	ret0, _ := ret[0].([]server.Operation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOperations indicates an expected call of ListOperations
+func (mr *MockOperationStoreMockRecorder) ListOperations() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOperations", reflect.TypeOf((*MockOperationStore)(nil).ListOperations))
+}
+
+// UpdateOperation mocks base method
+func (m *MockOperationStore) UpdateOperation(arg0 *server.Operation) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateOperation", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateOperation indicates an expected call of UpdateOperation
+func (mr *MockOperationStoreMockRecorder) UpdateOperation(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateOperation", reflect.TypeOf((*MockOperationStore)(nil).UpdateOperation), arg0)
+}
+
+// MockBootstrapTokenStore is a mock of BootstrapTokenStore interface
+type MockBootstrapTokenStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockBootstrapTokenStoreMockRecorder
+}
+
+// MockBootstrapTokenStoreMockRecorder is the mock recorder for MockBootstrapTokenStore
+type MockBootstrapTokenStoreMockRecorder struct {
+	mock *MockBootstrapTokenStore
+}
+
+// NewMockBootstrapTokenStore creates a new mock instance
+func NewMockBootstrapTokenStore(ctrl *gomock.Controller) *MockBootstrapTokenStore {
+	mock := &MockBootstrapTokenStore{ctrl: ctrl}
+	mock.recorder = &MockBootstrapTokenStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockBootstrapTokenStore) EXPECT() *MockBootstrapTokenStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateBootstrapToken mocks base method
+func (m *MockBootstrapTokenStore) CreateBootstrapToken(arg0 *server.BootstrapToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBootstrapToken", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBootstrapToken indicates an expected call of CreateBootstrapToken
+func (mr *MockBootstrapTokenStoreMockRecorder) CreateBootstrapToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBootstrapToken", reflect.TypeOf((*MockBootstrapTokenStore)(nil).CreateBootstrapToken), arg0)
+}
+
+// UseBootstrapToken mocks base method
+func (m *MockBootstrapTokenStore) UseBootstrapToken(arg0 string) (*server.BootstrapToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseBootstrapToken", arg0)
+	ret0, _ := ret[0].(*server.BootstrapToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseBootstrapToken indicates an expected call of UseBootstrapToken
+func (mr *MockBootstrapTokenStoreMockRecorder) UseBootstrapToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseBootstrapToken", reflect.TypeOf((*MockBootstrapTokenStore)(nil).UseBootstrapToken), arg0)
+}
+
+// MockTeamStore is a mock of TeamStore interface
+type MockTeamStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockTeamStoreMockRecorder
+}
+
+// MockTeamStoreMockRecorder is the mock recorder for MockTeamStore
+type MockTeamStoreMockRecorder struct {
+	mock *MockTeamStore
+}
+
+// NewMockTeamStore creates a new mock instance
+func NewMockTeamStore(ctrl *gomock.Controller) *MockTeamStore {
+	mock := &MockTeamStore{ctrl: ctrl}
+	mock.recorder = &MockTeamStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockTeamStore) EXPECT() *MockTeamStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateTeam mocks base method
+func (m *MockTeamStore) CreateTeam(arg0 *server.Team) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTeam", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateTeam indicates an expected call of CreateTeam
+func (mr *MockTeamStoreMockRecorder) CreateTeam(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTeam", reflect.TypeOf((*MockTeamStore)(nil).CreateTeam), arg0)
+}
+
+// DeleteTeam mocks base method
+func (m *MockTeamStore) DeleteTeam(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTeam", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTeam indicates an expected call of DeleteTeam
+func (mr *MockTeamStoreMockRecorder) DeleteTeam(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTeam", reflect.TypeOf((*MockTeamStore)(nil).DeleteTeam), arg0)
+}
+
+// GetTeam mocks base method
+func (m *MockTeamStore) GetTeam(arg0 string) (*server.Team, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTeam", arg0)
+	ret0, _ := ret[0].(*server.Team)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTeam indicates an expected call of GetTeam
+func (mr *MockTeamStoreMockRecorder) GetTeam(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTeam", reflect.TypeOf((*MockTeamStore)(nil).GetTeam), arg0)
+}
+
+// ListTeams mocks base method
+func (m *MockTeamStore) ListTeams() ([]server.Team, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTeams")
+	ret0, _ := ret[0].([]server.Team)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTeams indicates an expected call of ListTeams
+func (mr *MockTeamStoreMockRecorder) ListTeams() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTeams", reflect.TypeOf((*MockTeamStore)(nil).ListTeams))
+}
+
+// LockTeam mocks base method
+func (m *MockTeamStore) LockTeam(arg0 string) (func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockTeam", arg0)
+	ret0, _ := ret[0].(func())
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockTeam indicates an expected call of LockTeam
+func (mr *MockTeamStoreMockRecorder) LockTeam(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockTeam", reflect.TypeOf((*MockTeamStore)(nil).LockTeam), arg0)
+}
+
+// UpdateTeam mocks base method
+func (m *MockTeamStore) UpdateTeam(arg0 *server.Team) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTeam", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTeam indicates an expected call of UpdateTeam
+func (mr *MockTeamStoreMockRecorder) UpdateTeam(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTeam", reflect.TypeOf((*MockTeamStore)(nil).UpdateTeam), arg0)
+}
+
+// MockQuotaStore is a mock of QuotaStore interface
+type MockQuotaStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockQuotaStoreMockRecorder
+}
+
+// MockQuotaStoreMockRecorder is the mock recorder for MockQuotaStore
+type MockQuotaStoreMockRecorder struct {
+	mock *MockQuotaStore
+}
+
+// NewMockQuotaStore creates a new mock instance
+func NewMockQuotaStore(ctrl *gomock.Controller) *MockQuotaStore {
+	mock := &MockQuotaStore{ctrl: ctrl}
+	mock.recorder = &MockQuotaStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockQuotaStore) EXPECT() *MockQuotaStoreMockRecorder {
+	return m.recorder
+}
+
+// DeleteQuota mocks base method
+func (m *MockQuotaStore) DeleteQuota(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteQuota", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteQuota indicates an expected call of DeleteQuota
+func (mr *MockQuotaStoreMockRecorder) DeleteQuota(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteQuota", reflect.TypeOf((*MockQuotaStore)(nil).DeleteQuota), arg0)
+}
+
+// GetQuota mocks base method
+func (m *MockQuotaStore) GetQuota(arg0 string) (*server.Quota, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetQuota", arg0)
+	ret0, _ := ret[0].(*server.Quota)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetQuota indicates an expected call of GetQuota
+func (mr *MockQuotaStoreMockRecorder) GetQuota(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetQuota", reflect.TypeOf((*MockQuotaStore)(nil).GetQuota), arg0)
+}
+
+// LockQuota mocks base method
+func (m *MockQuotaStore) LockQuota(arg0 string) (func(), error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockQuota", arg0)
+	ret0, _ := ret[0].(func())
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockQuota indicates an expected call of LockQuota
+func (mr *MockQuotaStoreMockRecorder) LockQuota(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockQuota", reflect.TypeOf((*MockQuotaStore)(nil).LockQuota), arg0)
+}
+
+// SetQuota mocks base method
+func (m *MockQuotaStore) SetQuota(arg0 string, arg1 *server.Quota) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetQuota", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetQuota indicates an expected call of SetQuota
+func (mr *MockQuotaStoreMockRecorder) SetQuota(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetQuota", reflect.TypeOf((*MockQuotaStore)(nil).SetQuota), arg0, arg1)
+}
+
+// MockAPITokenStore is a mock of APITokenStore interface
+type MockAPITokenStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPITokenStoreMockRecorder
+}
+
+// MockAPITokenStoreMockRecorder is the mock recorder for MockAPITokenStore
+type MockAPITokenStoreMockRecorder struct {
+	mock *MockAPITokenStore
+}
+
+// NewMockAPITokenStore creates a new mock instance
+func NewMockAPITokenStore(ctrl *gomock.Controller) *MockAPITokenStore {
+	mock := &MockAPITokenStore{ctrl: ctrl}
+	mock.recorder = &MockAPITokenStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAPITokenStore) EXPECT() *MockAPITokenStoreMockRecorder {
+	return m.recorder
+}
+
+// CreateAPIToken mocks base method
+func (m *MockAPITokenStore) CreateAPIToken(arg0 *server.APIToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIToken", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateAPIToken indicates an expected call of CreateAPIToken
+func (mr *MockAPITokenStoreMockRecorder) CreateAPIToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIToken", reflect.TypeOf((*MockAPITokenStore)(nil).CreateAPIToken), arg0)
+}
+
+// DeleteAPIToken mocks base method
+func (m *MockAPITokenStore) DeleteAPIToken(arg0 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAPIToken", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAPIToken indicates an expected call of DeleteAPIToken
+func (mr *MockAPITokenStoreMockRecorder) DeleteAPIToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAPIToken", reflect.TypeOf((*MockAPITokenStore)(nil).DeleteAPIToken), arg0)
+}
+
+// GetAPIToken mocks base method
+func (m *MockAPITokenStore) GetAPIToken(arg0 string) (*server.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIToken", arg0)
+	ret0, _ := ret[0].(*server.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIToken indicates an expected call of GetAPIToken
+func (mr *MockAPITokenStoreMockRecorder) GetAPIToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIToken", reflect.TypeOf((*MockAPITokenStore)(nil).GetAPIToken), arg0)
+}
+
+// ListAPITokens mocks base method
+func (m *MockAPITokenStore) ListAPITokens(arg0 string) ([]server.APIToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPITokens", arg0)
+	ret0, _ := ret[0].([]server.APIToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPITokens indicates an expected call of ListAPITokens
+func (mr *MockAPITokenStoreMockRecorder) ListAPITokens(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPITokens", reflect.TypeOf((*MockAPITokenStore)(nil).ListAPITokens), arg0)
+}
+
+// UpdateAPIToken mocks base method
+func (m *MockAPITokenStore) UpdateAPIToken(arg0 *server.APIToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAPIToken", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAPIToken indicates an expected call of UpdateAPIToken
+func (mr *MockAPITokenStoreMockRecorder) UpdateAPIToken(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAPIToken", reflect.TypeOf((*MockAPITokenStore)(nil).UpdateAPIToken), arg0)
+}
+
+// MockAuditStore is a mock of AuditStore interface
+type MockAuditStore struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuditStoreMockRecorder
+}
+
+// MockAuditStoreMockRecorder is the mock recorder for MockAuditStore
+type MockAuditStoreMockRecorder struct {
+	mock *MockAuditStore
+}
+
+// NewMockAuditStore creates a new mock instance
+func NewMockAuditStore(ctrl *gomock.Controller) *MockAuditStore {
+	mock := &MockAuditStore{ctrl: ctrl}
+	mock.recorder = &MockAuditStoreMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockAuditStore) EXPECT() *MockAuditStoreMockRecorder {
+	return m.recorder
+}
+
+// AddAuditEvent mocks base method
+func (m *MockAuditStore) AddAuditEvent(arg0 *server.AuditEvent, arg1 time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddAuditEvent", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddAuditEvent indicates an expected call of AddAuditEvent
+func (mr *MockAuditStoreMockRecorder) AddAuditEvent(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAuditEvent", reflect.TypeOf((*MockAuditStore)(nil).AddAuditEvent), arg0, arg1)
+}
+
+// ListAuditEvents mocks base method
+func (m *MockAuditStore) ListAuditEvents() ([]server.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents")
+	ret0, _ := ret[0].([]server.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents
+func (mr *MockAuditStoreMockRecorder) ListAuditEvents() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockAuditStore)(nil).ListAuditEvents))
+}
@@ -24,24 +24,67 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+type GameserverAction int32
+
+const (
+	GameserverAction_ACTION_CREATE GameserverAction = 0
+	GameserverAction_ACTION_UPDATE GameserverAction = 1
+	GameserverAction_ACTION_START  GameserverAction = 2
+	GameserverAction_ACTION_STOP   GameserverAction = 3
+	GameserverAction_ACTION_REMOVE GameserverAction = 4
+)
+
+var GameserverAction_name = map[int32]string{
+	0: "ACTION_CREATE",
+	1: "ACTION_UPDATE",
+	2: "ACTION_START",
+	3: "ACTION_STOP",
+	4: "ACTION_REMOVE",
+}
+
+var GameserverAction_value = map[string]int32{
+	"ACTION_CREATE": 0,
+	"ACTION_UPDATE": 1,
+	"ACTION_START":  2,
+	"ACTION_STOP":   3,
+	"ACTION_REMOVE": 4,
+}
+
+func (x GameserverAction) String() string {
+	return proto.EnumName(GameserverAction_name, int32(x))
+}
+
+func (GameserverAction) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{0}
+}
+
 type GameserverStatus int32
 
 const (
-	GameserverStatus_RUNNING GameserverStatus = 0
-	GameserverStatus_PENDING GameserverStatus = 1
-	GameserverStatus_ERROR   GameserverStatus = 2
+	GameserverStatus_RUNNING       GameserverStatus = 0
+	GameserverStatus_PENDING       GameserverStatus = 1
+	GameserverStatus_ERROR         GameserverStatus = 2
+	GameserverStatus_STOPPED       GameserverStatus = 3
+	GameserverStatus_CRASH_LOOP    GameserverStatus = 4
+	GameserverStatus_PULLING_IMAGE GameserverStatus = 5
 )
 
 var GameserverStatus_name = map[int32]string{
 	0: "RUNNING",
 	1: "PENDING",
 	2: "ERROR",
+	3: "STOPPED",
+	4: "CRASH_LOOP",
+	5: "PULLING_IMAGE",
 }
 
 var GameserverStatus_value = map[string]int32{
-	"RUNNING": 0,
-	"PENDING": 1,
-	"ERROR":   2,
+	"RUNNING":       0,
+	"PENDING":       1,
+	"ERROR":         2,
+	"STOPPED":       3,
+	"CRASH_LOOP":    4,
+	"PULLING_IMAGE": 5,
 }
 
 func (x GameserverStatus) String() string {
@@ -49,7 +92,38 @@ func (x GameserverStatus) String() string {
 }
 
 func (GameserverStatus) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{0}
+	return fileDescriptor_dd830a99d5efef4e, []int{1}
+}
+
+type HealthStatus int32
+
+const (
+	HealthStatus_HEALTH_UNKNOWN HealthStatus = 0
+	HealthStatus_STARTING       HealthStatus = 1
+	HealthStatus_HEALTHY        HealthStatus = 2
+	HealthStatus_UNHEALTHY      HealthStatus = 3
+)
+
+var HealthStatus_name = map[int32]string{
+	0: "HEALTH_UNKNOWN",
+	1: "STARTING",
+	2: "HEALTHY",
+	3: "UNHEALTHY",
+}
+
+var HealthStatus_value = map[string]int32{
+	"HEALTH_UNKNOWN": 0,
+	"STARTING":       1,
+	"HEALTHY":        2,
+	"UNHEALTHY":      3,
+}
+
+func (x HealthStatus) String() string {
+	return proto.EnumName(HealthStatus_name, int32(x))
+}
+
+func (HealthStatus) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{2}
 }
 
 type NetworkProtocol int32
@@ -74,7 +148,175 @@ func (x NetworkProtocol) String() string {
 }
 
 func (NetworkProtocol) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{1}
+	return fileDescriptor_dd830a99d5efef4e, []int{3}
+}
+
+type QueryProtocol int32
+
+const (
+	QueryProtocol_QUERY_NONE      QueryProtocol = 0
+	QueryProtocol_QUERY_MINECRAFT QueryProtocol = 1
+	QueryProtocol_QUERY_FACTORIO  QueryProtocol = 2
+	QueryProtocol_QUERY_TEAMSPEAK QueryProtocol = 3
+)
+
+var QueryProtocol_name = map[int32]string{
+	0: "QUERY_NONE",
+	1: "QUERY_MINECRAFT",
+	2: "QUERY_FACTORIO",
+	3: "QUERY_TEAMSPEAK",
+}
+
+var QueryProtocol_value = map[string]int32{
+	"QUERY_NONE":      0,
+	"QUERY_MINECRAFT": 1,
+	"QUERY_FACTORIO":  2,
+	"QUERY_TEAMSPEAK": 3,
+}
+
+func (x QueryProtocol) String() string {
+	return proto.EnumName(QueryProtocol_name, int32(x))
+}
+
+func (QueryProtocol) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{4}
+}
+
+type HealthCheckType int32
+
+const (
+	HealthCheckType_HEALTHCHECK_NONE HealthCheckType = 0
+	HealthCheckType_HEALTHCHECK_TCP  HealthCheckType = 1
+	HealthCheckType_HEALTHCHECK_UDP  HealthCheckType = 2
+	HealthCheckType_HEALTHCHECK_EXEC HealthCheckType = 3
+)
+
+var HealthCheckType_name = map[int32]string{
+	0: "HEALTHCHECK_NONE",
+	1: "HEALTHCHECK_TCP",
+	2: "HEALTHCHECK_UDP",
+	3: "HEALTHCHECK_EXEC",
+}
+
+var HealthCheckType_value = map[string]int32{
+	"HEALTHCHECK_NONE": 0,
+	"HEALTHCHECK_TCP":  1,
+	"HEALTHCHECK_UDP":  2,
+	"HEALTHCHECK_EXEC": 3,
+}
+
+func (x HealthCheckType) String() string {
+	return proto.EnumName(HealthCheckType_name, int32(x))
+}
+
+func (HealthCheckType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{5}
+}
+
+type RestartPolicyType int32
+
+const (
+	RestartPolicyType_RESTART_ON_FAILURE RestartPolicyType = 0
+	RestartPolicyType_RESTART_ALWAYS     RestartPolicyType = 1
+	RestartPolicyType_RESTART_NEVER      RestartPolicyType = 2
+)
+
+var RestartPolicyType_name = map[int32]string{
+	0: "RESTART_ON_FAILURE",
+	1: "RESTART_ALWAYS",
+	2: "RESTART_NEVER",
+}
+
+var RestartPolicyType_value = map[string]int32{
+	"RESTART_ON_FAILURE": 0,
+	"RESTART_ALWAYS":     1,
+	"RESTART_NEVER":      2,
+}
+
+func (x RestartPolicyType) String() string {
+	return proto.EnumName(RestartPolicyType_name, int32(x))
+}
+
+func (RestartPolicyType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{6}
+}
+
+type ShutdownChannel int32
+
+const (
+	ShutdownChannel_SHUTDOWN_STDIN ShutdownChannel = 0
+	ShutdownChannel_SHUTDOWN_RCON  ShutdownChannel = 1
+)
+
+var ShutdownChannel_name = map[int32]string{
+	0: "SHUTDOWN_STDIN",
+	1: "SHUTDOWN_RCON",
+}
+
+var ShutdownChannel_value = map[string]int32{
+	"SHUTDOWN_STDIN": 0,
+	"SHUTDOWN_RCON":  1,
+}
+
+func (x ShutdownChannel) String() string {
+	return proto.EnumName(ShutdownChannel_name, int32(x))
+}
+
+func (ShutdownChannel) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{7}
+}
+
+type ImagePullPolicy int32
+
+const (
+	ImagePullPolicy_PULL_ALWAYS         ImagePullPolicy = 0
+	ImagePullPolicy_PULL_IF_NOT_PRESENT ImagePullPolicy = 1
+	ImagePullPolicy_PULL_NEVER          ImagePullPolicy = 2
+)
+
+var ImagePullPolicy_name = map[int32]string{
+	0: "PULL_ALWAYS",
+	1: "PULL_IF_NOT_PRESENT",
+	2: "PULL_NEVER",
+}
+
+var ImagePullPolicy_value = map[string]int32{
+	"PULL_ALWAYS":         0,
+	"PULL_IF_NOT_PRESENT": 1,
+	"PULL_NEVER":          2,
+}
+
+func (x ImagePullPolicy) String() string {
+	return proto.EnumName(ImagePullPolicy_name, int32(x))
+}
+
+func (ImagePullPolicy) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{8}
+}
+
+type MigrationTaskType int32
+
+const (
+	MigrationTaskType_MIGRATION_EXPORT MigrationTaskType = 0
+	MigrationTaskType_MIGRATION_IMPORT MigrationTaskType = 1
+)
+
+var MigrationTaskType_name = map[int32]string{
+	0: "MIGRATION_EXPORT",
+	1: "MIGRATION_IMPORT",
+}
+
+var MigrationTaskType_value = map[string]int32{
+	"MIGRATION_EXPORT": 0,
+	"MIGRATION_IMPORT": 1,
+}
+
+func (x MigrationTaskType) String() string {
+	return proto.EnumName(MigrationTaskType_name, int32(x))
+}
+
+func (MigrationTaskType) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{9}
 }
 
 type Empty struct {
@@ -108,13 +350,19 @@ func (m *Empty) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_Empty proto.InternalMessageInfo
 
+// AgentResources are the resources of the agent available for gameservers,
+// after subtracting the reservations. cpus are whole cores, cpuMillicores
+// are 1/1000 of a core, memory is in kB and disk in bytes.
 type AgentResources struct {
-	Cpus                 int64    `protobuf:"varint,1,opt,name=cpus,proto3" json:"cpus,omitempty"`
-	Memory               int64    `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`
-	IpAddresses          int64    `protobuf:"varint,3,opt,name=ipAddresses,proto3" json:"ipAddresses,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Cpus                 int64               `protobuf:"varint,1,opt,name=cpus,proto3" json:"cpus,omitempty"`
+	Memory               int64               `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	IpAddresses          int64               `protobuf:"varint,3,opt,name=ipAddresses,proto3" json:"ipAddresses,omitempty"`
+	CpuMillicores        int64               `protobuf:"varint,4,opt,name=cpuMillicores,proto3" json:"cpuMillicores,omitempty"`
+	Disk                 int64               `protobuf:"varint,5,opt,name=disk,proto3" json:"disk,omitempty"`
+	NetworkInterfaces    []*NetworkInterface `protobuf:"bytes,6,rep,name=networkInterfaces,proto3" json:"networkInterfaces,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
 }
 
 func (m *AgentResources) Reset()         { *m = AgentResources{} }
@@ -163,8 +411,105 @@ func (m *AgentResources) GetIpAddresses() int64 {
 	return 0
 }
 
+func (m *AgentResources) GetCpuMillicores() int64 {
+	if m != nil {
+		return m.CpuMillicores
+	}
+	return 0
+}
+
+func (m *AgentResources) GetDisk() int64 {
+	if m != nil {
+		return m.Disk
+	}
+	return 0
+}
+
+func (m *AgentResources) GetNetworkInterfaces() []*NetworkInterface {
+	if m != nil {
+		return m.NetworkInterfaces
+	}
+	return nil
+}
+
+// NetworkInterface describes a NIC of the agent. speed is in Mbit/s,
+// 0 when unknown.
+type NetworkInterface struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	HardwareAddress      string   `protobuf:"bytes,2,opt,name=hardwareAddress,proto3" json:"hardwareAddress,omitempty"`
+	Mtu                  int64    `protobuf:"varint,3,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	Speed                int64    `protobuf:"varint,4,opt,name=speed,proto3" json:"speed,omitempty"`
+	Addresses            []string `protobuf:"bytes,5,rep,name=addresses,proto3" json:"addresses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *NetworkInterface) Reset()         { *m = NetworkInterface{} }
+func (m *NetworkInterface) String() string { return proto.CompactTextString(m) }
+func (*NetworkInterface) ProtoMessage()    {}
+func (*NetworkInterface) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{2}
+}
+
+func (m *NetworkInterface) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NetworkInterface.Unmarshal(m, b)
+}
+func (m *NetworkInterface) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NetworkInterface.Marshal(b, m, deterministic)
+}
+func (m *NetworkInterface) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NetworkInterface.Merge(m, src)
+}
+func (m *NetworkInterface) XXX_Size() int {
+	return xxx_messageInfo_NetworkInterface.Size(m)
+}
+func (m *NetworkInterface) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetworkInterface.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NetworkInterface proto.InternalMessageInfo
+
+func (m *NetworkInterface) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *NetworkInterface) GetHardwareAddress() string {
+	if m != nil {
+		return m.HardwareAddress
+	}
+	return ""
+}
+
+func (m *NetworkInterface) GetMtu() int64 {
+	if m != nil {
+		return m.Mtu
+	}
+	return 0
+}
+
+func (m *NetworkInterface) GetSpeed() int64 {
+	if m != nil {
+		return m.Speed
+	}
+	return 0
+}
+
+func (m *NetworkInterface) GetAddresses() []string {
+	if m != nil {
+		return m.Addresses
+	}
+	return nil
+}
+
+// AgentResourceUsage is the memory in kB used on the agent
+// above the reservations and the free disk in bytes.
 type AgentResourceUsage struct {
 	Memory               int64    `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	DiskFree             int64    `protobuf:"varint,3,opt,name=diskFree,proto3" json:"diskFree,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -174,7 +519,7 @@ func (m *AgentResourceUsage) Reset()         { *m = AgentResourceUsage{} }
 func (m *AgentResourceUsage) String() string { return proto.CompactTextString(m) }
 func (*AgentResourceUsage) ProtoMessage()    {}
 func (*AgentResourceUsage) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{2}
+	return fileDescriptor_dd830a99d5efef4e, []int{3}
 }
 
 func (m *AgentResourceUsage) XXX_Unmarshal(b []byte) error {
@@ -202,559 +547,2019 @@ func (m *AgentResourceUsage) GetMemory() int64 {
 	return 0
 }
 
-type AgentState struct {
-	Hostname             string              `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
-	Resources            *AgentResources     `protobuf:"bytes,2,opt,name=resources,proto3" json:"resources,omitempty"`
-	ResourceUsage        *AgentResourceUsage `protobuf:"bytes,3,opt,name=resourceUsage,proto3" json:"resourceUsage,omitempty"`
-	RunningGameservers   []*Gameserver       `protobuf:"bytes,4,rep,name=runningGameservers,proto3" json:"runningGameservers,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
-	XXX_unrecognized     []byte              `json:"-"`
-	XXX_sizecache        int32               `json:"-"`
+func (m *AgentResourceUsage) GetDiskFree() int64 {
+	if m != nil {
+		return m.DiskFree
+	}
+	return 0
 }
 
-func (m *AgentState) Reset()         { *m = AgentState{} }
-func (m *AgentState) String() string { return proto.CompactTextString(m) }
-func (*AgentState) ProtoMessage()    {}
-func (*AgentState) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{3}
+// AgentCondition is a warning raised by the agent, e.g. LowDisk.
+type AgentCondition struct {
+	Type                 string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *AgentState) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_AgentState.Unmarshal(m, b)
+func (m *AgentCondition) Reset()         { *m = AgentCondition{} }
+func (m *AgentCondition) String() string { return proto.CompactTextString(m) }
+func (*AgentCondition) ProtoMessage()    {}
+func (*AgentCondition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{4}
 }
-func (m *AgentState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_AgentState.Marshal(b, m, deterministic)
+
+func (m *AgentCondition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AgentCondition.Unmarshal(m, b)
 }
-func (m *AgentState) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_AgentState.Merge(m, src)
+func (m *AgentCondition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AgentCondition.Marshal(b, m, deterministic)
 }
-func (m *AgentState) XXX_Size() int {
-	return xxx_messageInfo_AgentState.Size(m)
+func (m *AgentCondition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AgentCondition.Merge(m, src)
 }
-func (m *AgentState) XXX_DiscardUnknown() {
-	xxx_messageInfo_AgentState.DiscardUnknown(m)
+func (m *AgentCondition) XXX_Size() int {
+	return xxx_messageInfo_AgentCondition.Size(m)
 }
-
-var xxx_messageInfo_AgentState proto.InternalMessageInfo
-
-func (m *AgentState) GetHostname() string {
-	if m != nil {
-		return m.Hostname
-	}
-	return ""
+func (m *AgentCondition) XXX_DiscardUnknown() {
+	xxx_messageInfo_AgentCondition.DiscardUnknown(m)
 }
 
-func (m *AgentState) GetResources() *AgentResources {
-	if m != nil {
-		return m.Resources
-	}
-	return nil
-}
+var xxx_messageInfo_AgentCondition proto.InternalMessageInfo
 
-func (m *AgentState) GetResourceUsage() *AgentResourceUsage {
+func (m *AgentCondition) GetType() string {
 	if m != nil {
-		return m.ResourceUsage
+		return m.Type
 	}
-	return nil
+	return ""
 }
 
-func (m *AgentState) GetRunningGameservers() []*Gameserver {
+func (m *AgentCondition) GetMessage() string {
 	if m != nil {
-		return m.RunningGameservers
+		return m.Message
 	}
-	return nil
+	return ""
 }
 
-type Endpoint struct {
-	IpAddress            string   `protobuf:"bytes,1,opt,name=ipAddress,proto3" json:"ipAddress,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+type GameserverActionResult struct {
+	UUID                 string           `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Action               GameserverAction `protobuf:"varint,2,opt,name=action,proto3,enum=proto.GameserverAction" json:"action,omitempty"`
+	Success              bool             `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Error                string           `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Timestamp            int64            `protobuf:"varint,5,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Duration             int64            `protobuf:"varint,6,opt,name=duration,proto3" json:"duration,omitempty"`
+	Shutdown             *ShutdownResult  `protobuf:"bytes,7,opt,name=shutdown,proto3" json:"shutdown,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
 }
 
-func (m *Endpoint) Reset()         { *m = Endpoint{} }
-func (m *Endpoint) String() string { return proto.CompactTextString(m) }
-func (*Endpoint) ProtoMessage()    {}
-func (*Endpoint) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{4}
+func (m *GameserverActionResult) Reset()         { *m = GameserverActionResult{} }
+func (m *GameserverActionResult) String() string { return proto.CompactTextString(m) }
+func (*GameserverActionResult) ProtoMessage()    {}
+func (*GameserverActionResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{5}
 }
 
-func (m *Endpoint) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Endpoint.Unmarshal(m, b)
+func (m *GameserverActionResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GameserverActionResult.Unmarshal(m, b)
 }
-func (m *Endpoint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Endpoint.Marshal(b, m, deterministic)
+func (m *GameserverActionResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GameserverActionResult.Marshal(b, m, deterministic)
 }
-func (m *Endpoint) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Endpoint.Merge(m, src)
+func (m *GameserverActionResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GameserverActionResult.Merge(m, src)
 }
-func (m *Endpoint) XXX_Size() int {
-	return xxx_messageInfo_Endpoint.Size(m)
+func (m *GameserverActionResult) XXX_Size() int {
+	return xxx_messageInfo_GameserverActionResult.Size(m)
 }
-func (m *Endpoint) XXX_DiscardUnknown() {
-	xxx_messageInfo_Endpoint.DiscardUnknown(m)
+func (m *GameserverActionResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_GameserverActionResult.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Endpoint proto.InternalMessageInfo
+var xxx_messageInfo_GameserverActionResult proto.InternalMessageInfo
 
-func (m *Endpoint) GetIpAddress() string {
+func (m *GameserverActionResult) GetUUID() string {
 	if m != nil {
-		return m.IpAddress
+		return m.UUID
 	}
 	return ""
 }
 
-type Gameserver struct {
-	UUID                 string           `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
-	Status               GameserverStatus `protobuf:"varint,2,opt,name=status,proto3,enum=proto.GameserverStatus" json:"status,omitempty"`
-	Info                 string           `protobuf:"bytes,3,opt,name=info,proto3" json:"info,omitempty"`
-	Endpoint             *Endpoint        `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
-	XXX_unrecognized     []byte           `json:"-"`
-	XXX_sizecache        int32            `json:"-"`
+func (m *GameserverActionResult) GetAction() GameserverAction {
+	if m != nil {
+		return m.Action
+	}
+	return GameserverAction_ACTION_CREATE
+}
+
+func (m *GameserverActionResult) GetSuccess() bool {
+	if m != nil {
+		return m.Success
+	}
+	return false
+}
+
+func (m *GameserverActionResult) GetError() string {
+	if m != nil {
+		return m.Error
+	}
+	return ""
+}
+
+func (m *GameserverActionResult) GetTimestamp() int64 {
+	if m != nil {
+		return m.Timestamp
+	}
+	return 0
+}
+
+func (m *GameserverActionResult) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+func (m *GameserverActionResult) GetShutdown() *ShutdownResult {
+	if m != nil {
+		return m.Shutdown
+	}
+	return nil
+}
+
+// ShutdownResult describes how a gameserver was shut down.
+// duration is in milliseconds.
+type ShutdownResult struct {
+	Graceful             bool     `protobuf:"varint,1,opt,name=graceful,proto3" json:"graceful,omitempty"`
+	Method               string   `protobuf:"bytes,2,opt,name=method,proto3" json:"method,omitempty"`
+	Duration             int64    `protobuf:"varint,3,opt,name=duration,proto3" json:"duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShutdownResult) Reset()         { *m = ShutdownResult{} }
+func (m *ShutdownResult) String() string { return proto.CompactTextString(m) }
+func (*ShutdownResult) ProtoMessage()    {}
+func (*ShutdownResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{6}
+}
+
+func (m *ShutdownResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShutdownResult.Unmarshal(m, b)
+}
+func (m *ShutdownResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShutdownResult.Marshal(b, m, deterministic)
+}
+func (m *ShutdownResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShutdownResult.Merge(m, src)
+}
+func (m *ShutdownResult) XXX_Size() int {
+	return xxx_messageInfo_ShutdownResult.Size(m)
+}
+func (m *ShutdownResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShutdownResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShutdownResult proto.InternalMessageInfo
+
+func (m *ShutdownResult) GetGraceful() bool {
+	if m != nil {
+		return m.Graceful
+	}
+	return false
+}
+
+func (m *ShutdownResult) GetMethod() string {
+	if m != nil {
+		return m.Method
+	}
+	return ""
+}
+
+func (m *ShutdownResult) GetDuration() int64 {
+	if m != nil {
+		return m.Duration
+	}
+	return 0
+}
+
+type AgentState struct {
+	Hostname           string                    `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Resources          *AgentResources           `protobuf:"bytes,2,opt,name=resources,proto3" json:"resources,omitempty"`
+	ResourceUsage      *AgentResourceUsage       `protobuf:"bytes,3,opt,name=resourceUsage,proto3" json:"resourceUsage,omitempty"`
+	RunningGameservers []*Gameserver             `protobuf:"bytes,4,rep,name=runningGameservers,proto3" json:"runningGameservers,omitempty"`
+	ActionResults      []*GameserverActionResult `protobuf:"bytes,5,rep,name=actionResults,proto3" json:"actionResults,omitempty"`
+	Conditions         []*AgentCondition         `protobuf:"bytes,6,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	Labels             map[string]string         `protobuf:"bytes,7,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// runtime runs the gameservers: docker, podman or process
+	Runtime              string   `protobuf:"bytes,8,opt,name=runtime,proto3" json:"runtime,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AgentState) Reset()         { *m = AgentState{} }
+func (m *AgentState) String() string { return proto.CompactTextString(m) }
+func (*AgentState) ProtoMessage()    {}
+func (*AgentState) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{7}
+}
+
+func (m *AgentState) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AgentState.Unmarshal(m, b)
+}
+func (m *AgentState) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AgentState.Marshal(b, m, deterministic)
+}
+func (m *AgentState) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AgentState.Merge(m, src)
+}
+func (m *AgentState) XXX_Size() int {
+	return xxx_messageInfo_AgentState.Size(m)
+}
+func (m *AgentState) XXX_DiscardUnknown() {
+	xxx_messageInfo_AgentState.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AgentState proto.InternalMessageInfo
+
+func (m *AgentState) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+func (m *AgentState) GetResources() *AgentResources {
+	if m != nil {
+		return m.Resources
+	}
+	return nil
+}
+
+func (m *AgentState) GetResourceUsage() *AgentResourceUsage {
+	if m != nil {
+		return m.ResourceUsage
+	}
+	return nil
+}
+
+func (m *AgentState) GetRunningGameservers() []*Gameserver {
+	if m != nil {
+		return m.RunningGameservers
+	}
+	return nil
+}
+
+func (m *AgentState) GetActionResults() []*GameserverActionResult {
+	if m != nil {
+		return m.ActionResults
+	}
+	return nil
+}
+
+func (m *AgentState) GetConditions() []*AgentCondition {
+	if m != nil {
+		return m.Conditions
+	}
+	return nil
+}
+
+func (m *AgentState) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *AgentState) GetRuntime() string {
+	if m != nil {
+		return m.Runtime
+	}
+	return ""
+}
+
+type Endpoint struct {
+	IpAddress            string   `protobuf:"bytes,1,opt,name=ipAddress,proto3" json:"ipAddress,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Endpoint) Reset()         { *m = Endpoint{} }
+func (m *Endpoint) String() string { return proto.CompactTextString(m) }
+func (*Endpoint) ProtoMessage()    {}
+func (*Endpoint) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{8}
+}
+
+func (m *Endpoint) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Endpoint.Unmarshal(m, b)
+}
+func (m *Endpoint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Endpoint.Marshal(b, m, deterministic)
+}
+func (m *Endpoint) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Endpoint.Merge(m, src)
+}
+func (m *Endpoint) XXX_Size() int {
+	return xxx_messageInfo_Endpoint.Size(m)
+}
+func (m *Endpoint) XXX_DiscardUnknown() {
+	xxx_messageInfo_Endpoint.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Endpoint proto.InternalMessageInfo
+
+func (m *Endpoint) GetIpAddress() string {
+	if m != nil {
+		return m.IpAddress
+	}
+	return ""
+}
+
+// GameserverResourceUsage holds the container stats of a gameserver.
+// Memory is in kB, network and block IO counters are in bytes.
+type GameserverResourceUsage struct {
+	CpuPercent           float64  `protobuf:"fixed64,1,opt,name=cpuPercent,proto3" json:"cpuPercent,omitempty"`
+	MemoryUsage          int64    `protobuf:"varint,2,opt,name=memoryUsage,proto3" json:"memoryUsage,omitempty"`
+	MemoryLimit          int64    `protobuf:"varint,3,opt,name=memoryLimit,proto3" json:"memoryLimit,omitempty"`
+	NetworkRxBytes       int64    `protobuf:"varint,4,opt,name=networkRxBytes,proto3" json:"networkRxBytes,omitempty"`
+	NetworkTxBytes       int64    `protobuf:"varint,5,opt,name=networkTxBytes,proto3" json:"networkTxBytes,omitempty"`
+	BlockReadBytes       int64    `protobuf:"varint,6,opt,name=blockReadBytes,proto3" json:"blockReadBytes,omitempty"`
+	BlockWriteBytes      int64    `protobuf:"varint,7,opt,name=blockWriteBytes,proto3" json:"blockWriteBytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GameserverResourceUsage) Reset()         { *m = GameserverResourceUsage{} }
+func (m *GameserverResourceUsage) String() string { return proto.CompactTextString(m) }
+func (*GameserverResourceUsage) ProtoMessage()    {}
+func (*GameserverResourceUsage) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{9}
+}
+
+func (m *GameserverResourceUsage) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GameserverResourceUsage.Unmarshal(m, b)
+}
+func (m *GameserverResourceUsage) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GameserverResourceUsage.Marshal(b, m, deterministic)
+}
+func (m *GameserverResourceUsage) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GameserverResourceUsage.Merge(m, src)
+}
+func (m *GameserverResourceUsage) XXX_Size() int {
+	return xxx_messageInfo_GameserverResourceUsage.Size(m)
+}
+func (m *GameserverResourceUsage) XXX_DiscardUnknown() {
+	xxx_messageInfo_GameserverResourceUsage.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GameserverResourceUsage proto.InternalMessageInfo
+
+func (m *GameserverResourceUsage) GetCpuPercent() float64 {
+	if m != nil {
+		return m.CpuPercent
+	}
+	return 0
+}
+
+func (m *GameserverResourceUsage) GetMemoryUsage() int64 {
+	if m != nil {
+		return m.MemoryUsage
+	}
+	return 0
+}
+
+func (m *GameserverResourceUsage) GetMemoryLimit() int64 {
+	if m != nil {
+		return m.MemoryLimit
+	}
+	return 0
+}
+
+func (m *GameserverResourceUsage) GetNetworkRxBytes() int64 {
+	if m != nil {
+		return m.NetworkRxBytes
+	}
+	return 0
+}
+
+func (m *GameserverResourceUsage) GetNetworkTxBytes() int64 {
+	if m != nil {
+		return m.NetworkTxBytes
+	}
+	return 0
+}
+
+func (m *GameserverResourceUsage) GetBlockReadBytes() int64 {
+	if m != nil {
+		return m.BlockReadBytes
+	}
+	return 0
+}
+
+func (m *GameserverResourceUsage) GetBlockWriteBytes() int64 {
+	if m != nil {
+		return m.BlockWriteBytes
+	}
+	return 0
+}
+
+type GameserverQueryResult struct {
+	PlayersOnline        int64    `protobuf:"varint,1,opt,name=playersOnline,proto3" json:"playersOnline,omitempty"`
+	PlayersMax           int64    `protobuf:"varint,2,opt,name=playersMax,proto3" json:"playersMax,omitempty"`
+	Motd                 string   `protobuf:"bytes,3,opt,name=motd,proto3" json:"motd,omitempty"`
+	Version              string   `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GameserverQueryResult) Reset()         { *m = GameserverQueryResult{} }
+func (m *GameserverQueryResult) String() string { return proto.CompactTextString(m) }
+func (*GameserverQueryResult) ProtoMessage()    {}
+func (*GameserverQueryResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{10}
+}
+
+func (m *GameserverQueryResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GameserverQueryResult.Unmarshal(m, b)
+}
+func (m *GameserverQueryResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GameserverQueryResult.Marshal(b, m, deterministic)
+}
+func (m *GameserverQueryResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GameserverQueryResult.Merge(m, src)
+}
+func (m *GameserverQueryResult) XXX_Size() int {
+	return xxx_messageInfo_GameserverQueryResult.Size(m)
+}
+func (m *GameserverQueryResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_GameserverQueryResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GameserverQueryResult proto.InternalMessageInfo
+
+func (m *GameserverQueryResult) GetPlayersOnline() int64 {
+	if m != nil {
+		return m.PlayersOnline
+	}
+	return 0
+}
+
+func (m *GameserverQueryResult) GetPlayersMax() int64 {
+	if m != nil {
+		return m.PlayersMax
+	}
+	return 0
+}
+
+func (m *GameserverQueryResult) GetMotd() string {
+	if m != nil {
+		return m.Motd
+	}
+	return ""
+}
+
+func (m *GameserverQueryResult) GetVersion() string {
+	if m != nil {
+		return m.Version
+	}
+	return ""
+}
+
+type Gameserver struct {
+	UUID                 string                   `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Status               GameserverStatus         `protobuf:"varint,2,opt,name=status,proto3,enum=proto.GameserverStatus" json:"status,omitempty"`
+	Info                 string                   `protobuf:"bytes,3,opt,name=info,proto3" json:"info,omitempty"`
+	Endpoint             *Endpoint                `protobuf:"bytes,4,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	ResourceUsage        *GameserverResourceUsage `protobuf:"bytes,5,opt,name=resourceUsage,proto3" json:"resourceUsage,omitempty"`
+	Query                *GameserverQueryResult   `protobuf:"bytes,6,opt,name=query,proto3" json:"query,omitempty"`
+	Health               HealthStatus             `protobuf:"varint,7,opt,name=health,proto3,enum=proto.HealthStatus" json:"health,omitempty"`
+	RestartCount         int64                    `protobuf:"varint,8,opt,name=restartCount,proto3" json:"restartCount,omitempty"`
+	LastExitCode         int64                    `protobuf:"varint,9,opt,name=lastExitCode,proto3" json:"lastExitCode,omitempty"`
+	CrashLog             string                   `protobuf:"bytes,10,opt,name=crashLog,proto3" json:"crashLog,omitempty"`
+	PullProgress         float64                  `protobuf:"fixed64,11,opt,name=pullProgress,proto3" json:"pullProgress,omitempty"`
+	ImageDigest          string                   `protobuf:"bytes,12,opt,name=imageDigest,proto3" json:"imageDigest,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *Gameserver) Reset()         { *m = Gameserver{} }
+func (m *Gameserver) String() string { return proto.CompactTextString(m) }
+func (*Gameserver) ProtoMessage()    {}
+func (*Gameserver) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{11}
+}
+
+func (m *Gameserver) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Gameserver.Unmarshal(m, b)
+}
+func (m *Gameserver) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Gameserver.Marshal(b, m, deterministic)
+}
+func (m *Gameserver) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Gameserver.Merge(m, src)
+}
+func (m *Gameserver) XXX_Size() int {
+	return xxx_messageInfo_Gameserver.Size(m)
+}
+func (m *Gameserver) XXX_DiscardUnknown() {
+	xxx_messageInfo_Gameserver.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Gameserver proto.InternalMessageInfo
+
+func (m *Gameserver) GetUUID() string {
+	if m != nil {
+		return m.UUID
+	}
+	return ""
+}
+
+func (m *Gameserver) GetStatus() GameserverStatus {
+	if m != nil {
+		return m.Status
+	}
+	return GameserverStatus_RUNNING
+}
+
+func (m *Gameserver) GetInfo() string {
+	if m != nil {
+		return m.Info
+	}
+	return ""
+}
+
+func (m *Gameserver) GetEndpoint() *Endpoint {
+	if m != nil {
+		return m.Endpoint
+	}
+	return nil
+}
+
+func (m *Gameserver) GetResourceUsage() *GameserverResourceUsage {
+	if m != nil {
+		return m.ResourceUsage
+	}
+	return nil
+}
+
+func (m *Gameserver) GetQuery() *GameserverQueryResult {
+	if m != nil {
+		return m.Query
+	}
+	return nil
+}
+
+func (m *Gameserver) GetHealth() HealthStatus {
+	if m != nil {
+		return m.Health
+	}
+	return HealthStatus_HEALTH_UNKNOWN
+}
+
+func (m *Gameserver) GetRestartCount() int64 {
+	if m != nil {
+		return m.RestartCount
+	}
+	return 0
+}
+
+func (m *Gameserver) GetLastExitCode() int64 {
+	if m != nil {
+		return m.LastExitCode
+	}
+	return 0
+}
+
+func (m *Gameserver) GetCrashLog() string {
+	if m != nil {
+		return m.CrashLog
+	}
+	return ""
+}
+
+func (m *Gameserver) GetPullProgress() float64 {
+	if m != nil {
+		return m.PullProgress
+	}
+	return 0
+}
+
+func (m *Gameserver) GetImageDigest() string {
+	if m != nil {
+		return m.ImageDigest
+	}
+	return ""
+}
+
+type GetGameserverDeploymentsRequest struct {
+	Hostname             string   `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetGameserverDeploymentsRequest) Reset()         { *m = GetGameserverDeploymentsRequest{} }
+func (m *GetGameserverDeploymentsRequest) String() string { return proto.CompactTextString(m) }
+func (*GetGameserverDeploymentsRequest) ProtoMessage()    {}
+func (*GetGameserverDeploymentsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{12}
+}
+
+func (m *GetGameserverDeploymentsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetGameserverDeploymentsRequest.Unmarshal(m, b)
+}
+func (m *GetGameserverDeploymentsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetGameserverDeploymentsRequest.Marshal(b, m, deterministic)
+}
+func (m *GetGameserverDeploymentsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetGameserverDeploymentsRequest.Merge(m, src)
+}
+func (m *GetGameserverDeploymentsRequest) XXX_Size() int {
+	return xxx_messageInfo_GetGameserverDeploymentsRequest.Size(m)
+}
+func (m *GetGameserverDeploymentsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetGameserverDeploymentsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetGameserverDeploymentsRequest proto.InternalMessageInfo
+
+func (m *GetGameserverDeploymentsRequest) GetHostname() string {
+	if m != nil {
+		return m.Hostname
+	}
+	return ""
+}
+
+// ResourceRequirements of a gameserver. Memory is in kB, disk in bytes.
+type ResourceRequirements struct {
+	CpuReservation       int64    `protobuf:"varint,1,opt,name=cpuReservation,proto3" json:"cpuReservation,omitempty"`
+	CpuLimit             int64    `protobuf:"varint,2,opt,name=cpuLimit,proto3" json:"cpuLimit,omitempty"`
+	MemoryReservation    int64    `protobuf:"varint,3,opt,name=memoryReservation,proto3" json:"memoryReservation,omitempty"`
+	MemoryLimit          int64    `protobuf:"varint,4,opt,name=memoryLimit,proto3" json:"memoryLimit,omitempty"`
+	DiskReservation      int64    `protobuf:"varint,5,opt,name=diskReservation,proto3" json:"diskReservation,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResourceRequirements) Reset()         { *m = ResourceRequirements{} }
+func (m *ResourceRequirements) String() string { return proto.CompactTextString(m) }
+func (*ResourceRequirements) ProtoMessage()    {}
+func (*ResourceRequirements) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{13}
+}
+
+func (m *ResourceRequirements) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResourceRequirements.Unmarshal(m, b)
+}
+func (m *ResourceRequirements) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResourceRequirements.Marshal(b, m, deterministic)
+}
+func (m *ResourceRequirements) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResourceRequirements.Merge(m, src)
+}
+func (m *ResourceRequirements) XXX_Size() int {
+	return xxx_messageInfo_ResourceRequirements.Size(m)
+}
+func (m *ResourceRequirements) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResourceRequirements.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResourceRequirements proto.InternalMessageInfo
+
+func (m *ResourceRequirements) GetCpuReservation() int64 {
+	if m != nil {
+		return m.CpuReservation
+	}
+	return 0
+}
+
+func (m *ResourceRequirements) GetCpuLimit() int64 {
+	if m != nil {
+		return m.CpuLimit
+	}
+	return 0
+}
+
+func (m *ResourceRequirements) GetMemoryReservation() int64 {
+	if m != nil {
+		return m.MemoryReservation
+	}
+	return 0
+}
+
+func (m *ResourceRequirements) GetMemoryLimit() int64 {
+	if m != nil {
+		return m.MemoryLimit
+	}
+	return 0
+}
+
+func (m *ResourceRequirements) GetDiskReservation() int64 {
+	if m != nil {
+		return m.DiskReservation
+	}
+	return 0
+}
+
+type NetworkPort struct {
+	Protocol             NetworkProtocol `protobuf:"varint,1,opt,name=protocol,proto3,enum=proto.NetworkProtocol" json:"protocol,omitempty"`
+	ContainerPort        int64           `protobuf:"varint,2,opt,name=containerPort,proto3" json:"containerPort,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *NetworkPort) Reset()         { *m = NetworkPort{} }
+func (m *NetworkPort) String() string { return proto.CompactTextString(m) }
+func (*NetworkPort) ProtoMessage()    {}
+func (*NetworkPort) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{14}
+}
+
+func (m *NetworkPort) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_NetworkPort.Unmarshal(m, b)
+}
+func (m *NetworkPort) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_NetworkPort.Marshal(b, m, deterministic)
+}
+func (m *NetworkPort) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_NetworkPort.Merge(m, src)
+}
+func (m *NetworkPort) XXX_Size() int {
+	return xxx_messageInfo_NetworkPort.Size(m)
+}
+func (m *NetworkPort) XXX_DiscardUnknown() {
+	xxx_messageInfo_NetworkPort.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_NetworkPort proto.InternalMessageInfo
+
+func (m *NetworkPort) GetProtocol() NetworkProtocol {
+	if m != nil {
+		return m.Protocol
+	}
+	return NetworkProtocol_TCP
+}
+
+func (m *NetworkPort) GetContainerPort() int64 {
+	if m != nil {
+		return m.ContainerPort
+	}
+	return 0
+}
+
+type EnvironmentVariable struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EnvironmentVariable) Reset()         { *m = EnvironmentVariable{} }
+func (m *EnvironmentVariable) String() string { return proto.CompactTextString(m) }
+func (*EnvironmentVariable) ProtoMessage()    {}
+func (*EnvironmentVariable) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{15}
+}
+
+func (m *EnvironmentVariable) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnvironmentVariable.Unmarshal(m, b)
+}
+func (m *EnvironmentVariable) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnvironmentVariable.Marshal(b, m, deterministic)
+}
+func (m *EnvironmentVariable) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnvironmentVariable.Merge(m, src)
+}
+func (m *EnvironmentVariable) XXX_Size() int {
+	return xxx_messageInfo_EnvironmentVariable.Size(m)
+}
+func (m *EnvironmentVariable) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnvironmentVariable.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EnvironmentVariable proto.InternalMessageInfo
+
+func (m *EnvironmentVariable) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *EnvironmentVariable) GetValue() string {
+	if m != nil {
+		return m.Value
+	}
+	return ""
+}
+
+type GameserverQuery struct {
+	Protocol             QueryProtocol `protobuf:"varint,1,opt,name=protocol,proto3,enum=proto.QueryProtocol" json:"protocol,omitempty"`
+	Port                 int64         `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Password             string        `protobuf:"bytes,3,opt,name=password,proto3" json:"password,omitempty"`
+	PasswordFile         string        `protobuf:"bytes,4,opt,name=passwordFile,proto3" json:"passwordFile,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *GameserverQuery) Reset()         { *m = GameserverQuery{} }
+func (m *GameserverQuery) String() string { return proto.CompactTextString(m) }
+func (*GameserverQuery) ProtoMessage()    {}
+func (*GameserverQuery) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{16}
+}
+
+func (m *GameserverQuery) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GameserverQuery.Unmarshal(m, b)
+}
+func (m *GameserverQuery) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GameserverQuery.Marshal(b, m, deterministic)
+}
+func (m *GameserverQuery) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GameserverQuery.Merge(m, src)
+}
+func (m *GameserverQuery) XXX_Size() int {
+	return xxx_messageInfo_GameserverQuery.Size(m)
+}
+func (m *GameserverQuery) XXX_DiscardUnknown() {
+	xxx_messageInfo_GameserverQuery.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GameserverQuery proto.InternalMessageInfo
+
+func (m *GameserverQuery) GetProtocol() QueryProtocol {
+	if m != nil {
+		return m.Protocol
+	}
+	return QueryProtocol_QUERY_NONE
+}
+
+func (m *GameserverQuery) GetPort() int64 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *GameserverQuery) GetPassword() string {
+	if m != nil {
+		return m.Password
+	}
+	return ""
+}
+
+func (m *GameserverQuery) GetPasswordFile() string {
+	if m != nil {
+		return m.PasswordFile
+	}
+	return ""
+}
+
+// HealthCheck describes how to probe a gameserver.
+// All durations are in seconds.
+type HealthCheck struct {
+	Type                 HealthCheckType `protobuf:"varint,1,opt,name=type,proto3,enum=proto.HealthCheckType" json:"type,omitempty"`
+	Port                 int64           `protobuf:"varint,2,opt,name=port,proto3" json:"port,omitempty"`
+	Payload              string          `protobuf:"bytes,3,opt,name=payload,proto3" json:"payload,omitempty"`
+	Command              []string        `protobuf:"bytes,4,rep,name=command,proto3" json:"command,omitempty"`
+	Interval             int64           `protobuf:"varint,5,opt,name=interval,proto3" json:"interval,omitempty"`
+	Timeout              int64           `protobuf:"varint,6,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	StartPeriod          int64           `protobuf:"varint,7,opt,name=startPeriod,proto3" json:"startPeriod,omitempty"`
+	Retries              int64           `protobuf:"varint,8,opt,name=retries,proto3" json:"retries,omitempty"`
+	RestartAfter         int64           `protobuf:"varint,9,opt,name=restartAfter,proto3" json:"restartAfter,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *HealthCheck) Reset()         { *m = HealthCheck{} }
+func (m *HealthCheck) String() string { return proto.CompactTextString(m) }
+func (*HealthCheck) ProtoMessage()    {}
+func (*HealthCheck) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{17}
+}
+
+func (m *HealthCheck) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_HealthCheck.Unmarshal(m, b)
+}
+func (m *HealthCheck) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_HealthCheck.Marshal(b, m, deterministic)
+}
+func (m *HealthCheck) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_HealthCheck.Merge(m, src)
+}
+func (m *HealthCheck) XXX_Size() int {
+	return xxx_messageInfo_HealthCheck.Size(m)
+}
+func (m *HealthCheck) XXX_DiscardUnknown() {
+	xxx_messageInfo_HealthCheck.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_HealthCheck proto.InternalMessageInfo
+
+func (m *HealthCheck) GetType() HealthCheckType {
+	if m != nil {
+		return m.Type
+	}
+	return HealthCheckType_HEALTHCHECK_NONE
+}
+
+func (m *HealthCheck) GetPort() int64 {
+	if m != nil {
+		return m.Port
+	}
+	return 0
+}
+
+func (m *HealthCheck) GetPayload() string {
+	if m != nil {
+		return m.Payload
+	}
+	return ""
+}
+
+func (m *HealthCheck) GetCommand() []string {
+	if m != nil {
+		return m.Command
+	}
+	return nil
+}
+
+func (m *HealthCheck) GetInterval() int64 {
+	if m != nil {
+		return m.Interval
+	}
+	return 0
+}
+
+func (m *HealthCheck) GetTimeout() int64 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+func (m *HealthCheck) GetStartPeriod() int64 {
+	if m != nil {
+		return m.StartPeriod
+	}
+	return 0
+}
+
+func (m *HealthCheck) GetRetries() int64 {
+	if m != nil {
+		return m.Retries
+	}
+	return 0
+}
+
+func (m *HealthCheck) GetRestartAfter() int64 {
+	if m != nil {
+		return m.RestartAfter
+	}
+	return 0
+}
+
+// RestartPolicy describes how the agent restarts an exited gameserver.
+// crashLoopWindow is in seconds.
+type RestartPolicy struct {
+	Type                 RestartPolicyType `protobuf:"varint,1,opt,name=type,proto3,enum=proto.RestartPolicyType" json:"type,omitempty"`
+	MaxRetries           int64             `protobuf:"varint,2,opt,name=maxRetries,proto3" json:"maxRetries,omitempty"`
+	CrashLoopFailures    int64             `protobuf:"varint,3,opt,name=crashLoopFailures,proto3" json:"crashLoopFailures,omitempty"`
+	CrashLoopWindow      int64             `protobuf:"varint,4,opt,name=crashLoopWindow,proto3" json:"crashLoopWindow,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
+}
+
+func (m *RestartPolicy) Reset()         { *m = RestartPolicy{} }
+func (m *RestartPolicy) String() string { return proto.CompactTextString(m) }
+func (*RestartPolicy) ProtoMessage()    {}
+func (*RestartPolicy) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{18}
+}
+
+func (m *RestartPolicy) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RestartPolicy.Unmarshal(m, b)
+}
+func (m *RestartPolicy) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RestartPolicy.Marshal(b, m, deterministic)
+}
+func (m *RestartPolicy) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RestartPolicy.Merge(m, src)
+}
+func (m *RestartPolicy) XXX_Size() int {
+	return xxx_messageInfo_RestartPolicy.Size(m)
+}
+func (m *RestartPolicy) XXX_DiscardUnknown() {
+	xxx_messageInfo_RestartPolicy.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RestartPolicy proto.InternalMessageInfo
+
+func (m *RestartPolicy) GetType() RestartPolicyType {
+	if m != nil {
+		return m.Type
+	}
+	return RestartPolicyType_RESTART_ON_FAILURE
+}
+
+func (m *RestartPolicy) GetMaxRetries() int64 {
+	if m != nil {
+		return m.MaxRetries
+	}
+	return 0
+}
+
+func (m *RestartPolicy) GetCrashLoopFailures() int64 {
+	if m != nil {
+		return m.CrashLoopFailures
+	}
+	return 0
+}
+
+func (m *RestartPolicy) GetCrashLoopWindow() int64 {
+	if m != nil {
+		return m.CrashLoopWindow
+	}
+	return 0
+}
+
+// ShutdownPolicy describes how the agent stops a gameserver gracefully.
+// The command is sent to the console. The signal is sent, when there's
+// no command or it cannot be sent. The gameserver is killed after
+// the timeout in seconds.
+type ShutdownPolicy struct {
+	Signal               string          `protobuf:"bytes,1,opt,name=signal,proto3" json:"signal,omitempty"`
+	Command              string          `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Channel              ShutdownChannel `protobuf:"varint,3,opt,name=channel,proto3,enum=proto.ShutdownChannel" json:"channel,omitempty"`
+	RconPort             int64           `protobuf:"varint,4,opt,name=rconPort,proto3" json:"rconPort,omitempty"`
+	RconPassword         string          `protobuf:"bytes,5,opt,name=rconPassword,proto3" json:"rconPassword,omitempty"`
+	RconPasswordFile     string          `protobuf:"bytes,6,opt,name=rconPasswordFile,proto3" json:"rconPasswordFile,omitempty"`
+	Timeout              int64           `protobuf:"varint,7,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ShutdownPolicy) Reset()         { *m = ShutdownPolicy{} }
+func (m *ShutdownPolicy) String() string { return proto.CompactTextString(m) }
+func (*ShutdownPolicy) ProtoMessage()    {}
+func (*ShutdownPolicy) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{19}
+}
+
+func (m *ShutdownPolicy) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShutdownPolicy.Unmarshal(m, b)
+}
+func (m *ShutdownPolicy) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShutdownPolicy.Marshal(b, m, deterministic)
+}
+func (m *ShutdownPolicy) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShutdownPolicy.Merge(m, src)
+}
+func (m *ShutdownPolicy) XXX_Size() int {
+	return xxx_messageInfo_ShutdownPolicy.Size(m)
+}
+func (m *ShutdownPolicy) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShutdownPolicy.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShutdownPolicy proto.InternalMessageInfo
+
+func (m *ShutdownPolicy) GetSignal() string {
+	if m != nil {
+		return m.Signal
+	}
+	return ""
+}
+
+func (m *ShutdownPolicy) GetCommand() string {
+	if m != nil {
+		return m.Command
+	}
+	return ""
+}
+
+func (m *ShutdownPolicy) GetChannel() ShutdownChannel {
+	if m != nil {
+		return m.Channel
+	}
+	return ShutdownChannel_SHUTDOWN_STDIN
+}
+
+func (m *ShutdownPolicy) GetRconPort() int64 {
+	if m != nil {
+		return m.RconPort
+	}
+	return 0
+}
+
+func (m *ShutdownPolicy) GetRconPassword() string {
+	if m != nil {
+		return m.RconPassword
+	}
+	return ""
+}
+
+func (m *ShutdownPolicy) GetRconPasswordFile() string {
+	if m != nil {
+		return m.RconPasswordFile
+	}
+	return ""
+}
+
+func (m *ShutdownPolicy) GetTimeout() int64 {
+	if m != nil {
+		return m.Timeout
+	}
+	return 0
+}
+
+// Volume is a directory in the gameserver holding its data,
+// which is moved together with the gameserver
+type Volume struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Path                 string   `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Volume) Reset()         { *m = Volume{} }
+func (m *Volume) String() string { return proto.CompactTextString(m) }
+func (*Volume) ProtoMessage()    {}
+func (*Volume) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{20}
+}
+
+func (m *Volume) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Volume.Unmarshal(m, b)
+}
+func (m *Volume) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Volume.Marshal(b, m, deterministic)
+}
+func (m *Volume) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Volume.Merge(m, src)
+}
+func (m *Volume) XXX_Size() int {
+	return xxx_messageInfo_Volume.Size(m)
+}
+func (m *Volume) XXX_DiscardUnknown() {
+	xxx_messageInfo_Volume.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Volume proto.InternalMessageInfo
+
+func (m *Volume) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *Volume) GetPath() string {
+	if m != nil {
+		return m.Path
+	}
+	return ""
+}
+
+type GameserverDeployment struct {
+	UUID                 string                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
+	Name                 string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Agent                string                 `protobuf:"bytes,3,opt,name=agent,proto3" json:"agent,omitempty"`
+	Image                string                 `protobuf:"bytes,4,opt,name=image,proto3" json:"image,omitempty"`
+	ResourceRequirements *ResourceRequirements  `protobuf:"bytes,5,opt,name=resourceRequirements,proto3" json:"resourceRequirements,omitempty"`
+	Ports                []*NetworkPort         `protobuf:"bytes,6,rep,name=ports,proto3" json:"ports,omitempty"`
+	Environment          []*EnvironmentVariable `protobuf:"bytes,7,rep,name=environment,proto3" json:"environment,omitempty"`
+	Query                *GameserverQuery       `protobuf:"bytes,8,opt,name=query,proto3" json:"query,omitempty"`
+	Stopped              bool                   `protobuf:"varint,9,opt,name=stopped,proto3" json:"stopped,omitempty"`
+	HealthCheck          *HealthCheck           `protobuf:"bytes,10,opt,name=healthCheck,proto3" json:"healthCheck,omitempty"`
+	RestartPolicy        *RestartPolicy         `protobuf:"bytes,11,opt,name=restartPolicy,proto3" json:"restartPolicy,omitempty"`
+	ImagePullPolicy      ImagePullPolicy        `protobuf:"varint,12,opt,name=imagePullPolicy,proto3,enum=proto.ImagePullPolicy" json:"imagePullPolicy,omitempty"`
+	ImageDigest          string                 `protobuf:"bytes,13,opt,name=imageDigest,proto3" json:"imageDigest,omitempty"`
+	Command              []string               `protobuf:"bytes,14,rep,name=command,proto3" json:"command,omitempty"`
+	Shutdown             *ShutdownPolicy        `protobuf:"bytes,15,opt,name=shutdown,proto3" json:"shutdown,omitempty"`
+	Volumes              []*Volume              `protobuf:"bytes,16,rep,name=volumes,proto3" json:"volumes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *GameserverDeployment) Reset()         { *m = GameserverDeployment{} }
+func (m *GameserverDeployment) String() string { return proto.CompactTextString(m) }
+func (*GameserverDeployment) ProtoMessage()    {}
+func (*GameserverDeployment) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{21}
+}
+
+func (m *GameserverDeployment) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GameserverDeployment.Unmarshal(m, b)
+}
+func (m *GameserverDeployment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GameserverDeployment.Marshal(b, m, deterministic)
+}
+func (m *GameserverDeployment) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GameserverDeployment.Merge(m, src)
+}
+func (m *GameserverDeployment) XXX_Size() int {
+	return xxx_messageInfo_GameserverDeployment.Size(m)
+}
+func (m *GameserverDeployment) XXX_DiscardUnknown() {
+	xxx_messageInfo_GameserverDeployment.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GameserverDeployment proto.InternalMessageInfo
+
+func (m *GameserverDeployment) GetUUID() string {
+	if m != nil {
+		return m.UUID
+	}
+	return ""
+}
+
+func (m *GameserverDeployment) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *GameserverDeployment) GetAgent() string {
+	if m != nil {
+		return m.Agent
+	}
+	return ""
+}
+
+func (m *GameserverDeployment) GetImage() string {
+	if m != nil {
+		return m.Image
+	}
+	return ""
 }
 
-func (m *Gameserver) Reset()         { *m = Gameserver{} }
-func (m *Gameserver) String() string { return proto.CompactTextString(m) }
-func (*Gameserver) ProtoMessage()    {}
-func (*Gameserver) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{5}
+func (m *GameserverDeployment) GetResourceRequirements() *ResourceRequirements {
+	if m != nil {
+		return m.ResourceRequirements
+	}
+	return nil
 }
 
-func (m *Gameserver) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Gameserver.Unmarshal(m, b)
+func (m *GameserverDeployment) GetPorts() []*NetworkPort {
+	if m != nil {
+		return m.Ports
+	}
+	return nil
 }
-func (m *Gameserver) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Gameserver.Marshal(b, m, deterministic)
+
+func (m *GameserverDeployment) GetEnvironment() []*EnvironmentVariable {
+	if m != nil {
+		return m.Environment
+	}
+	return nil
 }
-func (m *Gameserver) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Gameserver.Merge(m, src)
+
+func (m *GameserverDeployment) GetQuery() *GameserverQuery {
+	if m != nil {
+		return m.Query
+	}
+	return nil
 }
-func (m *Gameserver) XXX_Size() int {
-	return xxx_messageInfo_Gameserver.Size(m)
+
+func (m *GameserverDeployment) GetStopped() bool {
+	if m != nil {
+		return m.Stopped
+	}
+	return false
 }
-func (m *Gameserver) XXX_DiscardUnknown() {
-	xxx_messageInfo_Gameserver.DiscardUnknown(m)
+
+func (m *GameserverDeployment) GetHealthCheck() *HealthCheck {
+	if m != nil {
+		return m.HealthCheck
+	}
+	return nil
 }
 
-var xxx_messageInfo_Gameserver proto.InternalMessageInfo
+func (m *GameserverDeployment) GetRestartPolicy() *RestartPolicy {
+	if m != nil {
+		return m.RestartPolicy
+	}
+	return nil
+}
 
-func (m *Gameserver) GetUUID() string {
+func (m *GameserverDeployment) GetImagePullPolicy() ImagePullPolicy {
 	if m != nil {
-		return m.UUID
+		return m.ImagePullPolicy
+	}
+	return ImagePullPolicy_PULL_ALWAYS
+}
+
+func (m *GameserverDeployment) GetImageDigest() string {
+	if m != nil {
+		return m.ImageDigest
 	}
 	return ""
 }
 
-func (m *Gameserver) GetStatus() GameserverStatus {
+func (m *GameserverDeployment) GetCommand() []string {
 	if m != nil {
-		return m.Status
+		return m.Command
 	}
-	return GameserverStatus_RUNNING
+	return nil
 }
 
-func (m *Gameserver) GetInfo() string {
+func (m *GameserverDeployment) GetShutdown() *ShutdownPolicy {
 	if m != nil {
-		return m.Info
+		return m.Shutdown
 	}
-	return ""
+	return nil
 }
 
-func (m *Gameserver) GetEndpoint() *Endpoint {
+func (m *GameserverDeployment) GetVolumes() []*Volume {
 	if m != nil {
-		return m.Endpoint
+		return m.Volumes
 	}
 	return nil
 }
 
-type GetGameserverDeploymentsRequest struct {
-	Hostname             string   `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+// MigrationTask asks the source agent to export the volumes of a stopped
+// gameserver to the server, or the target agent to import them
+type MigrationTask struct {
+	OperationId          string                `protobuf:"bytes,1,opt,name=operationId,proto3" json:"operationId,omitempty"`
+	Type                 MigrationTaskType     `protobuf:"varint,2,opt,name=type,proto3,enum=proto.MigrationTaskType" json:"type,omitempty"`
+	Deployment           *GameserverDeployment `protobuf:"bytes,3,opt,name=deployment,proto3" json:"deployment,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
 }
 
-func (m *GetGameserverDeploymentsRequest) Reset()         { *m = GetGameserverDeploymentsRequest{} }
-func (m *GetGameserverDeploymentsRequest) String() string { return proto.CompactTextString(m) }
-func (*GetGameserverDeploymentsRequest) ProtoMessage()    {}
-func (*GetGameserverDeploymentsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{6}
+func (m *MigrationTask) Reset()         { *m = MigrationTask{} }
+func (m *MigrationTask) String() string { return proto.CompactTextString(m) }
+func (*MigrationTask) ProtoMessage()    {}
+func (*MigrationTask) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{22}
 }
 
-func (m *GetGameserverDeploymentsRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetGameserverDeploymentsRequest.Unmarshal(m, b)
+func (m *MigrationTask) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MigrationTask.Unmarshal(m, b)
 }
-func (m *GetGameserverDeploymentsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetGameserverDeploymentsRequest.Marshal(b, m, deterministic)
+func (m *MigrationTask) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MigrationTask.Marshal(b, m, deterministic)
 }
-func (m *GetGameserverDeploymentsRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetGameserverDeploymentsRequest.Merge(m, src)
+func (m *MigrationTask) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MigrationTask.Merge(m, src)
 }
-func (m *GetGameserverDeploymentsRequest) XXX_Size() int {
-	return xxx_messageInfo_GetGameserverDeploymentsRequest.Size(m)
+func (m *MigrationTask) XXX_Size() int {
+	return xxx_messageInfo_MigrationTask.Size(m)
 }
-func (m *GetGameserverDeploymentsRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetGameserverDeploymentsRequest.DiscardUnknown(m)
+func (m *MigrationTask) XXX_DiscardUnknown() {
+	xxx_messageInfo_MigrationTask.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetGameserverDeploymentsRequest proto.InternalMessageInfo
+var xxx_messageInfo_MigrationTask proto.InternalMessageInfo
 
-func (m *GetGameserverDeploymentsRequest) GetHostname() string {
+func (m *MigrationTask) GetOperationId() string {
 	if m != nil {
-		return m.Hostname
+		return m.OperationId
 	}
 	return ""
 }
 
-type ResourceRequirements struct {
-	CpuReservation       int64    `protobuf:"varint,1,opt,name=cpuReservation,proto3" json:"cpuReservation,omitempty"`
-	CpuLimit             int64    `protobuf:"varint,2,opt,name=cpuLimit,proto3" json:"cpuLimit,omitempty"`
-	MemoryReservation    int64    `protobuf:"varint,3,opt,name=memoryReservation,proto3" json:"memoryReservation,omitempty"`
-	MemoryLimit          int64    `protobuf:"varint,4,opt,name=memoryLimit,proto3" json:"memoryLimit,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+func (m *MigrationTask) GetType() MigrationTaskType {
+	if m != nil {
+		return m.Type
+	}
+	return MigrationTaskType_MIGRATION_EXPORT
 }
 
-func (m *ResourceRequirements) Reset()         { *m = ResourceRequirements{} }
-func (m *ResourceRequirements) String() string { return proto.CompactTextString(m) }
-func (*ResourceRequirements) ProtoMessage()    {}
-func (*ResourceRequirements) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{7}
+func (m *MigrationTask) GetDeployment() *GameserverDeployment {
+	if m != nil {
+		return m.Deployment
+	}
+	return nil
 }
 
-func (m *ResourceRequirements) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_ResourceRequirements.Unmarshal(m, b)
+type MigrationTaskResult struct {
+	OperationId          string            `protobuf:"bytes,1,opt,name=operationId,proto3" json:"operationId,omitempty"`
+	Type                 MigrationTaskType `protobuf:"varint,2,opt,name=type,proto3,enum=proto.MigrationTaskType" json:"type,omitempty"`
+	Success              bool              `protobuf:"varint,3,opt,name=success,proto3" json:"success,omitempty"`
+	Error                string            `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
-func (m *ResourceRequirements) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_ResourceRequirements.Marshal(b, m, deterministic)
+
+func (m *MigrationTaskResult) Reset()         { *m = MigrationTaskResult{} }
+func (m *MigrationTaskResult) String() string { return proto.CompactTextString(m) }
+func (*MigrationTaskResult) ProtoMessage()    {}
+func (*MigrationTaskResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{23}
 }
-func (m *ResourceRequirements) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_ResourceRequirements.Merge(m, src)
+
+func (m *MigrationTaskResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_MigrationTaskResult.Unmarshal(m, b)
 }
-func (m *ResourceRequirements) XXX_Size() int {
-	return xxx_messageInfo_ResourceRequirements.Size(m)
+func (m *MigrationTaskResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_MigrationTaskResult.Marshal(b, m, deterministic)
 }
-func (m *ResourceRequirements) XXX_DiscardUnknown() {
-	xxx_messageInfo_ResourceRequirements.DiscardUnknown(m)
+func (m *MigrationTaskResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MigrationTaskResult.Merge(m, src)
+}
+func (m *MigrationTaskResult) XXX_Size() int {
+	return xxx_messageInfo_MigrationTaskResult.Size(m)
+}
+func (m *MigrationTaskResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_MigrationTaskResult.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_ResourceRequirements proto.InternalMessageInfo
+var xxx_messageInfo_MigrationTaskResult proto.InternalMessageInfo
 
-func (m *ResourceRequirements) GetCpuReservation() int64 {
+func (m *MigrationTaskResult) GetOperationId() string {
 	if m != nil {
-		return m.CpuReservation
+		return m.OperationId
 	}
-	return 0
+	return ""
 }
 
-func (m *ResourceRequirements) GetCpuLimit() int64 {
+func (m *MigrationTaskResult) GetType() MigrationTaskType {
 	if m != nil {
-		return m.CpuLimit
+		return m.Type
 	}
-	return 0
+	return MigrationTaskType_MIGRATION_EXPORT
 }
 
-func (m *ResourceRequirements) GetMemoryReservation() int64 {
+func (m *MigrationTaskResult) GetSuccess() bool {
 	if m != nil {
-		return m.MemoryReservation
+		return m.Success
 	}
-	return 0
+	return false
 }
 
-func (m *ResourceRequirements) GetMemoryLimit() int64 {
+func (m *MigrationTaskResult) GetError() string {
 	if m != nil {
-		return m.MemoryLimit
+		return m.Error
 	}
-	return 0
+	return ""
 }
 
-type NetworkPort struct {
-	Protocol             NetworkProtocol `protobuf:"varint,1,opt,name=protocol,proto3,enum=proto.NetworkProtocol" json:"protocol,omitempty"`
-	ContainerPort        int64           `protobuf:"varint,2,opt,name=containerPort,proto3" json:"containerPort,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+// VolumeChunk is a part of a volume tar archive
+type VolumeChunk struct {
+	OperationId          string   `protobuf:"bytes,1,opt,name=operationId,proto3" json:"operationId,omitempty"`
+	Volume               string   `protobuf:"bytes,2,opt,name=volume,proto3" json:"volume,omitempty"`
+	Data                 []byte   `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *NetworkPort) Reset()         { *m = NetworkPort{} }
-func (m *NetworkPort) String() string { return proto.CompactTextString(m) }
-func (*NetworkPort) ProtoMessage()    {}
-func (*NetworkPort) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{8}
+func (m *VolumeChunk) Reset()         { *m = VolumeChunk{} }
+func (m *VolumeChunk) String() string { return proto.CompactTextString(m) }
+func (*VolumeChunk) ProtoMessage()    {}
+func (*VolumeChunk) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{24}
 }
 
-func (m *NetworkPort) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_NetworkPort.Unmarshal(m, b)
+func (m *VolumeChunk) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VolumeChunk.Unmarshal(m, b)
 }
-func (m *NetworkPort) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_NetworkPort.Marshal(b, m, deterministic)
+func (m *VolumeChunk) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VolumeChunk.Marshal(b, m, deterministic)
 }
-func (m *NetworkPort) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_NetworkPort.Merge(m, src)
+func (m *VolumeChunk) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VolumeChunk.Merge(m, src)
 }
-func (m *NetworkPort) XXX_Size() int {
-	return xxx_messageInfo_NetworkPort.Size(m)
+func (m *VolumeChunk) XXX_Size() int {
+	return xxx_messageInfo_VolumeChunk.Size(m)
 }
-func (m *NetworkPort) XXX_DiscardUnknown() {
-	xxx_messageInfo_NetworkPort.DiscardUnknown(m)
+func (m *VolumeChunk) XXX_DiscardUnknown() {
+	xxx_messageInfo_VolumeChunk.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_NetworkPort proto.InternalMessageInfo
+var xxx_messageInfo_VolumeChunk proto.InternalMessageInfo
 
-func (m *NetworkPort) GetProtocol() NetworkProtocol {
+func (m *VolumeChunk) GetOperationId() string {
 	if m != nil {
-		return m.Protocol
+		return m.OperationId
 	}
-	return NetworkProtocol_TCP
+	return ""
 }
 
-func (m *NetworkPort) GetContainerPort() int64 {
+func (m *VolumeChunk) GetVolume() string {
 	if m != nil {
-		return m.ContainerPort
+		return m.Volume
 	}
-	return 0
+	return ""
 }
 
-type EnvironmentVariable struct {
-	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Value                string   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+func (m *VolumeChunk) GetData() []byte {
+	if m != nil {
+		return m.Data
+	}
+	return nil
+}
+
+type VolumeRequest struct {
+	OperationId          string   `protobuf:"bytes,1,opt,name=operationId,proto3" json:"operationId,omitempty"`
+	Volume               string   `protobuf:"bytes,2,opt,name=volume,proto3" json:"volume,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *EnvironmentVariable) Reset()         { *m = EnvironmentVariable{} }
-func (m *EnvironmentVariable) String() string { return proto.CompactTextString(m) }
-func (*EnvironmentVariable) ProtoMessage()    {}
-func (*EnvironmentVariable) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{9}
+func (m *VolumeRequest) Reset()         { *m = VolumeRequest{} }
+func (m *VolumeRequest) String() string { return proto.CompactTextString(m) }
+func (*VolumeRequest) ProtoMessage()    {}
+func (*VolumeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{25}
 }
 
-func (m *EnvironmentVariable) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_EnvironmentVariable.Unmarshal(m, b)
+func (m *VolumeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_VolumeRequest.Unmarshal(m, b)
 }
-func (m *EnvironmentVariable) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_EnvironmentVariable.Marshal(b, m, deterministic)
+func (m *VolumeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_VolumeRequest.Marshal(b, m, deterministic)
 }
-func (m *EnvironmentVariable) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_EnvironmentVariable.Merge(m, src)
+func (m *VolumeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_VolumeRequest.Merge(m, src)
 }
-func (m *EnvironmentVariable) XXX_Size() int {
-	return xxx_messageInfo_EnvironmentVariable.Size(m)
+func (m *VolumeRequest) XXX_Size() int {
+	return xxx_messageInfo_VolumeRequest.Size(m)
 }
-func (m *EnvironmentVariable) XXX_DiscardUnknown() {
-	xxx_messageInfo_EnvironmentVariable.DiscardUnknown(m)
+func (m *VolumeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_VolumeRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_EnvironmentVariable proto.InternalMessageInfo
+var xxx_messageInfo_VolumeRequest proto.InternalMessageInfo
 
-func (m *EnvironmentVariable) GetName() string {
+func (m *VolumeRequest) GetOperationId() string {
 	if m != nil {
-		return m.Name
+		return m.OperationId
+	}
+	return ""
+}
+
+func (m *VolumeRequest) GetVolume() string {
+	if m != nil {
+		return m.Volume
 	}
 	return ""
 }
 
-func (m *EnvironmentVariable) GetValue() string {
+type GetGameserverDeploymentsResponse struct {
+	Deployments          []*GameserverDeployment `protobuf:"bytes,1,rep,name=deployments,proto3" json:"deployments,omitempty"`
+	MigrationTasks       []*MigrationTask        `protobuf:"bytes,2,rep,name=migrationTasks,proto3" json:"migrationTasks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
+	XXX_unrecognized     []byte                  `json:"-"`
+	XXX_sizecache        int32                   `json:"-"`
+}
+
+func (m *GetGameserverDeploymentsResponse) Reset()         { *m = GetGameserverDeploymentsResponse{} }
+func (m *GetGameserverDeploymentsResponse) String() string { return proto.CompactTextString(m) }
+func (*GetGameserverDeploymentsResponse) ProtoMessage()    {}
+func (*GetGameserverDeploymentsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{26}
+}
+
+func (m *GetGameserverDeploymentsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetGameserverDeploymentsResponse.Unmarshal(m, b)
+}
+func (m *GetGameserverDeploymentsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetGameserverDeploymentsResponse.Marshal(b, m, deterministic)
+}
+func (m *GetGameserverDeploymentsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetGameserverDeploymentsResponse.Merge(m, src)
+}
+func (m *GetGameserverDeploymentsResponse) XXX_Size() int {
+	return xxx_messageInfo_GetGameserverDeploymentsResponse.Size(m)
+}
+func (m *GetGameserverDeploymentsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetGameserverDeploymentsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetGameserverDeploymentsResponse proto.InternalMessageInfo
+
+func (m *GetGameserverDeploymentsResponse) GetDeployments() []*GameserverDeployment {
+	if m != nil {
+		return m.Deployments
+	}
+	return nil
+}
+
+func (m *GetGameserverDeploymentsResponse) GetMigrationTasks() []*MigrationTask {
+	if m != nil {
+		return m.MigrationTasks
+	}
+	return nil
+}
+
+// DrainRequest is sent by an agent, which is shutting down
+// and wants its gameservers moved to other agents
+type DrainRequest struct {
+	Hostname             string   `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DrainRequest) Reset()         { *m = DrainRequest{} }
+func (m *DrainRequest) String() string { return proto.CompactTextString(m) }
+func (*DrainRequest) ProtoMessage()    {}
+func (*DrainRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{27}
+}
+
+func (m *DrainRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DrainRequest.Unmarshal(m, b)
+}
+func (m *DrainRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DrainRequest.Marshal(b, m, deterministic)
+}
+func (m *DrainRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DrainRequest.Merge(m, src)
+}
+func (m *DrainRequest) XXX_Size() int {
+	return xxx_messageInfo_DrainRequest.Size(m)
+}
+func (m *DrainRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DrainRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DrainRequest proto.InternalMessageInfo
+
+func (m *DrainRequest) GetHostname() string {
 	if m != nil {
-		return m.Value
+		return m.Hostname
 	}
 	return ""
 }
 
-type GameserverDeployment struct {
-	UUID                 string                 `protobuf:"bytes,1,opt,name=UUID,proto3" json:"UUID,omitempty"`
-	Name                 string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Agent                string                 `protobuf:"bytes,3,opt,name=agent,proto3" json:"agent,omitempty"`
-	Image                string                 `protobuf:"bytes,4,opt,name=image,proto3" json:"image,omitempty"`
-	ResourceRequirements *ResourceRequirements  `protobuf:"bytes,5,opt,name=resourceRequirements,proto3" json:"resourceRequirements,omitempty"`
-	Ports                []*NetworkPort         `protobuf:"bytes,6,rep,name=ports,proto3" json:"ports,omitempty"`
-	Environment          []*EnvironmentVariable `protobuf:"bytes,7,rep,name=environment,proto3" json:"environment,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
-	XXX_unrecognized     []byte                 `json:"-"`
-	XXX_sizecache        int32                  `json:"-"`
+// EnrollRequest is sent by a new agent, which has no client certificate
+// yet. The token is a one-time bootstrap token and csr is a DER encoded
+// certificate signing request
+type EnrollRequest struct {
+	Hostname             string   `protobuf:"bytes,1,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Token                string   `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Csr                  []byte   `protobuf:"bytes,3,opt,name=csr,proto3" json:"csr,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GameserverDeployment) Reset()         { *m = GameserverDeployment{} }
-func (m *GameserverDeployment) String() string { return proto.CompactTextString(m) }
-func (*GameserverDeployment) ProtoMessage()    {}
-func (*GameserverDeployment) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{10}
+func (m *EnrollRequest) Reset()         { *m = EnrollRequest{} }
+func (m *EnrollRequest) String() string { return proto.CompactTextString(m) }
+func (*EnrollRequest) ProtoMessage()    {}
+func (*EnrollRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{28}
 }
 
-func (m *GameserverDeployment) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GameserverDeployment.Unmarshal(m, b)
+func (m *EnrollRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EnrollRequest.Unmarshal(m, b)
 }
-func (m *GameserverDeployment) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GameserverDeployment.Marshal(b, m, deterministic)
+func (m *EnrollRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EnrollRequest.Marshal(b, m, deterministic)
 }
-func (m *GameserverDeployment) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GameserverDeployment.Merge(m, src)
+func (m *EnrollRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EnrollRequest.Merge(m, src)
 }
-func (m *GameserverDeployment) XXX_Size() int {
-	return xxx_messageInfo_GameserverDeployment.Size(m)
+func (m *EnrollRequest) XXX_Size() int {
+	return xxx_messageInfo_EnrollRequest.Size(m)
 }
-func (m *GameserverDeployment) XXX_DiscardUnknown() {
-	xxx_messageInfo_GameserverDeployment.DiscardUnknown(m)
+func (m *EnrollRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_EnrollRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GameserverDeployment proto.InternalMessageInfo
+var xxx_messageInfo_EnrollRequest proto.InternalMessageInfo
 
-func (m *GameserverDeployment) GetUUID() string {
+func (m *EnrollRequest) GetHostname() string {
 	if m != nil {
-		return m.UUID
+		return m.Hostname
 	}
 	return ""
 }
 
-func (m *GameserverDeployment) GetName() string {
+func (m *EnrollRequest) GetToken() string {
 	if m != nil {
-		return m.Name
+		return m.Token
 	}
 	return ""
 }
 
-func (m *GameserverDeployment) GetAgent() string {
+func (m *EnrollRequest) GetCsr() []byte {
 	if m != nil {
-		return m.Agent
+		return m.Csr
 	}
-	return ""
+	return nil
 }
 
-func (m *GameserverDeployment) GetImage() string {
-	if m != nil {
-		return m.Image
-	}
-	return ""
+// RenewCertificateRequest is sent by an enrolled agent to rotate
+// its client certificate before it expires
+type RenewCertificateRequest struct {
+	Csr                  []byte   `protobuf:"bytes,1,opt,name=csr,proto3" json:"csr,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GameserverDeployment) GetResourceRequirements() *ResourceRequirements {
-	if m != nil {
-		return m.ResourceRequirements
-	}
-	return nil
+func (m *RenewCertificateRequest) Reset()         { *m = RenewCertificateRequest{} }
+func (m *RenewCertificateRequest) String() string { return proto.CompactTextString(m) }
+func (*RenewCertificateRequest) ProtoMessage()    {}
+func (*RenewCertificateRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{29}
 }
 
-func (m *GameserverDeployment) GetPorts() []*NetworkPort {
-	if m != nil {
-		return m.Ports
-	}
-	return nil
+func (m *RenewCertificateRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RenewCertificateRequest.Unmarshal(m, b)
+}
+func (m *RenewCertificateRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RenewCertificateRequest.Marshal(b, m, deterministic)
+}
+func (m *RenewCertificateRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RenewCertificateRequest.Merge(m, src)
+}
+func (m *RenewCertificateRequest) XXX_Size() int {
+	return xxx_messageInfo_RenewCertificateRequest.Size(m)
+}
+func (m *RenewCertificateRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RenewCertificateRequest.DiscardUnknown(m)
 }
 
-func (m *GameserverDeployment) GetEnvironment() []*EnvironmentVariable {
+var xxx_messageInfo_RenewCertificateRequest proto.InternalMessageInfo
+
+func (m *RenewCertificateRequest) GetCsr() []byte {
 	if m != nil {
-		return m.Environment
+		return m.Csr
 	}
 	return nil
 }
 
-type GetGameserverDeploymentsResponse struct {
-	Deployments          []*GameserverDeployment `protobuf:"bytes,1,rep,name=deployments,proto3" json:"deployments,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                `json:"-"`
-	XXX_unrecognized     []byte                  `json:"-"`
-	XXX_sizecache        int32                   `json:"-"`
+// CertificateResponse contains the PEM encoded agent certificate
+// and the certificate of the server CA
+type CertificateResponse struct {
+	Certificate          []byte   `protobuf:"bytes,1,opt,name=certificate,proto3" json:"certificate,omitempty"`
+	CaCertificate        []byte   `protobuf:"bytes,2,opt,name=caCertificate,proto3" json:"caCertificate,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *GetGameserverDeploymentsResponse) Reset()         { *m = GetGameserverDeploymentsResponse{} }
-func (m *GetGameserverDeploymentsResponse) String() string { return proto.CompactTextString(m) }
-func (*GetGameserverDeploymentsResponse) ProtoMessage()    {}
-func (*GetGameserverDeploymentsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_dd830a99d5efef4e, []int{11}
+func (m *CertificateResponse) Reset()         { *m = CertificateResponse{} }
+func (m *CertificateResponse) String() string { return proto.CompactTextString(m) }
+func (*CertificateResponse) ProtoMessage()    {}
+func (*CertificateResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_dd830a99d5efef4e, []int{30}
 }
 
-func (m *GetGameserverDeploymentsResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_GetGameserverDeploymentsResponse.Unmarshal(m, b)
+func (m *CertificateResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CertificateResponse.Unmarshal(m, b)
 }
-func (m *GetGameserverDeploymentsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_GetGameserverDeploymentsResponse.Marshal(b, m, deterministic)
+func (m *CertificateResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CertificateResponse.Marshal(b, m, deterministic)
 }
-func (m *GetGameserverDeploymentsResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_GetGameserverDeploymentsResponse.Merge(m, src)
+func (m *CertificateResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CertificateResponse.Merge(m, src)
 }
-func (m *GetGameserverDeploymentsResponse) XXX_Size() int {
-	return xxx_messageInfo_GetGameserverDeploymentsResponse.Size(m)
+func (m *CertificateResponse) XXX_Size() int {
+	return xxx_messageInfo_CertificateResponse.Size(m)
 }
-func (m *GetGameserverDeploymentsResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_GetGameserverDeploymentsResponse.DiscardUnknown(m)
+func (m *CertificateResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CertificateResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_GetGameserverDeploymentsResponse proto.InternalMessageInfo
+var xxx_messageInfo_CertificateResponse proto.InternalMessageInfo
 
-func (m *GetGameserverDeploymentsResponse) GetDeployments() []*GameserverDeployment {
+func (m *CertificateResponse) GetCertificate() []byte {
 	if m != nil {
-		return m.Deployments
+		return m.Certificate
+	}
+	return nil
+}
+
+func (m *CertificateResponse) GetCaCertificate() []byte {
+	if m != nil {
+		return m.CaCertificate
 	}
 	return nil
 }
 
 func init() {
+	proto.RegisterEnum("proto.GameserverAction", GameserverAction_name, GameserverAction_value)
 	proto.RegisterEnum("proto.GameserverStatus", GameserverStatus_name, GameserverStatus_value)
+	proto.RegisterEnum("proto.HealthStatus", HealthStatus_name, HealthStatus_value)
 	proto.RegisterEnum("proto.NetworkProtocol", NetworkProtocol_name, NetworkProtocol_value)
+	proto.RegisterEnum("proto.QueryProtocol", QueryProtocol_name, QueryProtocol_value)
+	proto.RegisterEnum("proto.HealthCheckType", HealthCheckType_name, HealthCheckType_value)
+	proto.RegisterEnum("proto.RestartPolicyType", RestartPolicyType_name, RestartPolicyType_value)
+	proto.RegisterEnum("proto.ShutdownChannel", ShutdownChannel_name, ShutdownChannel_value)
+	proto.RegisterEnum("proto.ImagePullPolicy", ImagePullPolicy_name, ImagePullPolicy_value)
+	proto.RegisterEnum("proto.MigrationTaskType", MigrationTaskType_name, MigrationTaskType_value)
 	proto.RegisterType((*Empty)(nil), "proto.Empty")
 	proto.RegisterType((*AgentResources)(nil), "proto.AgentResources")
+	proto.RegisterType((*NetworkInterface)(nil), "proto.NetworkInterface")
 	proto.RegisterType((*AgentResourceUsage)(nil), "proto.AgentResourceUsage")
+	proto.RegisterType((*AgentCondition)(nil), "proto.AgentCondition")
+	proto.RegisterType((*GameserverActionResult)(nil), "proto.GameserverActionResult")
+	proto.RegisterType((*ShutdownResult)(nil), "proto.ShutdownResult")
 	proto.RegisterType((*AgentState)(nil), "proto.AgentState")
+	proto.RegisterMapType((map[string]string)(nil), "proto.AgentState.LabelsEntry")
 	proto.RegisterType((*Endpoint)(nil), "proto.Endpoint")
+	proto.RegisterType((*GameserverResourceUsage)(nil), "proto.GameserverResourceUsage")
+	proto.RegisterType((*GameserverQueryResult)(nil), "proto.GameserverQueryResult")
 	proto.RegisterType((*Gameserver)(nil), "proto.Gameserver")
 	proto.RegisterType((*GetGameserverDeploymentsRequest)(nil), "proto.GetGameserverDeploymentsRequest")
 	proto.RegisterType((*ResourceRequirements)(nil), "proto.ResourceRequirements")
 	proto.RegisterType((*NetworkPort)(nil), "proto.NetworkPort")
 	proto.RegisterType((*EnvironmentVariable)(nil), "proto.EnvironmentVariable")
+	proto.RegisterType((*GameserverQuery)(nil), "proto.GameserverQuery")
+	proto.RegisterType((*HealthCheck)(nil), "proto.HealthCheck")
+	proto.RegisterType((*RestartPolicy)(nil), "proto.RestartPolicy")
+	proto.RegisterType((*ShutdownPolicy)(nil), "proto.ShutdownPolicy")
+	proto.RegisterType((*Volume)(nil), "proto.Volume")
 	proto.RegisterType((*GameserverDeployment)(nil), "proto.GameserverDeployment")
+	proto.RegisterType((*MigrationTask)(nil), "proto.MigrationTask")
+	proto.RegisterType((*MigrationTaskResult)(nil), "proto.MigrationTaskResult")
+	proto.RegisterType((*VolumeChunk)(nil), "proto.VolumeChunk")
+	proto.RegisterType((*VolumeRequest)(nil), "proto.VolumeRequest")
 	proto.RegisterType((*GetGameserverDeploymentsResponse)(nil), "proto.GetGameserverDeploymentsResponse")
+	proto.RegisterType((*DrainRequest)(nil), "proto.DrainRequest")
+	proto.RegisterType((*EnrollRequest)(nil), "proto.EnrollRequest")
+	proto.RegisterType((*RenewCertificateRequest)(nil), "proto.RenewCertificateRequest")
+	proto.RegisterType((*CertificateResponse)(nil), "proto.CertificateResponse")
 }
 
 func init() { proto.RegisterFile("proto/agent.proto", fileDescriptor_dd830a99d5efef4e) }
 
 var fileDescriptor_dd830a99d5efef4e = []byte{
-	// 717 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0xdb, 0x6e, 0xdb, 0x38,
-	0x10, 0x8d, 0x7c, 0xf7, 0x28, 0x71, 0xec, 0x59, 0x6f, 0x56, 0xeb, 0x2c, 0xb0, 0x86, 0x76, 0x91,
-	0x1a, 0x69, 0x90, 0x00, 0xce, 0x43, 0x5f, 0x1a, 0x04, 0x41, 0x6d, 0x04, 0x01, 0x0a, 0xc7, 0x60,
-	0xea, 0x3e, 0x16, 0x50, 0x6c, 0xd6, 0x15, 0x62, 0x91, 0x2a, 0x49, 0xb9, 0xc8, 0x6f, 0xf4, 0x17,
-	0xfa, 0x52, 0xf4, 0xaf, 0xfa, 0x27, 0x05, 0x29, 0xd9, 0x92, 0x2f, 0x49, 0x9f, 0xc4, 0x19, 0x1e,
-	0x1e, 0x0d, 0xcf, 0x9c, 0x21, 0x34, 0x42, 0xc1, 0x15, 0x3f, 0xf3, 0xa6, 0x94, 0xa9, 0x53, 0xb3,
-	0xc6, 0xa2, 0xf9, 0xb8, 0x65, 0x28, 0xf6, 0x83, 0x50, 0x3d, 0xba, 0x1f, 0xa0, 0x76, 0xa5, 0xb7,
-	0x09, 0x95, 0x3c, 0x12, 0x63, 0x2a, 0x11, 0xa1, 0x30, 0x0e, 0x23, 0xe9, 0x58, 0x6d, 0xab, 0x93,
-	0x27, 0x66, 0x8d, 0x07, 0x50, 0x0a, 0x68, 0xc0, 0xc5, 0xa3, 0x93, 0x33, 0xd9, 0x24, 0xc2, 0x36,
-	0xd8, 0x7e, 0x78, 0x35, 0x99, 0x08, 0x2a, 0x25, 0x95, 0x4e, 0xde, 0x6c, 0x66, 0x53, 0xee, 0x09,
-	0xe0, 0x0a, 0xff, 0x48, 0x7a, 0x53, 0xfa, 0x14, 0x9f, 0xfb, 0xd3, 0x02, 0x30, 0xf0, 0x3b, 0xe5,
-	0x29, 0x8a, 0x2d, 0xa8, 0x7c, 0xe2, 0x52, 0x31, 0x2f, 0xa0, 0xa6, 0x9c, 0x2a, 0x59, 0xc6, 0x78,
-	0x0e, 0x55, 0xb1, 0xa8, 0xd9, 0xb0, 0xd8, 0xdd, 0x3f, 0xe3, 0x3b, 0x9e, 0xae, 0x5e, 0x88, 0xa4,
-	0x38, 0xbc, 0x84, 0x3d, 0x91, 0x2d, 0xc4, 0x54, 0x6c, 0x77, 0xff, 0xde, 0x76, 0xd0, 0x00, 0xc8,
-	0x2a, 0x1e, 0xaf, 0x00, 0x45, 0xc4, 0x98, 0xcf, 0xa6, 0xd7, 0x5e, 0x40, 0x25, 0x15, 0x73, 0x2a,
-	0xa4, 0x53, 0x68, 0xe7, 0x3b, 0x76, 0xb7, 0x91, 0xb0, 0xa4, 0x3b, 0x64, 0x0b, 0xd8, 0xed, 0x40,
-	0xa5, 0xcf, 0x26, 0x21, 0xf7, 0x99, 0xc2, 0x7f, 0xa0, 0xba, 0x14, 0x2b, 0xb9, 0x61, 0x9a, 0x70,
-	0xbf, 0x5a, 0x00, 0xe9, 0x49, 0xdd, 0x98, 0xd1, 0xe8, 0xa6, 0x97, 0xe0, 0xcc, 0x1a, 0xcf, 0xa0,
-	0x24, 0x95, 0xa7, 0xa2, 0x58, 0x82, 0x5a, 0xf7, 0xaf, 0x8d, 0x1a, 0xee, 0xcc, 0x36, 0x49, 0x60,
-	0x9a, 0xc4, 0x67, 0x1f, 0xb9, 0xb9, 0x78, 0x95, 0x98, 0x35, 0xbe, 0x84, 0x0a, 0x4d, 0x2a, 0x72,
-	0x0a, 0x46, 0x90, 0xfd, 0x84, 0x66, 0x51, 0x28, 0x59, 0x02, 0xdc, 0x0b, 0xf8, 0xf7, 0x9a, 0xaa,
-	0x94, 0xbf, 0x47, 0xc3, 0x19, 0x7f, 0x0c, 0x28, 0x53, 0x92, 0xd0, 0xcf, 0x11, 0x95, 0xea, 0xb9,
-	0xb6, 0xb9, 0x3f, 0x2c, 0x68, 0x2e, 0x14, 0xd6, 0x78, 0x5f, 0x50, 0x73, 0x16, 0x8f, 0xa0, 0x36,
-	0x0e, 0x23, 0x62, 0x58, 0x3d, 0xe5, 0x73, 0x96, 0x18, 0x70, 0x2d, 0xab, 0xc9, 0xc7, 0x61, 0xf4,
-	0xd6, 0x0f, 0x7c, 0x95, 0x98, 0x67, 0x19, 0xe3, 0x09, 0x34, 0x62, 0x23, 0x65, 0x69, 0x62, 0x53,
-	0x6e, 0x6e, 0x68, 0xf3, 0xc6, 0xc9, 0x98, 0xac, 0x10, 0x9b, 0x37, 0x93, 0x72, 0xa7, 0x60, 0x0f,
-	0xa8, 0xfa, 0xc2, 0xc5, 0xc3, 0x90, 0x0b, 0x85, 0x5d, 0xa8, 0x18, 0x59, 0xc6, 0x7c, 0x66, 0x8a,
-	0xab, 0x75, 0x0f, 0x12, 0x9d, 0x16, 0xa8, 0x64, 0x97, 0x2c, 0x71, 0xf8, 0x3f, 0xec, 0x8d, 0x39,
-	0x53, 0x9e, 0xcf, 0xa8, 0xd0, 0x24, 0x49, 0xcd, 0xab, 0x49, 0xf7, 0x12, 0xfe, 0xe8, 0xb3, 0xb9,
-	0x2f, 0x38, 0xd3, 0x62, 0xbc, 0xf7, 0x84, 0xef, 0xdd, 0xcf, 0xa8, 0x6e, 0x56, 0x46, 0x44, 0xb3,
-	0xc6, 0x26, 0x14, 0xe7, 0xde, 0x2c, 0xa2, 0x86, 0xa8, 0x4a, 0xe2, 0xc0, 0xfd, 0x96, 0x83, 0xe6,
-	0xb6, 0x9e, 0x6c, 0x35, 0xcd, 0x82, 0x36, 0xb7, 0x4a, 0x6b, 0x9e, 0x89, 0xc4, 0x18, 0x71, 0xa0,
-	0xb3, 0x7e, 0xa0, 0xe7, 0xa4, 0x10, 0x67, 0x4d, 0x80, 0xb7, 0xd0, 0x14, 0x5b, 0x5a, 0xe8, 0x14,
-	0x8d, 0x77, 0x0e, 0x13, 0x4d, 0xb6, 0x75, 0x99, 0x6c, 0x3d, 0x88, 0x1d, 0x28, 0x86, 0x5c, 0x28,
-	0xe9, 0x94, 0xcc, 0x20, 0xe1, 0x9a, 0xaa, 0x5c, 0x28, 0x12, 0x03, 0xf0, 0x35, 0xd8, 0x34, 0x15,
-	0xca, 0x29, 0x1b, 0x7c, 0x6b, 0xe9, 0xd6, 0x0d, 0x09, 0x49, 0x16, 0xee, 0x7a, 0xd0, 0x7e, 0xda,
-	0xbb, 0x32, 0xe4, 0x4c, 0x52, 0xbc, 0x00, 0x7b, 0x92, 0xa6, 0x1d, 0xcb, 0xfc, 0xe1, 0x70, 0x63,
-	0xac, 0xd2, 0xa3, 0x24, 0x8b, 0x3f, 0x7e, 0x05, 0xf5, 0xf5, 0xd9, 0x43, 0x1b, 0xca, 0x64, 0x34,
-	0x18, 0xdc, 0x0c, 0xae, 0xeb, 0x3b, 0x3a, 0x18, 0xf6, 0x07, 0x3d, 0x1d, 0x58, 0x58, 0x85, 0x62,
-	0x9f, 0x90, 0x5b, 0x52, 0xcf, 0x1d, 0xff, 0x07, 0xfb, 0x6b, 0x2e, 0xc2, 0x32, 0xe4, 0xdf, 0xbd,
-	0x19, 0xd6, 0x77, 0xf4, 0x62, 0xd4, 0x1b, 0xd6, 0xad, 0xee, 0x77, 0x0b, 0x76, 0xe3, 0xf7, 0x91,
-	0x8a, 0xb9, 0x3f, 0xa6, 0x7a, 0x74, 0x09, 0x9d, 0xfa, 0x52, 0x51, 0x81, 0x8d, 0xec, 0x2b, 0x66,
-	0x1e, 0xd0, 0xd6, 0xee, 0x42, 0x19, 0xfd, 0xd6, 0xe3, 0x03, 0x38, 0x4f, 0x5d, 0x1f, 0x8f, 0x16,
-	0x37, 0x7c, 0x7e, 0xb6, 0x5b, 0x2f, 0x7e, 0x8b, 0x8b, 0x75, 0xbc, 0x2f, 0x19, 0xdc, 0xf9, 0xaf,
-	0x00, 0x00, 0x00, 0xff, 0xff, 0xd4, 0xcc, 0xc0, 0x2d, 0x84, 0x06, 0x00, 0x00,
+	// 2470 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x59, 0xdd, 0x6e, 0xe3, 0xc6,
+	0xf5, 0x5f, 0x4a, 0x96, 0x3f, 0x8e, 0x24, 0x9b, 0x1e, 0x3b, 0x5e, 0xfd, 0xfd, 0x4f, 0xb7, 0x06,
+	0x1b, 0x24, 0x86, 0x12, 0x24, 0x1b, 0xa5, 0x01, 0xd2, 0x34, 0xe9, 0x56, 0x95, 0xe8, 0xb5, 0xb0,
+	0xb6, 0xa4, 0x8c, 0xa5, 0xdd, 0x04, 0x45, 0xe0, 0x72, 0xa9, 0x59, 0x89, 0x30, 0x45, 0x32, 0xc3,
+	0xe1, 0x7a, 0xf5, 0x0c, 0xbd, 0x6e, 0x03, 0x14, 0x05, 0xf2, 0x14, 0x45, 0x5f, 0xa4, 0xf7, 0xbd,
+	0xe8, 0x55, 0x5f, 0xa1, 0x57, 0xc5, 0x7c, 0x90, 0x1a, 0x52, 0xf2, 0x66, 0x5b, 0xf4, 0xca, 0x73,
+	0x7e, 0x3c, 0x67, 0xe6, 0xcc, 0x99, 0x73, 0x7e, 0x73, 0x46, 0x86, 0xfd, 0x88, 0x86, 0x2c, 0xfc,
+	0xc8, 0x99, 0x92, 0x80, 0x7d, 0x28, 0xc6, 0xa8, 0x22, 0xfe, 0x58, 0x5b, 0x50, 0xb1, 0xe7, 0x11,
+	0x5b, 0x58, 0x7f, 0x37, 0x60, 0xb7, 0xcd, 0xbf, 0x63, 0x12, 0x87, 0x09, 0x75, 0x49, 0x8c, 0x10,
+	0x6c, 0xb8, 0x51, 0x12, 0x37, 0x8c, 0x13, 0xe3, 0xb4, 0x8c, 0xc5, 0x18, 0x1d, 0xc1, 0xe6, 0x9c,
+	0xcc, 0x43, 0xba, 0x68, 0x94, 0x04, 0xaa, 0x24, 0x74, 0x02, 0x55, 0x2f, 0x6a, 0x4f, 0x26, 0x94,
+	0xc4, 0x31, 0x89, 0x1b, 0x65, 0xf1, 0x51, 0x87, 0xd0, 0x3b, 0x50, 0x77, 0xa3, 0xe4, 0xd2, 0xf3,
+	0x7d, 0xcf, 0x0d, 0x29, 0x89, 0x1b, 0x1b, 0x42, 0x27, 0x0f, 0xf2, 0x35, 0x27, 0x5e, 0x7c, 0xd3,
+	0xa8, 0xc8, 0x35, 0xf9, 0x18, 0xd9, 0xb0, 0x1f, 0x10, 0x76, 0x1b, 0xd2, 0x9b, 0x5e, 0xc0, 0x08,
+	0x7d, 0xe1, 0xb8, 0x24, 0x6e, 0x6c, 0x9e, 0x94, 0x4f, 0xab, 0xad, 0xfb, 0x72, 0x37, 0x1f, 0xf6,
+	0x0b, 0xdf, 0xf1, 0xaa, 0x85, 0xf5, 0x47, 0x03, 0xcc, 0xa2, 0x1e, 0x5f, 0x2f, 0x70, 0xe6, 0x44,
+	0xec, 0x71, 0x07, 0x8b, 0x31, 0x3a, 0x85, 0xbd, 0x99, 0x43, 0x27, 0xb7, 0x0e, 0x25, 0xca, 0x7d,
+	0xb1, 0xd9, 0x1d, 0x5c, 0x84, 0x91, 0x09, 0xe5, 0x39, 0x4b, 0xd4, 0x6e, 0xf9, 0x10, 0x1d, 0x42,
+	0x25, 0x8e, 0x08, 0x99, 0xa8, 0xdd, 0x49, 0x01, 0xbd, 0x0d, 0x3b, 0x4e, 0x16, 0x9b, 0xca, 0x49,
+	0xf9, 0x74, 0x07, 0x2f, 0x01, 0xeb, 0x1c, 0x50, 0x2e, 0xf2, 0xe3, 0xd8, 0x99, 0x92, 0x3b, 0x23,
+	0x7d, 0x0c, 0xdb, 0x3c, 0x2a, 0x67, 0x94, 0x10, 0xb5, 0x70, 0x26, 0x5b, 0xbf, 0x52, 0x67, 0xd8,
+	0x09, 0x83, 0x89, 0xc7, 0xbc, 0x30, 0xe0, 0xfb, 0x63, 0x8b, 0x28, 0xdb, 0x1f, 0x1f, 0xa3, 0x06,
+	0x6c, 0xcd, 0x49, 0xcc, 0x17, 0x51, 0xfb, 0x4a, 0x45, 0xeb, 0x5f, 0x06, 0x1c, 0x3d, 0x76, 0xe6,
+	0x24, 0x26, 0xf4, 0x25, 0xa1, 0x6d, 0x97, 0x4f, 0x81, 0x49, 0x9c, 0xf8, 0x8c, 0x4f, 0x34, 0x1e,
+	0xf7, 0xba, 0xe9, 0x44, 0x7c, 0x8c, 0x3e, 0x82, 0x4d, 0x47, 0xe8, 0x88, 0x79, 0x76, 0xb3, 0xd3,
+	0x58, 0x99, 0x42, 0xa9, 0xf1, 0x95, 0xe3, 0xc4, 0x75, 0x79, 0x44, 0xb9, 0xeb, 0xdb, 0x38, 0x15,
+	0x79, 0xdc, 0x08, 0xa5, 0x21, 0x15, 0x71, 0xdb, 0xc1, 0x52, 0xe0, 0x71, 0x63, 0xde, 0x9c, 0xc4,
+	0xcc, 0x99, 0x47, 0x2a, 0x25, 0x96, 0x80, 0x88, 0x44, 0x42, 0x1d, 0xe1, 0xc0, 0xa6, 0x8a, 0x84,
+	0x92, 0xd1, 0xc7, 0xb0, 0x1d, 0xcf, 0x12, 0x36, 0x09, 0x6f, 0x83, 0xc6, 0xd6, 0x89, 0x71, 0x5a,
+	0x6d, 0xbd, 0xa5, 0x9c, 0xbb, 0x52, 0xb0, 0xdc, 0x17, 0xce, 0xd4, 0xac, 0xdf, 0xc1, 0x6e, 0xfe,
+	0x1b, 0x5f, 0x60, 0x4a, 0x1d, 0x97, 0xbc, 0x48, 0x7c, 0xb1, 0xef, 0x6d, 0x9c, 0xc9, 0xf2, 0x78,
+	0xd8, 0x2c, 0x9c, 0xa8, 0x18, 0x2a, 0x29, 0xe7, 0x54, 0x39, 0xef, 0x94, 0xf5, 0xcf, 0x32, 0x80,
+	0x38, 0x9f, 0x2b, 0xe6, 0x30, 0xc2, 0x55, 0x67, 0x61, 0xcc, 0xb4, 0xfc, 0xcb, 0x64, 0xf4, 0x09,
+	0xec, 0xd0, 0xb4, 0x10, 0x1b, 0xa5, 0xdc, 0x06, 0xf2, 0x55, 0x8a, 0x97, 0x7a, 0xe8, 0x11, 0xd4,
+	0xa9, 0x9e, 0x43, 0xc2, 0x81, 0x6a, 0xeb, 0xff, 0xd6, 0x19, 0x0a, 0x05, 0x9c, 0xd7, 0x47, 0x6d,
+	0x40, 0x34, 0x09, 0x02, 0x2f, 0x98, 0x2e, 0x8f, 0x90, 0x17, 0x2a, 0x2f, 0xb5, 0xfd, 0x95, 0xc3,
+	0xc5, 0x6b, 0x94, 0x51, 0x07, 0xea, 0x8e, 0x96, 0x37, 0x32, 0xdd, 0xab, 0xad, 0x9f, 0xdc, 0x95,
+	0x1a, 0xf2, 0x14, 0xf2, 0x36, 0xe8, 0x53, 0x00, 0x37, 0x4d, 0xe1, 0xb4, 0xd4, 0x73, 0xdb, 0xcf,
+	0x12, 0x1c, 0x6b, 0x8a, 0xe8, 0x53, 0xd8, 0xf4, 0x9d, 0xe7, 0xc4, 0x8f, 0x1b, 0x5b, 0xb9, 0x45,
+	0x97, 0x31, 0xff, 0xf0, 0x42, 0x7c, 0xb7, 0x03, 0x46, 0x17, 0x58, 0x29, 0xf3, 0xac, 0xa4, 0x49,
+	0xc0, 0xf3, 0xaa, 0xb1, 0x2d, 0xeb, 0x41, 0x89, 0xc7, 0xbf, 0x80, 0xaa, 0x66, 0xc0, 0xcb, 0xfd,
+	0x86, 0x2c, 0xd4, 0x59, 0xf1, 0x21, 0x4f, 0xdb, 0x97, 0x8e, 0x9f, 0xa4, 0x85, 0x24, 0x85, 0xcf,
+	0x4b, 0x9f, 0x19, 0xd6, 0x29, 0x6c, 0xdb, 0xc1, 0x24, 0x0a, 0xbd, 0x80, 0xf1, 0x34, 0xce, 0x98,
+	0x50, 0x59, 0x2f, 0x01, 0xeb, 0x87, 0x12, 0xdc, 0xd7, 0x82, 0x9a, 0x3b, 0x90, 0x07, 0x00, 0x6e,
+	0x94, 0x0c, 0x09, 0x75, 0x49, 0xc0, 0x84, 0xa9, 0x81, 0x35, 0x84, 0xd3, 0xae, 0xa4, 0x85, 0x71,
+	0x56, 0xce, 0x65, 0xac, 0x43, 0x4b, 0x8d, 0x0b, 0x6f, 0xee, 0xb1, 0x94, 0x98, 0x35, 0x08, 0xbd,
+	0x0b, 0xbb, 0x8a, 0x2c, 0xf1, 0xab, 0xdf, 0x2c, 0x58, 0xc6, 0xcc, 0x05, 0x54, 0xd3, 0x1b, 0x29,
+	0xbd, 0x4a, 0x4e, 0x6f, 0xb4, 0xd4, 0x7b, 0xee, 0x87, 0xee, 0x0d, 0x26, 0xce, 0x44, 0xea, 0xc9,
+	0xe2, 0x2c, 0xa0, 0x9c, 0x66, 0x05, 0xf2, 0x8c, 0x7a, 0x8c, 0x48, 0xc5, 0x2d, 0xa1, 0x58, 0x84,
+	0xad, 0xdf, 0x1b, 0xf0, 0xd6, 0x32, 0x42, 0x5f, 0x25, 0x84, 0x2e, 0x54, 0x85, 0xbe, 0x03, 0xf5,
+	0xc8, 0x77, 0x16, 0x84, 0xc6, 0x83, 0xc0, 0xf7, 0x02, 0xa2, 0xee, 0xaa, 0x3c, 0xc8, 0xa3, 0xa8,
+	0x80, 0x4b, 0xe7, 0x95, 0x0a, 0x92, 0x86, 0x70, 0x6e, 0x9b, 0x87, 0x6c, 0x22, 0x82, 0xb3, 0x83,
+	0xc5, 0x98, 0x27, 0x05, 0xcf, 0x67, 0x5e, 0xc6, 0x92, 0x92, 0x52, 0xd1, 0xfa, 0x47, 0x19, 0x60,
+	0xe9, 0xcd, 0x5d, 0xc4, 0x18, 0x33, 0x87, 0x25, 0xf1, 0x9d, 0xc4, 0x78, 0x25, 0x3e, 0x63, 0xa5,
+	0xc6, 0x27, 0xf1, 0x82, 0x17, 0x61, 0xea, 0x01, 0x1f, 0xa3, 0xf7, 0x61, 0x9b, 0xa8, 0x0c, 0x12,
+	0x2e, 0x54, 0x5b, 0x7b, 0x6a, 0x9a, 0x34, 0xb1, 0x70, 0xa6, 0x80, 0xba, 0xc5, 0xd2, 0xaf, 0x08,
+	0x8b, 0x07, 0xab, 0x45, 0xfb, 0xba, 0xfa, 0x6f, 0x41, 0xe5, 0x3b, 0x1e, 0x5d, 0x71, 0x62, 0xd5,
+	0xd6, 0xdb, 0x2b, 0xd6, 0x5a, 0xec, 0xb1, 0x54, 0x45, 0xef, 0xc3, 0xe6, 0x8c, 0x38, 0x3e, 0x9b,
+	0x89, 0xd3, 0xdb, 0x6d, 0x1d, 0x28, 0xa3, 0x73, 0x01, 0xa6, 0xfb, 0x94, 0x2a, 0xc8, 0x82, 0x1a,
+	0xe5, 0xec, 0x4d, 0x59, 0x27, 0x4c, 0x02, 0x26, 0xea, 0xad, 0x8c, 0x73, 0x18, 0xd7, 0xf1, 0x9d,
+	0x98, 0xd9, 0xaf, 0x3c, 0xd6, 0x09, 0x27, 0xa4, 0xb1, 0x23, 0x75, 0x74, 0x8c, 0x53, 0xa7, 0x4b,
+	0x9d, 0x78, 0x76, 0x11, 0x4e, 0x1b, 0x20, 0xa9, 0x33, 0x95, 0xb9, 0x7d, 0x94, 0xf8, 0xfe, 0x90,
+	0x86, 0x53, 0x51, 0x70, 0x55, 0x51, 0x35, 0x39, 0x4c, 0xb4, 0x2b, 0x73, 0x67, 0x4a, 0xba, 0xde,
+	0x94, 0xc4, 0xac, 0x51, 0x13, 0x53, 0xe8, 0x90, 0xf5, 0x25, 0xfc, 0xf4, 0x31, 0x61, 0xcb, 0x9d,
+	0x77, 0x49, 0xe4, 0x87, 0x8b, 0x39, 0x09, 0x58, 0x8c, 0xc9, 0x77, 0x09, 0x89, 0xd9, 0xeb, 0xf8,
+	0xdb, 0xfa, 0x9b, 0x01, 0x87, 0x69, 0xa8, 0xb9, 0xbe, 0x47, 0x89, 0xb0, 0xe5, 0xd5, 0xe1, 0x46,
+	0x09, 0x16, 0xb3, 0xca, 0x5b, 0x42, 0xa6, 0x6c, 0x01, 0x15, 0x3b, 0x8c, 0x12, 0x59, 0xb4, 0x32,
+	0x63, 0x33, 0x19, 0x7d, 0x00, 0xfb, 0xb2, 0x80, 0xf5, 0x69, 0x64, 0x65, 0xaf, 0x7e, 0x28, 0x32,
+	0xc0, 0xc6, 0x2a, 0x03, 0x9c, 0xc2, 0x1e, 0x6f, 0x21, 0xf4, 0xd9, 0x64, 0x69, 0x17, 0x61, 0x6b,
+	0x0a, 0x55, 0xd5, 0x42, 0x0d, 0x43, 0xca, 0x50, 0x0b, 0xb6, 0xc5, 0x61, 0xbb, 0xa1, 0xbc, 0x20,
+	0x77, 0x5b, 0x47, 0xf9, 0x86, 0x6c, 0xa8, 0xbe, 0xe2, 0x4c, 0x4f, 0xf4, 0x81, 0x61, 0xc0, 0x1c,
+	0x2f, 0x20, 0x94, 0x4f, 0xa2, 0x76, 0x97, 0x07, 0xad, 0x47, 0x70, 0x60, 0x07, 0x2f, 0x3d, 0x1a,
+	0x06, 0x3c, 0x6c, 0x4f, 0x1d, 0xea, 0x39, 0xcf, 0xfd, 0xf5, 0xed, 0xda, 0x5a, 0x0e, 0xb6, 0xbe,
+	0x37, 0x60, 0xaf, 0x90, 0xb7, 0xe8, 0xe1, 0x8a, 0xbb, 0x87, 0xca, 0x5d, 0xf1, 0x7d, 0x8d, 0xb3,
+	0x08, 0x36, 0xa2, 0xa5, 0x8f, 0x62, 0xcc, 0x4f, 0x26, 0x72, 0xe2, 0xf8, 0x36, 0xa4, 0x29, 0x63,
+	0x64, 0xb2, 0xc8, 0x3d, 0x35, 0x3e, 0xf3, 0x7c, 0xa2, 0xa8, 0x23, 0x87, 0x59, 0xdf, 0x97, 0xa0,
+	0x2a, 0x8b, 0xa3, 0x33, 0x23, 0xee, 0x0d, 0x6a, 0x6a, 0x2d, 0xda, 0x32, 0x80, 0x9a, 0xc6, 0x68,
+	0x11, 0x11, 0xd5, 0xba, 0xad, 0xf3, 0xa7, 0x01, 0x5b, 0x91, 0xb3, 0xf0, 0x43, 0x27, 0x75, 0x27,
+	0x15, 0xf9, 0x17, 0x37, 0x9c, 0xcf, 0x9d, 0x60, 0x22, 0xee, 0xf0, 0x1d, 0x9c, 0x8a, 0x7c, 0x0f,
+	0x5e, 0xc0, 0xf8, 0xb1, 0xfa, 0xea, 0xa8, 0x33, 0x99, 0x5b, 0xf1, 0xcb, 0x2f, 0x4c, 0x98, 0x22,
+	0xee, 0x54, 0xe4, 0x99, 0x24, 0xea, 0x74, 0x48, 0xa8, 0x17, 0x4e, 0x14, 0x5b, 0xeb, 0x10, 0xb7,
+	0xa5, 0x84, 0x51, 0x8f, 0xc4, 0xaa, 0xb4, 0x53, 0x51, 0xab, 0xfc, 0xf6, 0x0b, 0x46, 0x68, 0x5a,
+	0xd5, 0x3a, 0x66, 0xfd, 0xc5, 0x80, 0x3a, 0x96, 0xc0, 0x30, 0xf4, 0x3d, 0x77, 0x81, 0x3e, 0xc8,
+	0xc5, 0xa6, 0xa1, 0x62, 0x93, 0xd3, 0xd1, 0xa2, 0xf3, 0x00, 0x60, 0xee, 0xbc, 0xc2, 0xca, 0x01,
+	0xc5, 0xf3, 0x4b, 0x84, 0xd7, 0x8d, 0x62, 0x89, 0x30, 0x3a, 0x73, 0x3c, 0x3f, 0xa1, 0xd9, 0x53,
+	0x65, 0xf5, 0x03, 0xaf, 0x8a, 0x0c, 0x7c, 0xe6, 0x05, 0x93, 0xf0, 0x56, 0xd5, 0x4e, 0x11, 0xe6,
+	0x6d, 0x73, 0xd6, 0x3a, 0x2a, 0xc7, 0x8f, 0x60, 0x33, 0xf6, 0xa6, 0x81, 0xe3, 0xab, 0x54, 0x55,
+	0x92, 0x7e, 0x24, 0xaa, 0xf7, 0x56, 0x22, 0x7a, 0x08, 0x5b, 0xee, 0xcc, 0x09, 0x02, 0xe2, 0x37,
+	0xca, 0xb9, 0x4c, 0x48, 0x67, 0xee, 0xc8, 0xaf, 0x38, 0x55, 0xe3, 0x87, 0x48, 0xdd, 0x30, 0x10,
+	0x45, 0x24, 0x3d, 0xcb, 0x64, 0x11, 0x6e, 0x3e, 0x4e, 0x13, 0xb5, 0x22, 0x13, 0x51, 0xc7, 0x50,
+	0x13, 0x4c, 0x5d, 0x16, 0x09, 0xbb, 0x29, 0xf4, 0x56, 0x70, 0x3d, 0x29, 0xb6, 0x72, 0x49, 0x61,
+	0x3d, 0x84, 0xcd, 0xa7, 0xa1, 0x9f, 0xcc, 0xd7, 0x17, 0x27, 0x4f, 0x58, 0x87, 0xcd, 0xd4, 0x66,
+	0xc5, 0xd8, 0xfa, 0x6b, 0x05, 0x0e, 0xd7, 0x11, 0xeb, 0xda, 0xab, 0x34, 0x9d, 0xb4, 0x94, 0xaf,
+	0x78, 0xf1, 0x94, 0x55, 0xf9, 0x2e, 0x05, 0x8e, 0x0a, 0x02, 0x4f, 0x9f, 0x10, 0x42, 0x40, 0x03,
+	0x38, 0xa4, 0x6b, 0x78, 0x58, 0xdd, 0x8f, 0xff, 0xbf, 0xcc, 0xa8, 0x15, 0x15, 0xbc, 0xd6, 0x10,
+	0x9d, 0x42, 0x85, 0x97, 0x5d, 0xda, 0x96, 0xa2, 0x02, 0xe1, 0x85, 0x94, 0x61, 0xa9, 0x80, 0xbe,
+	0x80, 0x2a, 0x59, 0x72, 0x98, 0xea, 0x49, 0x8f, 0xb3, 0x3b, 0x7c, 0x85, 0xdd, 0xb0, 0xae, 0x8e,
+	0x3e, 0x48, 0xef, 0xe2, 0x6d, 0xe1, 0xe9, 0xd1, 0x1d, 0x77, 0xb1, 0x54, 0x12, 0x2f, 0x2b, 0x16,
+	0x46, 0x11, 0x99, 0x34, 0x76, 0xd4, 0xcb, 0x4a, 0x8a, 0xe8, 0xe7, 0x50, 0x9d, 0x2d, 0xb9, 0x44,
+	0xdc, 0x96, 0x4b, 0xaf, 0x35, 0x96, 0xc1, 0xba, 0x1a, 0xfa, 0x5c, 0xf4, 0x13, 0xcb, 0x2a, 0x13,
+	0xb7, 0x68, 0xb5, 0x75, 0xb8, 0xae, 0x02, 0x71, 0x5e, 0x15, 0xfd, 0x1a, 0xf6, 0x44, 0xec, 0x87,
+	0xfc, 0xc6, 0x95, 0xd6, 0xb5, 0x5c, 0x46, 0xf7, 0xf2, 0x5f, 0x71, 0x51, 0xbd, 0x78, 0x3d, 0xd7,
+	0x57, 0xae, 0x67, 0xbd, 0x8e, 0x76, 0xf3, 0xd4, 0xa6, 0xbf, 0xfc, 0xf6, 0xd6, 0xbe, 0xfc, 0xd4,
+	0xaa, 0x99, 0x1a, 0x7a, 0x0f, 0xb6, 0x5e, 0x8a, 0x14, 0x8e, 0x1b, 0xa6, 0x38, 0xa4, 0xba, 0xb2,
+	0x90, 0x89, 0x8d, 0xd3, 0xaf, 0xd6, 0x9f, 0x0d, 0xa8, 0x5f, 0x7a, 0x53, 0xf9, 0x9c, 0x1b, 0x39,
+	0xf1, 0x0d, 0xf7, 0x34, 0x8c, 0x88, 0x04, 0x7a, 0x13, 0x95, 0xb9, 0x3a, 0x94, 0x51, 0x58, 0x29,
+	0x47, 0x61, 0xb9, 0x59, 0x34, 0x0a, 0xfb, 0x25, 0xc0, 0x24, 0x2b, 0x88, 0x46, 0x39, 0x97, 0xa4,
+	0xeb, 0x6a, 0x06, 0x6b, 0xea, 0xd6, 0x1f, 0x0c, 0x38, 0xc8, 0x4d, 0xac, 0xba, 0xe4, 0xff, 0xb5,
+	0x93, 0xff, 0xe1, 0x33, 0xde, 0xfa, 0x2d, 0x54, 0x65, 0x24, 0x3b, 0xb3, 0x24, 0x78, 0x93, 0x98,
+	0x1d, 0xc1, 0xa6, 0x0c, 0x79, 0xfa, 0xb8, 0x7e, 0x99, 0x31, 0xcc, 0xc4, 0x61, 0x8e, 0x58, 0xb5,
+	0x86, 0xc5, 0xd8, 0xea, 0x41, 0x5d, 0x1d, 0x93, 0x6a, 0xcb, 0xfe, 0xeb, 0xe9, 0xad, 0x1f, 0x0c,
+	0x38, 0xb9, 0xbb, 0xe9, 0x8b, 0xa3, 0x30, 0x88, 0x09, 0xfa, 0x12, 0xaa, 0xcb, 0x90, 0xf3, 0xe7,
+	0x5c, 0xf9, 0xc7, 0x8e, 0x48, 0xd7, 0x47, 0x5f, 0xc0, 0xee, 0x5c, 0x0f, 0x2b, 0xbf, 0xa7, 0xca,
+	0x5a, 0x65, 0xe5, 0xcf, 0xaf, 0xa0, 0x6b, 0x35, 0xa1, 0xd6, 0xa5, 0x8e, 0x17, 0xbc, 0x49, 0x0b,
+	0x7a, 0x05, 0x75, 0x3b, 0xa0, 0xa1, 0xef, 0xbf, 0x81, 0x32, 0x3f, 0x38, 0x16, 0xde, 0x90, 0x20,
+	0x6d, 0xa2, 0x84, 0xc0, 0x1f, 0xbc, 0x6e, 0x4c, 0x55, 0xb8, 0xf9, 0xd0, 0x7a, 0x1f, 0xee, 0x63,
+	0x12, 0x90, 0xdb, 0x0e, 0xa1, 0xcc, 0x7b, 0xe1, 0xb9, 0x0e, 0xcb, 0xe2, 0xae, 0x94, 0x8d, 0xa5,
+	0xf2, 0xb7, 0x70, 0x90, 0xd3, 0x53, 0x11, 0x3c, 0x81, 0xaa, 0xbb, 0x84, 0x95, 0x81, 0x0e, 0x89,
+	0x1e, 0xd1, 0xd1, 0x4c, 0x85, 0x57, 0x35, 0x9c, 0x07, 0x9b, 0x01, 0x98, 0xc5, 0x9f, 0x13, 0xd0,
+	0x3e, 0xd4, 0xdb, 0x9d, 0x51, 0x6f, 0xd0, 0xbf, 0xee, 0x60, 0xbb, 0x3d, 0xb2, 0xcd, 0x7b, 0x1a,
+	0x34, 0x1e, 0x76, 0x39, 0x64, 0x20, 0x13, 0x6a, 0x0a, 0xba, 0x1a, 0xb5, 0xf1, 0xc8, 0x2c, 0xa1,
+	0x3d, 0xa8, 0x66, 0xc8, 0x60, 0x68, 0x96, 0x35, 0x2b, 0x6c, 0x5f, 0x0e, 0x9e, 0xda, 0xe6, 0x46,
+	0x73, 0xaa, 0xaf, 0x27, 0x1f, 0x36, 0xa8, 0x0a, 0x5b, 0x78, 0xdc, 0xef, 0xf7, 0xfa, 0x8f, 0xcd,
+	0x7b, 0x5c, 0x18, 0xda, 0xfd, 0x2e, 0x17, 0x0c, 0xb4, 0x03, 0x15, 0x1b, 0xe3, 0x01, 0x36, 0x4b,
+	0x1c, 0xe7, 0xb3, 0x0e, 0xed, 0xae, 0x59, 0x46, 0xbb, 0x00, 0x1d, 0xdc, 0xbe, 0x3a, 0xbf, 0xbe,
+	0x18, 0x0c, 0x86, 0xe6, 0x06, 0x5f, 0x68, 0x38, 0xbe, 0xb8, 0xe8, 0xf5, 0x1f, 0x5f, 0xf7, 0x2e,
+	0xdb, 0x8f, 0x6d, 0xb3, 0xd2, 0xbc, 0x80, 0x9a, 0xfe, 0x7a, 0x42, 0x08, 0x76, 0xcf, 0xed, 0xf6,
+	0xc5, 0xe8, 0xfc, 0x7a, 0xdc, 0x7f, 0xd2, 0x1f, 0x3c, 0xeb, 0x9b, 0xf7, 0x50, 0x0d, 0xb6, 0x85,
+	0xef, 0x72, 0xb1, 0x2a, 0x6c, 0x49, 0x8d, 0x6f, 0xcc, 0x12, 0xaa, 0xc3, 0xce, 0xb8, 0x9f, 0x8a,
+	0xe5, 0xe6, 0xcf, 0x60, 0xaf, 0xd0, 0x8d, 0xa3, 0x2d, 0x28, 0x8f, 0x3a, 0x43, 0xf3, 0x1e, 0x1f,
+	0x8c, 0xbb, 0x43, 0xd3, 0x68, 0x7e, 0x0b, 0xf5, 0x5c, 0x0f, 0xcc, 0xdd, 0xfc, 0x6a, 0x6c, 0xe3,
+	0x6f, 0xae, 0xfb, 0x83, 0x3e, 0x8f, 0xe2, 0x01, 0xec, 0x49, 0xf9, 0xb2, 0xd7, 0xb7, 0x3b, 0xb8,
+	0x7d, 0x36, 0x32, 0x0d, 0xee, 0x98, 0x04, 0xcf, 0xda, 0x9d, 0xd1, 0x00, 0xf7, 0x06, 0x66, 0x69,
+	0xa9, 0x38, 0xb2, 0xdb, 0x97, 0x57, 0x43, 0xbb, 0xfd, 0xc4, 0x2c, 0x37, 0xa7, 0xb0, 0x57, 0x68,
+	0x68, 0xd1, 0x21, 0x98, 0xd2, 0xc7, 0xce, 0xb9, 0xdd, 0x79, 0xa2, 0x2d, 0xa3, 0xa3, 0xdc, 0x4b,
+	0xa3, 0x08, 0x72, 0x8f, 0x4b, 0x45, 0x7b, 0xfb, 0x6b, 0xbb, 0x63, 0x96, 0x9b, 0x18, 0xf6, 0x57,
+	0xba, 0x43, 0x74, 0x04, 0x08, 0xdb, 0x22, 0x5a, 0xd7, 0x83, 0xfe, 0xf5, 0x59, 0xbb, 0x77, 0x31,
+	0xc6, 0x7c, 0x31, 0x04, 0xbb, 0x29, 0xde, 0xbe, 0x78, 0xd6, 0xfe, 0xe6, 0xca, 0x34, 0xf8, 0x71,
+	0xa4, 0x58, 0xdf, 0x7e, 0x6a, 0x63, 0xb3, 0xd4, 0xfc, 0x0c, 0xf6, 0x0a, 0x3d, 0x18, 0xb7, 0xbc,
+	0x3a, 0x1f, 0x8f, 0xba, 0x83, 0x67, 0x3c, 0x61, 0xba, 0xbd, 0xbe, 0xcc, 0xb3, 0x0c, 0xc3, 0x9d,
+	0x41, 0xdf, 0x34, 0x9a, 0x4f, 0x60, 0xaf, 0x70, 0xd7, 0xf1, 0x44, 0xe3, 0xc7, 0x9d, 0x2e, 0x78,
+	0x0f, 0xdd, 0x87, 0x03, 0x01, 0xf4, 0xce, 0xae, 0xfb, 0x83, 0xd1, 0xf5, 0x10, 0xdb, 0x57, 0x76,
+	0x9f, 0x07, 0x77, 0x17, 0x40, 0x7c, 0x48, 0xdd, 0x78, 0x04, 0xfb, 0x2b, 0x84, 0xcc, 0xa3, 0x70,
+	0xd9, 0x7b, 0x8c, 0xdb, 0x22, 0x53, 0xed, 0xaf, 0x87, 0x03, 0x3c, 0x32, 0xef, 0xe5, 0xd1, 0xde,
+	0xa5, 0x40, 0x8d, 0xd6, 0x9f, 0x36, 0xa0, 0x26, 0x7f, 0x0a, 0x23, 0xf4, 0xa5, 0xe7, 0x12, 0xfe,
+	0x0b, 0x03, 0x26, 0x53, 0x2f, 0x66, 0x84, 0xa2, 0xfd, 0x95, 0xdf, 0xca, 0x8e, 0x6b, 0x0a, 0x12,
+	0xff, 0x20, 0x40, 0x37, 0xd0, 0xb8, 0x8b, 0x1b, 0xd1, 0xbb, 0x29, 0xfd, 0xbd, 0xfe, 0xc5, 0x7c,
+	0xfc, 0xde, 0x8f, 0xea, 0x29, 0x8a, 0xf8, 0x18, 0x6a, 0xca, 0x46, 0xd0, 0x1d, 0x4a, 0x7f, 0x54,
+	0xd0, 0xc9, 0xaf, 0xe0, 0x5f, 0x0b, 0x6a, 0xe3, 0x88, 0x3f, 0x7b, 0xd2, 0x6e, 0x34, 0x77, 0x87,
+	0x8b, 0x9b, 0x27, 0x6f, 0x71, 0x6a, 0x70, 0x32, 0xee, 0x86, 0xb7, 0x81, 0x66, 0x75, 0x98, 0xb3,
+	0x4a, 0x57, 0x5a, 0x33, 0xd7, 0x43, 0x03, 0x3d, 0x82, 0x03, 0x4c, 0xa2, 0x90, 0xb2, 0x7c, 0x4b,
+	0x70, 0xbc, 0x96, 0xc9, 0xc5, 0x4d, 0x5c, 0x70, 0xf9, 0x73, 0xd8, 0x94, 0x0c, 0x9d, 0x2d, 0x9b,
+	0x23, 0xec, 0xe3, 0x74, 0xa6, 0x75, 0x24, 0xda, 0x07, 0xb3, 0x48, 0xc4, 0xe8, 0x41, 0xd6, 0x9d,
+	0xad, 0x65, 0xe8, 0xd7, 0xcd, 0xf7, 0x7c, 0x53, 0x7c, 0xfa, 0xe4, 0xdf, 0x03, 0x00, 0xf3, 0x61,
+	0x2c, 0x8f, 0x2b, 0x1a, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -771,6 +2576,12 @@ const _ = grpc.SupportPackageIsVersion4
 type AgentServiceClient interface {
 	Register(ctx context.Context, in *AgentState, opts ...grpc.CallOption) (*Empty, error)
 	GetGameserverDeployments(ctx context.Context, in *GetGameserverDeploymentsRequest, opts ...grpc.CallOption) (*GetGameserverDeploymentsResponse, error)
+	RequestDrain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*Empty, error)
+	UploadVolume(ctx context.Context, opts ...grpc.CallOption) (AgentService_UploadVolumeClient, error)
+	DownloadVolume(ctx context.Context, in *VolumeRequest, opts ...grpc.CallOption) (AgentService_DownloadVolumeClient, error)
+	ReportMigrationTask(ctx context.Context, in *MigrationTaskResult, opts ...grpc.CallOption) (*Empty, error)
+	Enroll(ctx context.Context, in *EnrollRequest, opts ...grpc.CallOption) (*CertificateResponse, error)
+	RenewCertificate(ctx context.Context, in *RenewCertificateRequest, opts ...grpc.CallOption) (*CertificateResponse, error)
 }
 
 type agentServiceClient struct {
@@ -799,10 +2610,118 @@ func (c *agentServiceClient) GetGameserverDeployments(ctx context.Context, in *G
 	return out, nil
 }
 
+func (c *agentServiceClient) RequestDrain(ctx context.Context, in *DrainRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/RequestDrain", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) UploadVolume(ctx context.Context, opts ...grpc.CallOption) (AgentService_UploadVolumeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_AgentService_serviceDesc.Streams[0], "/proto.AgentService/UploadVolume", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceUploadVolumeClient{stream}
+	return x, nil
+}
+
+type AgentService_UploadVolumeClient interface {
+	Send(*VolumeChunk) error
+	CloseAndRecv() (*Empty, error)
+	grpc.ClientStream
+}
+
+type agentServiceUploadVolumeClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceUploadVolumeClient) Send(m *VolumeChunk) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *agentServiceUploadVolumeClient) CloseAndRecv() (*Empty, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(Empty)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) DownloadVolume(ctx context.Context, in *VolumeRequest, opts ...grpc.CallOption) (AgentService_DownloadVolumeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_AgentService_serviceDesc.Streams[1], "/proto.AgentService/DownloadVolume", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &agentServiceDownloadVolumeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AgentService_DownloadVolumeClient interface {
+	Recv() (*VolumeChunk, error)
+	grpc.ClientStream
+}
+
+type agentServiceDownloadVolumeClient struct {
+	grpc.ClientStream
+}
+
+func (x *agentServiceDownloadVolumeClient) Recv() (*VolumeChunk, error) {
+	m := new(VolumeChunk)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *agentServiceClient) ReportMigrationTask(ctx context.Context, in *MigrationTaskResult, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/ReportMigrationTask", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) Enroll(ctx context.Context, in *EnrollRequest, opts ...grpc.CallOption) (*CertificateResponse, error) {
+	out := new(CertificateResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/Enroll", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *agentServiceClient) RenewCertificate(ctx context.Context, in *RenewCertificateRequest, opts ...grpc.CallOption) (*CertificateResponse, error) {
+	out := new(CertificateResponse)
+	err := c.cc.Invoke(ctx, "/proto.AgentService/RenewCertificate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AgentServiceServer is the server API for AgentService service.
 type AgentServiceServer interface {
 	Register(context.Context, *AgentState) (*Empty, error)
 	GetGameserverDeployments(context.Context, *GetGameserverDeploymentsRequest) (*GetGameserverDeploymentsResponse, error)
+	RequestDrain(context.Context, *DrainRequest) (*Empty, error)
+	UploadVolume(AgentService_UploadVolumeServer) error
+	DownloadVolume(*VolumeRequest, AgentService_DownloadVolumeServer) error
+	ReportMigrationTask(context.Context, *MigrationTaskResult) (*Empty, error)
+	Enroll(context.Context, *EnrollRequest) (*CertificateResponse, error)
+	RenewCertificate(context.Context, *RenewCertificateRequest) (*CertificateResponse, error)
 }
 
 // UnimplementedAgentServiceServer can be embedded to have forward compatible implementations.
@@ -815,6 +2734,24 @@ func (*UnimplementedAgentServiceServer) Register(ctx context.Context, req *Agent
 func (*UnimplementedAgentServiceServer) GetGameserverDeployments(ctx context.Context, req *GetGameserverDeploymentsRequest) (*GetGameserverDeploymentsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetGameserverDeployments not implemented")
 }
+func (*UnimplementedAgentServiceServer) RequestDrain(ctx context.Context, req *DrainRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestDrain not implemented")
+}
+func (*UnimplementedAgentServiceServer) UploadVolume(srv AgentService_UploadVolumeServer) error {
+	return status.Errorf(codes.Unimplemented, "method UploadVolume not implemented")
+}
+func (*UnimplementedAgentServiceServer) DownloadVolume(req *VolumeRequest, srv AgentService_DownloadVolumeServer) error {
+	return status.Errorf(codes.Unimplemented, "method DownloadVolume not implemented")
+}
+func (*UnimplementedAgentServiceServer) ReportMigrationTask(ctx context.Context, req *MigrationTaskResult) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportMigrationTask not implemented")
+}
+func (*UnimplementedAgentServiceServer) Enroll(ctx context.Context, req *EnrollRequest) (*CertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Enroll not implemented")
+}
+func (*UnimplementedAgentServiceServer) RenewCertificate(ctx context.Context, req *RenewCertificateRequest) (*CertificateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCertificate not implemented")
+}
 
 func RegisterAgentServiceServer(s *grpc.Server, srv AgentServiceServer) {
 	s.RegisterService(&_AgentService_serviceDesc, srv)
@@ -856,6 +2793,125 @@ func _AgentService_GetGameserverDeployments_Handler(srv interface{}, ctx context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _AgentService_RequestDrain_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DrainRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).RequestDrain(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/RequestDrain",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).RequestDrain(ctx, req.(*DrainRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_UploadVolume_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AgentServiceServer).UploadVolume(&agentServiceUploadVolumeServer{stream})
+}
+
+type AgentService_UploadVolumeServer interface {
+	SendAndClose(*Empty) error
+	Recv() (*VolumeChunk, error)
+	grpc.ServerStream
+}
+
+type agentServiceUploadVolumeServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceUploadVolumeServer) SendAndClose(m *Empty) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *agentServiceUploadVolumeServer) Recv() (*VolumeChunk, error) {
+	m := new(VolumeChunk)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AgentService_DownloadVolume_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(VolumeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AgentServiceServer).DownloadVolume(m, &agentServiceDownloadVolumeServer{stream})
+}
+
+type AgentService_DownloadVolumeServer interface {
+	Send(*VolumeChunk) error
+	grpc.ServerStream
+}
+
+type agentServiceDownloadVolumeServer struct {
+	grpc.ServerStream
+}
+
+func (x *agentServiceDownloadVolumeServer) Send(m *VolumeChunk) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AgentService_ReportMigrationTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MigrationTaskResult)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).ReportMigrationTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/ReportMigrationTask",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).ReportMigrationTask(ctx, req.(*MigrationTaskResult))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_Enroll_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnrollRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).Enroll(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/Enroll",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).Enroll(ctx, req.(*EnrollRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _AgentService_RenewCertificate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewCertificateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AgentServiceServer).RenewCertificate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/proto.AgentService/RenewCertificate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AgentServiceServer).RenewCertificate(ctx, req.(*RenewCertificateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _AgentService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "proto.AgentService",
 	HandlerType: (*AgentServiceServer)(nil),
@@ -868,7 +2924,34 @@ var _AgentService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetGameserverDeployments",
 			Handler:    _AgentService_GetGameserverDeployments_Handler,
 		},
+		{
+			MethodName: "RequestDrain",
+			Handler:    _AgentService_RequestDrain_Handler,
+		},
+		{
+			MethodName: "ReportMigrationTask",
+			Handler:    _AgentService_ReportMigrationTask_Handler,
+		},
+		{
+			MethodName: "Enroll",
+			Handler:    _AgentService_Enroll_Handler,
+		},
+		{
+			MethodName: "RenewCertificate",
+			Handler:    _AgentService_RenewCertificate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "UploadVolume",
+			Handler:       _AgentService_UploadVolume_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "DownloadVolume",
+			Handler:       _AgentService_DownloadVolume_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/agent.proto",
 }
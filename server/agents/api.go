@@ -1,26 +1,65 @@
 package agents
 
 import (
+	"io"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/audit"
 	"github.com/Trojan295/chinchilla/server/auth"
 	"github.com/gin-gonic/gin"
 )
 
+// agentResources are in whole CPUs, CPU millicores, kB of memory
+// and bytes of disk
 type agentResources struct {
-	Cpus        int `json:"cpus"`
-	Memory      int `json:"memory"`
-	IPAddresses int `json:"ipAddresses"`
+	Cpus              int                     `json:"cpus"`
+	CPUMillicores     int                     `json:"cpuMillicores"`
+	Memory            int                     `json:"memory"`
+	DiskBytes         int64                   `json:"diskBytes"`
+	IPAddresses       int                     `json:"ipAddresses"`
+	NetworkInterfaces []agentNetworkInterface `json:"networkInterfaces"`
+}
+
+type agentNetworkInterface struct {
+	Name            string   `json:"name"`
+	HardwareAddress string   `json:"hardwareAddress"`
+	MTU             int      `json:"mtu"`
+	SpeedMbps       int      `json:"speedMbps"`
+	Addresses       []string `json:"addresses"`
 }
 
 type agentUsedResources struct {
-	Memory int `json:"memory"`
+	Memory        int   `json:"memory"`
+	DiskBytes     int64 `json:"diskBytes"`
+	FreeDiskBytes int64 `json:"freeDiskBytes"`
 }
 
 type agentReservedResources struct {
-	Memory int `json:"memory"`
+	Memory    int   `json:"memory"`
+	DiskBytes int64 `json:"diskBytes"`
+}
+
+type agentCondition struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+type drainedGameserver struct {
+	UUID        string `json:"uuid"`
+	Phase       string `json:"phase"`
+	TargetAgent string `json:"targetAgent,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+type agentDrain struct {
+	State       string              `json:"state"`
+	RequestedBy string              `json:"requestedBy"`
+	StartedAt   time.Time           `json:"startedAt"`
+	FinishedAt  *time.Time          `json:"finishedAt,omitempty"`
+	Gameservers []drainedGameserver `json:"gameservers"`
 }
 
 type getAgentReponse struct {
@@ -28,24 +67,63 @@ type getAgentReponse struct {
 	Resources         *agentResources         `json:"resources"`
 	UsedResources     *agentUsedResources     `json:"usedResources"`
 	ReservedResources *agentReservedResources `json:"reservedResources"`
+	Conditions        []agentCondition        `json:"conditions"`
+	Labels            map[string]string       `json:"labels"`
+	Runtime           string                  `json:"runtime"`
+	Cordoned          bool                    `json:"cordoned"`
+	Drain             *agentDrain             `json:"drain"`
 }
 
 type listAgentsResponse []getAgentReponse
 
+// createBootstrapTokenRequest restricts the token to a hostname.
+// TTL is in seconds
+type createBootstrapTokenRequest struct {
+	Hostname string `json:"hostname"`
+	TTL      int    `json:"ttl"`
+}
+
+type createBootstrapTokenResponse struct {
+	Token     string    `json:"token"`
+	Hostname  string    `json:"hostname,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+const defaultBootstrapTokenTTL = 24 * time.Hour
+
 type agentsAPI struct {
 	agentsStore     server.AgentStore
 	gameserverStore server.GameserverStore
+	tokenStore      server.BootstrapTokenStore
 }
 
 // MountAgentsAPI mounts the Agents API
-func MountAgentsAPI(r *gin.Engine, agentsStore server.AgentStore, gameserverStore server.GameserverStore) {
+func MountAgentsAPI(r *gin.Engine, agentsStore server.AgentStore, gameserverStore server.GameserverStore, tokenStore server.BootstrapTokenStore) {
 	api := agentsAPI{
 		agentsStore,
 		gameserverStore,
+		tokenStore,
 	}
 
+	r.POST("/bootstrap-tokens/", auth.Authorize(auth.ResourceAgents, auth.VerbCreate), audit.Log("bootstrap-token.create"), api.createBootstrapToken)
+
 	group := r.Group("/agents/")
-	group.GET("/", auth.Auth0Permission("read:agents"), api.getAgents)
+	group.GET("/", auth.Authorize(auth.ResourceAgents, auth.VerbRead), api.getAgents)
+	group.POST("/:hostname/cordon/", auth.Authorize(auth.ResourceAgents, auth.VerbUpdate), audit.Log("agent.cordon"), api.cordonAgent)
+	group.POST("/:hostname/uncordon/", auth.Authorize(auth.ResourceAgents, auth.VerbUpdate), audit.Log("agent.uncordon"), api.uncordonAgent)
+	group.POST("/:hostname/drain/", auth.Authorize(auth.ResourceAgents, auth.VerbUpdate), audit.Log("agent.drain"), api.drainAgent)
+}
+
+// maintenanceSummary describes the maintenance in the audit log
+func maintenanceSummary(maintenance *server.AgentMaintenance) string {
+	summary := "schedulable"
+	if maintenance.Cordoned {
+		summary = "cordoned"
+	}
+	if maintenance.Drain != nil {
+		summary += ", drain " + maintenance.Drain.State
+	}
+	return summary
 }
 
 func (api *agentsAPI) getAgents(c *gin.Context) {
@@ -59,30 +137,236 @@ func (api *agentsAPI) getAgents(c *gin.Context) {
 
 	for _, agent := range agents {
 		var reservedMemory *int
+		var reservedDisk int64
 		gameservers, err := server.GetGameserversForAgent(agent.State.Hostname, api.gameserverStore)
 		if err == nil {
 			acc := 0
 			for _, gs := range gameservers {
+				reservedDisk += gs.Deployment.ResourceRequirements.DiskReservation
+				if gs.Deployment.Stopped {
+					continue
+				}
 				acc += int(gs.Deployment.ResourceRequirements.MemoryReservation)
 			}
 			reservedMemory = &acc
 		}
 
+		usedResources := &agentUsedResources{
+			Memory: int(agent.State.ResourceUsage.Memory),
+		}
+		if free := agent.State.ResourceUsage.DiskFree; agent.State.Resources.Disk > 0 {
+			usedResources.DiskBytes = agent.State.Resources.Disk - free
+			usedResources.FreeDiskBytes = free
+		}
+
+		conditions := make([]agentCondition, 0)
+		for _, condition := range agent.State.Conditions {
+			conditions = append(conditions, agentCondition{
+				Type:    condition.Type,
+				Message: condition.Message,
+			})
+		}
+
+		networkInterfaces := make([]agentNetworkInterface, 0)
+		for _, nic := range agent.State.Resources.NetworkInterfaces {
+			networkInterfaces = append(networkInterfaces, agentNetworkInterface{
+				Name:            nic.Name,
+				HardwareAddress: nic.HardwareAddress,
+				MTU:             int(nic.Mtu),
+				SpeedMbps:       int(nic.Speed),
+				Addresses:       nic.Addresses,
+			})
+		}
+
 		response = append(response, getAgentReponse{
 			Hostname: agent.State.Hostname,
 			Resources: &agentResources{
-				Cpus:        int(agent.State.Resources.Cpus),
-				Memory:      int(agent.State.Resources.Memory),
-				IPAddresses: int(agent.State.Resources.IpAddresses),
-			},
-			UsedResources: &agentUsedResources{
-				Memory: int(agent.State.ResourceUsage.Memory),
+				Cpus:              int(agent.State.Resources.Cpus),
+				CPUMillicores:     int(agent.State.Resources.CpuMillicores),
+				Memory:            int(agent.State.Resources.Memory),
+				DiskBytes:         agent.State.Resources.Disk,
+				IPAddresses:       int(agent.State.Resources.IpAddresses),
+				NetworkInterfaces: networkInterfaces,
 			},
+			UsedResources: usedResources,
 			ReservedResources: &agentReservedResources{
-				Memory: *reservedMemory,
+				Memory:    *reservedMemory,
+				DiskBytes: reservedDisk,
 			},
+			Conditions: conditions,
+			Labels:     agent.State.Labels,
+			Runtime:    agent.State.Runtime,
+			Cordoned:   agent.Maintenance.Cordoned,
+			Drain:      newAgentDrain(agent.Maintenance.Drain),
 		})
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+func newAgentDrain(drain *server.AgentDrain) *agentDrain {
+	if drain == nil {
+		return nil
+	}
+
+	gameservers := make([]drainedGameserver, 0, len(drain.Gameservers))
+	for _, gs := range drain.Gameservers {
+		gameservers = append(gameservers, drainedGameserver{
+			UUID:        gs.UUID,
+			Phase:       gs.Phase,
+			TargetAgent: gs.TargetAgent,
+			Error:       gs.Error,
+		})
+	}
+
+	return &agentDrain{
+		State:       drain.State,
+		RequestedBy: drain.RequestedBy,
+		StartedAt:   drain.StartedAt,
+		FinishedAt:  drain.FinishedAt,
+		Gameservers: gameservers,
+	}
+}
+
+func (api *agentsAPI) getAgent(c *gin.Context) (*server.Agent, bool) {
+	agent, err := api.agentsStore.GetAgent(c.Param("hostname"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return nil, false
+	}
+	return agent, true
+}
+
+func (api *agentsAPI) cordonAgent(c *gin.Context) {
+	agent, ok := api.getAgent(c)
+	if !ok {
+		return
+	}
+
+	before := maintenanceSummary(&agent.Maintenance)
+	agent.Maintenance.Cordoned = true
+	audit.SetChange(c, before, maintenanceSummary(&agent.Maintenance))
+	if err := api.agentsStore.UpdateAgentMaintenance(agent.State.Hostname, &agent.Maintenance); err != nil {
+		log.Printf("AgentAPI cordonAgent error: %s", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot cordon agent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+// uncordonAgent allows scheduling on the agent again and cancels its drain.
+// Gameservers stopped by the cancelled drain are started again
+func (api *agentsAPI) uncordonAgent(c *gin.Context) {
+	agent, ok := api.getAgent(c)
+	if !ok {
+		return
+	}
+
+	before := maintenanceSummary(&agent.Maintenance)
+	maintenance := agent.Maintenance
+	maintenance.Cordoned = false
+	if drain := maintenance.Drain; drain != nil && drain.State == server.DrainInProgress {
+		api.cancelDrain(drain)
+	}
+	audit.SetChange(c, before, maintenanceSummary(&maintenance))
+
+	if err := api.agentsStore.UpdateAgentMaintenance(agent.State.Hostname, &maintenance); err != nil {
+		log.Printf("AgentAPI uncordonAgent error: %s", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot uncordon agent"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{})
+}
+
+func (api *agentsAPI) cancelDrain(drain *server.AgentDrain) {
+	for _, entry := range drain.Gameservers {
+		// Migrations started by the drain are finished on their own
+		if entry.Phase != server.DrainStopping || !entry.WasRunning || entry.OperationID != "" {
+			continue
+		}
+
+		api.startGameserver(entry.UUID)
+	}
+
+	now := time.Now()
+	drain.State = server.DrainCancelled
+	drain.FinishedAt = &now
+}
+
+// startGameserver starts the gameserver stopped by a cancelled drain
+func (api *agentsAPI) startGameserver(UUID string) {
+	unlock, err := api.gameserverStore.LockGameserver(UUID)
+	if err != nil {
+		log.Printf("AgentAPI cancelDrain cannot lock %s: %s", UUID, err)
+		return
+	}
+	defer unlock()
+
+	gameserver, err := api.gameserverStore.GetGameserver(UUID)
+	if err != nil {
+		return
+	}
+	gameserver.Deployment.Stopped = false
+	if err := api.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		log.Printf("AgentAPI cancelDrain cannot start %s: %s", UUID, err)
+	}
+}
+
+func (api *agentsAPI) drainAgent(c *gin.Context) {
+	agent, ok := api.getAgent(c)
+	if !ok {
+		return
+	}
+
+	before := maintenanceSummary(&agent.Maintenance)
+	if err := server.StartAgentDrain(api.agentsStore, agent, c.GetString("userID")); err != nil {
+		log.Printf("AgentAPI drainAgent error: %s", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot drain agent"})
+		return
+	}
+	audit.SetChange(c, before, maintenanceSummary(&agent.Maintenance))
+
+	c.JSON(http.StatusAccepted, newAgentDrain(agent.Maintenance.Drain))
+}
+
+// createBootstrapToken returns a one-time token for enrolling a new agent.
+// The token is not stored and cannot be shown again
+func (api *agentsAPI) createBootstrapToken(c *gin.Context) {
+	var body createBootstrapTokenRequest
+	if c.Request.Body != nil {
+		if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if body.TTL < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ttl cannot be negative"})
+		return
+	}
+
+	ttl := defaultBootstrapTokenTTL
+	if body.TTL > 0 {
+		ttl = time.Duration(body.TTL) * time.Second
+	}
+
+	token, bootstrapToken, err := server.NewBootstrapToken(body.Hostname, c.GetString("userID"), ttl)
+	if err != nil {
+		log.Printf("AgentAPI createBootstrapToken error: %s", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot create bootstrap token"})
+		return
+	}
+	if err := api.tokenStore.CreateBootstrapToken(bootstrapToken); err != nil {
+		log.Printf("AgentAPI createBootstrapToken error: %s", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot create bootstrap token"})
+		return
+	}
+
+	audit.SetTarget(c, bootstrapToken.Hostname)
+	c.JSON(http.StatusCreated, createBootstrapTokenResponse{
+		Token:     token,
+		Hostname:  bootstrapToken.Hostname,
+		ExpiresAt: bootstrapToken.ExpiresAt,
+	})
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Trojan295/chinchilla/mocks"
 	"github.com/Trojan295/chinchilla/proto"
@@ -24,12 +26,32 @@ func TestListAgents(t *testing.T) {
 			State: proto.AgentState{
 				Hostname: "localhost",
 				Resources: &proto.AgentResources{
-					Cpus:        2,
-					Memory:      2048,
-					IpAddresses: 2,
+					Cpus:          2,
+					CpuMillicores: 2500,
+					Memory:        2048,
+					Disk:          10 << 30,
+					IpAddresses:   2,
+					NetworkInterfaces: []*proto.NetworkInterface{
+						{Name: "eth0", Mtu: 1500, Speed: 1000, Addresses: []string{"10.0.0.1/24"}},
+					},
 				},
 				ResourceUsage: &proto.AgentResourceUsage{
-					Memory: 1024,
+					Memory:   1024,
+					DiskFree: 1 << 30,
+				},
+				Conditions: []*proto.AgentCondition{
+					{Type: "LowDisk", Message: "Only 10% free"},
+				},
+				Labels: map[string]string{"region": "eu-west"},
+			},
+			Maintenance: server.AgentMaintenance{
+				Cordoned: true,
+				Drain: &server.AgentDrain{
+					State:       server.DrainInProgress,
+					RequestedBy: "agent",
+					Gameservers: []server.DrainedGameserver{
+						{UUID: "uuid1", Phase: server.DrainStopping},
+					},
 				},
 			},
 		},
@@ -42,13 +64,14 @@ func TestListAgents(t *testing.T) {
 				Agent: "localhost",
 				ResourceRequirements: &proto.ResourceRequirements{
 					MemoryReservation: 1024,
+					DiskReservation:   2 << 30,
 				},
 			},
 		},
 	}, nil).AnyTimes()
 
 	router := utils.SetupRouter()
-	MountAgentsAPI(router, agentStore, gameserverStore)
+	MountAgentsAPI(router, agentStore, gameserverStore, mocks.NewMockBootstrapTokenStore(ctrl))
 
 	claims := map[string]interface{}{
 		"permissions": []string{"read:agents"},
@@ -70,6 +93,19 @@ func TestListAgents(t *testing.T) {
 	assert.Equal(t, 1024, res[0].UsedResources.Memory)
 	assert.Equal(t, 1024, res[0].ReservedResources.Memory)
 	assert.Equal(t, 2, res[0].Resources.IPAddresses)
+	assert.Equal(t, 2500, res[0].Resources.CPUMillicores)
+	assert.Equal(t, int64(10<<30), res[0].Resources.DiskBytes)
+	assert.Equal(t, []agentNetworkInterface{
+		{Name: "eth0", MTU: 1500, SpeedMbps: 1000, Addresses: []string{"10.0.0.1/24"}},
+	}, res[0].Resources.NetworkInterfaces)
+	assert.Equal(t, int64(9<<30), res[0].UsedResources.DiskBytes)
+	assert.Equal(t, int64(1<<30), res[0].UsedResources.FreeDiskBytes)
+	assert.Equal(t, int64(2<<30), res[0].ReservedResources.DiskBytes)
+	assert.Equal(t, []agentCondition{{Type: "LowDisk", Message: "Only 10% free"}}, res[0].Conditions)
+	assert.Equal(t, map[string]string{"region": "eu-west"}, res[0].Labels)
+	assert.True(t, res[0].Cordoned)
+	assert.Equal(t, "InProgress", res[0].Drain.State)
+	assert.Equal(t, []drainedGameserver{{UUID: "uuid1", Phase: "Stopping"}}, res[0].Drain.Gameservers)
 }
 
 func TestListAgentsWhenNotAuthorized(t *testing.T) {
@@ -80,7 +116,7 @@ func TestListAgentsWhenNotAuthorized(t *testing.T) {
 	gameserverStore := mocks.NewMockGameserverStore(ctrl)
 
 	router := utils.SetupRouter()
-	MountAgentsAPI(router, agentStore, gameserverStore)
+	MountAgentsAPI(router, agentStore, gameserverStore, mocks.NewMockBootstrapTokenStore(ctrl))
 
 	claims := map[string]interface{}{
 		"permissions": []string{},
@@ -94,3 +130,127 @@ func TestListAgentsWhenNotAuthorized(t *testing.T) {
 
 	assert.Equal(t, 403, w.Code)
 }
+
+func TestDrainAgent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var maintenance *server.AgentMaintenance
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().GetAgent("localhost").Return(&server.Agent{
+		State: proto.AgentState{Hostname: "localhost"},
+	}, nil)
+	agentStore.EXPECT().
+		UpdateAgentMaintenance("localhost", gomock.Any()).
+		Do(func(hostname string, m *server.AgentMaintenance) { maintenance = m }).
+		Return(nil)
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+
+	router := utils.SetupRouter()
+	MountAgentsAPI(router, agentStore, gameserverStore, mocks.NewMockBootstrapTokenStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub":         "admin",
+		"permissions": []string{"write:agents"},
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/agents/localhost/drain/", nil)
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 202, w.Code)
+	assert.True(t, maintenance.Cordoned)
+	assert.Equal(t, server.DrainInProgress, maintenance.Drain.State)
+	assert.Equal(t, "admin", maintenance.Drain.RequestedBy)
+}
+
+func TestUncordonAgentCancelsDrain(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var maintenance *server.AgentMaintenance
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().GetAgent("localhost").Return(&server.Agent{
+		State: proto.AgentState{Hostname: "localhost"},
+		Maintenance: server.AgentMaintenance{
+			Cordoned: true,
+			Drain: &server.AgentDrain{
+				State: server.DrainInProgress,
+				Gameservers: []server.DrainedGameserver{
+					{UUID: "uuid1", Phase: server.DrainStopping, WasRunning: true},
+					{UUID: "uuid2", Phase: server.DrainMigrated, WasRunning: true},
+				},
+			},
+		},
+	}, nil)
+	agentStore.EXPECT().
+		UpdateAgentMaintenance("localhost", gomock.Any()).
+		Do(func(hostname string, m *server.AgentMaintenance) { maintenance = m }).
+		Return(nil)
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().LockGameserver("uuid1").Return(func() {}, nil)
+	gameserverStore.EXPECT().GetGameserver("uuid1").Return(&server.Gameserver{
+		Definition: server.GameserverDefinition{UUID: "uuid1"},
+		Deployment: &proto.GameserverDeployment{Agent: "localhost", Stopped: true},
+	}, nil)
+	gameserverStore.EXPECT().
+		UpdateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) { assert.False(t, gs.Deployment.Stopped) }).
+		Return(nil)
+
+	router := utils.SetupRouter()
+	MountAgentsAPI(router, agentStore, gameserverStore, mocks.NewMockBootstrapTokenStore(ctrl))
+
+	claims := map[string]interface{}{
+		"permissions": []string{"write:agents"},
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/agents/localhost/uncordon/", nil)
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.False(t, maintenance.Cordoned)
+	assert.Equal(t, server.DrainCancelled, maintenance.Drain.State)
+}
+
+func TestCreateBootstrapToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var stored *server.BootstrapToken
+	tokenStore := mocks.NewMockBootstrapTokenStore(ctrl)
+	tokenStore.EXPECT().
+		CreateBootstrapToken(gomock.Any()).
+		Do(func(token *server.BootstrapToken) { stored = token }).
+		Return(nil)
+
+	router := utils.SetupRouter()
+	MountAgentsAPI(router, mocks.NewMockAgentStore(ctrl), mocks.NewMockGameserverStore(ctrl), tokenStore)
+
+	claims := map[string]interface{}{
+		"sub":         "admin",
+		"permissions": []string{"write:agents"},
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/bootstrap-tokens/", strings.NewReader(`{"hostname": "agent1", "ttl": 600}`))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+
+	router.ServeHTTP(w, req)
+
+	var resp createBootstrapTokenResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	assert.Equal(t, 201, w.Code)
+	assert.NotEmpty(t, resp.Token)
+	assert.Equal(t, server.HashToken(resp.Token), stored.Hash)
+	assert.Equal(t, "agent1", stored.Hostname)
+	assert.Equal(t, "admin", stored.CreatedBy)
+	assert.WithinDuration(t, time.Now().Add(10*time.Minute), stored.ExpiresAt, time.Minute)
+}
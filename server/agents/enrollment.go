@@ -0,0 +1,67 @@
+package agents
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Enroll issues the first client certificate of an agent,
+// which presents a valid bootstrap token
+func (rpcServer AgentServiceServer) Enroll(ctx context.Context, req *proto.EnrollRequest) (*proto.CertificateResponse, error) {
+	if rpcServer.CA == nil {
+		return nil, status.Error(codes.Unavailable, "TLS is not enabled on the server")
+	}
+	if req.Hostname == "" || req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "hostname and token are required")
+	}
+
+	token, err := rpcServer.BootstrapTokenStore.UseBootstrapToken(server.HashToken(req.Token))
+	if err != nil {
+		log.Printf("AgentServiceServer Enroll: invalid bootstrap token from %s: %v", req.Hostname, err)
+		return nil, status.Error(codes.PermissionDenied, "invalid bootstrap token")
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, status.Error(codes.PermissionDenied, "bootstrap token expired")
+	}
+	if token.Hostname != "" && token.Hostname != req.Hostname {
+		log.Printf("AgentServiceServer Enroll: token for %s used by %s", token.Hostname, req.Hostname)
+		return nil, status.Error(codes.PermissionDenied, "bootstrap token was issued for another agent")
+	}
+
+	log.Printf("AgentServiceServer Enroll: enrolling agent %s", req.Hostname)
+	return rpcServer.signCertificate(req.Csr, req.Hostname)
+}
+
+// RenewCertificate issues a new client certificate
+// for the identity of the current one
+func (rpcServer AgentServiceServer) RenewCertificate(ctx context.Context, req *proto.RenewCertificateRequest) (*proto.CertificateResponse, error) {
+	if rpcServer.CA == nil {
+		return nil, status.Error(codes.Unavailable, "TLS is not enabled on the server")
+	}
+
+	identity, _ := agentIdentity(ctx)
+	if identity == "" {
+		return nil, status.Error(codes.Unauthenticated, "client certificate required")
+	}
+
+	return rpcServer.signCertificate(req.Csr, identity)
+}
+
+func (rpcServer AgentServiceServer) signCertificate(csr []byte, hostname string) (*proto.CertificateResponse, error) {
+	certificate, err := rpcServer.CA.SignAgentCSR(csr, hostname, rpcServer.CertificateValidity)
+	if err != nil {
+		log.Printf("AgentServiceServer signCertificate error: %v", err)
+		return nil, status.Error(codes.InvalidArgument, "invalid certificate signing request")
+	}
+
+	return &proto.CertificateResponse{
+		Certificate:   certificate,
+		CaCertificate: rpcServer.CA.CertificatePEM,
+	}, nil
+}
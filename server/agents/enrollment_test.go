@@ -0,0 +1,181 @@
+package agents
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/pki"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func testCA(t *testing.T) *pki.CA {
+	dir, _ := ioutil.TempDir("", "pki")
+	defer os.RemoveAll(dir)
+
+	ca, err := pki.LoadOrCreateCA(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ca
+}
+
+// agentContext returns a context of a TLS connection, optionally
+// authenticated with a client certificate of the hostname
+func agentContext(t *testing.T, ca *pki.CA, hostname string) context.Context {
+	state := tls.ConnectionState{}
+	if hostname != "" {
+		key, _ := pki.GenerateKey()
+		csr, _ := pki.CertificateRequest(key, hostname)
+		certificatePEM, err := ca.SignAgentCSR(csr, hostname, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block, _ := pem.Decode(certificatePEM)
+		certificate, _ := x509.ParseCertificate(block.Bytes)
+		state.VerifiedChains = [][]*x509.Certificate{{certificate}}
+	}
+
+	return peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: state},
+	})
+}
+
+func certificateHostname(t *testing.T, res *proto.CertificateResponse) string {
+	block, _ := pem.Decode(res.Certificate)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate.Subject.CommonName
+}
+
+func TestEnrollWithBootstrapToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ca := testCA(t)
+	tokenStore := mocks.NewMockBootstrapTokenStore(ctrl)
+	tokenStore.EXPECT().
+		UseBootstrapToken(server.HashToken("token1")).
+		Return(&server.BootstrapToken{Hostname: "agent1", ExpiresAt: time.Now().Add(time.Hour)}, nil).
+		Times(1)
+
+	rpcServer := AgentServiceServer{
+		BootstrapTokenStore: tokenStore,
+		CA:                  ca,
+		CertificateValidity: time.Hour,
+	}
+
+	key, _ := pki.GenerateKey()
+	csr, _ := pki.CertificateRequest(key, "agent1")
+	res, err := rpcServer.Enroll(agentContext(t, ca, ""), &proto.EnrollRequest{
+		Hostname: "agent1",
+		Token:    "token1",
+		Csr:      csr,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "agent1", certificateHostname(t, res))
+	assert.Equal(t, ca.CertificatePEM, res.CaCertificate)
+}
+
+func TestEnrollRejectsInvalidTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokenStore := mocks.NewMockBootstrapTokenStore(ctrl)
+	tokenStore.EXPECT().
+		UseBootstrapToken(server.HashToken("used")).
+		Return(nil, errors.New("Key not found"))
+	tokenStore.EXPECT().
+		UseBootstrapToken(server.HashToken("expired")).
+		Return(&server.BootstrapToken{ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+	tokenStore.EXPECT().
+		UseBootstrapToken(server.HashToken("agent2")).
+		Return(&server.BootstrapToken{Hostname: "agent2", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	rpcServer := AgentServiceServer{
+		BootstrapTokenStore: tokenStore,
+		CA:                  testCA(t),
+	}
+
+	for _, token := range []string{"used", "expired", "agent2"} {
+		_, err := rpcServer.Enroll(context.Background(), &proto.EnrollRequest{
+			Hostname: "agent1",
+			Token:    token,
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err), token)
+	}
+}
+
+func TestRenewCertificateKeepsIdentity(t *testing.T) {
+	ca := testCA(t)
+	rpcServer := AgentServiceServer{CA: ca, CertificateValidity: time.Hour}
+
+	key, _ := pki.GenerateKey()
+	csr, _ := pki.CertificateRequest(key, "agent2")
+
+	res, err := rpcServer.RenewCertificate(agentContext(t, ca, "agent1"), &proto.RenewCertificateRequest{Csr: csr})
+	assert.NoError(t, err)
+	assert.Equal(t, "agent1", certificateHostname(t, res))
+
+	_, err = rpcServer.RenewCertificate(agentContext(t, ca, ""), &proto.RenewCertificateRequest{Csr: csr})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestRegisterRejectsHostnameMismatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ca := testCA(t)
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().
+		RegisterAgent(gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	rpcServer := AgentServiceServer{AgentStore: agentStore}
+
+	_, err := rpcServer.Register(agentContext(t, ca, "agent1"), &proto.AgentState{Hostname: "agent2"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = rpcServer.Register(agentContext(t, ca, ""), &proto.AgentState{Hostname: "agent1"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = rpcServer.Register(agentContext(t, ca, "agent1"), &proto.AgentState{Hostname: "agent1"})
+	assert.NoError(t, err)
+}
+
+func TestCertificateInterceptorAllowsOnlyEnroll(t *testing.T) {
+	ca := testCA(t)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &proto.Empty{}, nil
+	}
+	register := &grpc.UnaryServerInfo{FullMethod: "/proto.AgentService/Register"}
+	enroll := &grpc.UnaryServerInfo{FullMethod: enrollMethod}
+
+	_, err := UnaryCertificateInterceptor(agentContext(t, ca, ""), nil, register, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = UnaryCertificateInterceptor(agentContext(t, ca, ""), nil, enroll, handler)
+	assert.NoError(t, err)
+
+	_, err = UnaryCertificateInterceptor(agentContext(t, ca, "agent1"), nil, register, handler)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,73 @@
+package agents
+
+import (
+	"fmt"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+)
+
+// recordStatusChanges compares the gameservers reported by the agent
+// with its previous state, records the changes as gameserver events
+// and publishes the updates
+func (rpcServer AgentServiceServer) recordStatusChanges(agentState *proto.AgentState) {
+	if len(agentState.RunningGameservers) == 0 {
+		return
+	}
+
+	previousGameservers := make(map[string]*proto.Gameserver)
+	if previous, err := rpcServer.AgentStore.GetAgent(agentState.Hostname); err == nil {
+		for _, gameserver := range previous.State.RunningGameservers {
+			previousGameservers[gameserver.UUID] = gameserver
+		}
+	}
+
+	for _, gameserver := range agentState.RunningGameservers {
+		previous := previousGameservers[gameserver.UUID]
+		for _, event := range statusChanges(agentState.Hostname, previous, gameserver) {
+			server.RecordGameserverEvent(rpcServer.EventStore, gameserver.UUID, event.Type, event.Message)
+		}
+		rpcServer.publishUpdates(previous, gameserver)
+	}
+}
+
+// statusChanges returns the events describing the change of the gameserver.
+// The previous state is nil, when the agent didn't report the gameserver before
+func statusChanges(hostname string, previous, current *proto.Gameserver) []server.GameserverEvent {
+	events := make([]server.GameserverEvent, 0)
+	add := func(eventType, format string, args ...interface{}) {
+		events = append(events, server.GameserverEvent{Type: eventType, Message: fmt.Sprintf(format, args...)})
+	}
+
+	crashed := false
+	if previous == nil || previous.Status != current.Status {
+		switch current.Status {
+		case proto.GameserverStatus_PULLING_IMAGE:
+			add(server.EventImagePulling, "Pulling image on agent %s", hostname)
+		case proto.GameserverStatus_RUNNING:
+			add(server.EventStarted, "Started on agent %s", hostname)
+		case proto.GameserverStatus_STOPPED:
+			if previous != nil {
+				add(server.EventStopped, "Stopped on agent %s with exit code %d", hostname, current.LastExitCode)
+			}
+		case proto.GameserverStatus_CRASH_LOOP, proto.GameserverStatus_ERROR:
+			crashed = true
+			add(server.EventCrashed, "Crashed on agent %s with exit code %d: %s", hostname, current.LastExitCode, current.Info)
+		}
+	}
+
+	if !crashed && previous != nil && current.RestartCount > previous.RestartCount {
+		add(server.EventCrashed, "Crashed on agent %s with exit code %d and was restarted, %d restarts",
+			hostname, current.LastExitCode, current.RestartCount)
+	}
+
+	previousHealth := proto.HealthStatus_HEALTH_UNKNOWN
+	if previous != nil {
+		previousHealth = previous.Health
+	}
+	if current.Health != previousHealth && current.Health != proto.HealthStatus_HEALTH_UNKNOWN {
+		add(server.EventHealthChanged, "Health changed from %s to %s", previousHealth, current.Health)
+	}
+
+	return events
+}
@@ -2,36 +2,84 @@ package agents
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/Trojan295/chinchilla/proto"
 	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/pki"
 )
 
 // AgentServiceServer implements the gRPC AgentService server
 type AgentServiceServer struct {
 	AgentStore      server.AgentStore
 	GameserverStore server.GameserverStore
+	EventStore      server.EventStore
+	OperationStore  server.OperationStore
+	// Updates publishes the gameserver changes reported by the agents
+	Updates *server.UpdateBus
+	// VolumeDir holds the volume archives of the migrated gameservers
+	VolumeDir           string
+	BootstrapTokenStore server.BootstrapTokenStore
+	// CA signs the agent certificates. It's nil, when TLS is disabled
+	CA                  *pki.CA
+	CertificateValidity time.Duration
 }
 
 // Register handles registration of a new agent
 func (rpcServer AgentServiceServer) Register(ctx context.Context, agentState *proto.AgentState) (*proto.Empty, error) {
+	if err := checkAgentIdentity(ctx, agentState.Hostname); err != nil {
+		log.Printf("agentServiceServer Register: rejected agent %s: %v", agentState.Hostname, err)
+		return nil, err
+	}
+
 	log.Printf("agentServiceServer Register: register agent %s",
 		agentState.Hostname)
 
+	for _, result := range agentState.ActionResults {
+		rpcServer.recordActionResult(agentState.Hostname, result)
+	}
+	rpcServer.recordStatusChanges(agentState)
+
 	agent := &server.Agent{
 		State:       *agentState,
 		LastContact: time.Now(),
 	}
+	agent.State.ActionResults = nil
 
 	err := rpcServer.AgentStore.RegisterAgent(agent)
 
 	return &proto.Empty{}, err
 }
 
+func (rpcServer AgentServiceServer) recordActionResult(hostname string, result *proto.GameserverActionResult) {
+	eventType := server.EventActionSucceeded
+	message := fmt.Sprintf("%s on agent %s took %dms", result.Action, hostname, result.Duration)
+	if !result.Success {
+		eventType = server.EventActionFailed
+		message = fmt.Sprintf("%s on agent %s failed: %s", result.Action, hostname, result.Error)
+	}
+
+	server.RecordGameserverEvent(rpcServer.EventStore, result.UUID, eventType, message)
+
+	if shutdown := result.Shutdown; shutdown != nil {
+		if shutdown.Graceful {
+			message = fmt.Sprintf("Gameserver shut down gracefully using %s in %dms", shutdown.Method, shutdown.Duration)
+			server.RecordGameserverEvent(rpcServer.EventStore, result.UUID, server.EventShutdown, message)
+		} else {
+			message = fmt.Sprintf("Gameserver did not shut down using %s within the timeout and was killed", shutdown.Method)
+			server.RecordGameserverEvent(rpcServer.EventStore, result.UUID, server.EventShutdownKilled, message)
+		}
+	}
+}
+
 // GetGameserverDeployments func
 func (rpcServer AgentServiceServer) GetGameserverDeployments(ctx context.Context, req *proto.GetGameserverDeploymentsRequest) (*proto.GetGameserverDeploymentsResponse, error) {
+	if err := checkAgentIdentity(ctx, req.Hostname); err != nil {
+		return nil, err
+	}
+
 	gameservers, err := server.GetGameserversForAgent(req.Hostname, rpcServer.GameserverStore)
 	if err != nil {
 		log.Printf("AgentServiceServer GetGameServers error: %v", err)
@@ -43,7 +91,35 @@ func (rpcServer AgentServiceServer) GetGameserverDeployments(ctx context.Context
 		runConfigs = append(runConfigs, gs.Deployment)
 	}
 
+	migrationTasks, err := rpcServer.migrationTasks(req.Hostname)
+	if err != nil {
+		log.Printf("AgentServiceServer GetGameServers error: %v", err)
+		return nil, err
+	}
+
 	return &proto.GetGameserverDeploymentsResponse{
-		Deployments: runConfigs,
+		Deployments:    runConfigs,
+		MigrationTasks: migrationTasks,
 	}, nil
 }
+
+// RequestDrain cordons the agent and moves its gameservers to other agents.
+// It's sent by agents, which are shutting down
+func (rpcServer AgentServiceServer) RequestDrain(ctx context.Context, req *proto.DrainRequest) (*proto.Empty, error) {
+	if err := checkAgentIdentity(ctx, req.Hostname); err != nil {
+		return nil, err
+	}
+
+	agent, err := rpcServer.AgentStore.GetAgent(req.Hostname)
+	if err != nil {
+		log.Printf("AgentServiceServer RequestDrain error: %v", err)
+		return nil, err
+	}
+
+	if err := server.StartAgentDrain(rpcServer.AgentStore, agent, "agent"); err != nil {
+		log.Printf("AgentServiceServer RequestDrain error: %v", err)
+		return nil, err
+	}
+
+	return &proto.Empty{}, nil
+}
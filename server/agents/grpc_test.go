@@ -0,0 +1,252 @@
+package agents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRecordsActionResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().
+		RegisterAgent(gomock.Any()).
+		Do(func(agent *server.Agent) {
+			assert.Equal(t, "localhost", agent.State.Hostname)
+			assert.Nil(t, agent.State.ActionResults)
+		}).
+		Return(nil)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent("uuid1", gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			assert.Equal(t, server.EventActionSucceeded, event.Type)
+		}).
+		Return(nil)
+	eventStore.EXPECT().
+		AddGameserverEvent("uuid2", gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			assert.Equal(t, server.EventActionFailed, event.Type)
+			assert.Equal(t, "ACTION_CREATE on agent localhost failed: no space left", event.Message)
+		}).
+		Return(nil)
+
+	rpcServer := AgentServiceServer{
+		AgentStore: agentStore,
+		EventStore: eventStore,
+	}
+
+	_, err := rpcServer.Register(context.Background(), &proto.AgentState{
+		Hostname: "localhost",
+		ActionResults: []*proto.GameserverActionResult{
+			{
+				UUID:     "uuid1",
+				Action:   proto.GameserverAction_ACTION_START,
+				Success:  true,
+				Duration: 120,
+			},
+			{
+				UUID:   "uuid2",
+				Action: proto.GameserverAction_ACTION_CREATE,
+				Error:  "no space left",
+			},
+		},
+	})
+
+	assert.NoError(t, err)
+}
+
+func TestRegisterRecordsShutdownOutcome(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().RegisterAgent(gomock.Any()).Return(nil)
+
+	var events []*server.GameserverEvent
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent("uuid1", gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			events = append(events, event)
+		}).
+		Return(nil).
+		Times(2)
+
+	rpcServer := AgentServiceServer{
+		AgentStore: agentStore,
+		EventStore: eventStore,
+	}
+
+	_, err := rpcServer.Register(context.Background(), &proto.AgentState{
+		Hostname: "localhost",
+		ActionResults: []*proto.GameserverActionResult{
+			{
+				UUID:    "uuid1",
+				Action:  proto.GameserverAction_ACTION_STOP,
+				Success: true,
+				Shutdown: &proto.ShutdownResult{
+					Method: "signal SIGTERM",
+				},
+			},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, server.EventShutdownKilled, events[1].Type)
+	assert.Equal(t, "Gameserver did not shut down using signal SIGTERM within the timeout and was killed", events[1].Message)
+}
+
+func TestRequestDrainCordonsAgent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var maintenance *server.AgentMaintenance
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().GetAgent("localhost").Return(&server.Agent{
+		State: proto.AgentState{Hostname: "localhost"},
+	}, nil)
+	agentStore.EXPECT().
+		UpdateAgentMaintenance("localhost", gomock.Any()).
+		Do(func(hostname string, m *server.AgentMaintenance) { maintenance = m }).
+		Return(nil)
+
+	rpcServer := AgentServiceServer{
+		AgentStore: agentStore,
+	}
+
+	_, err := rpcServer.RequestDrain(context.Background(), &proto.DrainRequest{Hostname: "localhost"})
+
+	assert.NoError(t, err)
+	assert.True(t, maintenance.Cordoned)
+	assert.Equal(t, server.DrainInProgress, maintenance.Drain.State)
+	assert.Equal(t, "agent", maintenance.Drain.RequestedBy)
+}
+
+func TestRegisterRecordsStatusChanges(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().
+		GetAgent("localhost").
+		Return(&server.Agent{State: proto.AgentState{
+			Hostname: "localhost",
+			RunningGameservers: []*proto.Gameserver{
+				{UUID: "uuid1", Status: proto.GameserverStatus_PULLING_IMAGE},
+				{UUID: "uuid2", Status: proto.GameserverStatus_RUNNING, Health: proto.HealthStatus_HEALTHY},
+			},
+		}}, nil)
+	agentStore.EXPECT().RegisterAgent(gomock.Any()).Return(nil)
+
+	events := make(map[string][]string)
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent(gomock.Any(), gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			events[UUID] = append(events[UUID], event.Type)
+		}).
+		Return(nil).
+		AnyTimes()
+
+	rpcServer := AgentServiceServer{
+		AgentStore: agentStore,
+		EventStore: eventStore,
+	}
+
+	_, err := rpcServer.Register(context.Background(), &proto.AgentState{
+		Hostname: "localhost",
+		RunningGameservers: []*proto.Gameserver{
+			{UUID: "uuid1", Status: proto.GameserverStatus_RUNNING},
+			{UUID: "uuid2", Status: proto.GameserverStatus_CRASH_LOOP, Health: proto.HealthStatus_UNHEALTHY, LastExitCode: 137},
+			{UUID: "uuid3", Status: proto.GameserverStatus_PULLING_IMAGE},
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{server.EventStarted}, events["uuid1"])
+	assert.Equal(t, []string{server.EventCrashed, server.EventHealthChanged}, events["uuid2"])
+	assert.Equal(t, []string{server.EventImagePulling}, events["uuid3"])
+}
+
+func TestStatusChangesDetectsRestarts(t *testing.T) {
+	previous := &proto.Gameserver{Status: proto.GameserverStatus_RUNNING, RestartCount: 1}
+	current := &proto.Gameserver{Status: proto.GameserverStatus_RUNNING, RestartCount: 2, LastExitCode: 1}
+
+	events := statusChanges("localhost", previous, current)
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, server.EventCrashed, events[0].Type)
+	assert.Equal(t, "Crashed on agent localhost with exit code 1 and was restarted, 2 restarts", events[0].Message)
+	assert.Empty(t, statusChanges("localhost", current, current))
+}
+
+func TestRegisterPublishesUpdates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().
+		GetAgent("localhost").
+		Return(&server.Agent{State: proto.AgentState{
+			Hostname: "localhost",
+			RunningGameservers: []*proto.Gameserver{
+				{UUID: "uuid1", Status: proto.GameserverStatus_RUNNING, Endpoint: &proto.Endpoint{IpAddress: "10.0.0.1"}},
+				{UUID: "uuid2", Status: proto.GameserverStatus_RUNNING},
+			},
+		}}, nil)
+	agentStore.EXPECT().RegisterAgent(gomock.Any()).Return(nil)
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("uuid1").
+		Return(&server.Gameserver{Definition: server.GameserverDefinition{UUID: "uuid1", Owner: "user1", Team: "team1"}}, nil).
+		Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().AddGameserverEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+	updates := server.NewUpdateBus(10)
+	subscription := updates.Subscribe(0)
+	defer subscription.Close()
+
+	rpcServer := AgentServiceServer{
+		AgentStore:      agentStore,
+		GameserverStore: gameserverStore,
+		EventStore:      eventStore,
+		Updates:         updates,
+	}
+
+	_, err := rpcServer.Register(context.Background(), &proto.AgentState{
+		Hostname: "localhost",
+		RunningGameservers: []*proto.Gameserver{
+			{
+				UUID:     "uuid1",
+				Status:   proto.GameserverStatus_RUNNING,
+				Endpoint: &proto.Endpoint{IpAddress: "10.0.0.1"},
+				Query:    &proto.GameserverQueryResult{PlayersOnline: 3, PlayersMax: 20},
+			},
+			{UUID: "uuid2", Status: proto.GameserverStatus_RUNNING},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, subscription.Updates, 1)
+	update := <-subscription.Updates
+	assert.Equal(t, server.UpdatePlayers, update.Type)
+	assert.Equal(t, "uuid1", update.UUID)
+	assert.Equal(t, "user1", update.Owner)
+	assert.Equal(t, "team1", update.Team)
+	assert.Equal(t, "RUNNING", update.Status)
+	assert.Equal(t, "10.0.0.1", update.Address)
+	assert.Equal(t, 3, update.PlayersOnline)
+}
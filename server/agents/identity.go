@@ -0,0 +1,78 @@
+package agents
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// enrollMethod is the only method, which agents without
+// a client certificate are allowed to call
+const enrollMethod = "/proto.AgentService/Enroll"
+
+// agentIdentity returns the hostname from the verified client certificate.
+// tls is false, when the connection doesn't use TLS
+func agentIdentity(ctx context.Context) (identity string, tls bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return "", false
+	}
+
+	chains := tlsInfo.State.VerifiedChains
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return "", true
+	}
+	return chains[0][0].Subject.CommonName, true
+}
+
+// checkAgentIdentity rejects requests of agents acting as another agent.
+// Connections without TLS are not checked
+func checkAgentIdentity(ctx context.Context, hostname string) error {
+	identity, tls := agentIdentity(ctx)
+	if !tls {
+		return nil
+	}
+	if identity == "" {
+		return status.Error(codes.Unauthenticated, "client certificate required")
+	}
+	if identity != hostname {
+		return status.Errorf(codes.PermissionDenied, "agent %s cannot act as %s", identity, hostname)
+	}
+	return nil
+}
+
+func requireCertificate(ctx context.Context, method string) error {
+	if method == enrollMethod {
+		return nil
+	}
+	if identity, _ := agentIdentity(ctx); identity == "" {
+		return status.Error(codes.Unauthenticated, "client certificate required")
+	}
+	return nil
+}
+
+// UnaryCertificateInterceptor rejects calls of agents
+// without a client certificate, except for enrollment
+func UnaryCertificateInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := requireCertificate(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// StreamCertificateInterceptor rejects streams of agents
+// without a client certificate
+func StreamCertificateInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := requireCertificate(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
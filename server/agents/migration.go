@@ -0,0 +1,201 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+)
+
+const volumeChunkSize = 1 << 20
+
+var volumeNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// migrationTasks returns the volume exports and imports, which the agent
+// has to run for the migrations in progress
+func (rpcServer AgentServiceServer) migrationTasks(hostname string) ([]*proto.MigrationTask, error) {
+	tasks := make([]*proto.MigrationTask, 0)
+	if rpcServer.OperationStore == nil {
+		return tasks, nil
+	}
+
+	operations, err := rpcServer.OperationStore.ListOperations()
+	if err != nil {
+		return tasks, err
+	}
+
+	for _, operation := range operations {
+		if operation.Finished() {
+			continue
+		}
+
+		task := &proto.MigrationTask{OperationId: operation.ID}
+		switch {
+		case operation.Phase == server.MigrationExporting && operation.SourceAgent == hostname && !operation.ExportDone:
+			task.Type = proto.MigrationTaskType_MIGRATION_EXPORT
+		case operation.Phase == server.MigrationImporting && operation.TargetAgent == hostname && !operation.ImportDone:
+			task.Type = proto.MigrationTaskType_MIGRATION_IMPORT
+		default:
+			continue
+		}
+
+		gameserver, err := rpcServer.GameserverStore.GetGameserver(operation.GameserverUUID)
+		if err != nil {
+			continue
+		}
+		task.Deployment = gameserver.Deployment
+		tasks = append(tasks, task)
+	}
+
+	return tasks, nil
+}
+
+// volumePath returns the path of the volume archive of a migration in progress.
+// Only the source agent uploads and only the target agent downloads it
+func (rpcServer AgentServiceServer) volumePath(ctx context.Context, operationID, volume string, upload bool) (string, error) {
+	if !volumeNameRegexp.MatchString(volume) {
+		return "", fmt.Errorf("Invalid volume name %s", volume)
+	}
+
+	operation, err := rpcServer.OperationStore.GetOperation(operationID)
+	if err != nil {
+		return "", err
+	}
+	if operation.Finished() {
+		return "", fmt.Errorf("Operation %s is finished", operationID)
+	}
+
+	agent := operation.TargetAgent
+	if upload {
+		agent = operation.SourceAgent
+	}
+	if err := checkAgentIdentity(ctx, agent); err != nil {
+		return "", err
+	}
+
+	return filepath.Join(rpcServer.VolumeDir, operation.ID, volume+".tar"), nil
+}
+
+// UploadVolume stores a volume archive exported by the source agent
+func (rpcServer AgentServiceServer) UploadVolume(stream proto.AgentService_UploadVolumeServer) error {
+	chunk, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	path, err := rpcServer.volumePath(stream.Context(), chunk.OperationId, chunk.Volume, true)
+	if err != nil {
+		log.Printf("AgentServiceServer UploadVolume error: %v", err)
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	for {
+		if _, err := file.Write(chunk.Data); err != nil {
+			file.Close()
+			return err
+		}
+
+		chunk, err = stream.Recv()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(file.Name(), path); err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(&proto.Empty{})
+}
+
+// DownloadVolume streams a volume archive to the target agent
+func (rpcServer AgentServiceServer) DownloadVolume(req *proto.VolumeRequest, stream proto.AgentService_DownloadVolumeServer) error {
+	path, err := rpcServer.volumePath(stream.Context(), req.OperationId, req.Volume, false)
+	if err != nil {
+		log.Printf("AgentServiceServer DownloadVolume error: %v", err)
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, volumeChunkSize)
+	for {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			if err := stream.Send(&proto.VolumeChunk{Data: buffer[:n]}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// ReportMigrationTask stores the result of a volume export or import.
+// The volume archives are removed, after the import
+func (rpcServer AgentServiceServer) ReportMigrationTask(ctx context.Context, result *proto.MigrationTaskResult) (*proto.Empty, error) {
+	operation, err := rpcServer.OperationStore.GetOperation(result.OperationId)
+	if err != nil {
+		log.Printf("AgentServiceServer ReportMigrationTask error: %v", err)
+		return nil, err
+	}
+	if operation.Finished() {
+		return nil, errors.New("Operation is finished")
+	}
+
+	agent := operation.TargetAgent
+	if result.Type == proto.MigrationTaskType_MIGRATION_EXPORT {
+		agent = operation.SourceAgent
+	}
+	if err := checkAgentIdentity(ctx, agent); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case !result.Success:
+		operation.Error = fmt.Sprintf("%s failed: %s", result.Type, result.Error)
+	case result.Type == proto.MigrationTaskType_MIGRATION_EXPORT:
+		operation.ExportDone = true
+	default:
+		operation.ImportDone = true
+	}
+
+	if !result.Success || result.Type == proto.MigrationTaskType_MIGRATION_IMPORT {
+		os.RemoveAll(filepath.Join(rpcServer.VolumeDir, operation.ID))
+	}
+
+	if err := rpcServer.OperationStore.UpdateOperation(operation); err != nil {
+		log.Printf("AgentServiceServer ReportMigrationTask error: %v", err)
+		return nil, err
+	}
+	return &proto.Empty{}, nil
+}
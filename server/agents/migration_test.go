@@ -0,0 +1,143 @@
+package agents
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+)
+
+type fakeUploadStream struct {
+	grpc.ServerStream
+	chunks []*proto.VolumeChunk
+	closed bool
+}
+
+func (stream *fakeUploadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (stream *fakeUploadStream) Recv() (*proto.VolumeChunk, error) {
+	if len(stream.chunks) == 0 {
+		return nil, io.EOF
+	}
+	chunk := stream.chunks[0]
+	stream.chunks = stream.chunks[1:]
+	return chunk, nil
+}
+
+func (stream *fakeUploadStream) SendAndClose(*proto.Empty) error {
+	stream.closed = true
+	return nil
+}
+
+type fakeDownloadStream struct {
+	grpc.ServerStream
+	data []byte
+}
+
+func (stream *fakeDownloadStream) Context() context.Context {
+	return context.Background()
+}
+
+func (stream *fakeDownloadStream) Send(chunk *proto.VolumeChunk) error {
+	stream.data = append(stream.data, chunk.Data...)
+	return nil
+}
+
+func TestTransferVolumeThroughServer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dir, _ := ioutil.TempDir("", "volumes")
+	defer os.RemoveAll(dir)
+
+	operation := &server.Operation{ID: "op1", Phase: server.MigrationExporting}
+	operationStore := mocks.NewMockOperationStore(ctrl)
+	operationStore.EXPECT().GetOperation("op1").Return(operation, nil).AnyTimes()
+	operationStore.EXPECT().UpdateOperation(operation).Return(nil).Times(2)
+
+	rpcServer := AgentServiceServer{
+		OperationStore: operationStore,
+		VolumeDir:      dir,
+	}
+
+	upload := &fakeUploadStream{chunks: []*proto.VolumeChunk{
+		{OperationId: "op1", Volume: "data", Data: []byte("hello ")},
+		{OperationId: "op1", Volume: "data", Data: []byte("world")},
+	}}
+	assert.NoError(t, rpcServer.UploadVolume(upload))
+	assert.True(t, upload.closed)
+
+	_, err := rpcServer.ReportMigrationTask(context.Background(), &proto.MigrationTaskResult{
+		OperationId: "op1",
+		Type:        proto.MigrationTaskType_MIGRATION_EXPORT,
+		Success:     true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, operation.ExportDone)
+
+	download := &fakeDownloadStream{}
+	assert.NoError(t, rpcServer.DownloadVolume(&proto.VolumeRequest{OperationId: "op1", Volume: "data"}, download))
+	assert.Equal(t, "hello world", string(download.data))
+
+	_, err = rpcServer.ReportMigrationTask(context.Background(), &proto.MigrationTaskResult{
+		OperationId: "op1",
+		Type:        proto.MigrationTaskType_MIGRATION_IMPORT,
+		Success:     true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, operation.ImportDone)
+	_, err = os.Stat(filepath.Join(dir, "op1"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUploadVolumeRejectsInvalidName(t *testing.T) {
+	rpcServer := AgentServiceServer{VolumeDir: "/tmp"}
+
+	upload := &fakeUploadStream{chunks: []*proto.VolumeChunk{
+		{OperationId: "op1", Volume: "../../etc/passwd"},
+	}}
+	assert.Error(t, rpcServer.UploadVolume(upload))
+}
+
+func TestGetGameserverDeploymentsReturnsMigrationTasks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	deployment := &proto.GameserverDeployment{UUID: "uuid1", Agent: "source"}
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{{Deployment: deployment}}, nil).AnyTimes()
+	gameserverStore.EXPECT().GetGameserver("uuid1").Return(&server.Gameserver{Deployment: deployment}, nil).AnyTimes()
+
+	operationStore := mocks.NewMockOperationStore(ctrl)
+	operationStore.EXPECT().ListOperations().Return([]server.Operation{
+		{ID: "op1", GameserverUUID: "uuid1", Phase: server.MigrationImporting, SourceAgent: "source", TargetAgent: "target"},
+	}, nil).AnyTimes()
+
+	rpcServer := AgentServiceServer{
+		GameserverStore: gameserverStore,
+		OperationStore:  operationStore,
+	}
+
+	res, err := rpcServer.GetGameserverDeployments(context.Background(), &proto.GetGameserverDeploymentsRequest{Hostname: "target"})
+	assert.NoError(t, err)
+	assert.Empty(t, res.Deployments)
+	assert.Len(t, res.MigrationTasks, 1)
+	assert.Equal(t, proto.MigrationTaskType_MIGRATION_IMPORT, res.MigrationTasks[0].Type)
+	assert.Equal(t, "uuid1", res.MigrationTasks[0].Deployment.UUID)
+
+	res, err = rpcServer.GetGameserverDeployments(context.Background(), &proto.GetGameserverDeploymentsRequest{Hostname: "source"})
+	assert.NoError(t, err)
+	assert.Len(t, res.Deployments, 1)
+	assert.Empty(t, res.MigrationTasks)
+}
@@ -0,0 +1,80 @@
+package agents
+
+import (
+	"log"
+
+	"github.com/Trojan295/chinchilla/proto"
+	"github.com/Trojan295/chinchilla/server"
+)
+
+// publishUpdates publishes the changes of the status, the endpoint
+// and the player count of the gameserver
+func (rpcServer AgentServiceServer) publishUpdates(previous, current *proto.Gameserver) {
+	if rpcServer.Updates == nil {
+		return
+	}
+
+	types := updateTypes(previous, current)
+	if len(types) == 0 {
+		return
+	}
+
+	gameserver, err := rpcServer.GameserverStore.GetGameserver(current.UUID)
+	if err != nil {
+		log.Printf("agentServiceServer publishUpdates: cannot get gameserver %s: %v", current.UUID, err)
+		return
+	}
+
+	online, max := playerCount(current)
+	for _, updateType := range types {
+		rpcServer.Updates.Publish(server.GameserverUpdate{
+			Type:          updateType,
+			UUID:          current.UUID,
+			Owner:         gameserver.Definition.Owner,
+			Team:          gameserver.Definition.Team,
+			Status:        current.Status.String(),
+			Address:       endpointAddress(current),
+			PlayersOnline: online,
+			PlayersMax:    max,
+		})
+	}
+}
+
+// updateTypes returns the types of the changes of the gameserver.
+// The previous state is nil, when the agent didn't report the gameserver before
+func updateTypes(previous, current *proto.Gameserver) []string {
+	types := make([]string, 0)
+
+	if previous == nil || previous.Status != current.Status {
+		types = append(types, server.UpdateStatus)
+	}
+
+	previousAddress := ""
+	previousOnline, previousMax := 0, 0
+	if previous != nil {
+		previousAddress = endpointAddress(previous)
+		previousOnline, previousMax = playerCount(previous)
+	}
+	if endpointAddress(current) != previousAddress {
+		types = append(types, server.UpdateEndpoint)
+	}
+	if online, max := playerCount(current); online != previousOnline || max != previousMax {
+		types = append(types, server.UpdatePlayers)
+	}
+
+	return types
+}
+
+func endpointAddress(gameserver *proto.Gameserver) string {
+	if gameserver.Endpoint == nil {
+		return ""
+	}
+	return gameserver.Endpoint.IpAddress
+}
+
+func playerCount(gameserver *proto.Gameserver) (int, int) {
+	if gameserver.Query == nil {
+		return 0, 0
+	}
+	return int(gameserver.Query.PlayersOnline), int(gameserver.Query.PlayersMax)
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// AuditLog records audit events in the store and, if configured,
+// as JSON lines in a file. A nil AuditLog records nothing
+type AuditLog struct {
+	store     AuditStore
+	retention time.Duration
+	mutex     sync.Mutex
+	sink      io.Writer
+}
+
+// NewAuditLog creates an AuditLog. The file is used as a sink,
+// if the path is not empty
+func NewAuditLog(store AuditStore, retention time.Duration, path string) (*AuditLog, error) {
+	auditLog := &AuditLog{store: store, retention: retention}
+	if path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+		if err != nil {
+			return nil, err
+		}
+		auditLog.sink = file
+	}
+	return auditLog, nil
+}
+
+// Record stores the audit event. Failures are only logged,
+// so they don't break the audited actions
+func (auditLog *AuditLog) Record(event *AuditEvent) {
+	if auditLog == nil {
+		return
+	}
+
+	event.ID = uuid.NewV4().String()
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	if err := auditLog.store.AddAuditEvent(event, auditLog.retention); err != nil {
+		log.Printf("Cannot record audit event %s of %s: %v", event.Action, event.Target, err)
+	}
+
+	if auditLog.sink != nil {
+		line, _ := json.Marshal(event)
+
+		auditLog.mutex.Lock()
+		defer auditLog.mutex.Unlock()
+		if _, err := auditLog.sink.Write(append(line, '\n')); err != nil {
+			log.Printf("Cannot write audit event %s of %s: %v", event.Action, event.Target, err)
+		}
+	}
+}
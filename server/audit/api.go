@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type listAuditEventsResponse []server.AuditEvent
+
+type auditAPI struct {
+	auditStore server.AuditStore
+}
+
+// MountAuditAPI mounts the audit log API
+func MountAuditAPI(r *gin.Engine, auditStore server.AuditStore) {
+	api := auditAPI{auditStore}
+
+	r.GET("/audit", auth.Authorize(auth.ResourceAudit, auth.VerbRead), api.listAuditEvents)
+}
+
+// UseAuditLog is a gin middleware, which sets the audit log used by Log
+// and the request ID. The request ID is taken from the X-Request-ID header
+// or generated
+func UseAuditLog(auditLog *server.AuditLog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("x-request-id")
+		if requestID == "" {
+			requestID = uuid.NewV4().String()
+		}
+		c.Set("requestID", requestID)
+		c.Header("X-Request-ID", requestID)
+
+		if auditLog != nil {
+			c.Set("auditLog", auditLog)
+		}
+	}
+}
+
+// Log is a gin middleware recording the action in the audit log, after
+// the request is handled. The target defaults to the path parameters
+func Log(action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		value, ok := c.Get("auditLog")
+		if !ok {
+			return
+		}
+
+		target := c.GetString("auditTarget")
+		if target == "" {
+			params := make([]string, 0, len(c.Params))
+			for _, param := range c.Params {
+				params = append(params, param.Value)
+			}
+			target = strings.Join(params, "/")
+		}
+
+		value.(*server.AuditLog).Record(&server.AuditEvent{
+			Actor:     c.GetString("userID"),
+			Action:    action,
+			Target:    target,
+			Before:    c.GetString("auditBefore"),
+			After:     c.GetString("auditAfter"),
+			Status:    c.Writer.Status(),
+			RequestID: c.GetString("requestID"),
+		})
+	}
+}
+
+// SetTarget sets the target of the audited action
+func SetTarget(c *gin.Context, target string) {
+	c.Set("auditTarget", target)
+}
+
+// SetChange sets the summaries of the target before and after the action
+func SetChange(c *gin.Context, before, after string) {
+	c.Set("auditBefore", before)
+	c.Set("auditAfter", after)
+}
+
+// listAuditEvents returns the newest audit events matching the filters
+func (api *auditAPI) listAuditEvents(c *gin.Context) {
+	if !auth.CanAccess(c, "") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Audit log is restricted to admins"})
+		return
+	}
+
+	var since, until time.Time
+	for name, value := range map[string]*time.Time{"since": &since, "until": &until} {
+		if query := c.Query(name); query != "" {
+			parsed, err := time.Parse(time.RFC3339, query)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+				return
+			}
+			*value = parsed
+		}
+	}
+
+	limit := defaultLimit
+	if query := c.Query("limit"); query != "" {
+		parsed, err := strconv.Atoi(query)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = parsed
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	events, err := api.auditStore.ListAuditEvents()
+	if err != nil {
+		log.Printf("auditAPI listAuditEvents error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot list audit events"})
+		return
+	}
+
+	actor, action, target := c.Query("actor"), c.Query("action"), c.Query("target")
+	resp := make(listAuditEventsResponse, 0)
+	for i := len(events) - 1; i >= 0 && len(resp) < limit; i-- {
+		event := events[i]
+		if (actor != "" && event.Actor != actor) ||
+			(action != "" && !strings.HasPrefix(event.Action, action)) ||
+			(target != "" && event.Target != target) ||
+			(!since.IsZero() && event.Time.Before(since)) ||
+			(!until.IsZero() && event.Time.After(until)) {
+			continue
+		}
+		resp = append(resp, event)
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,126 @@
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/Trojan295/chinchilla/server/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogRecordsAction(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	file, _ := ioutil.TempFile("", "audit")
+	file.Close()
+	defer os.Remove(file.Name())
+
+	auditStore := mocks.NewMockAuditStore(ctrl)
+	auditStore.EXPECT().
+		AddAuditEvent(gomock.Any(), 24*time.Hour).
+		Do(func(event *server.AuditEvent, retention time.Duration) {
+			assert.NotEmpty(t, event.ID)
+			assert.Equal(t, "user1", event.Actor)
+			assert.Equal(t, "agent.cordon", event.Action)
+			assert.Equal(t, "agent1", event.Target)
+			assert.Equal(t, "schedulable", event.Before)
+			assert.Equal(t, "cordoned", event.After)
+			assert.Equal(t, 200, event.Status)
+			assert.Equal(t, "request1", event.RequestID)
+		}).
+		Return(nil).
+		Times(1)
+	auditLog, err := server.NewAuditLog(auditStore, 24*time.Hour, file.Name())
+	assert.NoError(t, err)
+
+	router := utils.SetupRouter()
+	router.Use(UseAuditLog(auditLog))
+	router.POST("/agents/:hostname/cordon/", auth.Authorize(auth.ResourceAgents, auth.VerbUpdate), Log("agent.cordon"), func(c *gin.Context) {
+		SetChange(c, "schedulable", "cordoned")
+		c.JSON(http.StatusOK, gin.H{})
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/agents/agent1/cordon/", nil)
+	req.Header.Add("x-request-id", "request1")
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{
+		"sub":         "user1",
+		"permissions": []string{"update:agents"},
+	}))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "request1", w.Header().Get("X-Request-ID"))
+
+	content, _ := ioutil.ReadFile(file.Name())
+	event := server.AuditEvent{}
+	assert.NoError(t, json.Unmarshal(content, &event))
+	assert.Equal(t, "agent.cordon", event.Action)
+	assert.Equal(t, "agent1", event.Target)
+}
+
+func TestListAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	now := time.Now()
+	auditStore := mocks.NewMockAuditStore(ctrl)
+	auditStore.EXPECT().
+		ListAuditEvents().
+		Return([]server.AuditEvent{
+			{ID: "1", Time: now.Add(-2 * time.Hour), Actor: "user1", Action: "gameserver.create", Target: "uuid1"},
+			{ID: "2", Time: now.Add(-time.Hour), Actor: "scheduler", Action: "gameserver.schedule", Target: "uuid1"},
+			{ID: "3", Time: now, Actor: "user2", Action: "gameserver.delete", Target: "uuid1"},
+			{ID: "4", Time: now, Actor: "user1", Action: "agent.cordon", Target: "agent1"},
+		}, nil).
+		AnyTimes()
+
+	policy := auth.DefaultPolicy
+	policy.Bindings = []auth.Binding{{Role: auth.RoleAdmin, Users: []string{"admin1"}}}
+
+	router := utils.SetupRouter()
+	router.Use(auth.UsePolicy(&policy))
+	MountAuditAPI(router, auditStore)
+
+	request := func(userID, query string) (int, []string) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/audit"+query, nil)
+		req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": userID}))
+		router.ServeHTTP(w, req)
+
+		res := listAuditEventsResponse{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		IDs := make([]string, 0)
+		for _, event := range res {
+			IDs = append(IDs, event.ID)
+		}
+		return w.Code, IDs
+	}
+
+	code, _ := request("user1", "")
+	assert.Equal(t, 403, code)
+
+	code, IDs := request("admin1", "")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, []string{"4", "3", "2", "1"}, IDs)
+
+	_, IDs = request("admin1", "?action=gameserver.&target=uuid1&limit=2")
+	assert.Equal(t, []string{"3", "2"}, IDs)
+
+	_, IDs = request("admin1", "?actor=user1&since="+now.Add(-3*time.Hour).Format(time.RFC3339)+"&until="+now.Add(-time.Minute).Format(time.RFC3339))
+	assert.Equal(t, []string{"1"}, IDs)
+
+	code, _ = request("admin1", "?since=yesterday")
+	assert.Equal(t, 400, code)
+}
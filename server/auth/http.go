@@ -1,22 +1,39 @@
 package auth
 
 import (
+	"fmt"
 	"log"
-	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
+	"github.com/Trojan295/chinchilla/server"
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 )
 
+// lastUsedResolution limits how often the last use of an API token is stored
+const lastUsedResolution = time.Minute
+
 // SetupAuthentication registers the propert authentication
-// mechanism based on the Configuration
-func SetupAuthentication(router *gin.Engine, authConfig map[string]interface{}) {
+// mechanism based on the Configuration. Personal API tokens
+// are accepted with any mechanism, if the tokenStore is set
+func SetupAuthentication(router *gin.Engine, authConfig map[string]interface{}, tokenStore server.APITokenStore) {
+	router.Use(streamToken)
+
 	if authConfig["type"] == "jwt" {
 		log.Println("Using JWT based authentication")
 
 		secret := authConfig["key"].(string)
 		router.Use(jwtToken(secret))
+	} else if authConfig["type"] == "oidc" {
+		config := newOIDCConfig(authConfig)
+		if config.Issuer == "" || config.Audience == "" {
+			panic("OIDC authentication requires issuer and audience")
+		}
+		log.Printf("Using OIDC based authentication with issuer %s", config.Issuer)
+
+		router.Use(oidcToken(config))
 	} else if authConfig["type"] == "header" {
 		log.Println("Using header based authentication")
 
@@ -24,6 +41,20 @@ func SetupAuthentication(router *gin.Engine, authConfig map[string]interface{})
 	} else {
 		panic("Wrong authentication config")
 	}
+
+	if tokenStore != nil {
+		router.Use(apiToken(tokenStore))
+	}
+
+	policy := &DefaultPolicy
+	if path, ok := authConfig["policy"].(string); ok && path != "" {
+		var err error
+		if policy, err = LoadPolicy(path); err != nil {
+			panic(err)
+		}
+		log.Printf("Using RBAC policy from %s", path)
+	}
+	router.Use(UsePolicy(policy))
 }
 
 func headerAuth(c *gin.Context) {
@@ -38,6 +69,83 @@ func headerAuth(c *gin.Context) {
 		c.Set("permissions", permissions)
 	}
 
+	groupsHeader := c.GetHeader("x-groups")
+	if groupsHeader != "" {
+		c.Set("groups", strings.Split(groupsHeader, ","))
+	}
+
+}
+
+// streamToken is a gin middleware accepting the bearer token of Server-Sent
+// Events and WebSocket requests in the access_token query parameter,
+// as browsers cannot set their headers. Logger keeps these tokens out of the logs
+func streamToken(c *gin.Context) {
+	token := c.Query("access_token")
+	if token == "" || c.GetHeader("authorization") != "" {
+		return
+	}
+
+	if strings.Contains(c.GetHeader("accept"), "text/event-stream") ||
+		strings.EqualFold(c.GetHeader("upgrade"), "websocket") {
+		c.Request.Header.Set("authorization", "Bearer "+token)
+	}
+}
+
+// Logger is a gin request logger, which doesn't log
+// the bearer tokens sent in the access_token query parameter
+func Logger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(redactedLogFormatter)
+}
+
+func redactedLogFormatter(param gin.LogFormatterParams) string {
+	if path, err := url.Parse(param.Path); err == nil {
+		query := path.Query()
+		if _, ok := query["access_token"]; ok {
+			query.Set("access_token", "REDACTED")
+			path.RawQuery = query.Encode()
+			param.Path = path.String()
+		}
+	}
+
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %s\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		param.StatusCode,
+		param.Latency,
+		param.ClientIP,
+		param.Method,
+		param.Path,
+		param.ErrorMessage,
+	)
+}
+
+// apiToken is a gin middleware to validate personal API tokens.
+// The token scopes restrict the permissions checked by Authorize
+func apiToken(store server.APITokenStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("authorization")
+		if !strings.HasPrefix(authHeader, "Bearer "+server.APITokenPrefix) {
+			return
+		}
+
+		token, err := store.GetAPIToken(server.HashToken(strings.TrimPrefix(authHeader, "Bearer ")))
+		if err != nil || token.Expired() {
+			return
+		}
+
+		c.Set("userID", token.UserID)
+		c.Set("tokenScopes", token.Scopes)
+		if token.ExpiresAt != nil {
+			c.Set("tokenExpiresAt", *token.ExpiresAt)
+		}
+
+		now := time.Now()
+		if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedResolution {
+			token.LastUsedAt = &now
+			if err := store.UpdateAPIToken(token); err != nil {
+				log.Printf("Cannot update API token %s: %v", token.ID, err)
+			}
+		}
+	}
 }
 
 // JWTToken is a gin middleware to validate JWT tokens
@@ -52,6 +160,9 @@ func jwtToken(secret string) gin.HandlerFunc {
 		tokenString := strings.Split(authHeader, " ")[1]
 
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+			}
 			return []byte(secret), nil
 		})
 
@@ -69,42 +180,7 @@ func jwtToken(secret string) gin.HandlerFunc {
 
 			c.Set("permissions", permissions)
 		}
-	}
-}
-
-// LoginRequired is a gin middleware, which ensures user is logged in
-func LoginRequired() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		if _, ok := c.Get("userID"); !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login required"})
-			c.Abort()
-		}
-	}
-}
-
-// Auth0Permission is a gin authorization middleware
-// for validating Auth0 permissions
-func Auth0Permission(scope string) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		permissionsObj, ok := c.Get("permissions")
-		permissions := permissionsObj.([]string)
-
-		if ok == false {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Missing scopes"})
-			c.Abort()
-		}
-
-		contains := false
-		for _, s := range permissions {
-			if scope == s {
-				contains = true
-			}
-		}
-
-		if !contains {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Missing scopes"})
-			c.Abort()
-		}
 
+		c.Set("groups", claimList(token.Claims.(jwt.MapClaims)["groups"]))
 	}
 }
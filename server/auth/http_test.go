@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func apiTokenRequest(tokenStore server.APITokenStore, token, verb string) (int, string) {
+	router := gin.New()
+	SetupAuthentication(router, map[string]interface{}{"type": "jwt", "key": "secret"}, tokenStore)
+
+	userID := ""
+	router.GET("/", Authorize(ResourceGameservers, verb), func(c *gin.Context) {
+		userID = c.GetString("userID")
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add("authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w.Code, userID
+}
+
+func TestAPITokenAuthentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	token, apiToken, _ := server.NewAPIToken("user1", "ci", nil, nil)
+
+	tokenStore := mocks.NewMockAPITokenStore(ctrl)
+	tokenStore.EXPECT().
+		GetAPIToken(apiToken.Hash).
+		Return(apiToken, nil).
+		Times(2)
+	tokenStore.EXPECT().
+		GetAPIToken(gomock.Any()).
+		Return(nil, assert.AnError).
+		Times(1)
+	tokenStore.EXPECT().
+		UpdateAPIToken(gomock.Any()).
+		Do(func(token *server.APIToken) {
+			assert.WithinDuration(t, time.Now(), *token.LastUsedAt, time.Second)
+		}).
+		Return(nil).
+		Times(1)
+
+	code, userID := apiTokenRequest(tokenStore, token, VerbDelete)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "user1", userID)
+
+	code, _ = apiTokenRequest(tokenStore, token, VerbRead)
+	assert.Equal(t, 200, code)
+
+	code, _ = apiTokenRequest(tokenStore, server.APITokenPrefix+"unknown", VerbRead)
+	assert.Equal(t, 401, code)
+}
+
+func TestAPITokenScopesAndExpiry(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	token, apiToken, _ := server.NewAPIToken("user1", "ci", []string{"read:gameservers"}, nil)
+	expiredToken, expiredAPIToken, _ := server.NewAPIToken("user1", "old", nil, &yesterday)
+
+	tokenStore := mocks.NewMockAPITokenStore(ctrl)
+	tokenStore.EXPECT().GetAPIToken(apiToken.Hash).Return(apiToken, nil).AnyTimes()
+	tokenStore.EXPECT().GetAPIToken(expiredAPIToken.Hash).Return(expiredAPIToken, nil).AnyTimes()
+	tokenStore.EXPECT().UpdateAPIToken(gomock.Any()).Return(nil).AnyTimes()
+
+	code, _ := apiTokenRequest(tokenStore, token, VerbRead)
+	assert.Equal(t, 200, code)
+
+	code, _ = apiTokenRequest(tokenStore, token, VerbDelete)
+	assert.Equal(t, 403, code)
+
+	code, _ = apiTokenRequest(tokenStore, expiredToken, VerbRead)
+	assert.Equal(t, 401, code)
+}
+
+func TestStreamTokenInQuery(t *testing.T) {
+	token, apiToken, _ := server.NewAPIToken("user1", "ui", nil, nil)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokenStore := mocks.NewMockAPITokenStore(ctrl)
+	tokenStore.EXPECT().
+		GetAPIToken(apiToken.Hash).
+		Return(apiToken, nil).
+		Times(1)
+	tokenStore.EXPECT().
+		UpdateAPIToken(gomock.Any()).
+		Return(nil).
+		AnyTimes()
+
+	router := gin.New()
+	SetupAuthentication(router, map[string]interface{}{"type": "jwt", "key": "secret"}, tokenStore)
+	router.GET("/events", Authorize(ResourceGameservers, VerbRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(accept string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/events?access_token="+token, nil)
+		req.Header.Add("accept", accept)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("text/event-stream"))
+	// other requests must send the token in the header
+	assert.Equal(t, http.StatusUnauthorized, request("application/json"))
+}
+
+func TestLoggerRedactsStreamToken(t *testing.T) {
+	line := redactedLogFormatter(gin.LogFormatterParams{
+		Method: "GET",
+		Path:   "/events?access_token=secret&lastEventId=1",
+	})
+
+	assert.NotContains(t, line, "secret")
+	assert.Contains(t, line, "access_token=REDACTED")
+	assert.Contains(t, line, "lastEventId=1")
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+// jwksCacheTTL is how long the fetched keys are used without refreshing
+const jwksCacheTTL = time.Hour
+
+// jwksMinRefreshInterval limits refreshes caused by unknown key IDs
+var jwksMinRefreshInterval = time.Minute
+
+// OIDCConfig configures the validation of tokens issued by an OpenID
+// Connect provider. The keys are fetched from JWKSURL or from the jwks_uri
+// of the issuer discovery document
+type OIDCConfig struct {
+	Issuer           string
+	Audience         string
+	JWKSURL          string
+	UserClaim        string
+	PermissionsClaim string
+	GroupsClaim      string
+}
+
+func newOIDCConfig(authConfig map[string]interface{}) OIDCConfig {
+	str := func(key, defaultValue string) string {
+		if value, ok := authConfig[key].(string); ok && value != "" {
+			return value
+		}
+		return defaultValue
+	}
+
+	return OIDCConfig{
+		Issuer:           str("issuer", ""),
+		Audience:         str("audience", ""),
+		JWKSURL:          str("jwksURL", ""),
+		UserClaim:        str("userClaim", "sub"),
+		PermissionsClaim: str("permissionsClaim", "permissions"),
+		GroupsClaim:      str("groupsClaim", "groups"),
+	}
+}
+
+// jwks caches the signing keys of the issuer. Unknown key IDs trigger
+// a refresh, so rotated keys are picked up before the cache expires.
+// A single request fetches the keys without holding the mutex, the
+// other requests wait for it
+type jwks struct {
+	config OIDCConfig
+	client *http.Client
+
+	mutex       sync.Mutex
+	keysURL     string
+	keys        map[string]interface{}
+	fetchedAt   time.Time
+	refreshedAt time.Time
+	// refreshing is closed, when the running refresh finishes
+	refreshing chan struct{}
+}
+
+func newJWKS(config OIDCConfig) *jwks {
+	return &jwks{
+		config:  config,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		keysURL: config.JWKSURL,
+	}
+}
+
+func (cache *jwks) key(kid string) (interface{}, error) {
+	cache.mutex.Lock()
+	key, ok := cache.keys[kid]
+	expired := time.Since(cache.fetchedAt) > jwksCacheTTL
+	if ok && !expired {
+		cache.mutex.Unlock()
+		return key, nil
+	}
+
+	refreshing := cache.refreshing
+	if refreshing == nil && (expired || time.Since(cache.refreshedAt) > jwksMinRefreshInterval) {
+		cache.refreshedAt = time.Now()
+		cache.refreshing = make(chan struct{})
+		keysURL := cache.keysURL
+		cache.mutex.Unlock()
+
+		cache.refresh(keysURL)
+	} else {
+		cache.mutex.Unlock()
+		if refreshing != nil {
+			<-refreshing
+		}
+	}
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if key, ok := cache.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key ID %s", kid)
+}
+
+// refresh fetches the keys and wakes up the requests waiting for them
+func (cache *jwks) refresh(keysURL string) {
+	keys, keysURL, err := cache.fetch(keysURL)
+
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if err != nil {
+		log.Printf("Cannot fetch the JWKS of %s: %v", cache.config.Issuer, err)
+	} else {
+		cache.keys = keys
+		cache.keysURL = keysURL
+		cache.fetchedAt = time.Now()
+	}
+	close(cache.refreshing)
+	cache.refreshing = nil
+}
+
+// fetch downloads the signing keys. The keys URL is discovered,
+// when it's empty
+func (cache *jwks) fetch(keysURL string) (map[string]interface{}, string, error) {
+	if keysURL == "" {
+		url, err := cache.discoverKeysURL()
+		if err != nil {
+			return nil, "", err
+		}
+		keysURL = url
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := cache.get(keysURL, &document); err != nil {
+		return nil, "", err
+	}
+
+	keys := make(map[string]interface{}, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			log.Printf("Skipping the JWK %s: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, keysURL, nil
+}
+
+// discoverKeysURL reads the jwks_uri from the OpenID Connect discovery document
+func (cache *jwks) discoverKeysURL() (string, error) {
+	var document struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	url := strings.TrimSuffix(cache.config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := cache.get(url, &document); err != nil {
+		return "", err
+	}
+
+	if document.Issuer != cache.config.Issuer {
+		return "", fmt.Errorf("discovery document issuer %s doesn't match", document.Issuer)
+	}
+	if document.JWKSURI == "" {
+		return "", errors.New("discovery document has no jwks_uri")
+	}
+	return document.JWKSURI, nil
+}
+
+func (cache *jwks) get(url string, value interface{}) error {
+	res, err := cache.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned %s", url, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(value)
+}
+
+// jsonWebKey is a RSA or EC public key from a JWKS
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (jwk jsonWebKey) publicKey() (interface{}, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		if !key.Curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	bytes, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(bytes), nil
+}
+
+// oidcToken is a gin middleware validating tokens of an OpenID Connect
+// provider. Only RS256 and ES256 signatures are accepted and the tokens
+// must have the configured issuer and audience and must not be expired
+func oidcToken(config OIDCConfig) gin.HandlerFunc {
+	keys := newJWKS(config)
+
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("authorization")
+
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return
+		}
+
+		claims, err := validateOIDCToken(config, keys, strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			log.Printf("oidcToken: rejected token: %v", err)
+			return
+		}
+
+		if userID, ok := claims[config.UserClaim].(string); ok && userID != "" {
+			c.Set("userID", userID)
+		}
+		c.Set("permissions", claimStrings(claims[config.PermissionsClaim]))
+		c.Set("groups", claimList(claims[config.GroupsClaim]))
+	}
+}
+
+func validateOIDCToken(config OIDCConfig, keys *jwks, tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method {
+		case jwt.SigningMethodRS256, jwt.SigningMethodES256:
+		default:
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return keys.key(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch claims["exp"].(type) {
+	case float64, json.Number:
+	default:
+		return nil, errors.New("token has no valid expiry")
+	}
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return nil, errors.New("token is expired")
+	}
+	if !claims.VerifyIssuer(config.Issuer, true) {
+		return nil, fmt.Errorf("unexpected issuer %v", claims["iss"])
+	}
+	if !hasAudience(claims["aud"], config.Audience) {
+		return nil, fmt.Errorf("unexpected audience %v", claims["aud"])
+	}
+
+	return claims, nil
+}
+
+// hasAudience checks the aud claim, which is a string or a list of strings
+func hasAudience(claim interface{}, audience string) bool {
+	for _, aud := range claimList(claim) {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// claimList converts a list claim to a list of strings.
+// A string claim is a single value
+func claimList(claim interface{}) []string {
+	values := make([]string, 0)
+	switch claim := claim.(type) {
+	case string:
+		values = append(values, claim)
+	case []interface{}:
+		for _, value := range claim {
+			if value, ok := value.(string); ok {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// claimStrings converts a list claim or a space separated claim,
+// like scope, to a list of strings
+func claimStrings(claim interface{}) []string {
+	if claim, ok := claim.(string); ok {
+		return strings.Fields(claim)
+	}
+	return claimList(claim)
+}
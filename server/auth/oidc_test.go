@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// testIssuer is a local OpenID Connect provider serving
+// the discovery document and the JWKS
+type testIssuer struct {
+	server *httptest.Server
+
+	mutex sync.Mutex
+	keys  []map[string]string
+}
+
+func newTestIssuer() *testIssuer {
+	issuer := &testIssuer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL + "/",
+			"jwks_uri": issuer.server.URL + "/jwks.json",
+		})
+	})
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mutex.Lock()
+		defer issuer.mutex.Unlock()
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": issuer.keys})
+	})
+	issuer.server = httptest.NewServer(mux)
+	return issuer
+}
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func (issuer *testIssuer) addRSAKey(kid string) *rsa.PrivateKey {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+	issuer.keys = append(issuer.keys, map[string]string{
+		"kid": kid,
+		"kty": "RSA",
+		"use": "sig",
+		"n":   encodeBigInt(key.N),
+		"e":   encodeBigInt(big.NewInt(int64(key.E))),
+	})
+	return key
+}
+
+func (issuer *testIssuer) addECKey(kid string) *ecdsa.PrivateKey {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	issuer.mutex.Lock()
+	defer issuer.mutex.Unlock()
+	issuer.keys = append(issuer.keys, map[string]string{
+		"kid": kid,
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   encodeBigInt(key.X),
+		"y":   encodeBigInt(key.Y),
+	})
+	return key
+}
+
+func (issuer *testIssuer) claims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":         issuer.server.URL + "/",
+		"aud":         []string{"chinchilla", "https://example.eu.auth0.com/userinfo"},
+		"sub":         "user1",
+		"exp":         time.Now().Add(time.Hour).Unix(),
+		"permissions": []string{"read:agents"},
+	}
+}
+
+func signToken(method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	tokenString, _ := token.SignedString(key)
+	return tokenString
+}
+
+func setupOIDCRouter(issuer *testIssuer) *gin.Engine {
+	router := gin.New()
+	SetupAuthentication(router, map[string]interface{}{
+		"type":     "oidc",
+		"issuer":   issuer.server.URL + "/",
+		"audience": "chinchilla",
+	}, nil)
+	router.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"userID":      c.GetString("userID"),
+			"permissions": c.GetStringSlice("permissions"),
+		})
+	})
+	return router
+}
+
+func authenticate(router *gin.Engine, token string) (string, []string) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/me", nil)
+	req.Header.Add("authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+
+	var resp struct {
+		UserID      string   `json:"userID"`
+		Permissions []string `json:"permissions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	return resp.UserID, resp.Permissions
+}
+
+func TestOIDCValidTokens(t *testing.T) {
+	issuer := newTestIssuer()
+	defer issuer.server.Close()
+	rsaKey := issuer.addRSAKey("rsa1")
+	ecKey := issuer.addECKey("ec1")
+	router := setupOIDCRouter(issuer)
+
+	userID, permissions := authenticate(router, signToken(jwt.SigningMethodRS256, "rsa1", rsaKey, issuer.claims()))
+	assert.Equal(t, "user1", userID)
+	assert.Equal(t, []string{"read:agents"}, permissions)
+
+	userID, _ = authenticate(router, signToken(jwt.SigningMethodES256, "ec1", ecKey, issuer.claims()))
+	assert.Equal(t, "user1", userID)
+
+	claims := issuer.claims()
+	claims["aud"] = "chinchilla"
+	userID, _ = authenticate(router, signToken(jwt.SigningMethodRS256, "rsa1", rsaKey, claims))
+	assert.Equal(t, "user1", userID)
+}
+
+func TestOIDCRejectsInvalidTokens(t *testing.T) {
+	issuer := newTestIssuer()
+	defer issuer.server.Close()
+	key := issuer.addRSAKey("rsa1")
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	router := setupOIDCRouter(issuer)
+
+	tokens := map[string]string{
+		"wrong signature": signToken(jwt.SigningMethodRS256, "rsa1", otherKey, issuer.claims()),
+		"HMAC":            signToken(jwt.SigningMethodHS256, "rsa1", []byte("secret"), issuer.claims()),
+		"RS512":           signToken(jwt.SigningMethodRS512, "rsa1", key, issuer.claims()),
+	}
+
+	claims := issuer.claims()
+	claims["aud"] = "other"
+	tokens["audience"] = signToken(jwt.SigningMethodRS256, "rsa1", key, claims)
+
+	claims = issuer.claims()
+	claims["aud"] = "other chinchilla"
+	tokens["space separated audience"] = signToken(jwt.SigningMethodRS256, "rsa1", key, claims)
+
+	claims = issuer.claims()
+	claims["iss"] = "https://attacker.example.com/"
+	tokens["issuer"] = signToken(jwt.SigningMethodRS256, "rsa1", key, claims)
+
+	claims = issuer.claims()
+	claims["exp"] = time.Now().Add(-time.Minute).Unix()
+	tokens["expired"] = signToken(jwt.SigningMethodRS256, "rsa1", key, claims)
+
+	claims = issuer.claims()
+	delete(claims, "exp")
+	tokens["no expiry"] = signToken(jwt.SigningMethodRS256, "rsa1", key, claims)
+
+	claims = issuer.claims()
+	claims["exp"] = "never"
+	tokens["non-numeric expiry"] = signToken(jwt.SigningMethodRS256, "rsa1", key, claims)
+
+	for name, token := range tokens {
+		userID, _ := authenticate(router, token)
+		assert.Empty(t, userID, name)
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	issuer := newTestIssuer()
+	defer issuer.server.Close()
+	key := issuer.addRSAKey("rsa1")
+	router := setupOIDCRouter(issuer)
+
+	userID, _ := authenticate(router, signToken(jwt.SigningMethodRS256, "rsa1", key, issuer.claims()))
+	assert.Equal(t, "user1", userID)
+
+	// unknown key IDs don't refresh the keys too often
+	rotated := issuer.addRSAKey("rsa2")
+	userID, _ = authenticate(router, signToken(jwt.SigningMethodRS256, "rsa2", rotated, issuer.claims()))
+	assert.Empty(t, userID)
+
+	jwksMinRefreshInterval = 0
+	defer func() { jwksMinRefreshInterval = time.Minute }()
+	userID, _ = authenticate(router, signToken(jwt.SigningMethodRS256, "rsa2", rotated, issuer.claims()))
+	assert.Equal(t, "user1", userID)
+}
+
+func TestOIDCKeyRefreshDoesNotBlockKnownKeys(t *testing.T) {
+	issuer := newTestIssuer()
+	defer issuer.server.Close()
+	key := issuer.addRSAKey("rsa1")
+	router := setupOIDCRouter(issuer)
+
+	userID, _ := authenticate(router, signToken(jwt.SigningMethodRS256, "rsa1", key, issuer.claims()))
+	assert.Equal(t, "user1", userID)
+
+	jwksMinRefreshInterval = 0
+	defer func() { jwksMinRefreshInterval = time.Minute }()
+
+	// the issuer hangs while refreshing the keys for an unknown key ID
+	issuer.mutex.Lock()
+	refreshed := make(chan struct{})
+	go func() {
+		authenticate(router, signToken(jwt.SigningMethodRS256, "rsa2", key, issuer.claims()))
+		close(refreshed)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	userID, _ = authenticate(router, signToken(jwt.SigningMethodRS256, "rsa1", key, issuer.claims()))
+	assert.Equal(t, "user1", userID)
+
+	issuer.mutex.Unlock()
+	<-refreshed
+}
+
+func TestOIDCClaimMapping(t *testing.T) {
+	issuer := newTestIssuer()
+	defer issuer.server.Close()
+	key := issuer.addRSAKey("rsa1")
+
+	router := gin.New()
+	SetupAuthentication(router, map[string]interface{}{
+		"type":             "oidc",
+		"issuer":           issuer.server.URL + "/",
+		"audience":         "chinchilla",
+		"userClaim":        "email",
+		"permissionsClaim": "scope",
+	}, nil)
+	router.GET("/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"userID":      c.GetString("userID"),
+			"permissions": c.GetStringSlice("permissions"),
+		})
+	})
+
+	claims := issuer.claims()
+	claims["email"] = "user1@example.com"
+	claims["scope"] = "read:agents write:agents"
+
+	userID, permissions := authenticate(router, signToken(jwt.SigningMethodRS256, "rsa1", key, claims))
+	assert.Equal(t, "user1@example.com", userID)
+	assert.Equal(t, []string{"read:agents", "write:agents"}, permissions)
+}
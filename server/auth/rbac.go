@@ -0,0 +1,379 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/gin-gonic/gin"
+)
+
+// Verbs of the permissions
+const (
+	VerbRead   = "read"
+	VerbCreate = "create"
+	VerbUpdate = "update"
+	VerbDelete = "delete"
+)
+
+// Resources of the permissions
+const (
+	ResourceGameservers = "gameservers"
+	ResourceAgents      = "agents"
+	ResourceBackups     = "backups"
+	ResourceConsole     = "console"
+	ResourceTeams       = "teams"
+	ResourceQuotas      = "quotas"
+	ResourceTokens      = "tokens"
+	ResourceAudit       = "audit"
+)
+
+var resources = []string{
+	ResourceGameservers, ResourceAgents, ResourceBackups, ResourceConsole,
+	ResourceTeams, ResourceQuotas, ResourceTokens, ResourceAudit,
+}
+
+// Scopes of the permissions. ScopeOwn grants access only
+// to the objects owned by the user or by the teams of the user
+const (
+	ScopeOwn = "own"
+	ScopeAll = "all"
+)
+
+// Built-in roles
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleUser     = "user"
+	RoleViewer   = "viewer"
+)
+
+const wildcard = "*"
+
+// Rule grants the verbs on the resources in the scope
+type Rule struct {
+	Resources []string
+	Verbs     []string
+	Scope     string
+}
+
+// Role is a named set of rules. The gameservers of users
+// with the role are limited by the Quota, if set
+type Role struct {
+	Rules []Rule
+	Quota *server.Quota
+}
+
+// Binding assigns the role to users and to members of groups
+type Binding struct {
+	Role   string
+	Users  []string
+	Groups []string
+}
+
+// Policy is the RBAC policy. Logged in users without
+// a binding get the DefaultRole. TeamQuota limits the gameservers of teams
+type Policy struct {
+	DefaultRole string
+	Roles       map[string]Role
+	Bindings    []Binding
+	TeamQuota   *server.Quota
+}
+
+// DefaultPolicy is used, when no policy file is configured
+var DefaultPolicy = Policy{
+	DefaultRole: RoleUser,
+	Roles: map[string]Role{
+		RoleAdmin: {Rules: []Rule{
+			{Resources: []string{wildcard}, Verbs: []string{wildcard}, Scope: ScopeAll},
+		}},
+		RoleOperator: {Rules: []Rule{
+			{Resources: []string{ResourceAgents}, Verbs: []string{wildcard}, Scope: ScopeAll},
+			{Resources: []string{ResourceGameservers, ResourceBackups}, Verbs: []string{VerbRead, VerbUpdate}, Scope: ScopeAll},
+			{Resources: []string{ResourceConsole}, Verbs: []string{VerbRead}, Scope: ScopeAll},
+		}},
+		RoleUser: {
+			Rules: []Rule{
+				{Resources: []string{ResourceGameservers, ResourceBackups, ResourceConsole, ResourceTeams, ResourceTokens}, Verbs: []string{wildcard}, Scope: ScopeOwn},
+			},
+			Quota: &server.Quota{MaxGameservers: 5},
+		},
+		RoleViewer: {Rules: []Rule{
+			{Resources: []string{ResourceGameservers, ResourceBackups, ResourceConsole, ResourceTeams}, Verbs: []string{VerbRead}, Scope: ScopeOwn},
+			{Resources: []string{ResourceTokens}, Verbs: []string{wildcard}, Scope: ScopeOwn},
+		}},
+	},
+	TeamQuota: &server.Quota{MaxGameservers: 10},
+}
+
+// LoadPolicy reads a policy from a toml file
+func LoadPolicy(path string) (*Policy, error) {
+	policy := &Policy{}
+	if _, err := toml.DecodeFile(path, policy); err != nil {
+		return nil, err
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+func (policy *Policy) validate() error {
+	if _, ok := policy.Roles[policy.DefaultRole]; policy.DefaultRole != "" && !ok {
+		return fmt.Errorf("unknown default role %s", policy.DefaultRole)
+	}
+
+	if err := validateQuota(policy.TeamQuota); err != nil {
+		return fmt.Errorf("team quota %v", err)
+	}
+
+	for name, role := range policy.Roles {
+		if err := validateQuota(role.Quota); err != nil {
+			return fmt.Errorf("role %s quota %v", name, err)
+		}
+		for _, rule := range role.Rules {
+			if rule.Scope != ScopeOwn && rule.Scope != ScopeAll {
+				return fmt.Errorf("role %s has an invalid scope %q", name, rule.Scope)
+			}
+			for _, verb := range rule.Verbs {
+				switch verb {
+				case VerbRead, VerbCreate, VerbUpdate, VerbDelete, wildcard:
+				default:
+					return fmt.Errorf("role %s has an invalid verb %q", name, verb)
+				}
+			}
+		}
+	}
+
+	for _, binding := range policy.Bindings {
+		if _, ok := policy.Roles[binding.Role]; !ok {
+			return fmt.Errorf("binding of unknown role %s", binding.Role)
+		}
+	}
+	return nil
+}
+
+func validateQuota(quota *server.Quota) error {
+	if quota != nil && (quota.MaxGameservers < 0 || quota.MemoryKB < 0 || quota.CPUMillicores < 0) {
+		return fmt.Errorf("has negative limits")
+	}
+	return nil
+}
+
+// QuotaOf returns the quota of a user with the roles. The limits of
+// multiple roles are combined to the most permissive ones
+// and roles without a quota are unlimited
+func (policy *Policy) QuotaOf(roles []string) *server.Quota {
+	var quota *server.Quota
+	for _, name := range roles {
+		role, ok := policy.Roles[name]
+		if !ok {
+			continue
+		}
+		if role.Quota == nil {
+			return nil
+		}
+		if quota == nil {
+			quota = &server.Quota{}
+			*quota = *role.Quota
+			quota.Games = append([]string(nil), role.Quota.Games...)
+			continue
+		}
+
+		quota.MaxGameservers = int(widerLimit(int64(quota.MaxGameservers), int64(role.Quota.MaxGameservers)))
+		quota.MemoryKB = widerLimit(quota.MemoryKB, role.Quota.MemoryKB)
+		quota.CPUMillicores = widerLimit(quota.CPUMillicores, role.Quota.CPUMillicores)
+		if len(quota.Games) == 0 || len(role.Quota.Games) == 0 {
+			quota.Games = nil
+		} else {
+			for _, game := range role.Quota.Games {
+				if !contains(quota.Games, game) {
+					quota.Games = append(quota.Games, game)
+				}
+			}
+		}
+	}
+	return quota
+}
+
+// widerLimit returns the higher limit, where zero is unlimited
+func widerLimit(a, b int64) int64 {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// RolesOf returns the roles bound to the user or its groups
+func (policy *Policy) RolesOf(userID string, groups []string) []string {
+	roles := make([]string, 0)
+	if userID == "" {
+		return roles
+	}
+
+	for _, binding := range policy.Bindings {
+		if contains(binding.Users, userID) || containsAny(binding.Groups, groups) {
+			roles = append(roles, binding.Role)
+		}
+	}
+	if len(roles) == 0 && policy.DefaultRole != "" {
+		roles = append(roles, policy.DefaultRole)
+	}
+	return roles
+}
+
+// Scope returns the widest scope, in which the roles grant the verb
+// on the resource. It's empty, when the roles don't grant it
+func (policy *Policy) Scope(roles []string, resource, verb string) string {
+	scope := ""
+	for _, name := range roles {
+		for _, rule := range policy.Roles[name].Rules {
+			if matches(rule.Resources, resource) && matches(rule.Verbs, verb) {
+				scope = widerScope(scope, rule.Scope)
+			}
+		}
+	}
+	return scope
+}
+
+// ValidPermission checks, if the permission is like read:gameservers.
+// The write verb covers all verbs except read
+func ValidPermission(permission string) bool {
+	parts := strings.SplitN(permission, ":", 2)
+	if len(parts) != 2 || !contains(resources, parts[1]) {
+		return false
+	}
+
+	switch parts[0] {
+	case VerbRead, VerbCreate, VerbUpdate, VerbDelete, "write":
+		return true
+	}
+	return false
+}
+
+// CoversPermission checks, if the scopes grant the permission.
+// The write permission requires all verbs except read
+func CoversPermission(scopes []string, permission string) bool {
+	parts := strings.SplitN(permission, ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	verbs := []string{parts[0]}
+	if parts[0] == "write" {
+		verbs = []string{VerbCreate, VerbUpdate, VerbDelete}
+	}
+	for _, verb := range verbs {
+		if permissionScope(scopes, parts[1], verb) == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// permissionScope maps the token permissions, like read:agents
+// or write:agents, to the verbs on all objects of the resource
+func permissionScope(permissions []string, resource, verb string) string {
+	for _, permission := range permissions {
+		parts := strings.SplitN(permission, ":", 2)
+		if len(parts) != 2 || parts[1] != resource {
+			continue
+		}
+		if parts[0] == verb || (parts[0] == "write" && verb != VerbRead) {
+			return ScopeAll
+		}
+	}
+	return ""
+}
+
+func widerScope(a, b string) string {
+	if a == ScopeAll || b == ScopeAll {
+		return ScopeAll
+	}
+	if a == ScopeOwn || b == ScopeOwn {
+		return ScopeOwn
+	}
+	return ""
+}
+
+func matches(values []string, value string) bool {
+	return contains(values, wildcard) || contains(values, value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values []string, others []string) bool {
+	for _, other := range others {
+		if contains(values, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// UsePolicy is a gin middleware, which sets the policy evaluated by Authorize
+func UsePolicy(policy *Policy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("policy", policy)
+	}
+}
+
+// CurrentPolicy returns the policy set by UsePolicy or the DefaultPolicy
+func CurrentPolicy(c *gin.Context) *Policy {
+	if value, ok := c.Get("policy"); ok {
+		return value.(*Policy)
+	}
+	return &DefaultPolicy
+}
+
+// Authorize is a gin authorization middleware evaluating the RBAC policy
+// and the token permissions. The granted scope is checked against
+// the owner of the accessed object using CanAccess
+func Authorize(resource, verb string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		permissions := c.GetStringSlice("permissions")
+		if _, authenticated := c.Get("permissions"); userID == "" && !authenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login required"})
+			c.Abort()
+			return
+		}
+
+		policy := CurrentPolicy(c)
+		roles := policy.RolesOf(userID, c.GetStringSlice("groups"))
+		scope := widerScope(policy.Scope(roles, resource, verb), permissionScope(permissions, resource, verb))
+		if scopes := c.GetStringSlice("tokenScopes"); len(scopes) > 0 && permissionScope(scopes, resource, verb) == "" {
+			scope = ""
+		}
+		if scope == "" {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Permission denied to %s %s", verb, resource)})
+			c.Abort()
+			return
+		}
+
+		c.Set("scope", scope)
+		c.Set("verb", verb)
+		c.Set("roles", roles)
+	}
+}
+
+// CanAccess checks, if the authorized request can access an object
+// of the owner
+func CanAccess(c *gin.Context, owner string) bool {
+	if c.GetString("scope") == ScopeAll {
+		return true
+	}
+	return owner != "" && owner == c.GetString("userID")
+}
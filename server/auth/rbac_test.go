@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultPolicy(t *testing.T) {
+	policy := &DefaultPolicy
+
+	cases := []struct {
+		role, resource, verb, scope string
+	}{
+		{RoleAdmin, ResourceAgents, VerbDelete, ScopeAll},
+		{RoleAdmin, ResourceGameservers, VerbUpdate, ScopeAll},
+		{RoleOperator, ResourceAgents, VerbUpdate, ScopeAll},
+		{RoleOperator, ResourceGameservers, VerbRead, ScopeAll},
+		{RoleOperator, ResourceGameservers, VerbDelete, ""},
+		{RoleUser, ResourceGameservers, VerbCreate, ScopeOwn},
+		{RoleUser, ResourceAgents, VerbRead, ""},
+		{RoleViewer, ResourceConsole, VerbRead, ScopeOwn},
+		{RoleViewer, ResourceGameservers, VerbDelete, ""},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.scope, policy.Scope([]string{c.role}, c.resource, c.verb), "%s %s %s", c.role, c.verb, c.resource)
+	}
+}
+
+func TestRolesOf(t *testing.T) {
+	policy := DefaultPolicy
+	policy.Bindings = []Binding{
+		{Role: RoleAdmin, Users: []string{"admin1"}},
+		{Role: RoleOperator, Groups: []string{"ops"}},
+	}
+
+	assert.Equal(t, []string{RoleAdmin}, policy.RolesOf("admin1", nil))
+	assert.Equal(t, []string{RoleOperator}, policy.RolesOf("user2", []string{"players", "ops"}))
+	assert.Equal(t, []string{RoleUser}, policy.RolesOf("user3", nil))
+	assert.Empty(t, policy.RolesOf("", []string{"ops"}))
+}
+
+func TestQuotaOf(t *testing.T) {
+	policy := Policy{Roles: map[string]Role{
+		"small":     {Quota: &server.Quota{MaxGameservers: 2, MemoryKB: 1024, Games: []string{"Minecraft"}}},
+		"large":     {Quota: &server.Quota{MaxGameservers: 5, CPUMillicores: 2000, Games: []string{"Factorio"}}},
+		"unlimited": {},
+	}}
+
+	assert.Equal(t, &server.Quota{MaxGameservers: 2, MemoryKB: 1024, Games: []string{"Minecraft"}}, policy.QuotaOf([]string{"small"}))
+	assert.Equal(t, &server.Quota{MaxGameservers: 5, Games: []string{"Minecraft", "Factorio"}}, policy.QuotaOf([]string{"small", "large"}))
+	assert.Nil(t, policy.QuotaOf([]string{"small", "unlimited"}))
+	assert.Equal(t, []string{"Minecraft"}, policy.Roles["small"].Quota.Games)
+}
+
+func TestLoadPolicy(t *testing.T) {
+	policy, err := LoadPolicy("../../policy.toml")
+	assert.NoError(t, err)
+	assert.Equal(t, RoleUser, policy.DefaultRole)
+	assert.Equal(t, []string{RoleAdmin}, policy.RolesOf("user1", []string{"chinchilla-admins"}))
+	assert.Equal(t, ScopeAll, policy.Scope([]string{RoleAdmin}, ResourceBackups, VerbDelete))
+	assert.Equal(t, 5, policy.QuotaOf([]string{RoleUser}).MaxGameservers)
+	assert.Equal(t, 10, policy.TeamQuota.MaxGameservers)
+
+	file, _ := ioutil.TempFile("", "policy")
+	defer os.Remove(file.Name())
+	file.WriteString(`
+[roles.user]
+rules = [{ resources = ["gameservers"], verbs = ["destroy"], scope = "own" }]
+`)
+	file.Close()
+
+	_, err = LoadPolicy(file.Name())
+	assert.Error(t, err)
+}
+
+func authorizeRequest(header map[string]string, resource, verb, owner string) (int, bool) {
+	router := gin.New()
+	router.Use(headerAuth, UsePolicy(&Policy{
+		DefaultRole: RoleViewer,
+		Roles:       DefaultPolicy.Roles,
+		Bindings:    []Binding{{Role: RoleAdmin, Groups: []string{"admins"}}},
+	}))
+
+	canAccess := false
+	router.GET("/", Authorize(resource, verb), func(c *gin.Context) {
+		canAccess = CanAccess(c, owner)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/", nil)
+	for key, value := range header {
+		req.Header.Add(key, value)
+	}
+	router.ServeHTTP(w, req)
+	return w.Code, canAccess
+}
+
+func TestAuthorize(t *testing.T) {
+	code, _ := authorizeRequest(nil, ResourceGameservers, VerbRead, "user1")
+	assert.Equal(t, 401, code)
+
+	code, canAccess := authorizeRequest(map[string]string{"x-user": "user1"}, ResourceGameservers, VerbRead, "user1")
+	assert.Equal(t, 200, code)
+	assert.True(t, canAccess)
+
+	code, canAccess = authorizeRequest(map[string]string{"x-user": "user1"}, ResourceGameservers, VerbRead, "user2")
+	assert.Equal(t, 200, code)
+	assert.False(t, canAccess)
+
+	code, _ = authorizeRequest(map[string]string{"x-user": "user1"}, ResourceGameservers, VerbDelete, "user1")
+	assert.Equal(t, 403, code)
+
+	code, canAccess = authorizeRequest(map[string]string{"x-user": "user1", "x-groups": "admins"}, ResourceGameservers, VerbDelete, "user2")
+	assert.Equal(t, 200, code)
+	assert.True(t, canAccess)
+
+	code, _ = authorizeRequest(map[string]string{"x-permissions": "write:agents"}, ResourceAgents, VerbUpdate, "")
+	assert.Equal(t, 200, code)
+
+	code, _ = authorizeRequest(map[string]string{"x-permissions": "read:agents"}, ResourceAgents, VerbUpdate, "")
+	assert.Equal(t, 403, code)
+}
@@ -1,10 +1,16 @@
 package gameservers
 
 import (
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"regexp"
+	"time"
 
+	"github.com/Trojan295/chinchilla/proto"
 	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/audit"
 	"github.com/Trojan295/chinchilla/server/auth"
 
 	"github.com/gin-gonic/gin"
@@ -23,41 +29,146 @@ type supportedGameserverResponse struct {
 	Options []supportedGameserverOptions `json:"options"`
 }
 
+type gameserverResourceUsage struct {
+	CPUPercent      float64 `json:"cpuPercent"`
+	MemoryUsage     int     `json:"memoryUsage"`
+	MemoryLimit     int     `json:"memoryLimit"`
+	NetworkRxBytes  int     `json:"networkRxBytes"`
+	NetworkTxBytes  int     `json:"networkTxBytes"`
+	BlockReadBytes  int     `json:"blockReadBytes"`
+	BlockWriteBytes int     `json:"blockWriteBytes"`
+}
+
+type gameserverQueryResult struct {
+	PlayersOnline int    `json:"playersOnline"`
+	PlayersMax    int    `json:"playersMax"`
+	MOTD          string `json:"motd"`
+	Version       string `json:"version"`
+}
+
 type getGameserverResponse struct {
-	UUID    string  `json:"uuid"`
-	Name    string  `json:"name"`
-	Game    string  `json:"game"`
-	Version string  `json:"version"`
-	Address *string `json:"address"`
-	Status  string  `json:"status"`
+	UUID          string                   `json:"uuid"`
+	Owner         string                   `json:"owner"`
+	Team          string                   `json:"team,omitempty"`
+	Name          string                   `json:"name"`
+	Game          string                   `json:"game"`
+	Version       string                   `json:"version"`
+	Address       *string                  `json:"address"`
+	Status        string                   `json:"status"`
+	Info          string                   `json:"info,omitempty"`
+	PullProgress  float64                  `json:"pullProgress,omitempty"`
+	Health        string                   `json:"health"`
+	ResourceUsage *gameserverResourceUsage `json:"resourceUsage"`
+	Query         *gameserverQueryResult   `json:"query"`
+	ImageDigest   string                   `json:"imageDigest,omitempty"`
+	RestartCount  int                      `json:"restartCount"`
+	LastExitCode  int                      `json:"lastExitCode"`
+	CrashLog      string                   `json:"crashLog,omitempty"`
+	Placement     *placement               `json:"placement,omitempty"`
+
+	UnschedulableReasons []string `json:"unschedulableReasons,omitempty"`
 }
 
 type listGameserversResponse []getGameserverResponse
 
+type idlePolicy struct {
+	StopAfterMinutes int `json:"stopAfterMinutes" binding:"min=1"`
+}
+
+type placement struct {
+	NodeSelector    map[string]string `json:"nodeSelector"`
+	PreferredRegion string            `json:"preferredRegion"`
+	AntiAffinity    bool              `json:"antiAffinity"`
+}
+
+type restartPolicy struct {
+	Type       string `json:"type" binding:"required"`
+	MaxRetries int    `json:"maxRetries" binding:"min=0"`
+}
+
+var restartPolicyTypes = map[string]proto.RestartPolicyType{
+	"never":      proto.RestartPolicyType_RESTART_NEVER,
+	"on-failure": proto.RestartPolicyType_RESTART_ON_FAILURE,
+	"always":     proto.RestartPolicyType_RESTART_ALWAYS,
+}
+
+var imagePullPolicies = map[string]proto.ImagePullPolicy{
+	"Always":       proto.ImagePullPolicy_PULL_ALWAYS,
+	"IfNotPresent": proto.ImagePullPolicy_PULL_IF_NOT_PRESENT,
+	"Never":        proto.ImagePullPolicy_PULL_NEVER,
+}
+
+var imageDigestRegexp = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
 type createGameserverRequest struct {
-	Name       string            `json:"name" binding:"required"`
-	Game       string            `json:"game" binding:"required"`
-	Version    string            `json:"version" binding:"required"`
-	Parameters map[string]string `json:"parameters" binding:"required"`
+	Name            string            `json:"name" binding:"required"`
+	Game            string            `json:"game" binding:"required"`
+	Version         string            `json:"version" binding:"required"`
+	Parameters      map[string]string `json:"parameters" binding:"required"`
+	IdlePolicy      *idlePolicy       `json:"idlePolicy"`
+	RestartPolicy   *restartPolicy    `json:"restartPolicy"`
+	ImagePullPolicy string            `json:"imagePullPolicy"`
+	ImageDigest     string            `json:"imageDigest"`
+	Command         []string          `json:"command"`
+	Placement       *placement        `json:"placement"`
+	Team            string            `json:"team"`
 }
 
 type createGameserverResponse getGameserverResponse
 
+type gameserverEvent struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Message string    `json:"message"`
+}
+
+type listGameserverEventsResponse []gameserverEvent
+
+type migrateGameserverRequest struct {
+	TargetAgent string     `json:"targetAgent"`
+	Placement   *placement `json:"placement"`
+}
+
+type gameserverOperation struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Phase       string     `json:"phase"`
+	Error       string     `json:"error,omitempty"`
+	SourceAgent string     `json:"sourceAgent"`
+	TargetAgent string     `json:"targetAgent,omitempty"`
+	StartedAt   time.Time  `json:"startedAt"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+type listGameserverOperationsResponse []gameserverOperation
+
 type gameserversAPI struct {
 	agentsStore       server.AgentStore
 	gameserverStore   server.GameserverStore
+	eventStore        server.EventStore
+	operationStore    server.OperationStore
+	teamStore         server.TeamStore
+	quotaStore        server.QuotaStore
 	gameserverManager GameserverManager
 }
 
 // MountGameserverAPI func
-func MountGameserverAPI(r *gin.Engine, agStore server.AgentStore, gsStore server.GameserverStore) {
-	api := gameserversAPI{agStore, gsStore, NewGameserverManager()}
+func MountGameserverAPI(r *gin.Engine, agStore server.AgentStore, gsStore server.GameserverStore, eventStore server.EventStore, operationStore server.OperationStore, teamStore server.TeamStore, quotaStore server.QuotaStore) {
+	api := gameserversAPI{agStore, gsStore, eventStore, operationStore, teamStore, quotaStore, NewGameserverManager()}
+
+	r.GET("/quota", auth.Authorize(auth.ResourceGameservers, auth.VerbRead), api.getQuota)
+	r.PUT("/quotas/:kind/:id", auth.Authorize(auth.ResourceQuotas, auth.VerbUpdate), audit.Log("quota.set"), api.setQuota)
+	r.DELETE("/quotas/:kind/:id", auth.Authorize(auth.ResourceQuotas, auth.VerbDelete), audit.Log("quota.delete"), api.deleteQuota)
 
 	group := r.Group("/gameservers/")
 	group.OPTIONS("/", api.getSupportedGameservers)
-	group.GET("/", auth.LoginRequired(), api.listGameservers)
-	group.POST("/", auth.LoginRequired(), api.createGameserver)
-	group.DELETE("/:uuid/", auth.LoginRequired(), api.deleteGameserver)
+	group.GET("/", auth.Authorize(auth.ResourceGameservers, auth.VerbRead), api.listGameservers)
+	group.POST("/", auth.Authorize(auth.ResourceGameservers, auth.VerbCreate), audit.Log("gameserver.create"), api.createGameserver)
+	group.DELETE("/:uuid/", auth.Authorize(auth.ResourceGameservers, auth.VerbDelete), audit.Log("gameserver.delete"), api.deleteGameserver)
+	group.POST("/:uuid/wake/", auth.Authorize(auth.ResourceGameservers, auth.VerbUpdate), audit.Log("gameserver.wake"), api.wakeGameserver)
+	group.GET("/:uuid/events/", auth.Authorize(auth.ResourceGameservers, auth.VerbRead), api.listGameserverEvents)
+	group.POST("/:uuid/migrate/", auth.Authorize(auth.ResourceGameservers, auth.VerbUpdate), audit.Log("gameserver.migrate"), api.migrateGameserver)
+	group.GET("/:uuid/operations/", auth.Authorize(auth.ResourceGameservers, auth.VerbRead), api.listGameserverOperations)
 }
 
 func (api *gameserversAPI) getSupportedGameservers(c *gin.Context) {
@@ -89,6 +200,37 @@ func (api *gameserversAPI) createGameserver(c *gin.Context) {
 		return
 	}
 
+	if body.RestartPolicy != nil {
+		if _, ok := restartPolicyTypes[body.RestartPolicy.Type]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid restart policy type"})
+			return
+		}
+	}
+
+	if _, ok := imagePullPolicies[body.ImagePullPolicy]; body.ImagePullPolicy != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image pull policy"})
+		return
+	}
+	if body.ImageDigest != "" && !imageDigestRegexp.MatchString(body.ImageDigest) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid image digest"})
+		return
+	}
+
+	if !validCommand(body.Command) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid command"})
+		return
+	}
+
+	if !validPlacement(body.Placement) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node selector"})
+		return
+	}
+
+	if body.Team != "" && !api.canCreateInTeam(c, body.Team) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot create gameservers in the team"})
+		return
+	}
+
 	owner := c.GetString("userID")
 	uuid := uuid.NewV4().String()
 
@@ -97,34 +239,87 @@ func (api *gameserversAPI) createGameserver(c *gin.Context) {
 			UUID:       uuid,
 			Name:       body.Name,
 			Owner:      owner,
+			Team:       body.Team,
 			Game:       body.Game,
 			Version:    body.Version,
 			Parameters: body.Parameters,
 		},
 	}
 
+	if body.IdlePolicy != nil {
+		gs.Definition.IdlePolicy = &server.IdlePolicy{
+			StopAfterMinutes: body.IdlePolicy.StopAfterMinutes,
+		}
+	}
+
+	if body.Placement != nil {
+		gs.Definition.Placement = &server.Placement{
+			NodeSelector:    body.Placement.NodeSelector,
+			PreferredRegion: body.Placement.PreferredRegion,
+			AntiAffinity:    body.Placement.AntiAffinity,
+		}
+	}
+
 	deployment, err := api.gameserverManager.CreateGameserverDeployment(&gs.Definition)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"error": err})
 		return
 	}
 
+	if body.RestartPolicy != nil {
+		deployment.RestartPolicy = &proto.RestartPolicy{
+			Type:       restartPolicyTypes[body.RestartPolicy.Type],
+			MaxRetries: int64(body.RestartPolicy.MaxRetries),
+		}
+	}
+
+	if body.ImagePullPolicy != "" {
+		deployment.ImagePullPolicy = imagePullPolicies[body.ImagePullPolicy]
+	}
+	deployment.ImageDigest = body.ImageDigest
+	if len(body.Command) > 0 {
+		deployment.Command = body.Command
+	}
+
 	gs.Deployment = deployment
-	api.gameserverStore.CreateGameserver(&gs)
+
+	unlock, ok := api.reserveQuota(c, owner, gs.Definition.Team, gs.Definition.Game, server.GameserverResources(&gs))
+	if !ok {
+		return
+	}
+	err = api.gameserverStore.CreateGameserver(&gs)
+	unlock()
+	if err != nil {
+		log.Printf("gameserversAPI createGameserver error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot create gameserver"})
+		return
+	}
+
+	server.RecordGameserverEvent(api.eventStore, gs.Definition.UUID, server.EventCreated,
+		fmt.Sprintf("Created by %s", owner))
+	audit.SetTarget(c, gs.Definition.UUID)
+	audit.SetChange(c, "", gameserverSummary(&gs))
 
 	response := createGameserverResponse{
-		UUID:    gs.Definition.UUID,
-		Name:    gs.Definition.Name,
-		Game:    gs.Definition.Game,
-		Status:  "UNKNOWN",
-		Version: gs.Definition.Version,
+		UUID:      gs.Definition.UUID,
+		Owner:     gs.Definition.Owner,
+		Team:      gs.Definition.Team,
+		Name:      gs.Definition.Name,
+		Game:      gs.Definition.Game,
+		Status:    "UNKNOWN",
+		Version:   gs.Definition.Version,
+		Placement: body.Placement,
 	}
 
 	c.JSON(http.StatusAccepted, response)
 }
 
+// listGameservers returns the gameservers accessible by the user,
+// optionally filtered by the owner or the team
 func (api *gameserversAPI) listGameservers(c *gin.Context) {
-	userID := c.GetString("userID")
+	owner := c.Query("owner")
+	teamID := c.Query("team")
+	teams := make(map[string]*server.Team)
 
 	gameservers, err := api.gameserverStore.ListGameservers()
 	if err != nil {
@@ -133,32 +328,70 @@ func (api *gameserversAPI) listGameservers(c *gin.Context) {
 
 	resp := listGameserversResponse{}
 	for _, gameserver := range gameservers {
-		if gameserver.Definition.Owner != userID {
+		if !api.canAccess(c, &gameserver, teams) {
+			continue
+		}
+		if owner != "" && gameserver.Definition.Owner != owner {
+			continue
+		}
+		if teamID != "" && gameserver.Definition.Team != teamID {
 			continue
 		}
 
 		agent, err := api.agentsStore.GetAgent(gameserver.Deployment.Agent)
 
 		var address string
+		var resourceUsage *gameserverResourceUsage
+		var queryResult *gameserverQueryResult
+		var restartCount, lastExitCode int
+		var crashLog, info, imageDigest string
+		var pullProgress float64
 		status := "UNKNOWN"
+		health := proto.HealthStatus_HEALTH_UNKNOWN.String()
+		if gameserver.Deployment.Stopped {
+			status = proto.GameserverStatus_STOPPED.String()
+		}
 		if err != nil {
 			log.Printf("gameserversAPI GetAgentState error: %v", err)
 		} else {
 			for _, agentServer := range agent.State.RunningGameservers {
 				if agentServer.UUID == gameserver.Definition.UUID {
 					status = string(agentServer.Status.String())
+					health = agentServer.Health.String()
 					address, _ = api.gameserverManager.Endpoint(&gameserver, agentServer)
+					resourceUsage = newGameserverResourceUsage(agentServer.ResourceUsage)
+					queryResult = newGameserverQueryResult(agentServer.Query)
+					restartCount = int(agentServer.RestartCount)
+					lastExitCode = int(agentServer.LastExitCode)
+					crashLog = agentServer.CrashLog
+					info = agentServer.Info
+					pullProgress = agentServer.PullProgress
+					imageDigest = agentServer.ImageDigest
 				}
 			}
 		}
 
 		resp = append(resp, getGameserverResponse{
-			UUID:    gameserver.Definition.UUID,
-			Name:    gameserver.Definition.Name,
-			Game:    gameserver.Definition.Game,
-			Version: gameserver.Definition.Version,
-			Address: &address,
-			Status:  status,
+			UUID:          gameserver.Definition.UUID,
+			Owner:         gameserver.Definition.Owner,
+			Team:          gameserver.Definition.Team,
+			Name:          gameserver.Definition.Name,
+			Game:          gameserver.Definition.Game,
+			Version:       gameserver.Definition.Version,
+			Address:       &address,
+			Status:        status,
+			Info:          info,
+			PullProgress:  pullProgress,
+			ImageDigest:   imageDigest,
+			Health:        health,
+			ResourceUsage: resourceUsage,
+			Query:         queryResult,
+			RestartCount:  restartCount,
+			LastExitCode:  lastExitCode,
+			CrashLog:      crashLog,
+			Placement:     newPlacement(gameserver.Definition.Placement),
+
+			UnschedulableReasons: gameserver.UnschedulableReasons,
 		})
 
 	}
@@ -166,26 +399,358 @@ func (api *gameserversAPI) listGameservers(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
+// validCommand checks, that the command has a program to run
+func validCommand(command []string) bool {
+	return len(command) == 0 || command[0] != ""
+}
+
+// validPlacement checks the node selector keys of the placement
+func validPlacement(placement *placement) bool {
+	if placement == nil {
+		return true
+	}
+	for key := range placement.NodeSelector {
+		if key == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func newPlacement(definition *server.Placement) *placement {
+	if definition == nil {
+		return nil
+	}
+
+	return &placement{
+		NodeSelector:    definition.NodeSelector,
+		PreferredRegion: definition.PreferredRegion,
+		AntiAffinity:    definition.AntiAffinity,
+	}
+}
+
+func newGameserverResourceUsage(usage *proto.GameserverResourceUsage) *gameserverResourceUsage {
+	if usage == nil {
+		return nil
+	}
+
+	return &gameserverResourceUsage{
+		CPUPercent:      usage.CpuPercent,
+		MemoryUsage:     int(usage.MemoryUsage),
+		MemoryLimit:     int(usage.MemoryLimit),
+		NetworkRxBytes:  int(usage.NetworkRxBytes),
+		NetworkTxBytes:  int(usage.NetworkTxBytes),
+		BlockReadBytes:  int(usage.BlockReadBytes),
+		BlockWriteBytes: int(usage.BlockWriteBytes),
+	}
+}
+
+func newGameserverQueryResult(result *proto.GameserverQueryResult) *gameserverQueryResult {
+	if result == nil {
+		return nil
+	}
+
+	return &gameserverQueryResult{
+		PlayersOnline: int(result.PlayersOnline),
+		PlayersMax:    int(result.PlayersMax),
+		MOTD:          result.Motd,
+		Version:       result.Version,
+	}
+}
+
+// gameserverSummary describes the gameserver in the audit log
+func gameserverSummary(gameserver *server.Gameserver) string {
+	summary := fmt.Sprintf("%s %s %q", gameserver.Definition.Game, gameserver.Definition.Version, gameserver.Definition.Name)
+	if deployment := gameserver.Deployment; deployment != nil {
+		state := "running"
+		if deployment.Stopped {
+			state = "stopped"
+		}
+		summary += fmt.Sprintf(" on agent %q, %s", deployment.Agent, state)
+	}
+	return summary
+}
+
 func (api *gameserversAPI) deleteGameserver(c *gin.Context) {
+	gameserver, ok := api.getOwnedGameserver(c)
+	if !ok {
+		return
+	}
+	audit.SetChange(c, gameserverSummary(gameserver), "")
+
+	err := api.gameserverStore.DeleteGameserver(c.Param("uuid"))
+	if err != nil {
+		log.Printf("gameserversAPI deleteGameserver error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, "")
+		return
+	}
+
+	server.RecordGameserverEvent(api.eventStore, c.Param("uuid"), server.EventDeleted,
+		fmt.Sprintf("Deleted by %s", c.GetString("userID")))
+	c.JSON(http.StatusAccepted, gin.H{})
+}
+
+// canAccess checks, if the request can access the gameserver. Gameservers
+// of a team are accessible by its members, but viewers can only read them.
+// The teams are cached in the map during the request
+func (api *gameserversAPI) canAccess(c *gin.Context, gameserver *server.Gameserver, teams map[string]*server.Team) bool {
+	if gameserver.Definition.Team == "" {
+		return auth.CanAccess(c, gameserver.Definition.Owner)
+	}
+	if auth.CanAccess(c, "") {
+		return true
+	}
+
+	team, ok := teams[gameserver.Definition.Team]
+	if !ok {
+		team, _ = api.teamStore.GetTeam(gameserver.Definition.Team)
+		teams[gameserver.Definition.Team] = team
+	}
+	if team == nil {
+		return false
+	}
+
+	member := team.Member(c.GetString("userID"))
+	if member == nil {
+		return false
+	}
+	return member.Role != server.TeamViewer || c.GetString("verb") == auth.VerbRead
+}
+
+func (api *gameserversAPI) canCreateInTeam(c *gin.Context, teamID string) bool {
+	team, err := api.teamStore.GetTeam(teamID)
+	if err != nil {
+		return false
+	}
+	if auth.CanAccess(c, "") {
+		return true
+	}
+
+	member := team.Member(c.GetString("userID"))
+	return member != nil && member.Role != server.TeamViewer
+}
+
+// getOwnedGameserver returns the gameserver, if the request is authorized
+// to access it. Others' gameservers are reported as not found
+func (api *gameserversAPI) getOwnedGameserver(c *gin.Context) (*server.Gameserver, bool) {
 	UUID := c.Param("uuid")
-	userID := c.GetString("userID")
 
 	gameserver, err := api.gameserverStore.GetGameserver(UUID)
+	if err != nil || !api.canAccess(c, gameserver, make(map[string]*server.Team)) {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return nil, false
+	}
+
+	return gameserver, true
+}
+
+func (api *gameserversAPI) wakeGameserver(c *gin.Context) {
+	if _, ok := api.getOwnedGameserver(c); !ok {
+		return
+	}
+
+	unlock, err := api.gameserverStore.LockGameserver(c.Param("uuid"))
+	if err != nil {
+		log.Printf("gameserversAPI wakeGameserver error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot wake gameserver"})
+		return
+	}
+	defer unlock()
+
+	gameserver, err := api.gameserverStore.GetGameserver(c.Param("uuid"))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{})
 		return
 	}
 
-	if gameserver.Definition.Owner != userID {
+	if !gameserver.Deployment.Stopped {
+		c.JSON(http.StatusConflict, gin.H{"error": "Gameserver is not stopped"})
+		return
+	}
+
+	if !gameserver.WakeRequested {
+		requested := server.GameserverResources(gameserver)
+		requested.Gameservers = 0
+		unlockQuota, ok := api.reserveQuota(c, gameserver.Definition.Owner, gameserver.Definition.Team, gameserver.Definition.Game, requested)
+		if !ok {
+			return
+		}
+
+		before := gameserverSummary(gameserver)
+		gameserver.WakeRequested = true
+		audit.SetChange(c, before, "wake requested")
+		err := api.gameserverStore.UpdateGameserver(gameserver)
+		unlockQuota()
+		if err != nil {
+			log.Printf("gameserversAPI wakeGameserver error: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot wake gameserver"})
+			return
+		}
+
+		server.RecordGameserverEvent(api.eventStore, gameserver.Definition.UUID, server.EventWakeRequested, "Wake requested by the owner")
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{})
+}
+
+func (api *gameserversAPI) listGameserverEvents(c *gin.Context) {
+	gameserver, ok := api.getOwnedGameserver(c)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	if query := c.Query("since"); query != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, query); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since"})
+			return
+		}
+	}
+
+	events, err := api.eventStore.ListGameserverEvents(gameserver.Definition.UUID)
+	if err != nil {
+		log.Printf("gameserversAPI listGameserverEvents error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot list events"})
+		return
+	}
+
+	eventType := c.Query("type")
+	resp := listGameserverEventsResponse{}
+	for _, event := range events {
+		if (eventType != "" && event.Type != eventType) || event.Time.Before(since) {
+			continue
+		}
+		resp = append(resp, gameserverEvent{
+			Time:    event.Time,
+			Type:    event.Type,
+			Message: event.Message,
+		})
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// migrateGameserver starts moving the gameserver with its data to another
+// agent. The placement replaces the placement of the gameserver. The
+// gameserver is locked, so concurrent requests cannot start two migrations
+func (api *gameserversAPI) migrateGameserver(c *gin.Context) {
+	var body migrateGameserverRequest
+	if c.Request.Body != nil {
+		if err := c.ShouldBindJSON(&body); err != nil && err != io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !validPlacement(body.Placement) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid node selector"})
+		return
+	}
+
+	if _, ok := api.getOwnedGameserver(c); !ok {
+		return
+	}
+
+	unlock, err := api.gameserverStore.LockGameserver(c.Param("uuid"))
+	if err != nil {
+		log.Printf("gameserversAPI migrateGameserver error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot migrate gameserver"})
+		return
+	}
+	defer unlock()
+
+	gameserver, err := api.gameserverStore.GetGameserver(c.Param("uuid"))
+	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{})
 		return
 	}
 
-	err = api.gameserverStore.DeleteGameserver(UUID)
+	if gameserver.Deployment.Agent == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Gameserver is not scheduled"})
+		return
+	}
+	if gameserver.OperationID != "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Gameserver is already being migrated"})
+		return
+	}
+	if body.TargetAgent == gameserver.Deployment.Agent {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Gameserver already runs on the target agent"})
+		return
+	}
+	if body.TargetAgent != "" {
+		if _, err := api.agentsStore.GetAgent(body.TargetAgent); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown target agent"})
+			return
+		}
+	}
+
+	before := gameserverSummary(gameserver)
+	previousPlacement := gameserver.Definition.Placement
+	if body.Placement != nil {
+		gameserver.Definition.Placement = &server.Placement{
+			NodeSelector:    body.Placement.NodeSelector,
+			PreferredRegion: body.Placement.PreferredRegion,
+			AntiAffinity:    body.Placement.AntiAffinity,
+		}
+	}
+
+	// The operation is created after the gameserver is updated,
+	// so a failed update doesn't leave an orphaned operation
+	operation := server.NewMigration(gameserver, body.TargetAgent, c.GetString("userID"))
+	gameserver.OperationID = operation.ID
+	if err := api.gameserverStore.UpdateGameserver(gameserver); err != nil {
+		log.Printf("gameserversAPI migrateGameserver error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot migrate gameserver"})
+		return
+	}
+
+	if err := api.operationStore.CreateOperation(operation); err != nil {
+		log.Printf("gameserversAPI migrateGameserver error: %v", err)
+		gameserver.OperationID = ""
+		gameserver.Definition.Placement = previousPlacement
+		if err := api.gameserverStore.UpdateGameserver(gameserver); err != nil {
+			log.Printf("gameserversAPI migrateGameserver error: cannot revert gameserver %s: %v", gameserver.Definition.UUID, err)
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot migrate gameserver"})
+		return
+	}
+
+	audit.SetChange(c, before, fmt.Sprintf("migrating to agent %q", operation.TargetAgent))
+	c.JSON(http.StatusAccepted, newGameserverOperation(operation))
+}
+
+func (api *gameserversAPI) listGameserverOperations(c *gin.Context) {
+	gameserver, ok := api.getOwnedGameserver(c)
+	if !ok {
+		return
+	}
+
+	operations, err := api.operationStore.ListOperations()
 	if err != nil {
-		log.Printf("gameserversAPI deleteGameserver error: %v", err)
-		c.JSON(http.StatusServiceUnavailable, "")
+		log.Printf("gameserversAPI listGameserverOperations error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot list operations"})
 		return
 	}
-	c.JSON(http.StatusAccepted, gin.H{})
+
+	resp := listGameserverOperationsResponse{}
+	for i := range operations {
+		if operations[i].GameserverUUID == gameserver.Definition.UUID {
+			resp = append(resp, newGameserverOperation(&operations[i]))
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func newGameserverOperation(operation *server.Operation) gameserverOperation {
+	return gameserverOperation{
+		ID:          operation.ID,
+		Type:        operation.Type,
+		Phase:       operation.Phase,
+		Error:       operation.Error,
+		SourceAgent: operation.SourceAgent,
+		TargetAgent: operation.TargetAgent,
+		StartedAt:   operation.StartedAt,
+		FinishedAt:  operation.FinishedAt,
+	}
 }
@@ -3,13 +3,17 @@ package gameservers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Trojan295/chinchilla/mocks"
 	"github.com/Trojan295/chinchilla/proto"
 	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/auth"
 	"github.com/Trojan295/chinchilla/server/utils"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -22,8 +26,10 @@ func TestListAvailableGameservers(t *testing.T) {
 	agentStore := mocks.NewMockAgentStore(ctrl)
 	gameserverStore := mocks.NewMockGameserverStore(ctrl)
 
+	eventStore := mocks.NewMockEventStore(ctrl)
+
 	router := utils.SetupRouter()
-	MountGameserverAPI(router, agentStore, gameserverStore)
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
 
 	claims := map[string]interface{}{}
 
@@ -57,6 +63,7 @@ func TestListUserGameservers(t *testing.T) {
 			Version: "1.12",
 			Owner:   "user1",
 		},
+		UnschedulableReasons: []string{"1 agent(s): insufficient memory"},
 	}
 	otherGameserver := server.Gameserver{
 		Deployment: &proto.GameserverDeployment{
@@ -74,9 +81,25 @@ func TestListUserGameservers(t *testing.T) {
 	gameserverInstance := &proto.Gameserver{
 		UUID:   gameserver.Definition.UUID,
 		Status: proto.GameserverStatus_RUNNING,
+		Health: proto.HealthStatus_HEALTHY,
 		Endpoint: &proto.Endpoint{
 			IpAddress: "10.0.0.14",
 		},
+		ResourceUsage: &proto.GameserverResourceUsage{
+			CpuPercent:     12.5,
+			MemoryUsage:    1024,
+			MemoryLimit:    2048,
+			NetworkRxBytes: 100,
+		},
+		Query: &proto.GameserverQueryResult{
+			PlayersOnline: 3,
+			PlayersMax:    20,
+			Motd:          "hello all!",
+			Version:       "1.12",
+		},
+		RestartCount: 2,
+		LastExitCode: 137,
+		ImageDigest:  "sha256:abcd",
 	}
 
 	agentStore := mocks.NewMockAgentStore(ctrl)
@@ -95,8 +118,10 @@ func TestListUserGameservers(t *testing.T) {
 		Return([]server.Gameserver{gameserver, otherGameserver}, nil).
 		AnyTimes()
 
+	eventStore := mocks.NewMockEventStore(ctrl)
+
 	router := utils.SetupRouter()
-	MountGameserverAPI(router, agentStore, gameserverStore)
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
 
 	claims := map[string]interface{}{
 		"sub": "user1",
@@ -119,6 +144,18 @@ func TestListUserGameservers(t *testing.T) {
 	assert.Equal(t, "1.12", res[0].Version)
 	assert.Equal(t, "10.0.0.14", *res[0].Address)
 	assert.Equal(t, "RUNNING", res[0].Status)
+	assert.Equal(t, "HEALTHY", res[0].Health)
+	assert.Equal(t, 12.5, res[0].ResourceUsage.CPUPercent)
+	assert.Equal(t, 1024, res[0].ResourceUsage.MemoryUsage)
+	assert.Equal(t, 2048, res[0].ResourceUsage.MemoryLimit)
+	assert.Equal(t, 100, res[0].ResourceUsage.NetworkRxBytes)
+	assert.Equal(t, 3, res[0].Query.PlayersOnline)
+	assert.Equal(t, 20, res[0].Query.PlayersMax)
+	assert.Equal(t, "hello all!", res[0].Query.MOTD)
+	assert.Equal(t, 2, res[0].RestartCount)
+	assert.Equal(t, 137, res[0].LastExitCode)
+	assert.Equal(t, "sha256:abcd", res[0].ImageDigest)
+	assert.Equal(t, []string{"1 agent(s): insufficient memory"}, res[0].UnschedulableReasons)
 }
 
 func TestCreateNewServer(t *testing.T) {
@@ -128,13 +165,26 @@ func TestCreateNewServer(t *testing.T) {
 	agentStore := mocks.NewMockAgentStore(ctrl)
 
 	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return([]server.Gameserver{}, nil).
+		Times(1)
 	gameserverStore.EXPECT().
 		CreateGameserver(gomock.Any()).
 		Return(nil).
 		Times(1)
 
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent(gomock.Any(), gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			assert.Equal(t, server.EventCreated, event.Type)
+		}).
+		Return(nil).
+		Times(1)
+
 	router := utils.SetupRouter()
-	MountGameserverAPI(router, agentStore, gameserverStore)
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
 
 	claims := map[string]interface{}{
 		"sub": "user1",
@@ -168,6 +218,168 @@ func TestCreateNewServer(t *testing.T) {
 	assert.Equal(t, "1.12", res.Version)
 }
 
+func TestCreateServerWithRestartPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+
+	var created *server.Gameserver
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return([]server.Gameserver{}, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		CreateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) { created = gs }).
+		Return(nil).
+		Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent(gomock.Any(), gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			assert.Equal(t, server.EventCreated, event.Type)
+		}).
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub": "user1",
+	}
+	payload := createGameserverRequest{
+		Name:    "My server",
+		Game:    "Minecraft",
+		Version: "1.12",
+		Parameters: map[string]string{
+			"motd": "hello all!",
+		},
+		RestartPolicy: &restartPolicy{
+			Type:       "always",
+			MaxRetries: 3,
+		},
+		ImagePullPolicy: "IfNotPresent",
+		ImageDigest:     "sha256:" + strings.Repeat("a", 64),
+		Command:         []string{"./server", "--nogui"},
+	}
+	payloadBytes, _ := json.Marshal(payload)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", bytes.NewReader(payloadBytes))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 202, w.Code)
+	assert.Equal(t, proto.RestartPolicyType_RESTART_ALWAYS, created.Deployment.RestartPolicy.Type)
+	assert.Equal(t, int64(3), created.Deployment.RestartPolicy.MaxRetries)
+	assert.Equal(t, proto.ImagePullPolicy_PULL_IF_NOT_PRESENT, created.Deployment.ImagePullPolicy)
+	assert.Equal(t, "sha256:"+strings.Repeat("a", 64), created.Deployment.ImageDigest)
+	assert.Equal(t, []string{"./server", "--nogui"}, created.Deployment.Command)
+}
+
+func TestCreateServerWithPlacement(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+
+	var created *server.Gameserver
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return([]server.Gameserver{}, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		CreateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) { created = gs }).
+		Return(nil).
+		Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent(gomock.Any(), gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			assert.Equal(t, server.EventCreated, event.Type)
+		}).
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub": "user1",
+	}
+	payload := createGameserverRequest{
+		Name:    "My server",
+		Game:    "Minecraft",
+		Version: "1.12",
+		Parameters: map[string]string{
+			"motd": "hello all!",
+		},
+		Placement: &placement{
+			NodeSelector:    map[string]string{"hardware": "ssd"},
+			PreferredRegion: "eu-west",
+			AntiAffinity:    true,
+		},
+	}
+	payloadBytes, _ := json.Marshal(payload)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", bytes.NewReader(payloadBytes))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 202, w.Code)
+	assert.Equal(t, &server.Placement{
+		NodeSelector:    map[string]string{"hardware": "ssd"},
+		PreferredRegion: "eu-west",
+		AntiAffinity:    true,
+	}, created.Definition.Placement)
+}
+
+func TestCreateServerWithInvalidRestartPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	eventStore := mocks.NewMockEventStore(ctrl)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub": "user1",
+	}
+	payload := createGameserverRequest{
+		Name:    "My server",
+		Game:    "Minecraft",
+		Version: "1.12",
+		Parameters: map[string]string{
+			"motd": "hello all!",
+		},
+		RestartPolicy: &restartPolicy{
+			Type: "sometimes",
+		},
+	}
+	payloadBytes, _ := json.Marshal(payload)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", bytes.NewReader(payloadBytes))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
 func TestCannotDeleteOtherUserServer(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -185,8 +397,10 @@ func TestCannotDeleteOtherUserServer(t *testing.T) {
 		Return(&gameserver, nil).
 		Times(1)
 
+	eventStore := mocks.NewMockEventStore(ctrl)
+
 	router := utils.SetupRouter()
-	MountGameserverAPI(router, agentStore, gameserverStore)
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
 
 	claims := map[string]interface{}{
 		"sub": "user1",
@@ -223,13 +437,292 @@ func TestDeleteServer(t *testing.T) {
 		Return(&gameserver, nil).
 		Times(1)
 
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent("serverUUID", gomock.Any()).
+		Do(func(UUID string, event *server.GameserverEvent) {
+			assert.Equal(t, server.EventDeleted, event.Type)
+			assert.Equal(t, "Deleted by user1", event.Message)
+		}).
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub": "user1",
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/gameservers/serverUUID/", nil)
+
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 202, w.Code)
+}
+
+func TestWakeStoppedServer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+
+	gameserver := server.Gameserver{
+		Definition: server.GameserverDefinition{
+			UUID:  "serverUUID",
+			Owner: "user1",
+		},
+		Deployment: &proto.GameserverDeployment{
+			Agent:   "localhost",
+			Stopped: true,
+		},
+	}
+
+	unlocked := false
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("serverUUID").
+		Return(&gameserver, nil).
+		Times(2)
+	gameserverStore.EXPECT().
+		LockGameserver("serverUUID").
+		Return(func() { unlocked = true }, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return([]server.Gameserver{gameserver}, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		UpdateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) {
+			assert.True(t, gs.WakeRequested)
+			assert.False(t, unlocked)
+		}).
+		Return(nil).
+		Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent("serverUUID", gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, agentStore, gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub": "user1",
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/serverUUID/wake/", nil)
+
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 202, w.Code)
+	assert.True(t, unlocked)
+}
+
+func TestMigrateServer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	agentStore := mocks.NewMockAgentStore(ctrl)
+	agentStore.EXPECT().
+		GetAgent("agent2").
+		Return(&server.Agent{}, nil).
+		Times(1)
+
+	gameserver := server.Gameserver{
+		Definition: server.GameserverDefinition{
+			UUID:  "serverUUID",
+			Owner: "user1",
+		},
+		Deployment: &proto.GameserverDeployment{
+			Agent: "agent1",
+		},
+	}
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("serverUUID").
+		Return(&gameserver, nil).
+		Times(2)
+	unlocked := false
+	gameserverStore.EXPECT().
+		LockGameserver("serverUUID").
+		Return(func() { unlocked = true }, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		UpdateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) {
+			assert.NotEmpty(t, gs.OperationID)
+		}).
+		Return(nil).
+		Times(1)
+
+	operationStore := mocks.NewMockOperationStore(ctrl)
+	operationStore.EXPECT().
+		CreateOperation(gomock.Any()).
+		Do(func(operation *server.Operation) {
+			assert.Equal(t, server.OperationMigrate, operation.Type)
+			assert.Equal(t, "agent1", operation.SourceAgent)
+			assert.Equal(t, "agent2", operation.TargetAgent)
+			assert.Equal(t, "user1", operation.RequestedBy)
+		}).
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, agentStore, gameserverStore, mocks.NewMockEventStore(ctrl), operationStore, mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub": "user1",
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/serverUUID/migrate/", strings.NewReader(`{"targetAgent": "agent2"}`))
+
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 202, w.Code)
+	assert.True(t, unlocked)
+}
+
+func TestMigrateServerAlreadyMigrating(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := server.Gameserver{
+		Definition: server.GameserverDefinition{
+			UUID:  "serverUUID",
+			Owner: "user1",
+		},
+		Deployment: &proto.GameserverDeployment{
+			Agent: "agent1",
+		},
+		OperationID: "op1",
+	}
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("serverUUID").
+		Return(&gameserver, nil).
+		Times(2)
+	unlocked := false
+	gameserverStore.EXPECT().
+		LockGameserver("serverUUID").
+		Return(func() { unlocked = true }, nil).
+		Times(1)
+
 	router := utils.SetupRouter()
-	MountGameserverAPI(router, agentStore, gameserverStore)
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
 
 	claims := map[string]interface{}{
 		"sub": "user1",
 	}
 
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/serverUUID/migrate/", nil)
+
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 409, w.Code)
+	assert.True(t, unlocked)
+}
+
+func TestMigrateServerRevertsWhenOperationFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := server.Gameserver{
+		Definition: server.GameserverDefinition{
+			UUID:  "serverUUID",
+			Owner: "user1",
+		},
+		Deployment: &proto.GameserverDeployment{
+			Agent: "agent1",
+		},
+	}
+
+	var operationIDs []string
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("serverUUID").
+		Return(&gameserver, nil).
+		Times(2)
+	gameserverStore.EXPECT().
+		LockGameserver("serverUUID").
+		Return(func() {}, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		UpdateGameserver(gomock.Any()).
+		Do(func(gs *server.Gameserver) {
+			operationIDs = append(operationIDs, gs.OperationID)
+		}).
+		Return(nil).
+		Times(2)
+
+	operationStore := mocks.NewMockOperationStore(ctrl)
+	operationStore.EXPECT().
+		CreateOperation(gomock.Any()).
+		Return(errors.New("etcd unavailable")).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, mocks.NewMockEventStore(ctrl), operationStore, mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	request := func(body string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/gameservers/serverUUID/migrate/", strings.NewReader(body))
+		req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, 400, request(`{"placement": {"nodeSelector": {"": "x"}}}`))
+	assert.Equal(t, 503, request(`{}`))
+	assert.Len(t, operationIDs, 2)
+	assert.NotEmpty(t, operationIDs[0])
+	assert.Empty(t, operationIDs[1])
+}
+
+func TestAdminCanDeleteOtherUserServer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("serverUUID").
+		Return(&server.Gameserver{Definition: server.GameserverDefinition{Owner: "user1"}}, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		DeleteGameserver("serverUUID").
+		Return(nil).
+		Times(1)
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		AddGameserverEvent("serverUUID", gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	policy := auth.DefaultPolicy
+	policy.Bindings = []auth.Binding{{Role: auth.RoleAdmin, Users: []string{"admin1"}}}
+
+	router := utils.SetupRouter()
+	router.Use(auth.UsePolicy(&policy))
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub": "admin1",
+	}
+
 	w := httptest.NewRecorder()
 	req, _ := http.NewRequest("DELETE", "/gameservers/serverUUID/", nil)
 
@@ -238,3 +731,414 @@ func TestDeleteServer(t *testing.T) {
 
 	assert.Equal(t, 202, w.Code)
 }
+
+func TestViewerCannotDeleteServer(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	policy := auth.DefaultPolicy
+	policy.Bindings = []auth.Binding{{Role: auth.RoleViewer, Groups: []string{"viewers"}}}
+
+	router := utils.SetupRouter()
+	router.Use(auth.UsePolicy(&policy))
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), mocks.NewMockGameserverStore(ctrl), mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	claims := map[string]interface{}{
+		"sub":    "user1",
+		"groups": []string{"viewers"},
+	}
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/gameservers/serverUUID/", nil)
+
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(claims))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 403, w.Code)
+}
+
+func TestTeamMembersAccessTeamServers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserver := server.Gameserver{
+		Definition: server.GameserverDefinition{
+			UUID:  "serverUUID",
+			Owner: "user1",
+			Team:  "team1",
+		},
+		Deployment: &proto.GameserverDeployment{},
+	}
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("serverUUID").
+		Return(&gameserver, nil).
+		AnyTimes()
+	gameserverStore.EXPECT().
+		DeleteGameserver("serverUUID").
+		Return(nil).
+		Times(1)
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().
+		GetTeam("team1").
+		Return(&server.Team{
+			ID: "team1",
+			Members: []server.TeamMembership{
+				{UserID: "user2", Role: server.TeamMember},
+				{UserID: "user3", Role: server.TeamViewer},
+			},
+		}, nil).
+		AnyTimes()
+
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		ListGameserverEvents("serverUUID").
+		Return([]server.GameserverEvent{}, nil).
+		Times(1)
+	eventStore.EXPECT().
+		AddGameserverEvent("serverUUID", gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), teamStore, newQuotaStore(ctrl))
+
+	request := func(method, path, userID string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": userID}))
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// the creator is not a member of the team anymore
+	assert.Equal(t, 404, request("DELETE", "/gameservers/serverUUID/", "user1"))
+	// viewers can only read
+	assert.Equal(t, 200, request("GET", "/gameservers/serverUUID/events/", "user3"))
+	assert.Equal(t, 404, request("DELETE", "/gameservers/serverUUID/", "user3"))
+	assert.Equal(t, 202, request("DELETE", "/gameservers/serverUUID/", "user2"))
+}
+
+func TestCreateServerInTeamRequiresMembership(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().
+		GetTeam("team1").
+		Return(&server.Team{
+			ID:      "team1",
+			Members: []server.TeamMembership{{UserID: "user2", Role: server.TeamViewer}},
+		}, nil).
+		Times(2)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), mocks.NewMockGameserverStore(ctrl), mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), teamStore, newQuotaStore(ctrl))
+
+	body := `{"name": "test", "game": "minecraft", "version": "1.14.4", "parameters": {}, "team": "team1"}`
+	for _, userID := range []string{"user1", "user2"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/gameservers/", strings.NewReader(body))
+		req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": userID}))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, 403, w.Code, userID)
+	}
+}
+
+func TestCreateServerExceedingQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameservers := make([]server.Gameserver, 0)
+	for i := 0; i < 5; i++ {
+		gameservers = append(gameservers, server.Gameserver{
+			Definition: server.GameserverDefinition{Owner: "user1", Game: "Minecraft"},
+			Deployment: &proto.GameserverDeployment{Stopped: true},
+		})
+	}
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return(gameservers, nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	body := `{"name": "test", "game": "Minecraft", "version": "1.14.4", "parameters": {}}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", strings.NewReader(body))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 422, w.Code)
+
+	res := struct {
+		Quota quotaLimits `json:"quota"`
+		Usage quotaUsage  `json:"usage"`
+	}{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, 5, res.Quota.MaxGameservers)
+	assert.Equal(t, 5, res.Usage.Gameservers)
+}
+
+func TestCreateServerInSecondTeamExceedingUserQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// user1 used its quota in the first team
+	gameservers := make([]server.Gameserver, 0)
+	for i := 0; i < 5; i++ {
+		gameservers = append(gameservers, server.Gameserver{
+			Definition: server.GameserverDefinition{Owner: "user1", Team: "team1", Game: "Minecraft"},
+			Deployment: &proto.GameserverDeployment{Stopped: true},
+		})
+	}
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return(gameservers, nil).
+		Times(1)
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().
+		GetTeam("team2").
+		Return(&server.Team{
+			ID:      "team2",
+			Members: []server.TeamMembership{{UserID: "user1", Role: server.TeamOwner}},
+		}, nil).
+		AnyTimes()
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), teamStore, newQuotaStore(ctrl))
+
+	body := `{"name": "test", "game": "Minecraft", "version": "1.14.4", "parameters": {}, "team": "team2"}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", strings.NewReader(body))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 422, w.Code)
+
+	res := struct {
+		Quota quotaLimits `json:"quota"`
+		Usage quotaUsage  `json:"usage"`
+	}{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, 5, res.Quota.MaxGameservers)
+	assert.Equal(t, 5, res.Usage.Gameservers)
+}
+
+func TestCreateServerWithGameNotAllowedByQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return([]server.Gameserver{}, nil).
+		Times(1)
+
+	unlocked := false
+	quotaStore := mocks.NewMockQuotaStore(ctrl)
+	quotaStore.EXPECT().
+		GetQuota("user:user1").
+		Return(&server.Quota{Games: []string{"Factorio"}}, nil).
+		Times(1)
+	quotaStore.EXPECT().
+		LockQuota("user:user1").
+		Return(func() { unlocked = true }, nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), quotaStore)
+
+	body := `{"name": "test", "game": "Minecraft", "version": "1.14.4", "parameters": {}}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", strings.NewReader(body))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 403, w.Code)
+	assert.True(t, unlocked)
+}
+
+func TestGetQuota(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return([]server.Gameserver{
+			{
+				Definition: server.GameserverDefinition{Owner: "user1"},
+				Deployment: &proto.GameserverDeployment{ResourceRequirements: &proto.ResourceRequirements{MemoryReservation: 1024}},
+			},
+			{
+				Definition: server.GameserverDefinition{Owner: "user1"},
+				Deployment: &proto.GameserverDeployment{Stopped: true, ResourceRequirements: &proto.ResourceRequirements{MemoryReservation: 2048}},
+			},
+			{
+				Definition: server.GameserverDefinition{Owner: "user1", Team: "team1"},
+				Deployment: &proto.GameserverDeployment{ResourceRequirements: &proto.ResourceRequirements{CpuReservation: 500}},
+			},
+			{
+				Definition: server.GameserverDefinition{Owner: "user2"},
+				Deployment: &proto.GameserverDeployment{},
+			},
+		}, nil).
+		Times(1)
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().
+		ListTeams().
+		Return([]server.Team{
+			{ID: "team1", Name: "Team", Members: []server.TeamMembership{{UserID: "user1", Role: server.TeamMember}}},
+			{ID: "team2", Name: "Other", Members: []server.TeamMembership{{UserID: "user2", Role: server.TeamOwner}}},
+		}, nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), teamStore, newQuotaStore(ctrl))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quota", nil)
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+
+	res := getQuotaResponse{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.Equal(t, 5, res.User.Quota.MaxGameservers)
+	// the team gameservers created by the user count against its quota
+	assert.Equal(t, quotaUsage{Gameservers: 3, MemoryKB: 1024, CPUMillicores: 500}, res.User.Usage)
+	if assert.Len(t, res.Teams, 1) {
+		assert.Equal(t, "team1", res.Teams[0].Team)
+		assert.Equal(t, 10, res.Teams[0].Quota.MaxGameservers)
+		assert.Equal(t, quotaUsage{Gameservers: 1, CPUMillicores: 500}, res.Teams[0].Usage)
+	}
+}
+
+func TestOnlyAdminsSetQuotas(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	quotaStore := mocks.NewMockQuotaStore(ctrl)
+	quotaStore.EXPECT().
+		SetQuota("team:team1", &server.Quota{MaxGameservers: 20, Games: []string{"Factorio"}}).
+		Return(nil).
+		Times(1)
+
+	policy := auth.DefaultPolicy
+	policy.Bindings = []auth.Binding{{Role: auth.RoleAdmin, Users: []string{"admin1"}}}
+
+	router := utils.SetupRouter()
+	router.Use(auth.UsePolicy(&policy))
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), mocks.NewMockGameserverStore(ctrl), mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), quotaStore)
+
+	body := `{"maxGameservers": 20, "games": ["Factorio"]}`
+	for userID, code := range map[string]int{"user1": 403, "admin1": 200} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("PUT", "/quotas/teams/team1", strings.NewReader(body))
+		req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": userID}))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, code, w.Code, userID)
+	}
+}
+
+// newQuotaStore returns a quota store without quota overrides
+func TestListGameserverEventsFilters(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		GetGameserver("serverUUID").
+		Return(&server.Gameserver{Definition: server.GameserverDefinition{UUID: "serverUUID", Owner: "user1"}}, nil).
+		AnyTimes()
+
+	now := time.Now()
+	eventStore := mocks.NewMockEventStore(ctrl)
+	eventStore.EXPECT().
+		ListGameserverEvents("serverUUID").
+		Return([]server.GameserverEvent{
+			{Time: now.Add(-2 * time.Hour), Type: server.EventCreated, Message: "Created by user1"},
+			{Time: now.Add(-time.Hour), Type: server.EventStarted, Message: "Started on agent agent1"},
+			{Time: now, Type: server.EventCrashed, Message: "Crashed on agent agent1 with exit code 1"},
+		}, nil).
+		AnyTimes()
+
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, eventStore, mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	request := func(query string) (int, listGameserverEventsResponse) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/gameservers/serverUUID/events/"+query, nil)
+		req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+		router.ServeHTTP(w, req)
+
+		res := listGameserverEventsResponse{}
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return w.Code, res
+	}
+
+	code, events := request("?type=" + server.EventCrashed)
+	assert.Equal(t, 200, code)
+	assert.Len(t, events, 1)
+	assert.Equal(t, server.EventCrashed, events[0].Type)
+
+	code, events = request("?since=" + now.Add(-90*time.Minute).Format(time.RFC3339))
+	assert.Equal(t, 200, code)
+	assert.Len(t, events, 2)
+
+	code, _ = request("?since=yesterday")
+	assert.Equal(t, 400, code)
+}
+
+func TestCreateServerStoreFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().
+		ListGameservers().
+		Return([]server.Gameserver{}, nil).
+		Times(1)
+	gameserverStore.EXPECT().
+		CreateGameserver(gomock.Any()).
+		Return(errors.New("etcd unavailable")).
+		Times(1)
+
+	// no event is recorded
+	router := utils.SetupRouter()
+	MountGameserverAPI(router, mocks.NewMockAgentStore(ctrl), gameserverStore, mocks.NewMockEventStore(ctrl), mocks.NewMockOperationStore(ctrl), mocks.NewMockTeamStore(ctrl), newQuotaStore(ctrl))
+
+	payloadBytes, _ := json.Marshal(createGameserverRequest{
+		Name:       "My server",
+		Game:       "Minecraft",
+		Version:    "1.12",
+		Parameters: map[string]string{},
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/gameservers/", bytes.NewReader(payloadBytes))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 503, w.Code)
+}
+
+func newQuotaStore(ctrl *gomock.Controller) *mocks.MockQuotaStore {
+	quotaStore := mocks.NewMockQuotaStore(ctrl)
+	quotaStore.EXPECT().GetQuota(gomock.Any()).Return(nil, nil).AnyTimes()
+	quotaStore.EXPECT().LockQuota(gomock.Any()).Return(func() {}, nil).AnyTimes()
+	return quotaStore
+}
@@ -47,6 +47,7 @@ func (manager factorioGameserverManager) createDeployment(definition *server.Gam
 		Image: fmt.Sprintf("factoriotools/factorio:%s", definition.Version),
 		ResourceRequirements: &proto.ResourceRequirements{
 			MemoryReservation: 512 * 1024,
+			DiskReservation:   2 << 30,
 		},
 		Ports: []*proto.NetworkPort{
 			&proto.NetworkPort{
@@ -55,5 +56,28 @@ func (manager factorioGameserverManager) createDeployment(definition *server.Gam
 			},
 		},
 		Environment: envVars,
+		Query: &proto.GameserverQuery{
+			Protocol:     proto.QueryProtocol_QUERY_FACTORIO,
+			Port:         27015,
+			PasswordFile: "/factorio/config/rconpw",
+		},
+		HealthCheck: &proto.HealthCheck{
+			Type:         proto.HealthCheckType_HEALTHCHECK_TCP,
+			Port:         27015,
+			Interval:     30,
+			StartPeriod:  120,
+			RestartAfter: 600,
+		},
+		Shutdown: &proto.ShutdownPolicy{
+			Command:          "/quit",
+			Channel:          proto.ShutdownChannel_SHUTDOWN_RCON,
+			RconPort:         27015,
+			RconPasswordFile: "/factorio/config/rconpw",
+			Signal:           "SIGINT",
+			Timeout:          60,
+		},
+		Volumes: []*proto.Volume{
+			{Name: "factorio", Path: "/factorio"},
+		},
 	}, nil
 }
@@ -49,6 +49,7 @@ func (manager minecraftGameserverManager) createDeployment(definition *server.Ga
 		Image: "itzg/minecraft-server",
 		ResourceRequirements: &proto.ResourceRequirements{
 			MemoryReservation: 1536 * 1024,
+			DiskReservation:   5 << 30,
 		},
 		Ports: []*proto.NetworkPort{
 			&proto.NetworkPort{
@@ -57,5 +58,26 @@ func (manager minecraftGameserverManager) createDeployment(definition *server.Ga
 			},
 		},
 		Environment: envVars,
+		Query: &proto.GameserverQuery{
+			Protocol: proto.QueryProtocol_QUERY_MINECRAFT,
+			Port:     25565,
+		},
+		HealthCheck: &proto.HealthCheck{
+			Type:         proto.HealthCheckType_HEALTHCHECK_EXEC,
+			Command:      []string{"mc-health"},
+			Interval:     30,
+			Timeout:      10,
+			StartPeriod:  300,
+			Retries:      3,
+			RestartAfter: 600,
+		},
+		Shutdown: &proto.ShutdownPolicy{
+			Command: "stop",
+			Channel: proto.ShutdownChannel_SHUTDOWN_STDIN,
+			Timeout: 60,
+		},
+		Volumes: []*proto.Volume{
+			{Name: "data", Path: "/data"},
+		},
 	}, nil
 }
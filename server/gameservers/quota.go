@@ -0,0 +1,271 @@
+package gameservers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/audit"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/gin-gonic/gin"
+)
+
+type quotaLimits struct {
+	MaxGameservers int      `json:"maxGameservers" binding:"min=0"`
+	MemoryKB       int64    `json:"memoryKB" binding:"min=0"`
+	CPUMillicores  int64    `json:"cpuMillicores" binding:"min=0"`
+	Games          []string `json:"games"`
+}
+
+type quotaUsage struct {
+	Gameservers   int   `json:"gameservers"`
+	MemoryKB      int64 `json:"memoryKB"`
+	CPUMillicores int64 `json:"cpuMillicores"`
+}
+
+type quotaStatus struct {
+	Team     string       `json:"team,omitempty"`
+	TeamName string       `json:"teamName,omitempty"`
+	Quota    *quotaLimits `json:"quota"`
+	Usage    quotaUsage   `json:"usage"`
+}
+
+type getQuotaResponse struct {
+	User  quotaStatus   `json:"user"`
+	Teams []quotaStatus `json:"teams"`
+}
+
+func newQuotaLimits(quota *server.Quota) *quotaLimits {
+	if quota == nil {
+		return nil
+	}
+
+	return &quotaLimits{
+		MaxGameservers: quota.MaxGameservers,
+		MemoryKB:       quota.MemoryKB,
+		CPUMillicores:  quota.CPUMillicores,
+		Games:          quota.Games,
+	}
+}
+
+func newQuotaUsage(usage server.QuotaUsage) quotaUsage {
+	return quotaUsage{
+		Gameservers:   usage.Gameservers,
+		MemoryKB:      usage.MemoryKB,
+		CPUMillicores: usage.CPUMillicores,
+	}
+}
+
+// quotaOf returns the quota subject and the quota of the gameservers
+// of the owner or the team. The stored quotas override the policy defaults
+func (api *gameserversAPI) quotaOf(c *gin.Context, owner, teamID string) (string, *server.Quota, error) {
+	policy := auth.CurrentPolicy(c)
+
+	subject := server.TeamQuotaSubject(teamID)
+	quota := policy.TeamQuota
+	if teamID == "" {
+		roles := c.GetStringSlice("roles")
+		if owner != c.GetString("userID") {
+			roles = policy.RolesOf(owner, nil)
+		}
+		subject = server.UserQuotaSubject(owner)
+		quota = policy.QuotaOf(roles)
+	}
+
+	override, err := api.quotaStore.GetQuota(subject)
+	if err != nil {
+		return "", nil, err
+	}
+	if override != nil {
+		quota = override
+	}
+	return subject, quota, nil
+}
+
+// reserveQuota checks the requested resources against the quota of the owner
+// and of the team and responds with the usage, if they don't fit. The quotas
+// stay locked until the returned unlock function is called, so concurrent
+// requests cannot exceed them
+func (api *gameserversAPI) reserveQuota(c *gin.Context, owner, teamID, game string, requested server.QuotaUsage) (func(), bool) {
+	unlockOwner, ok := api.reserveSubjectQuota(c, owner, "", game, requested)
+	if !ok || teamID == "" {
+		return unlockOwner, ok
+	}
+
+	unlockTeam, ok := api.reserveSubjectQuota(c, owner, teamID, game, requested)
+	if !ok {
+		unlockOwner()
+		return nil, false
+	}
+	return func() {
+		unlockTeam()
+		unlockOwner()
+	}, true
+}
+
+// reserveSubjectQuota checks the requested resources
+// against the quota of the owner or the team
+func (api *gameserversAPI) reserveSubjectQuota(c *gin.Context, owner, teamID, game string, requested server.QuotaUsage) (func(), bool) {
+	subject, quota, err := api.quotaOf(c, owner, teamID)
+	if err != nil {
+		log.Printf("gameserversAPI reserveSubjectQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot check quota"})
+		return nil, false
+	}
+	if quota == nil {
+		return func() {}, true
+	}
+
+	unlock, err := api.quotaStore.LockQuota(subject)
+	if err != nil {
+		log.Printf("gameserversAPI reserveSubjectQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot check quota"})
+		return nil, false
+	}
+
+	gameservers, err := api.gameserverStore.ListGameservers()
+	if err != nil {
+		unlock()
+		log.Printf("gameserversAPI reserveSubjectQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot check quota"})
+		return nil, false
+	}
+
+	usage := server.QuotaUsageOf(gameservers, owner, teamID)
+	if err := server.CheckQuota(quota, usage, requested, game); err != nil {
+		unlock()
+		status := http.StatusUnprocessableEntity
+		if err == server.ErrGameNotAllowed {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+			"quota": newQuotaLimits(quota),
+			"usage": newQuotaUsage(usage),
+		})
+		return nil, false
+	}
+
+	return unlock, true
+}
+
+// getQuota returns the quotas and the usage of the user and its teams
+func (api *gameserversAPI) getQuota(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	gameservers, err := api.gameserverStore.ListGameservers()
+	if err != nil {
+		log.Printf("gameserversAPI getQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot get quota"})
+		return
+	}
+	teams, err := api.teamStore.ListTeams()
+	if err != nil {
+		log.Printf("gameserversAPI getQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot get quota"})
+		return
+	}
+
+	_, quota, err := api.quotaOf(c, userID, "")
+	if err != nil {
+		log.Printf("gameserversAPI getQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot get quota"})
+		return
+	}
+
+	res := getQuotaResponse{
+		User: quotaStatus{
+			Quota: newQuotaLimits(quota),
+			Usage: newQuotaUsage(server.QuotaUsageOf(gameservers, userID, "")),
+		},
+		Teams: make([]quotaStatus, 0),
+	}
+
+	for _, team := range teams {
+		if team.Member(userID) == nil {
+			continue
+		}
+
+		_, quota, err := api.quotaOf(c, "", team.ID)
+		if err != nil {
+			log.Printf("gameserversAPI getQuota error: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot get quota"})
+			return
+		}
+
+		res.Teams = append(res.Teams, quotaStatus{
+			Team:     team.ID,
+			TeamName: team.Name,
+			Quota:    newQuotaLimits(quota),
+			Usage:    newQuotaUsage(server.QuotaUsageOf(gameservers, "", team.ID)),
+		})
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+// quotaSubject returns the subject of /quotas/users/:id or /quotas/teams/:id
+func quotaSubject(c *gin.Context) (string, bool) {
+	switch c.Param("kind") {
+	case "users":
+		return server.UserQuotaSubject(c.Param("id")), true
+	case "teams":
+		return server.TeamQuotaSubject(c.Param("id")), true
+	}
+	c.JSON(http.StatusNotFound, gin.H{})
+	return "", false
+}
+
+// setQuota overrides the policy quota of a user or a team
+func (api *gameserversAPI) setQuota(c *gin.Context) {
+	if !auth.CanAccess(c, "") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot manage quotas"})
+		return
+	}
+	subject, ok := quotaSubject(c)
+	if !ok {
+		return
+	}
+
+	var body quotaLimits
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quota := &server.Quota{
+		MaxGameservers: body.MaxGameservers,
+		MemoryKB:       body.MemoryKB,
+		CPUMillicores:  body.CPUMillicores,
+		Games:          body.Games,
+	}
+	if err := api.quotaStore.SetQuota(subject, quota); err != nil {
+		log.Printf("gameserversAPI setQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot set quota"})
+		return
+	}
+
+	audit.SetChange(c, "", fmt.Sprintf("%+v", *quota))
+	c.JSON(http.StatusOK, newQuotaLimits(quota))
+}
+
+// deleteQuota restores the policy quota of a user or a team
+func (api *gameserversAPI) deleteQuota(c *gin.Context) {
+	if !auth.CanAccess(c, "") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot manage quotas"})
+		return
+	}
+	subject, ok := quotaSubject(c)
+	if !ok {
+		return
+	}
+
+	if err := api.quotaStore.DeleteQuota(subject); err != nil {
+		log.Printf("gameserversAPI deleteQuota error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot delete quota"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{})
+}
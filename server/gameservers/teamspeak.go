@@ -40,6 +40,7 @@ func (manager teamspeakGameserverManager) createDeployment(definition *server.Ga
 		Image: fmt.Sprintf("teamspeak:%s", definition.Version),
 		ResourceRequirements: &proto.ResourceRequirements{
 			MemoryReservation: 64 * 1024,
+			DiskReservation:   256 << 20,
 		},
 		Ports: []*proto.NetworkPort{
 			&proto.NetworkPort{
@@ -52,5 +53,22 @@ func (manager teamspeakGameserverManager) createDeployment(definition *server.Ga
 			},
 		},
 		Environment: envVars,
+		Query: &proto.GameserverQuery{
+			Protocol: proto.QueryProtocol_QUERY_TEAMSPEAK,
+			Port:     10011,
+		},
+		HealthCheck: &proto.HealthCheck{
+			Type:        proto.HealthCheckType_HEALTHCHECK_TCP,
+			Port:        10011,
+			Interval:    30,
+			StartPeriod: 60,
+		},
+		Shutdown: &proto.ShutdownPolicy{
+			Signal:  "SIGTERM",
+			Timeout: 10,
+		},
+		Volumes: []*proto.Volume{
+			{Name: "data", Path: "/var/ts3server"},
+		},
 	}, nil
 }
@@ -0,0 +1,199 @@
+package gameservers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// keepAliveInterval is the interval of the keep-alive messages.
+	// The cached team memberships are refreshed with it
+	keepAliveInterval = 30 * time.Second
+	writeTimeout      = 10 * time.Second
+)
+
+// updateReset tells the client to reload the gameservers,
+// as the updates after the last event ID are not available
+const updateReset = "reset"
+
+var upgrader = websocket.Upgrader{}
+
+// updateStream sends the updates to a client
+type updateStream interface {
+	send(id uint64, event string, data interface{}) error
+	keepAlive() error
+	done() <-chan struct{}
+}
+
+type updatesAPI struct {
+	gameserversAPI
+	bus *server.UpdateBus
+}
+
+// MountUpdatesAPI mounts the /events endpoint, which pushes the gameserver
+// updates using Server-Sent Events or WebSocket
+func MountUpdatesAPI(r *gin.Engine, bus *server.UpdateBus, teamStore server.TeamStore) {
+	api := updatesAPI{gameserversAPI{teamStore: teamStore}, bus}
+
+	r.GET("/events", auth.Authorize(auth.ResourceGameservers, auth.VerbRead), api.streamUpdates)
+}
+
+// lastEventID returns the ID of the last update received by the client
+// from the Last-Event-ID header or the lastEventId query parameter
+func lastEventID(c *gin.Context) (uint64, error) {
+	value := c.GetHeader("last-event-id")
+	if value == "" {
+		value = c.Query("lastEventId")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseUint(value, 10, 64)
+}
+
+// streamUpdates pushes the updates of the gameservers accessible by
+// the client, starting after the last event ID
+func (api *updatesAPI) streamUpdates(c *gin.Context) {
+	lastID, err := lastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last event ID"})
+		return
+	}
+
+	var stream updateStream
+	if websocket.IsWebSocketUpgrade(c.Request) {
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			log.Printf("updatesAPI streamUpdates error: %v", err)
+			return
+		}
+		defer conn.Close()
+		stream = newWebSocketStream(conn)
+	} else {
+		stream = newSSEStream(c)
+	}
+
+	subscription := api.bus.Subscribe(lastID)
+	defer subscription.Close()
+
+	teams := make(map[string]*server.Team)
+	send := func(update server.GameserverUpdate) error {
+		gameserver := server.Gameserver{Definition: server.GameserverDefinition{Owner: update.Owner, Team: update.Team}}
+		if !api.canAccess(c, &gameserver, teams) {
+			return nil
+		}
+		return stream.send(update.ID, update.Type, update)
+	}
+
+	if !subscription.Resumed {
+		if err := stream.send(0, updateReset, gin.H{"type": updateReset}); err != nil {
+			return
+		}
+	}
+	for _, update := range subscription.Missed {
+		if err := send(update); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stream.done():
+			return
+		case update, ok := <-subscription.Updates:
+			// slow clients are disconnected and resume after reconnecting
+			if !ok || send(update) != nil {
+				return
+			}
+		case <-ticker.C:
+			teams = make(map[string]*server.Team)
+			if err := stream.keepAlive(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+type sseStream struct {
+	c *gin.Context
+}
+
+func newSSEStream(c *gin.Context) *sseStream {
+	header := c.Writer.Header()
+	header.Set("Content-Type", sse.ContentType)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	return &sseStream{c}
+}
+
+func (stream *sseStream) send(id uint64, event string, data interface{}) error {
+	sseEvent := sse.Event{Event: event, Data: data}
+	if id != 0 {
+		sseEvent.Id = strconv.FormatUint(id, 10)
+	}
+	if err := sse.Encode(stream.c.Writer, sseEvent); err != nil {
+		return err
+	}
+	stream.c.Writer.Flush()
+	return nil
+}
+
+func (stream *sseStream) keepAlive() error {
+	if _, err := fmt.Fprint(stream.c.Writer, ": keep-alive\n\n"); err != nil {
+		return err
+	}
+	stream.c.Writer.Flush()
+	return nil
+}
+
+func (stream *sseStream) done() <-chan struct{} {
+	return stream.c.Request.Context().Done()
+}
+
+type webSocketStream struct {
+	conn   *websocket.Conn
+	closed chan struct{}
+}
+
+// newWebSocketStream creates a stream, which is done when the client
+// closes the connection. The messages from the client are ignored
+func newWebSocketStream(conn *websocket.Conn) *webSocketStream {
+	stream := &webSocketStream{conn, make(chan struct{})}
+	go func() {
+		defer close(stream.closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	return stream
+}
+
+func (stream *webSocketStream) send(id uint64, event string, data interface{}) error {
+	stream.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return stream.conn.WriteJSON(data)
+}
+
+func (stream *webSocketStream) keepAlive() error {
+	return stream.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeTimeout))
+}
+
+func (stream *webSocketStream) done() <-chan struct{} {
+	return stream.closed
+}
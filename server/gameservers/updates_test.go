@@ -0,0 +1,180 @@
+package gameservers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/utils"
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// publishUpdates publishes the updates and returns their IDs
+func publishUpdates(bus *server.UpdateBus, updates ...server.GameserverUpdate) []uint64 {
+	subscription := bus.Subscribe(0)
+	defer subscription.Close()
+
+	ids := make([]uint64, 0, len(updates))
+	for _, update := range updates {
+		bus.Publish(update)
+		ids = append(ids, (<-subscription.Updates).ID)
+	}
+	return ids
+}
+
+// readSSEvent reads the fields of the next event from the stream
+func readSSEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	event := make(map[string]string)
+	for {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return event
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			if len(event) > 0 {
+				return event
+			}
+			continue
+		}
+
+		field := strings.SplitN(line, ":", 2)
+		event[field[0]] = field[1]
+	}
+}
+
+func TestStreamUpdatesOverSSE(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	bus := server.NewUpdateBus(10)
+	ids := publishUpdates(bus,
+		server.GameserverUpdate{Type: server.UpdateStatus, UUID: "uuid1", Owner: "user1", Status: "RUNNING"},
+		server.GameserverUpdate{Type: server.UpdateStatus, UUID: "uuid2", Owner: "user2", Status: "RUNNING"},
+		server.GameserverUpdate{Type: server.UpdatePlayers, UUID: "uuid1", Owner: "user1", PlayersOnline: 2},
+	)
+
+	router := utils.SetupRouter()
+	MountUpdatesAPI(router, bus, mocks.NewMockTeamStore(ctrl))
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/events", nil)
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	req.Header.Add("accept", "text/event-stream")
+	req.Header.Add("last-event-id", fmt.Sprint(ids[0]))
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	assert.Equal(t, 200, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("content-type"))
+	reader := bufio.NewReader(res.Body)
+
+	// the missed update of user2 is skipped
+	event := readSSEvent(t, reader)
+	assert.Equal(t, fmt.Sprint(ids[2]), event["id"])
+	assert.Equal(t, server.UpdatePlayers, event["event"])
+
+	bus.Publish(server.GameserverUpdate{Type: server.UpdateStatus, UUID: "uuid2", Owner: "user2", Status: "STOPPED"})
+	bus.Publish(server.GameserverUpdate{Type: server.UpdateEndpoint, UUID: "uuid1", Owner: "user1", Address: "10.0.0.1"})
+
+	event = readSSEvent(t, reader)
+	assert.Equal(t, server.UpdateEndpoint, event["event"])
+
+	update := server.GameserverUpdate{}
+	json.Unmarshal([]byte(event["data"]), &update)
+	assert.Equal(t, "uuid1", update.UUID)
+	assert.Equal(t, "10.0.0.1", update.Address)
+}
+
+func TestStreamUpdatesResetsUnknownEventID(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	router := utils.SetupRouter()
+	MountUpdatesAPI(router, server.NewUpdateBus(10), mocks.NewMockTeamStore(ctrl))
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	req, _ := http.NewRequest("GET", ts.URL+"/events?lastEventId=1", nil)
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	res, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer res.Body.Close()
+
+	event := readSSEvent(t, bufio.NewReader(res.Body))
+	assert.Equal(t, updateReset, event["event"])
+	assert.Empty(t, event["id"])
+}
+
+func TestStreamUpdatesOverWebSocket(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().
+		GetTeam("team1").
+		Return(&server.Team{
+			ID:      "team1",
+			Members: []server.TeamMembership{{UserID: "user1", Role: server.TeamViewer}},
+		}, nil).
+		Times(1)
+	teamStore.EXPECT().
+		GetTeam("team2").
+		Return(&server.Team{ID: "team2"}, nil).
+		Times(1)
+
+	bus := server.NewUpdateBus(10)
+	ids := publishUpdates(bus,
+		server.GameserverUpdate{Type: server.UpdateStatus, UUID: "uuid1", Owner: "user2", Team: "team2"},
+		server.GameserverUpdate{Type: server.UpdateStatus, UUID: "uuid2", Owner: "user2", Team: "team1"},
+	)
+
+	router := utils.SetupRouter()
+	MountUpdatesAPI(router, bus, teamStore)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	url := fmt.Sprintf("ws%s/events?lastEventId=%d", strings.TrimPrefix(ts.URL, "http"), ids[0]-1)
+	header := http.Header{}
+	header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": "user1"}))
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// only the gameserver of the team of user1 is visible
+	update := server.GameserverUpdate{}
+	assert.NoError(t, conn.ReadJSON(&update))
+	assert.Equal(t, ids[1], update.ID)
+	assert.Equal(t, "uuid2", update.UUID)
+}
+
+func TestStreamUpdatesRequiresLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	router := utils.SetupRouter()
+	MountUpdatesAPI(router, server.NewUpdateBus(10), mocks.NewMockTeamStore(ctrl))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/events", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 401, w.Code)
+}
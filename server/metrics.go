@@ -1,17 +1,82 @@
 package server
 
 import (
+	"log"
 	"time"
 
+	"github.com/Trojan295/chinchilla/proto"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
+var gameserverLabels = []string{"name", "owner", "game", "agent"}
+
+var (
+	gameserverCPUPercent = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_cpu_percent",
+		Help: "CPU usage of the gameserver container in percent",
+	}, gameserverLabels)
+	gameserverMemoryUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_memory_usage_bytes",
+		Help: "Memory RSS of the gameserver container",
+	}, gameserverLabels)
+	gameserverMemoryLimit = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_memory_limit_bytes",
+		Help: "Memory limit of the gameserver container",
+	}, gameserverLabels)
+	gameserverNetworkRx = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_network_receive_bytes",
+		Help: "Bytes received by the gameserver container",
+	}, gameserverLabels)
+	gameserverNetworkTx = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_network_transmit_bytes",
+		Help: "Bytes transmitted by the gameserver container",
+	}, gameserverLabels)
+	gameserverBlockRead = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_block_read_bytes",
+		Help: "Bytes read from block devices by the gameserver container",
+	}, gameserverLabels)
+	gameserverBlockWrite = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_block_write_bytes",
+		Help: "Bytes written to block devices by the gameserver container",
+	}, gameserverLabels)
+	gameserverPlayers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_players",
+		Help: "Players online on the gameserver",
+	}, gameserverLabels)
+	gameserverPlayersMax = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "gameserver_players_max",
+		Help: "Maximum players allowed on the gameserver",
+	}, gameserverLabels)
+)
+
+var gameserverUsageMetrics = []*prometheus.GaugeVec{
+	gameserverCPUPercent,
+	gameserverMemoryUsage,
+	gameserverMemoryLimit,
+	gameserverNetworkRx,
+	gameserverNetworkTx,
+	gameserverBlockRead,
+	gameserverBlockWrite,
+	gameserverPlayers,
+	gameserverPlayersMax,
+}
+
 // StartMetrics start pushing Prometheus metrics
-func StartMetrics(gameserverStore GameserverStore) {
+func StartMetrics(gameserverStore GameserverStore, agentStore AgentStore) {
+	for _, metric := range gameserverUsageMetrics {
+		prometheus.MustRegister(metric)
+	}
+
 	go func() {
 		metrics := make(map[string]prometheus.Gauge, 0)
+		usageLabels := make(map[string]prometheus.Labels, 0)
 		for {
 			servers, _ := gameserverStore.ListGameservers()
+			runningServers, err := GetRunningGameservers(agentStore)
+			if err != nil {
+				log.Printf("StartMetrics GetRunningGameservers error: %v", err)
+			}
+
 			for _, server := range servers {
 				if _, ok := metrics[server.Definition.UUID]; !ok {
 					metric := prometheus.NewGauge(prometheus.GaugeOpts{
@@ -26,6 +91,23 @@ func StartMetrics(gameserverStore GameserverStore) {
 					metrics[server.Definition.UUID] = metric
 				}
 				metrics[server.Definition.UUID].Set(1)
+
+				runningServer, ok := runningServers[server.Definition.UUID]
+				if !ok {
+					continue
+				}
+
+				labels := prometheus.Labels{
+					"name":  server.Definition.UUID,
+					"owner": server.Definition.Owner,
+					"game":  server.Definition.Game,
+					"agent": server.Deployment.Agent,
+				}
+				if previous, ok := usageLabels[server.Definition.UUID]; ok && previous["agent"] != labels["agent"] {
+					deleteUsageMetrics(previous)
+				}
+				usageLabels[server.Definition.UUID] = labels
+				setUsageMetrics(labels, runningServer)
 			}
 
 			for UUID, metric := range metrics {
@@ -40,6 +122,11 @@ func StartMetrics(gameserverStore GameserverStore) {
 				if toRemove == true {
 					prometheus.Unregister(metric)
 					delete(metrics, UUID)
+
+					if labels, ok := usageLabels[UUID]; ok {
+						deleteUsageMetrics(labels)
+						delete(usageLabels, UUID)
+					}
 				}
 			}
 
@@ -47,3 +134,32 @@ func StartMetrics(gameserverStore GameserverStore) {
 		}
 	}()
 }
+
+func setUsageMetrics(labels prometheus.Labels, gameserver *proto.Gameserver) {
+	if gameserver.Query != nil {
+		gameserverPlayers.With(labels).Set(float64(gameserver.Query.PlayersOnline))
+		gameserverPlayersMax.With(labels).Set(float64(gameserver.Query.PlayersMax))
+	} else {
+		gameserverPlayers.Delete(labels)
+		gameserverPlayersMax.Delete(labels)
+	}
+
+	usage := gameserver.ResourceUsage
+	if usage == nil {
+		return
+	}
+
+	gameserverCPUPercent.With(labels).Set(usage.CpuPercent)
+	gameserverMemoryUsage.With(labels).Set(float64(usage.MemoryUsage * 1024))
+	gameserverMemoryLimit.With(labels).Set(float64(usage.MemoryLimit * 1024))
+	gameserverNetworkRx.With(labels).Set(float64(usage.NetworkRxBytes))
+	gameserverNetworkTx.With(labels).Set(float64(usage.NetworkTxBytes))
+	gameserverBlockRead.With(labels).Set(float64(usage.BlockReadBytes))
+	gameserverBlockWrite.With(labels).Set(float64(usage.BlockWriteBytes))
+}
+
+func deleteUsageMetrics(labels prometheus.Labels) {
+	for _, metric := range gameserverUsageMetrics {
+		metric.Delete(labels)
+	}
+}
@@ -6,25 +6,261 @@ import (
 	"github.com/Trojan295/chinchilla/proto"
 )
 
-// GameserverDefinition represents the receipe for the game server
+// IdlePolicy describes when an idle gameserver should be stopped
+type IdlePolicy struct {
+	StopAfterMinutes int
+}
+
+// RegionLabel is the agent label used by the preferred region
+const RegionLabel = "region"
+
+// Placement constraints the agents running the gameserver.
+// NodeSelector and AntiAffinity are required, PreferredRegion is only
+// preferred, when choosing between the agents
+type Placement struct {
+	NodeSelector    map[string]string
+	PreferredRegion string
+	AntiAffinity    bool
+}
+
+// GameserverDefinition describes a gameserver. Gameservers with a Team
+// are owned by the team, the Owner is the user, who created them
 type GameserverDefinition struct {
 	UUID       string
 	Name       string
 	Owner      string
+	Team       string
 	Game       string
 	Version    string
 	Parameters map[string]string
+	IdlePolicy *IdlePolicy
+	Placement  *Placement
 }
 
 // Gameserver glues GameserverDefinition and GameserverDeployment
 type Gameserver struct {
-	Definition GameserverDefinition
-	Deployment *proto.GameserverDeployment
+	Definition    GameserverDefinition
+	Deployment    *proto.GameserverDeployment
+	IdleSince     *time.Time
+	WakeRequested bool
+	// UnschedulableReasons explain, why no agent can run the gameserver
+	UnschedulableReasons []string
+	// OperationID is the migration in progress
+	OperationID string
+}
+
+// Gameserver event types
+const (
+	EventIdleStopped     = "IdleStopped"
+	EventWakeRequested   = "WakeRequested"
+	EventWoken           = "Woken"
+	EventActionSucceeded = "ActionSucceeded"
+	EventActionFailed    = "ActionFailed"
+	EventShutdown        = "Shutdown"
+	EventShutdownKilled  = "ShutdownKilled"
+	EventUnschedulable   = "Unschedulable"
+	EventDrainStopping   = "DrainStopping"
+	EventDrained         = "Drained"
+	EventMigrationStart  = "MigrationStarted"
+	EventMigrated        = "Migrated"
+	EventMigrationFailed = "MigrationFailed"
+	EventCreated         = "Created"
+	EventScheduled       = "Scheduled"
+	EventImagePulling    = "ImagePulling"
+	EventStarted         = "Started"
+	EventHealthChanged   = "HealthChanged"
+	EventCrashed         = "Crashed"
+	EventStopped         = "Stopped"
+	EventDeleted         = "Deleted"
+)
+
+// GameserverEvent is an entry in the gameserver history
+type GameserverEvent struct {
+	Time    time.Time
+	Type    string
+	Message string
+}
+
+// Gameserver update types
+const (
+	UpdateStatus   = "status"
+	UpdateEndpoint = "endpoint"
+	UpdatePlayers  = "players"
+)
+
+// GameserverUpdate is a change of the gameserver state pushed to
+// the clients. It contains the current status, address and player
+// count, the type tells which of them changed
+type GameserverUpdate struct {
+	ID            uint64    `json:"id"`
+	Time          time.Time `json:"time"`
+	Type          string    `json:"type"`
+	UUID          string    `json:"uuid"`
+	Owner         string    `json:"owner"`
+	Team          string    `json:"team,omitempty"`
+	Status        string    `json:"status"`
+	Address       string    `json:"address"`
+	PlayersOnline int       `json:"playersOnline"`
+	PlayersMax    int       `json:"playersMax"`
+}
+
+// Operation types
+const (
+	OperationMigrate = "Migrate"
+)
+
+// Migration phases. A failed migration is rolled back to the source agent
+const (
+	MigrationPending    = "Pending"
+	MigrationStopping   = "Stopping"
+	MigrationExporting  = "Exporting"
+	MigrationImporting  = "Importing"
+	MigrationCompleted  = "Completed"
+	MigrationRolledBack = "RolledBack"
+)
+
+// Operation is a long running task on a gameserver driven by the scheduler
+type Operation struct {
+	ID             string
+	Type           string
+	GameserverUUID string
+	RequestedBy    string
+	Phase          string
+	Error          string
+	StartedAt      time.Time
+	PhaseStartedAt time.Time
+	FinishedAt     *time.Time
+
+	SourceAgent string
+	TargetAgent string
+	WasRunning  bool
+	ExportDone  bool
+	ImportDone  bool
+
+	// StartOnTarget starts the gameserver on the target agent,
+	// when it's migrated to be woken up
+	StartOnTarget bool
+}
+
+// Finished tells, if the operation is not in progress anymore
+func (operation *Operation) Finished() bool {
+	return operation.FinishedAt != nil
+}
+
+// Team roles. Owners and admins manage the members, viewers
+// can only read the gameservers of the team
+const (
+	TeamOwner  = "owner"
+	TeamAdmin  = "admin"
+	TeamMember = "member"
+	TeamViewer = "viewer"
+)
+
+// Team shares its gameservers between the members
+type Team struct {
+	ID          string
+	Name        string
+	CreatedAt   time.Time
+	Members     []TeamMembership
+	Invitations []TeamInvitation
+}
+
+// TeamMembership is the role of a user in a team
+type TeamMembership struct {
+	UserID   string
+	Role     string
+	JoinedAt time.Time
+}
+
+// TeamInvitation invites a user to a team. The invitation is removed,
+// when the user accepts or declines it
+type TeamInvitation struct {
+	ID        string
+	UserID    string
+	Role      string
+	InvitedBy string
+	CreatedAt time.Time
+}
+
+// Member returns the membership of the user or nil
+func (team *Team) Member(userID string) *TeamMembership {
+	for i := range team.Members {
+		if team.Members[i].UserID == userID {
+			return &team.Members[i]
+		}
+	}
+	return nil
+}
+
+// Invitation returns the invitation with the ID or nil
+func (team *Team) Invitation(ID string) *TeamInvitation {
+	for i := range team.Invitations {
+		if team.Invitations[i].ID == ID {
+			return &team.Invitations[i]
+		}
+	}
+	return nil
 }
 
 type Agent struct {
 	LastContact time.Time
 	State       proto.AgentState
+	// Maintenance is stored separately, so it's not overwritten
+	// by the state registered by the agent
+	Maintenance AgentMaintenance `json:"-"`
+}
+
+// Drain states
+const (
+	DrainInProgress = "InProgress"
+	DrainCompleted  = "Completed"
+	DrainCancelled  = "Cancelled"
+)
+
+// Phases of a gameserver moved out of a drained agent
+const (
+	DrainPending  = "Pending"
+	DrainStopping = "Stopping"
+	DrainMigrated = "Migrated"
+	DrainRemoved  = "Removed"
+)
+
+// AgentMaintenance holds the cordon and drain state of an agent.
+// Cordoned agents don't get new gameservers
+type AgentMaintenance struct {
+	Cordoned bool
+	Drain    *AgentDrain
+}
+
+// AgentDrain tracks the gameservers moved out of the agent
+type AgentDrain struct {
+	State       string
+	RequestedBy string
+	StartedAt   time.Time
+	FinishedAt  *time.Time
+	Gameservers []DrainedGameserver
+}
+
+// DrainedGameserver is the drain progress of a single gameserver.
+// WasRunning gameservers are started again on the target agent.
+// Gameservers with volumes are moved by a migration operation
+type DrainedGameserver struct {
+	UUID        string
+	Phase       string
+	WasRunning  bool
+	TargetAgent string
+	OperationID string
+	Error       string
+}
+
+// BootstrapToken allows a new agent to enroll and get its client
+// certificate once. Only the hash of the token is stored. An empty
+// hostname allows any agent to use the token
+type BootstrapToken struct {
+	Hash      string
+	Hostname  string
+	CreatedBy string
+	ExpiresAt time.Time
 }
 
 // AgentStore is an interface for an agents storage
@@ -32,6 +268,117 @@ type AgentStore interface {
 	RegisterAgent(*Agent) error
 	ListAgents() ([]Agent, error)
 	GetAgent(UUID string) (*Agent, error)
+	UpdateAgentMaintenance(hostname string, maintenance *AgentMaintenance) error
+}
+
+// EventStore is an interface for the gameserver events storage
+type EventStore interface {
+	AddGameserverEvent(UUID string, event *GameserverEvent) error
+	ListGameserverEvents(UUID string) ([]GameserverEvent, error)
+}
+
+// OperationStore is an interface for the operations storage
+type OperationStore interface {
+	CreateOperation(*Operation) error
+	UpdateOperation(*Operation) error
+	GetOperation(ID string) (*Operation, error)
+	ListOperations() ([]Operation, error)
+}
+
+// BootstrapTokenStore is an interface for the bootstrap tokens storage.
+// UseBootstrapToken removes the token, so it can be used only once
+type BootstrapTokenStore interface {
+	CreateBootstrapToken(*BootstrapToken) error
+	UseBootstrapToken(hash string) (*BootstrapToken, error)
+}
+
+// AuditEvent records a mutating API call or a scheduler decision.
+// Status is the HTTP status of API calls
+type AuditEvent struct {
+	ID        string    `json:"id"`
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	Before    string    `json:"before,omitempty"`
+	After     string    `json:"after,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	RequestID string    `json:"requestID,omitempty"`
+}
+
+// AuditStore is an interface for the audit log storage. The events
+// are removed after the retention
+type AuditStore interface {
+	AddAuditEvent(event *AuditEvent, retention time.Duration) error
+	ListAuditEvents() ([]AuditEvent, error)
+}
+
+// APITokenPrefix starts the personal API tokens
+const APITokenPrefix = "chc_"
+
+// APIToken is a personal API token of a user. Only the hash of the token
+// is stored. Scopes like read:gameservers restrict the token to a part
+// of the permissions of the user, no scopes grant all of them
+type APIToken struct {
+	ID         string
+	Hash       string
+	UserID     string
+	Name       string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+}
+
+// Expired checks, if the token expired
+func (token *APIToken) Expired() bool {
+	return token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt)
+}
+
+// APITokenStore is an interface for the API tokens storage
+type APITokenStore interface {
+	CreateAPIToken(*APIToken) error
+	UpdateAPIToken(*APIToken) error
+	GetAPIToken(hash string) (*APIToken, error)
+	ListAPITokens(userID string) ([]APIToken, error)
+	DeleteAPIToken(hash string) error
+}
+
+// Quota limits the gameservers of a user or a team. Zero limits are
+// unlimited and empty Games allow all games. Memory in kB and CPU
+// in millicores are counted for the active gameservers only
+type Quota struct {
+	MaxGameservers int
+	MemoryKB       int64
+	CPUMillicores  int64
+	Games          []string
+}
+
+// QuotaUsage is the usage of a quota
+type QuotaUsage struct {
+	Gameservers   int
+	MemoryKB      int64
+	CPUMillicores int64
+}
+
+// QuotaStore is an interface for the storage of the quotas overriding
+// the role defaults. LockQuota serializes the quota checks of a subject
+type QuotaStore interface {
+	GetQuota(subject string) (*Quota, error)
+	SetQuota(subject string, quota *Quota) error
+	DeleteQuota(subject string) error
+	LockQuota(subject string) (unlock func(), err error)
+}
+
+// TeamStore is an interface for the teams storage. LockTeam
+// serializes the changes of the members and invitations of a team
+type TeamStore interface {
+	CreateTeam(*Team) error
+	UpdateTeam(*Team) error
+	GetTeam(ID string) (*Team, error)
+	ListTeams() ([]Team, error)
+	DeleteTeam(ID string) error
+	LockTeam(ID string) (unlock func(), err error)
 }
 
 // GameserverStore interface
@@ -41,4 +388,5 @@ type GameserverStore interface {
 	ListGameservers() ([]Gameserver, error)
 	GetGameserver(UUID string) (*Gameserver, error)
 	DeleteGameserver(UUID string) error
+	LockGameserver(UUID string) (unlock func(), err error)
 }
@@ -0,0 +1,211 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caCertificateFile = "ca.crt"
+	caKeyFile         = "ca.key"
+	caValidity        = 10 * 365 * 24 * time.Hour
+	serverValidity    = 365 * 24 * time.Hour
+)
+
+// CA signs the client certificates of the agents
+// and the certificate of the gRPC server
+type CA struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	// CertificatePEM is the PEM encoded CA certificate
+	CertificatePEM []byte
+}
+
+// LoadOrCreateCA loads the CA from the directory. A new CA
+// is created, when the directory contains none
+func LoadOrCreateCA(dir string) (*CA, error) {
+	certificatePath := filepath.Join(dir, caCertificateFile)
+	keyPath := filepath.Join(dir, caKeyFile)
+
+	if _, err := os.Stat(certificatePath); os.IsNotExist(err) {
+		if err := createCA(certificatePath, keyPath); err != nil {
+			return nil, fmt.Errorf("cannot create the CA: %v", err)
+		}
+	}
+
+	certificatePEM, err := ioutil.ReadFile(certificatePath)
+	if err != nil {
+		return nil, err
+	}
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pair, err := tls.X509KeyPair(certificatePEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	certificate, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("CA key is not an ECDSA key")
+	}
+
+	return &CA{
+		certificate:    certificate,
+		key:            key,
+		CertificatePEM: certificatePEM,
+	}, nil
+}
+
+func createCA(certificatePath, keyPath string) error {
+	if err := os.MkdirAll(filepath.Dir(certificatePath), 0700); err != nil {
+		return err
+	}
+
+	key, err := GenerateKey()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{CommonName: "Chinchilla CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return err
+	}
+
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(certificatePath, encodeCertificate(der), 0644)
+}
+
+// Pool returns a certificate pool containing the CA
+func (ca *CA) Pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.certificate)
+	return pool
+}
+
+// SignAgentCSR issues a client certificate for the agent. The identity of
+// the agent is the hostname, which replaces the subject of the request
+func (ca *CA) SignAgentCSR(csrDER []byte, hostname string, validity time.Duration) ([]byte, error) {
+	csr, err := x509.ParseCertificateRequest(csrDER)
+	if err != nil {
+		return nil, err
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: hostname},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, csr.PublicKey, ca.key)
+	if err != nil {
+		return nil, err
+	}
+	return encodeCertificate(der), nil
+}
+
+// ServerCertificate issues a certificate for the gRPC server,
+// valid for the hostnames and IP addresses
+func (ca *CA) ServerCertificate(hosts []string) (tls.Certificate, error) {
+	key, err := GenerateKey()
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{CommonName: "Chinchilla server"},
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(serverValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.certificate, &key.PublicKey, ca.key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
+
+// GenerateKey generates a new private key for a certificate
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// encodeKey PEM encodes the private key
+func encodeKey(key *ecdsa.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+}
+
+// CertificateRequest creates a DER encoded certificate signing request
+func CertificateRequest(key *ecdsa.PrivateKey, hostname string) ([]byte, error) {
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: hostname},
+	}
+	return x509.CreateCertificateRequest(rand.Reader, template, key)
+}
+
+func encodeCertificate(der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func randomSerial() *big.Int {
+	serial, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	return serial
+}
@@ -0,0 +1,68 @@
+package pki
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOrCreateCA(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pki")
+	defer os.RemoveAll(dir)
+
+	ca, err := LoadOrCreateCA(dir)
+	assert.NoError(t, err)
+
+	loaded, err := LoadOrCreateCA(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, ca.CertificatePEM, loaded.CertificatePEM)
+
+	info, _ := os.Stat(dir + "/ca.key")
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestSignAgentCSR(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pki")
+	defer os.RemoveAll(dir)
+	ca, _ := LoadOrCreateCA(dir)
+
+	key, _ := GenerateKey()
+	csr, _ := CertificateRequest(key, "other-agent")
+
+	certificatePEM, err := ca.SignAgentCSR(csr, "agent1", time.Hour)
+	assert.NoError(t, err)
+
+	block, _ := pem.Decode(certificatePEM)
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	assert.NoError(t, err)
+	assert.Equal(t, "agent1", certificate.Subject.CommonName)
+
+	_, err = certificate.Verify(x509.VerifyOptions{
+		Roots:     ca.Pool(),
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	assert.NoError(t, err)
+
+	_, err = ca.SignAgentCSR([]byte("invalid"), "agent1", time.Hour)
+	assert.Error(t, err)
+}
+
+func TestServerCertificate(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "pki")
+	defer os.RemoveAll(dir)
+	ca, _ := LoadOrCreateCA(dir)
+
+	pair, err := ca.ServerCertificate([]string{"chinchilla.example.com", "10.0.0.1"})
+	assert.NoError(t, err)
+
+	certificate, _ := x509.ParseCertificate(pair.Certificate[0])
+	for _, host := range []string{"chinchilla.example.com", "10.0.0.1"} {
+		_, err = certificate.Verify(x509.VerifyOptions{Roots: ca.Pool(), DNSName: host})
+		assert.NoError(t, err)
+	}
+}
@@ -5,11 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/Trojan295/chinchilla/server"
 	"go.etcd.io/etcd/client"
 )
 
+// The gameserver history keeps at most maxGameserverEvents events
+// of each gameserver, which expire after gameserverEventTTL
+const (
+	maxGameserverEvents = 100
+	gameserverEventTTL  = 30 * 24 * time.Hour
+)
+
 // EtcdStore is a etcd implementation of the AgentStore
 type EtcdStore struct {
 	keysAPI client.KeysAPI
@@ -80,6 +88,7 @@ func (store *EtcdStore) ListAgents() ([]server.Agent, error) {
 		detailsRes, _ := store.keysAPI.Get(context.Background(), fmt.Sprintf("%s/state", agentNode.Key), nil)
 		var agentDetails server.Agent
 		json.Unmarshal([]byte(detailsRes.Node.Value), &agentDetails)
+		store.getAgentMaintenance(agentNode.Key, &agentDetails)
 		agents = append(agents, agentDetails)
 	}
 	return agents, err
@@ -94,9 +103,31 @@ func (store *EtcdStore) GetAgent(UUID string) (*server.Agent, error) {
 
 	var agentState server.Agent
 	json.Unmarshal([]byte(agentStateRes.Node.Value), &agentState)
+	store.getAgentMaintenance(fmt.Sprintf("/agents/%s", UUID), &agentState)
 	return &agentState, nil
 }
 
+func (store *EtcdStore) getAgentMaintenance(agentKey string, agent *server.Agent) {
+	maintenanceRes, err := store.keysAPI.Get(context.Background(), fmt.Sprintf("%s/maintenance", agentKey), nil)
+	if err != nil {
+		return
+	}
+	json.Unmarshal([]byte(maintenanceRes.Node.Value), &agent.Maintenance)
+}
+
+// UpdateAgentMaintenance stores the cordon and drain state of the agent
+func (store *EtcdStore) UpdateAgentMaintenance(hostname string, maintenance *server.AgentMaintenance) error {
+	value, _ := json.Marshal(maintenance)
+
+	_, err := store.keysAPI.Set(
+		context.Background(),
+		fmt.Sprintf("/agents/%s/maintenance", hostname),
+		string(value), nil,
+	)
+
+	return err
+}
+
 // ListGameservers returns a Gameserver list
 func (store *EtcdStore) ListGameservers() ([]server.Gameserver, error) {
 	gameservers := make([]server.Gameserver, 0)
@@ -159,3 +190,331 @@ func (store *EtcdStore) UpdateGameserver(gs *server.Gameserver) error {
 	_, err := store.keysAPI.Update(context.Background(), fmt.Sprintf("/gameservers/%s", gs.Definition.UUID), string(gsData))
 	return err
 }
+
+// AddGameserverEvent appends an event to the gameserver history
+// and removes the oldest events over the limit
+func (store *EtcdStore) AddGameserverEvent(UUID string, event *server.GameserverEvent) error {
+	eventData, _ := json.Marshal(*event)
+	key := fmt.Sprintf("/events/%s", UUID)
+	if _, err := store.keysAPI.CreateInOrder(context.Background(), key, string(eventData), &client.CreateInOrderOptions{
+		TTL: gameserverEventTTL,
+	}); err != nil {
+		return err
+	}
+
+	eventsRes, err := store.keysAPI.Get(context.Background(), key, &client.GetOptions{
+		Sort: true,
+	})
+	if err != nil {
+		return err
+	}
+	nodes := eventsRes.Node.Nodes
+	for i := 0; i < len(nodes)-maxGameserverEvents; i++ {
+		store.keysAPI.Delete(context.Background(), nodes[i].Key, nil)
+	}
+	return nil
+}
+
+// ListGameserverEvents returns the gameserver history, oldest first
+func (store *EtcdStore) ListGameserverEvents(UUID string) ([]server.GameserverEvent, error) {
+	events := make([]server.GameserverEvent, 0)
+
+	eventsRes, err := store.keysAPI.Get(context.Background(), fmt.Sprintf("/events/%s", UUID), &client.GetOptions{
+		Sort: true,
+	})
+	if client.IsKeyNotFound(err) {
+		return events, nil
+	} else if err != nil {
+		return events, err
+	}
+
+	for _, eventNode := range eventsRes.Node.Nodes {
+		var event server.GameserverEvent
+		json.Unmarshal([]byte(eventNode.Value), &event)
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// CreateOperation stores a new operation
+func (store *EtcdStore) CreateOperation(operation *server.Operation) error {
+	value, _ := json.Marshal(*operation)
+	_, err := store.keysAPI.Create(context.Background(), fmt.Sprintf("/operations/%s", operation.ID), string(value))
+	return err
+}
+
+// UpdateOperation func
+func (store *EtcdStore) UpdateOperation(operation *server.Operation) error {
+	value, _ := json.Marshal(*operation)
+	_, err := store.keysAPI.Update(context.Background(), fmt.Sprintf("/operations/%s", operation.ID), string(value))
+	return err
+}
+
+// GetOperation func
+func (store *EtcdStore) GetOperation(ID string) (*server.Operation, error) {
+	operationRes, err := store.keysAPI.Get(context.Background(), fmt.Sprintf("/operations/%s", ID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	operation := &server.Operation{}
+	json.Unmarshal([]byte(operationRes.Node.Value), operation)
+	return operation, nil
+}
+
+// ListOperations returns all operations
+func (store *EtcdStore) ListOperations() ([]server.Operation, error) {
+	operations := make([]server.Operation, 0)
+
+	operationsRes, err := store.keysAPI.Get(context.Background(), "/operations", nil)
+	if client.IsKeyNotFound(err) {
+		return operations, nil
+	} else if err != nil {
+		return operations, err
+	}
+
+	for _, operationNode := range operationsRes.Node.Nodes {
+		var operation server.Operation
+		json.Unmarshal([]byte(operationNode.Value), &operation)
+		operations = append(operations, operation)
+	}
+
+	return operations, nil
+}
+
+// CreateBootstrapToken stores a new bootstrap token. The key expires
+// together with the token
+func (store *EtcdStore) CreateBootstrapToken(token *server.BootstrapToken) error {
+	value, _ := json.Marshal(*token)
+	_, err := store.keysAPI.Set(context.Background(), fmt.Sprintf("/bootstrap-tokens/%s", token.Hash), string(value), &client.SetOptions{
+		PrevExist: client.PrevNoExist,
+		TTL:       time.Until(token.ExpiresAt),
+	})
+	return err
+}
+
+// UseBootstrapToken deletes the bootstrap token and returns it
+func (store *EtcdStore) UseBootstrapToken(hash string) (*server.BootstrapToken, error) {
+	tokenRes, err := store.keysAPI.Delete(context.Background(), fmt.Sprintf("/bootstrap-tokens/%s", hash), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &server.BootstrapToken{}
+	json.Unmarshal([]byte(tokenRes.PrevNode.Value), token)
+	return token, nil
+}
+
+// CreateTeam stores a new team
+func (store *EtcdStore) CreateTeam(team *server.Team) error {
+	value, _ := json.Marshal(*team)
+	_, err := store.keysAPI.Create(context.Background(), fmt.Sprintf("/teams/%s", team.ID), string(value))
+	return err
+}
+
+// UpdateTeam func
+func (store *EtcdStore) UpdateTeam(team *server.Team) error {
+	value, _ := json.Marshal(*team)
+	_, err := store.keysAPI.Update(context.Background(), fmt.Sprintf("/teams/%s", team.ID), string(value))
+	return err
+}
+
+// GetTeam func
+func (store *EtcdStore) GetTeam(ID string) (*server.Team, error) {
+	teamRes, err := store.keysAPI.Get(context.Background(), fmt.Sprintf("/teams/%s", ID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	team := &server.Team{}
+	json.Unmarshal([]byte(teamRes.Node.Value), team)
+	return team, nil
+}
+
+// ListTeams returns all teams
+func (store *EtcdStore) ListTeams() ([]server.Team, error) {
+	teams := make([]server.Team, 0)
+
+	teamsRes, err := store.keysAPI.Get(context.Background(), "/teams", nil)
+	if client.IsKeyNotFound(err) {
+		return teams, nil
+	} else if err != nil {
+		return teams, err
+	}
+
+	for _, teamNode := range teamsRes.Node.Nodes {
+		var team server.Team
+		json.Unmarshal([]byte(teamNode.Value), &team)
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+// DeleteTeam func
+func (store *EtcdStore) DeleteTeam(ID string) error {
+	_, err := store.keysAPI.Delete(context.Background(), fmt.Sprintf("/teams/%s", ID), nil)
+	return err
+}
+
+// GetQuota returns the quota of the subject or nil, when it has none
+func (store *EtcdStore) GetQuota(subject string) (*server.Quota, error) {
+	quotaRes, err := store.keysAPI.Get(context.Background(), fmt.Sprintf("/quotas/%s", subject), nil)
+	if client.IsKeyNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	quota := &server.Quota{}
+	json.Unmarshal([]byte(quotaRes.Node.Value), quota)
+	return quota, nil
+}
+
+// SetQuota func
+func (store *EtcdStore) SetQuota(subject string, quota *server.Quota) error {
+	value, _ := json.Marshal(*quota)
+	_, err := store.keysAPI.Set(context.Background(), fmt.Sprintf("/quotas/%s", subject), string(value), nil)
+	return err
+}
+
+// DeleteQuota func
+func (store *EtcdStore) DeleteQuota(subject string) error {
+	_, err := store.keysAPI.Delete(context.Background(), fmt.Sprintf("/quotas/%s", subject), nil)
+	if client.IsKeyNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// LockGameserver locks the gameserver for read-modify-write changes
+func (store *EtcdStore) LockGameserver(UUID string) (func(), error) {
+	return store.lock(fmt.Sprintf("/gameserver-locks/%s", UUID))
+}
+
+// LockTeam locks the team for read-modify-write changes
+func (store *EtcdStore) LockTeam(ID string) (func(), error) {
+	return store.lock(fmt.Sprintf("/team-locks/%s", ID))
+}
+
+// LockQuota locks the quota of the subject
+func (store *EtcdStore) LockQuota(subject string) (func(), error) {
+	return store.lock(fmt.Sprintf("/quota-locks/%s", subject))
+}
+
+// lock creates the lock key. The lock expires, if the server dies before
+// releasing it. Unlocking deletes only the key created by this lock, so
+// an expired lock doesn't release the lock of the next holder
+func (store *EtcdStore) lock(key string) (func(), error) {
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		res, err := store.keysAPI.Set(context.Background(), key, "", &client.SetOptions{
+			PrevExist: client.PrevNoExist,
+			TTL:       10 * time.Second,
+		})
+		if err == nil {
+			index := res.Node.ModifiedIndex
+			return func() {
+				store.keysAPI.Delete(context.Background(), key, &client.DeleteOptions{PrevIndex: index})
+			}, nil
+		}
+
+		if etcdErr, ok := err.(client.Error); !ok || etcdErr.Code != client.ErrorCodeNodeExist {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%s is locked", key)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// CreateAPIToken func
+func (store *EtcdStore) CreateAPIToken(token *server.APIToken) error {
+	value, _ := json.Marshal(*token)
+	_, err := store.keysAPI.Set(context.Background(), fmt.Sprintf("/api-tokens/%s", token.Hash), string(value), &client.SetOptions{
+		PrevExist: client.PrevNoExist,
+	})
+	return err
+}
+
+// UpdateAPIToken func
+func (store *EtcdStore) UpdateAPIToken(token *server.APIToken) error {
+	value, _ := json.Marshal(*token)
+	_, err := store.keysAPI.Update(context.Background(), fmt.Sprintf("/api-tokens/%s", token.Hash), string(value))
+	return err
+}
+
+// GetAPIToken returns the API token with the hash
+func (store *EtcdStore) GetAPIToken(hash string) (*server.APIToken, error) {
+	tokenRes, err := store.keysAPI.Get(context.Background(), fmt.Sprintf("/api-tokens/%s", hash), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &server.APIToken{}
+	json.Unmarshal([]byte(tokenRes.Node.Value), token)
+	return token, nil
+}
+
+// ListAPITokens returns the API tokens of the user
+func (store *EtcdStore) ListAPITokens(userID string) ([]server.APIToken, error) {
+	tokens := make([]server.APIToken, 0)
+
+	tokensRes, err := store.keysAPI.Get(context.Background(), "/api-tokens", nil)
+	if client.IsKeyNotFound(err) {
+		return tokens, nil
+	} else if err != nil {
+		return tokens, err
+	}
+
+	for _, tokenNode := range tokensRes.Node.Nodes {
+		var token server.APIToken
+		json.Unmarshal([]byte(tokenNode.Value), &token)
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens, nil
+}
+
+// DeleteAPIToken func
+func (store *EtcdStore) DeleteAPIToken(hash string) error {
+	_, err := store.keysAPI.Delete(context.Background(), fmt.Sprintf("/api-tokens/%s", hash), nil)
+	return err
+}
+
+// AddAuditEvent appends an event to the audit log. The key expires
+// after the retention
+func (store *EtcdStore) AddAuditEvent(event *server.AuditEvent, retention time.Duration) error {
+	value, _ := json.Marshal(*event)
+	_, err := store.keysAPI.CreateInOrder(context.Background(), "/audit", string(value), &client.CreateInOrderOptions{
+		TTL: retention,
+	})
+	return err
+}
+
+// ListAuditEvents returns the audit log, oldest first
+func (store *EtcdStore) ListAuditEvents() ([]server.AuditEvent, error) {
+	events := make([]server.AuditEvent, 0)
+
+	eventsRes, err := store.keysAPI.Get(context.Background(), "/audit", &client.GetOptions{
+		Sort: true,
+	})
+	if client.IsKeyNotFound(err) {
+		return events, nil
+	} else if err != nil {
+		return events, err
+	}
+
+	for _, eventNode := range eventsRes.Node.Nodes {
+		var event server.AuditEvent
+		json.Unmarshal([]byte(eventNode.Value), &event)
+		events = append(events, event)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,519 @@
+package teams
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/audit"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+var teamRoles = map[string]bool{
+	server.TeamOwner:  true,
+	server.TeamAdmin:  true,
+	server.TeamMember: true,
+	server.TeamViewer: true,
+}
+
+type createTeamRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type inviteMemberRequest struct {
+	UserID string `json:"userID" binding:"required"`
+	Role   string `json:"role"`
+}
+
+type updateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type teamMember struct {
+	UserID   string    `json:"userID"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joinedAt"`
+}
+
+type teamInvitation struct {
+	ID        string    `json:"id"`
+	TeamID    string    `json:"teamID"`
+	TeamName  string    `json:"teamName"`
+	UserID    string    `json:"userID"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invitedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type getTeamResponse struct {
+	ID          string           `json:"id"`
+	Name        string           `json:"name"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	Role        string           `json:"role"`
+	Members     []teamMember     `json:"members"`
+	Invitations []teamInvitation `json:"invitations"`
+}
+
+type listTeamsResponse []getTeamResponse
+
+type listInvitationsResponse []teamInvitation
+
+type teamsAPI struct {
+	teamStore       server.TeamStore
+	gameserverStore server.GameserverStore
+}
+
+// MountTeamsAPI mounts the Teams API
+func MountTeamsAPI(r *gin.Engine, teamStore server.TeamStore, gameserverStore server.GameserverStore) {
+	api := teamsAPI{teamStore, gameserverStore}
+
+	r.GET("/invitations/", auth.Authorize(auth.ResourceTeams, auth.VerbRead), api.listInvitations)
+
+	group := r.Group("/teams/")
+	group.GET("/", auth.Authorize(auth.ResourceTeams, auth.VerbRead), api.listTeams)
+	group.POST("/", auth.Authorize(auth.ResourceTeams, auth.VerbCreate), audit.Log("team.create"), api.createTeam)
+	group.GET("/:id/", auth.Authorize(auth.ResourceTeams, auth.VerbRead), api.getTeam)
+	group.DELETE("/:id/", auth.Authorize(auth.ResourceTeams, auth.VerbDelete), audit.Log("team.delete"), api.deleteTeam)
+	group.POST("/:id/invitations/", auth.Authorize(auth.ResourceTeams, auth.VerbUpdate), audit.Log("team.invite"), api.inviteMember)
+	group.DELETE("/:id/invitations/:invitation/", auth.Authorize(auth.ResourceTeams, auth.VerbUpdate), audit.Log("team.invitation.delete"), api.deleteInvitation)
+	// The invitation authorizes the invited user to accept it
+	group.POST("/:id/invitations/:invitation/accept/", audit.Log("team.invitation.accept"), api.acceptInvitation)
+	group.PATCH("/:id/members/:user/", auth.Authorize(auth.ResourceTeams, auth.VerbUpdate), audit.Log("team.member.update"), api.updateMember)
+	group.DELETE("/:id/members/:user/", auth.Authorize(auth.ResourceTeams, auth.VerbUpdate), audit.Log("team.member.remove"), api.removeMember)
+}
+
+// teamRole returns the role of the user in the team. Users
+// authorized for all teams act as owners
+func teamRole(c *gin.Context, team *server.Team) string {
+	if member := team.Member(c.GetString("userID")); member != nil {
+		return member.Role
+	}
+	if auth.CanAccess(c, "") {
+		return server.TeamOwner
+	}
+	return ""
+}
+
+func managesMembers(role string) bool {
+	return role == server.TeamOwner || role == server.TeamAdmin
+}
+
+func countOwners(team *server.Team) int {
+	owners := 0
+	for _, member := range team.Members {
+		if member.Role == server.TeamOwner {
+			owners++
+		}
+	}
+	return owners
+}
+
+func newGetTeamResponse(team *server.Team, role string) getTeamResponse {
+	resp := getTeamResponse{
+		ID:          team.ID,
+		Name:        team.Name,
+		CreatedAt:   team.CreatedAt,
+		Role:        role,
+		Members:     make([]teamMember, 0, len(team.Members)),
+		Invitations: make([]teamInvitation, 0, len(team.Invitations)),
+	}
+	for _, member := range team.Members {
+		resp.Members = append(resp.Members, teamMember{
+			UserID:   member.UserID,
+			Role:     member.Role,
+			JoinedAt: member.JoinedAt,
+		})
+	}
+	for _, invitation := range team.Invitations {
+		resp.Invitations = append(resp.Invitations, newTeamInvitation(team, &invitation))
+	}
+	return resp
+}
+
+func newTeamInvitation(team *server.Team, invitation *server.TeamInvitation) teamInvitation {
+	return teamInvitation{
+		ID:        invitation.ID,
+		TeamID:    team.ID,
+		TeamName:  team.Name,
+		UserID:    invitation.UserID,
+		Role:      invitation.Role,
+		InvitedBy: invitation.InvitedBy,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
+
+// loadTeam returns the team and the role of the user in it. Teams
+// of others are reported as not found
+func (api *teamsAPI) loadTeam(c *gin.Context) (*server.Team, string, bool) {
+	team, err := api.teamStore.GetTeam(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return nil, "", false
+	}
+
+	return team, teamRole(c, team), true
+}
+
+// lockTeam locks the team for changing its members and invitations and
+// returns its latest state
+func (api *teamsAPI) lockTeam(c *gin.Context) (*server.Team, string, func(), bool) {
+	if _, _, ok := api.loadTeam(c); !ok {
+		return nil, "", nil, false
+	}
+
+	unlock, err := api.teamStore.LockTeam(c.Param("id"))
+	if err != nil {
+		log.Printf("teamsAPI lockTeam error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot update team"})
+		return nil, "", nil, false
+	}
+
+	team, role, ok := api.loadTeam(c)
+	if !ok {
+		unlock()
+		return nil, "", nil, false
+	}
+	return team, role, unlock, true
+}
+
+func (api *teamsAPI) updateTeam(c *gin.Context, team *server.Team, status int, resp interface{}) {
+	if err := api.teamStore.UpdateTeam(team); err != nil {
+		log.Printf("teamsAPI updateTeam error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot update team"})
+		return
+	}
+	c.JSON(status, resp)
+}
+
+func (api *teamsAPI) listTeams(c *gin.Context) {
+	teams, err := api.teamStore.ListTeams()
+	if err != nil {
+		log.Printf("teamsAPI listTeams error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot list teams"})
+		return
+	}
+
+	resp := listTeamsResponse{}
+	for i := range teams {
+		if role := teamRole(c, &teams[i]); role != "" {
+			resp = append(resp, newGetTeamResponse(&teams[i], role))
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// listInvitations returns the pending invitations of the user
+func (api *teamsAPI) listInvitations(c *gin.Context) {
+	userID := c.GetString("userID")
+
+	teams, err := api.teamStore.ListTeams()
+	if err != nil {
+		log.Printf("teamsAPI listInvitations error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot list invitations"})
+		return
+	}
+
+	resp := listInvitationsResponse{}
+	for i := range teams {
+		for _, invitation := range teams[i].Invitations {
+			if invitation.UserID == userID {
+				resp = append(resp, newTeamInvitation(&teams[i], &invitation))
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// createTeam creates a team owned by the user
+func (api *teamsAPI) createTeam(c *gin.Context) {
+	var body createTeamRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login required"})
+		return
+	}
+
+	now := time.Now()
+	team := &server.Team{
+		ID:        uuid.NewV4().String(),
+		Name:      body.Name,
+		CreatedAt: now,
+		Members: []server.TeamMembership{
+			{UserID: userID, Role: server.TeamOwner, JoinedAt: now},
+		},
+	}
+
+	if err := api.teamStore.CreateTeam(team); err != nil {
+		log.Printf("teamsAPI createTeam error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot create team"})
+		return
+	}
+
+	audit.SetTarget(c, team.ID)
+	audit.SetChange(c, "", team.Name)
+	c.JSON(http.StatusCreated, newGetTeamResponse(team, server.TeamOwner))
+}
+
+func (api *teamsAPI) getTeam(c *gin.Context) {
+	team, role, ok := api.loadTeam(c)
+	if !ok {
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+
+	c.JSON(http.StatusOK, newGetTeamResponse(team, role))
+}
+
+// deleteTeam removes the team. Teams owning gameservers cannot be removed
+func (api *teamsAPI) deleteTeam(c *gin.Context) {
+	team, role, ok := api.loadTeam(c)
+	if !ok {
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+	if role != server.TeamOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can delete the team"})
+		return
+	}
+
+	gameservers, err := api.gameserverStore.ListGameservers()
+	if err != nil {
+		log.Printf("teamsAPI deleteTeam error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot delete team"})
+		return
+	}
+	for _, gameserver := range gameservers {
+		if gameserver.Definition.Team == team.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Team owns gameservers"})
+			return
+		}
+	}
+
+	if err := api.teamStore.DeleteTeam(team.ID); err != nil {
+		log.Printf("teamsAPI deleteTeam error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot delete team"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{})
+}
+
+// inviteMember invites a user to the team. Only owners can invite owners
+func (api *teamsAPI) inviteMember(c *gin.Context) {
+	team, role, unlock, ok := api.lockTeam(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+	if role == "" {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+	if !managesMembers(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners and admins can invite members"})
+		return
+	}
+
+	var body inviteMemberRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if body.Role == "" {
+		body.Role = server.TeamMember
+	}
+	if !teamRoles[body.Role] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team role"})
+		return
+	}
+	if body.Role == server.TeamOwner && role != server.TeamOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can invite owners"})
+		return
+	}
+
+	if team.Member(body.UserID) != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "User is already a member"})
+		return
+	}
+	for _, invitation := range team.Invitations {
+		if invitation.UserID == body.UserID {
+			c.JSON(http.StatusConflict, gin.H{"error": "User is already invited"})
+			return
+		}
+	}
+
+	invitation := server.TeamInvitation{
+		ID:        uuid.NewV4().String(),
+		UserID:    body.UserID,
+		Role:      body.Role,
+		InvitedBy: c.GetString("userID"),
+		CreatedAt: time.Now(),
+	}
+	team.Invitations = append(team.Invitations, invitation)
+
+	api.updateTeam(c, team, http.StatusCreated, newTeamInvitation(team, &invitation))
+}
+
+// deleteInvitation revokes the invitation or declines it, when called
+// by the invited user
+func (api *teamsAPI) deleteInvitation(c *gin.Context) {
+	team, role, unlock, ok := api.lockTeam(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	invitation := team.Invitation(c.Param("invitation"))
+	invited := invitation != nil && invitation.UserID == c.GetString("userID")
+	if invitation == nil || (role == "" && !invited) {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+	if !managesMembers(role) && !invited {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners and admins can revoke invitations"})
+		return
+	}
+
+	invitations := make([]server.TeamInvitation, 0, len(team.Invitations))
+	for _, other := range team.Invitations {
+		if other.ID != invitation.ID {
+			invitations = append(invitations, other)
+		}
+	}
+	team.Invitations = invitations
+
+	api.updateTeam(c, team, http.StatusOK, gin.H{})
+}
+
+// acceptInvitation adds the invited user to the team
+func (api *teamsAPI) acceptInvitation(c *gin.Context) {
+	userID := c.GetString("userID")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login required"})
+		return
+	}
+
+	team, _, unlock, ok := api.lockTeam(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	invitation := team.Invitation(c.Param("invitation"))
+	if invitation == nil || invitation.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+
+	role := invitation.Role
+	invitations := make([]server.TeamInvitation, 0, len(team.Invitations))
+	for _, other := range team.Invitations {
+		if other.ID != invitation.ID {
+			invitations = append(invitations, other)
+		}
+	}
+	team.Invitations = invitations
+	team.Members = append(team.Members, server.TeamMembership{
+		UserID:   userID,
+		Role:     role,
+		JoinedAt: time.Now(),
+	})
+
+	api.updateTeam(c, team, http.StatusOK, newGetTeamResponse(team, role))
+}
+
+// updateMember changes the role of a member. Admins cannot change
+// the owners and the last owner cannot be demoted
+func (api *teamsAPI) updateMember(c *gin.Context) {
+	team, role, unlock, ok := api.lockTeam(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	member := team.Member(c.Param("user"))
+	if role == "" || member == nil {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+	if !managesMembers(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners and admins can change members"})
+		return
+	}
+
+	var body updateMemberRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !teamRoles[body.Role] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team role"})
+		return
+	}
+	if role != server.TeamOwner && (member.Role == server.TeamOwner || body.Role == server.TeamOwner) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can change owners"})
+		return
+	}
+	if member.Role == server.TeamOwner && body.Role != server.TeamOwner && countOwners(team) == 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Team must have an owner"})
+		return
+	}
+
+	member.Role = body.Role
+	api.updateTeam(c, team, http.StatusOK, newGetTeamResponse(team, teamRole(c, team)))
+}
+
+// removeMember removes a member from the team. Members can leave
+// the team, but the last owner cannot
+func (api *teamsAPI) removeMember(c *gin.Context) {
+	team, role, unlock, ok := api.lockTeam(c)
+	if !ok {
+		return
+	}
+	defer unlock()
+
+	member := team.Member(c.Param("user"))
+	if role == "" || member == nil {
+		c.JSON(http.StatusNotFound, gin.H{})
+		return
+	}
+
+	leaving := member.UserID == c.GetString("userID")
+	if !leaving && !managesMembers(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners and admins can remove members"})
+		return
+	}
+	if !leaving && role != server.TeamOwner && member.Role == server.TeamOwner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only owners can remove owners"})
+		return
+	}
+	if member.Role == server.TeamOwner && countOwners(team) == 1 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Team must have an owner"})
+		return
+	}
+
+	members := make([]server.TeamMembership, 0, len(team.Members))
+	for _, other := range team.Members {
+		if other.UserID != member.UserID {
+			members = append(members, other)
+		}
+	}
+	team.Members = members
+
+	api.updateTeam(c, team, http.StatusOK, gin.H{})
+}
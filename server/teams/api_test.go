@@ -0,0 +1,254 @@
+package teams
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/Trojan295/chinchilla/server/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func testTeam() *server.Team {
+	return &server.Team{
+		ID:   "team1",
+		Name: "Clan",
+		Members: []server.TeamMembership{
+			{UserID: "owner1", Role: server.TeamOwner},
+			{UserID: "admin1", Role: server.TeamAdmin},
+			{UserID: "member1", Role: server.TeamMember},
+		},
+		Invitations: []server.TeamInvitation{
+			{ID: "invitation1", UserID: "user2", Role: server.TeamViewer, InvitedBy: "owner1"},
+		},
+	}
+}
+
+func setupTeamsRouter(ctrl *gomock.Controller, team *server.Team) (*gin.Engine, *mocks.MockTeamStore) {
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().GetTeam(team.ID).Return(team, nil).AnyTimes()
+	teamStore.EXPECT().GetTeam(gomock.Any()).Return(nil, assert.AnError).AnyTimes()
+	teamStore.EXPECT().LockTeam(team.ID).Return(func() {}, nil).AnyTimes()
+
+	router := utils.SetupRouter()
+	MountTeamsAPI(router, teamStore, mocks.NewMockGameserverStore(ctrl))
+	return router, teamStore
+}
+
+func teamRequest(router *gin.Engine, method, path, userID, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": userID}))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateTeam(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().
+		CreateTeam(gomock.Any()).
+		Do(func(team *server.Team) {
+			assert.Equal(t, "Clan", team.Name)
+			assert.Equal(t, server.TeamOwner, team.Member("user1").Role)
+		}).
+		Return(nil)
+
+	router := utils.SetupRouter()
+	MountTeamsAPI(router, teamStore, mocks.NewMockGameserverStore(ctrl))
+
+	w := teamRequest(router, "POST", "/teams/", "user1", `{"name": "Clan"}`)
+	assert.Equal(t, 201, w.Code)
+}
+
+func TestInviteAndAcceptMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	team := testTeam()
+	router, teamStore := setupTeamsRouter(ctrl, team)
+	teamStore.EXPECT().UpdateTeam(team).Return(nil).Times(2)
+
+	w := teamRequest(router, "POST", "/teams/team1/invitations/", "admin1", `{"userID": "user3"}`)
+	assert.Equal(t, 201, w.Code)
+
+	var invitation teamInvitation
+	json.Unmarshal(w.Body.Bytes(), &invitation)
+	assert.Equal(t, server.TeamMember, invitation.Role)
+
+	// only the invited user can accept
+	w = teamRequest(router, "POST", "/teams/team1/invitations/"+invitation.ID+"/accept/", "user2", "")
+	assert.Equal(t, 404, w.Code)
+
+	w = teamRequest(router, "POST", "/teams/team1/invitations/"+invitation.ID+"/accept/", "user3", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, server.TeamMember, team.Member("user3").Role)
+	assert.Len(t, team.Invitations, 1)
+}
+
+func TestAcceptInvitationWithReadOnlyRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	team := testTeam()
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().GetTeam(team.ID).Return(team, nil).AnyTimes()
+	teamStore.EXPECT().LockTeam(team.ID).Return(func() {}, nil).AnyTimes()
+	teamStore.EXPECT().UpdateTeam(team).Return(nil).Times(1)
+
+	policy := auth.DefaultPolicy
+	policy.Bindings = []auth.Binding{{Role: auth.RoleViewer, Users: []string{"user2"}}}
+
+	router := utils.SetupRouter()
+	router.Use(auth.UsePolicy(&policy))
+	MountTeamsAPI(router, teamStore, mocks.NewMockGameserverStore(ctrl))
+
+	w := teamRequest(router, "POST", "/teams/team1/invitations/invitation1/accept/", "user2", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, server.TeamViewer, team.Member("user2").Role)
+}
+
+func TestMembershipChangesUseLatestTeam(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// another request invited user3, after the team was loaded
+	stale := testTeam()
+	current := testTeam()
+	current.Invitations = append(current.Invitations, server.TeamInvitation{ID: "invitation2", UserID: "user3", Role: server.TeamMember})
+
+	locked := false
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	gomock.InOrder(
+		teamStore.EXPECT().GetTeam("team1").Return(stale, nil),
+		teamStore.EXPECT().LockTeam("team1").Return(func() { locked = false }, nil).Do(func(string) { locked = true }),
+		teamStore.EXPECT().GetTeam("team1").Return(current, nil),
+		teamStore.EXPECT().
+			UpdateTeam(gomock.Any()).
+			Do(func(team *server.Team) {
+				assert.True(t, locked)
+				assert.NotNil(t, team.Member("user2"))
+				assert.NotNil(t, team.Invitation("invitation2"))
+			}).
+			Return(nil),
+	)
+
+	router := utils.SetupRouter()
+	MountTeamsAPI(router, teamStore, mocks.NewMockGameserverStore(ctrl))
+
+	w := teamRequest(router, "POST", "/teams/team1/invitations/invitation1/accept/", "user2", "")
+	assert.Equal(t, 200, w.Code)
+	assert.False(t, locked)
+}
+
+func TestInviteRequiresManagerRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	router, _ := setupTeamsRouter(ctrl, testTeam())
+
+	w := teamRequest(router, "POST", "/teams/team1/invitations/", "member1", `{"userID": "user3"}`)
+	assert.Equal(t, 403, w.Code)
+
+	w = teamRequest(router, "POST", "/teams/team1/invitations/", "admin1", `{"userID": "user3", "role": "owner"}`)
+	assert.Equal(t, 403, w.Code)
+
+	w = teamRequest(router, "POST", "/teams/team1/invitations/", "owner1", `{"userID": "member1"}`)
+	assert.Equal(t, 409, w.Code)
+
+	w = teamRequest(router, "POST", "/teams/team1/invitations/", "user3", `{"userID": "user4"}`)
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestListInvitations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().ListTeams().Return([]server.Team{*testTeam()}, nil)
+
+	router := utils.SetupRouter()
+	MountTeamsAPI(router, teamStore, mocks.NewMockGameserverStore(ctrl))
+
+	w := teamRequest(router, "GET", "/invitations/", "user2", "")
+
+	var resp listInvitationsResponse
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Len(t, resp, 1)
+	assert.Equal(t, "team1", resp[0].TeamID)
+	assert.Equal(t, "Clan", resp[0].TeamName)
+}
+
+func TestRemoveMembers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	team := testTeam()
+	router, teamStore := setupTeamsRouter(ctrl, team)
+	teamStore.EXPECT().UpdateTeam(team).Return(nil).Times(2)
+
+	w := teamRequest(router, "DELETE", "/teams/team1/members/owner1/", "owner1", "")
+	assert.Equal(t, 409, w.Code)
+
+	w = teamRequest(router, "DELETE", "/teams/team1/members/owner1/", "admin1", "")
+	assert.Equal(t, 403, w.Code)
+
+	w = teamRequest(router, "DELETE", "/teams/team1/members/member1/", "member1", "")
+	assert.Equal(t, 200, w.Code)
+
+	w = teamRequest(router, "DELETE", "/teams/team1/members/admin1/", "owner1", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Len(t, team.Members, 1)
+}
+
+func TestUpdateMemberRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	team := testTeam()
+	router, teamStore := setupTeamsRouter(ctrl, team)
+	teamStore.EXPECT().UpdateTeam(team).Return(nil).Times(1)
+
+	w := teamRequest(router, "PATCH", "/teams/team1/members/member1/", "admin1", `{"role": "owner"}`)
+	assert.Equal(t, 403, w.Code)
+
+	w = teamRequest(router, "PATCH", "/teams/team1/members/owner1/", "owner1", `{"role": "member"}`)
+	assert.Equal(t, 409, w.Code)
+
+	w = teamRequest(router, "PATCH", "/teams/team1/members/member1/", "admin1", `{"role": "viewer"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, server.TeamViewer, team.Member("member1").Role)
+}
+
+func TestDeleteTeamOwningGameservers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	teamStore := mocks.NewMockTeamStore(ctrl)
+	teamStore.EXPECT().GetTeam("team1").Return(testTeam(), nil).AnyTimes()
+
+	gameserverStore := mocks.NewMockGameserverStore(ctrl)
+	gameserverStore.EXPECT().ListGameservers().Return([]server.Gameserver{
+		{Definition: server.GameserverDefinition{UUID: "uuid1", Team: "team1"}},
+	}, nil)
+
+	router := utils.SetupRouter()
+	MountTeamsAPI(router, teamStore, gameserverStore)
+
+	w := teamRequest(router, "DELETE", "/teams/team1/", "admin1", "")
+	assert.Equal(t, 403, w.Code)
+
+	w = teamRequest(router, "DELETE", "/teams/team1/", "owner1", "")
+	assert.Equal(t, 409, w.Code)
+}
@@ -0,0 +1,164 @@
+package tokens
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/audit"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/gin-gonic/gin"
+)
+
+type createTokenRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"min=0"`
+}
+
+type apiToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+type createTokenResponse struct {
+	apiToken
+	Token string `json:"token"`
+}
+
+type listTokensResponse []apiToken
+
+type tokensAPI struct {
+	tokenStore server.APITokenStore
+}
+
+// MountTokensAPI mounts the personal API tokens API
+func MountTokensAPI(r *gin.Engine, tokenStore server.APITokenStore) {
+	api := tokensAPI{tokenStore}
+
+	group := r.Group("/tokens/")
+	group.GET("/", auth.Authorize(auth.ResourceTokens, auth.VerbRead), api.listTokens)
+	group.POST("/", auth.Authorize(auth.ResourceTokens, auth.VerbCreate), audit.Log("token.create"), api.createToken)
+	group.DELETE("/:id/", auth.Authorize(auth.ResourceTokens, auth.VerbDelete), audit.Log("token.delete"), api.deleteToken)
+}
+
+func newAPIToken(token *server.APIToken) apiToken {
+	scopes := token.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+
+	return apiToken{
+		ID:         token.ID,
+		Name:       token.Name,
+		Scopes:     scopes,
+		CreatedAt:  token.CreatedAt,
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+	}
+}
+
+func (api *tokensAPI) listTokens(c *gin.Context) {
+	tokens, err := api.tokenStore.ListAPITokens(c.GetString("userID"))
+	if err != nil {
+		log.Printf("tokensAPI listTokens error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot list tokens"})
+		return
+	}
+
+	resp := make(listTokensResponse, 0, len(tokens))
+	for i := range tokens {
+		resp = append(resp, newAPIToken(&tokens[i]))
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (api *tokensAPI) createToken(c *gin.Context) {
+	var body createTokenRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, scope := range body.Scopes {
+		if !auth.ValidPermission(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope " + scope})
+			return
+		}
+	}
+
+	// Scoped tokens can create only tokens with narrower scopes
+	if callerScopes := c.GetStringSlice("tokenScopes"); len(callerScopes) > 0 {
+		if len(body.Scopes) == 0 {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Scoped tokens cannot create unscoped tokens"})
+			return
+		}
+		for _, scope := range body.Scopes {
+			if !auth.CoversPermission(callerScopes, scope) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Scope " + scope + " is not granted to the token"})
+				return
+			}
+		}
+	}
+
+	var expiresAt *time.Time
+	if body.ExpiresInDays > 0 {
+		expiration := time.Now().AddDate(0, 0, body.ExpiresInDays)
+		expiresAt = &expiration
+	}
+	// Tokens created with a token don't outlive it
+	if value, ok := c.Get("tokenExpiresAt"); ok {
+		callerExpiresAt := value.(time.Time)
+		if expiresAt == nil || expiresAt.After(callerExpiresAt) {
+			expiresAt = &callerExpiresAt
+		}
+	}
+
+	secret, token, err := server.NewAPIToken(c.GetString("userID"), body.Name, body.Scopes, expiresAt)
+	if err != nil {
+		log.Printf("tokensAPI createToken error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Cannot create token"})
+		return
+	}
+
+	if err := api.tokenStore.CreateAPIToken(token); err != nil {
+		log.Printf("tokensAPI createToken error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot create token"})
+		return
+	}
+
+	audit.SetTarget(c, token.ID)
+	audit.SetChange(c, "", fmt.Sprintf("%s %v", token.Name, token.Scopes))
+	c.JSON(http.StatusCreated, createTokenResponse{newAPIToken(token), secret})
+}
+
+func (api *tokensAPI) deleteToken(c *gin.Context) {
+	tokens, err := api.tokenStore.ListAPITokens(c.GetString("userID"))
+	if err != nil {
+		log.Printf("tokensAPI deleteToken error: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot revoke token"})
+		return
+	}
+
+	for _, token := range tokens {
+		if token.ID != c.Param("id") {
+			continue
+		}
+
+		if err := api.tokenStore.DeleteAPIToken(token.Hash); err != nil {
+			log.Printf("tokensAPI deleteToken error: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Cannot revoke token"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{})
+		return
+	}
+
+	c.JSON(http.StatusNotFound, gin.H{})
+}
@@ -0,0 +1,191 @@
+package tokens
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Trojan295/chinchilla/mocks"
+	"github.com/Trojan295/chinchilla/server"
+	"github.com/Trojan295/chinchilla/server/auth"
+	"github.com/Trojan295/chinchilla/server/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func tokenRequest(router *gin.Engine, method, path, userID, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Add("authorization", "Bearer "+utils.BuildToken(map[string]interface{}{"sub": userID}))
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var stored *server.APIToken
+	tokenStore := mocks.NewMockAPITokenStore(ctrl)
+	tokenStore.EXPECT().
+		CreateAPIToken(gomock.Any()).
+		Do(func(token *server.APIToken) { stored = token }).
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountTokensAPI(router, tokenStore)
+
+	w := tokenRequest(router, "POST", "/tokens/", "user1", `{"name": "ci", "scopes": ["read:gameservers"], "expiresInDays": 30}`)
+
+	assert.Equal(t, 201, w.Code)
+
+	res := createTokenResponse{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	assert.True(t, strings.HasPrefix(res.Token, server.APITokenPrefix))
+	assert.Equal(t, "ci", res.Name)
+	assert.Equal(t, []string{"read:gameservers"}, res.Scopes)
+	assert.NotNil(t, res.ExpiresAt)
+
+	assert.Equal(t, "user1", stored.UserID)
+	assert.Equal(t, server.HashToken(res.Token), stored.Hash)
+	assert.NotContains(t, w.Body.String(), stored.Hash)
+}
+
+func TestCreateTokenWithInvalidScope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	router := utils.SetupRouter()
+	MountTokensAPI(router, mocks.NewMockAPITokenStore(ctrl))
+
+	for _, scope := range []string{"read", "destroy:gameservers", "read:planets"} {
+		w := tokenRequest(router, "POST", "/tokens/", "user1", `{"name": "ci", "scopes": ["`+scope+`"]}`)
+		assert.Equal(t, 400, w.Code, scope)
+	}
+}
+
+// scopedTokenRouter sets up a router authenticating the API token of user1
+func scopedTokenRouter(ctrl *gomock.Controller, scopes []string, expiresAt *time.Time) (*gin.Engine, *mocks.MockAPITokenStore, string) {
+	token, apiToken, _ := server.NewAPIToken("user1", "ci", scopes, expiresAt)
+
+	tokenStore := mocks.NewMockAPITokenStore(ctrl)
+	tokenStore.EXPECT().
+		GetAPIToken(apiToken.Hash).
+		Return(apiToken, nil).
+		AnyTimes()
+	tokenStore.EXPECT().
+		UpdateAPIToken(gomock.Any()).
+		Return(nil).
+		AnyTimes()
+
+	router := gin.Default()
+	auth.SetupAuthentication(router, map[string]interface{}{
+		"type": "jwt",
+		"key":  "secret",
+	}, tokenStore)
+	MountTokensAPI(router, tokenStore)
+
+	return router, tokenStore, token
+}
+
+func scopedTokenRequest(router *gin.Engine, token, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/tokens/", strings.NewReader(body))
+	req.Header.Add("authorization", "Bearer "+token)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateTokenWithScopedToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	router, tokenStore, token := scopedTokenRouter(ctrl, []string{"read:gameservers", "write:tokens"}, nil)
+	tokenStore.EXPECT().
+		CreateAPIToken(gomock.Any()).
+		Return(nil).
+		Times(1)
+
+	assert.Equal(t, 403, scopedTokenRequest(router, token, `{"name": "ci"}`).Code)
+	assert.Equal(t, 403, scopedTokenRequest(router, token, `{"name": "ci", "scopes": ["write:gameservers"]}`).Code)
+	assert.Equal(t, 403, scopedTokenRequest(router, token, `{"name": "ci", "scopes": ["read:tokens"]}`).Code)
+	assert.Equal(t, 201, scopedTokenRequest(router, token, `{"name": "ci", "scopes": ["read:gameservers"]}`).Code)
+}
+
+func TestCreateTokenCapsExpiration(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	router, tokenStore, token := scopedTokenRouter(ctrl, nil, &expiresAt)
+
+	var stored []*server.APIToken
+	tokenStore.EXPECT().
+		CreateAPIToken(gomock.Any()).
+		Do(func(token *server.APIToken) { stored = append(stored, token) }).
+		Return(nil).
+		Times(2)
+
+	assert.Equal(t, 201, scopedTokenRequest(router, token, `{"name": "ci"}`).Code)
+	assert.Equal(t, 201, scopedTokenRequest(router, token, `{"name": "ci", "expiresInDays": 30}`).Code)
+
+	if assert.Len(t, stored, 2) {
+		for _, token := range stored {
+			if assert.NotNil(t, token.ExpiresAt) {
+				assert.True(t, expiresAt.Equal(*token.ExpiresAt))
+			}
+		}
+	}
+}
+
+func TestListTokens(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokenStore := mocks.NewMockAPITokenStore(ctrl)
+	tokenStore.EXPECT().
+		ListAPITokens("user1").
+		Return([]server.APIToken{{ID: "token1", Hash: "hash1", UserID: "user1", Name: "ci"}}, nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountTokensAPI(router, tokenStore)
+
+	w := tokenRequest(router, "GET", "/tokens/", "user1", "")
+
+	assert.Equal(t, 200, w.Code)
+	assert.NotContains(t, w.Body.String(), "hash1")
+
+	res := listTokensResponse{}
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if assert.Len(t, res, 1) {
+		assert.Equal(t, "token1", res[0].ID)
+		assert.Equal(t, []string{}, res[0].Scopes)
+	}
+}
+
+func TestRevokeToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tokenStore := mocks.NewMockAPITokenStore(ctrl)
+	tokenStore.EXPECT().
+		ListAPITokens("user1").
+		Return([]server.APIToken{{ID: "token1", Hash: "hash1", UserID: "user1"}}, nil).
+		Times(2)
+	tokenStore.EXPECT().
+		DeleteAPIToken("hash1").
+		Return(nil).
+		Times(1)
+
+	router := utils.SetupRouter()
+	MountTokensAPI(router, tokenStore)
+
+	assert.Equal(t, 404, tokenRequest(router, "DELETE", "/tokens/token2/", "user1", "").Code)
+	assert.Equal(t, 202, tokenRequest(router, "DELETE", "/tokens/token1/", "user1", "").Code)
+}
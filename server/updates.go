@@ -0,0 +1,118 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// updateBufferSize is the number of updates buffered for a subscriber.
+// Slower subscribers are disconnected and have to resume
+const updateBufferSize = 64
+
+// UpdateBus publishes the gameserver updates to the subscribers and keeps
+// the latest updates, so the subscribers can resume after reconnecting.
+// A nil UpdateBus publishes nothing
+type UpdateBus struct {
+	mutex       sync.Mutex
+	lastID      uint64
+	history     []GameserverUpdate
+	historySize int
+	subscribers map[chan GameserverUpdate]bool
+}
+
+// UpdateSubscription receives the updates published after it was created.
+// Missed contains the updates published after the resumed ID. Updates is
+// closed, when the subscriber is too slow
+type UpdateSubscription struct {
+	Missed  []GameserverUpdate
+	Resumed bool
+	Updates <-chan GameserverUpdate
+
+	bus     *UpdateBus
+	channel chan GameserverUpdate
+}
+
+// NewUpdateBus creates an UpdateBus keeping historySize updates
+func NewUpdateBus(historySize int) *UpdateBus {
+	return &UpdateBus{
+		// IDs start at the creation time, so IDs of
+		// a previous server process are never resumed
+		lastID:      uint64(time.Now().Unix()) * 1000000,
+		historySize: historySize,
+		subscribers: make(map[chan GameserverUpdate]bool),
+	}
+}
+
+// Publish assigns the next ID to the update and sends it to the subscribers
+func (bus *UpdateBus) Publish(update GameserverUpdate) {
+	if bus == nil {
+		return
+	}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	bus.lastID++
+	update.ID = bus.lastID
+	if update.Time.IsZero() {
+		update.Time = time.Now()
+	}
+
+	bus.history = append(bus.history, update)
+	if len(bus.history) > bus.historySize {
+		bus.history = bus.history[len(bus.history)-bus.historySize:]
+	}
+
+	for channel := range bus.subscribers {
+		select {
+		case channel <- update:
+		default:
+			delete(bus.subscribers, channel)
+			close(channel)
+		}
+	}
+}
+
+// Subscribe subscribes to the updates published after lastID. Resumed is
+// false, if these updates are not kept anymore and the subscriber has to
+// reload the state. A lastID of 0 subscribes only to the next updates
+func (bus *UpdateBus) Subscribe(lastID uint64) *UpdateSubscription {
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	channel := make(chan GameserverUpdate, updateBufferSize)
+	bus.subscribers[channel] = true
+
+	subscription := &UpdateSubscription{
+		Updates: channel,
+		bus:     bus,
+		channel: channel,
+	}
+
+	oldestID := bus.lastID + 1
+	if len(bus.history) > 0 {
+		oldestID = bus.history[0].ID
+	}
+	if lastID == 0 || (lastID+1 >= oldestID && lastID <= bus.lastID) {
+		subscription.Resumed = true
+		for _, update := range bus.history {
+			if lastID != 0 && update.ID > lastID {
+				subscription.Missed = append(subscription.Missed, update)
+			}
+		}
+	}
+
+	return subscription
+}
+
+// Close unsubscribes from the updates
+func (subscription *UpdateSubscription) Close() {
+	bus := subscription.bus
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.subscribers[subscription.channel] {
+		delete(bus.subscribers, subscription.channel)
+		close(subscription.channel)
+	}
+}
@@ -1,5 +1,18 @@
 package server
 
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Trojan295/chinchilla/proto"
+	uuid "github.com/satori/go.uuid"
+)
+
 // GetGameserversForAgent func
 func GetGameserversForAgent(agentHostname string, store GameserverStore) ([]Gameserver, error) {
 	gameservers, err := store.ListGameservers()
@@ -15,3 +28,205 @@ func GetGameserversForAgent(agentHostname string, store GameserverStore) ([]Game
 	}
 	return agentGameservers, nil
 }
+
+// GetRunningGameservers returns the gameservers reported by all agents by their UUID
+func GetRunningGameservers(store AgentStore) (map[string]*proto.Gameserver, error) {
+	runningGameservers := make(map[string]*proto.Gameserver)
+
+	agents, err := store.ListAgents()
+	if err != nil {
+		return runningGameservers, err
+	}
+
+	for _, agent := range agents {
+		for _, gameserver := range agent.State.RunningGameservers {
+			runningGameservers[gameserver.UUID] = gameserver
+		}
+	}
+	return runningGameservers, nil
+}
+
+// StartAgentDrain cordons the agent and starts moving its gameservers
+// to other agents. A drain already in progress is left untouched
+func StartAgentDrain(store AgentStore, agent *Agent, requestedBy string) error {
+	maintenance := agent.Maintenance
+	if maintenance.Drain != nil && maintenance.Drain.State == DrainInProgress {
+		return nil
+	}
+
+	maintenance.Cordoned = true
+	maintenance.Drain = &AgentDrain{
+		State:       DrainInProgress,
+		RequestedBy: requestedBy,
+		StartedAt:   time.Now(),
+		Gameservers: make([]DrainedGameserver, 0),
+	}
+	if err := store.UpdateAgentMaintenance(agent.State.Hostname, &maintenance); err != nil {
+		return err
+	}
+
+	agent.Maintenance = maintenance
+	log.Printf("Draining agent %s requested by %s", agent.State.Hostname, requestedBy)
+	return nil
+}
+
+// NewMigration creates a migration operation of the gameserver. An empty
+// target agent is chosen by the scheduler
+func NewMigration(gameserver *Gameserver, targetAgent, requestedBy string) *Operation {
+	now := time.Now()
+	return &Operation{
+		ID:             uuid.NewV4().String(),
+		Type:           OperationMigrate,
+		GameserverUUID: gameserver.Definition.UUID,
+		RequestedBy:    requestedBy,
+		Phase:          MigrationPending,
+		StartedAt:      now,
+		PhaseStartedAt: now,
+		SourceAgent:    gameserver.Deployment.Agent,
+		TargetAgent:    targetAgent,
+	}
+}
+
+// NewBootstrapToken generates a bootstrap token for enrolling an agent.
+// The token is returned once, the BootstrapToken contains only its hash
+func NewBootstrapToken(hostname, createdBy string, ttl time.Duration) (string, *BootstrapToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+
+	token := hex.EncodeToString(secret)
+	return token, &BootstrapToken{
+		Hash:      HashToken(token),
+		Hostname:  hostname,
+		CreatedBy: createdBy,
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
+// NewAPIToken generates a personal API token of the user. The token
+// is returned once, the APIToken contains only its hash
+func NewAPIToken(userID, name string, scopes []string, expiresAt *time.Time) (string, *APIToken, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+
+	token := APITokenPrefix + hex.EncodeToString(secret)
+	return token, &APIToken{
+		ID:        uuid.NewV4().String(),
+		Hash:      HashToken(token),
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// HashToken returns the hash, under which a token is stored
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// ErrGameNotAllowed is returned, when the quota doesn't allow the game
+var ErrGameNotAllowed = errors.New("Game is not allowed by the quota")
+
+// QuotaExceededError is returned, when a request exceeds the quota
+type QuotaExceededError struct {
+	Resource string
+}
+
+func (err *QuotaExceededError) Error() string {
+	return fmt.Sprintf("Quota of %s exceeded", err.Resource)
+}
+
+// UserQuotaSubject is the quota subject of the gameservers of a user
+func UserQuotaSubject(userID string) string {
+	return "user:" + userID
+}
+
+// TeamQuotaSubject is the quota subject of the gameservers of a team
+func TeamQuotaSubject(teamID string) string {
+	return "team:" + teamID
+}
+
+// QuotaUsageOf sums the gameservers of the team, when teamID is not empty,
+// or the gameservers created by the user. These include the gameservers of
+// the teams of the user, so more teams don't raise the limit of the user
+func QuotaUsageOf(gameservers []Gameserver, userID, teamID string) QuotaUsage {
+	usage := QuotaUsage{}
+	for _, gameserver := range gameservers {
+		if teamID != "" && gameserver.Definition.Team != teamID {
+			continue
+		}
+		if teamID == "" && gameserver.Definition.Owner != userID {
+			continue
+		}
+
+		usage.Gameservers++
+		if GameserverActive(&gameserver) {
+			requested := GameserverResources(&gameserver)
+			usage.MemoryKB += requested.MemoryKB
+			usage.CPUMillicores += requested.CPUMillicores
+		}
+	}
+	return usage
+}
+
+// GameserverActive checks, if the gameserver runs or is going to be started
+func GameserverActive(gameserver *Gameserver) bool {
+	return !gameserver.Deployment.Stopped || gameserver.WakeRequested
+}
+
+// GameserverResources returns the resources reserved by the gameserver
+func GameserverResources(gameserver *Gameserver) QuotaUsage {
+	usage := QuotaUsage{Gameservers: 1}
+	if requirements := gameserver.Deployment.ResourceRequirements; requirements != nil {
+		usage.MemoryKB = requirements.MemoryReservation
+		usage.CPUMillicores = requirements.CpuReservation
+	}
+	return usage
+}
+
+// CheckQuota checks, if the requested resources and the game fit
+// into the quota. A nil quota is unlimited
+func CheckQuota(quota *Quota, usage, requested QuotaUsage, game string) error {
+	if quota == nil {
+		return nil
+	}
+
+	if len(quota.Games) > 0 {
+		allowed := false
+		for _, allowedGame := range quota.Games {
+			allowed = allowed || allowedGame == game
+		}
+		if !allowed {
+			return ErrGameNotAllowed
+		}
+	}
+
+	switch {
+	case quota.MaxGameservers > 0 && usage.Gameservers+requested.Gameservers > quota.MaxGameservers:
+		return &QuotaExceededError{"gameservers"}
+	case quota.MemoryKB > 0 && usage.MemoryKB+requested.MemoryKB > quota.MemoryKB:
+		return &QuotaExceededError{"memory"}
+	case quota.CPUMillicores > 0 && usage.CPUMillicores+requested.CPUMillicores > quota.CPUMillicores:
+		return &QuotaExceededError{"CPU"}
+	}
+	return nil
+}
+
+// RecordGameserverEvent stores a new event in the gameserver history
+func RecordGameserverEvent(store EventStore, UUID string, eventType string, message string) {
+	event := &GameserverEvent{
+		Time:    time.Now(),
+		Type:    eventType,
+		Message: message,
+	}
+
+	if err := store.AddGameserverEvent(UUID, event); err != nil {
+		log.Printf("Cannot record %s event of %s: %v", eventType, UUID, err)
+	}
+}
@@ -20,6 +20,6 @@ func SetupRouter() *gin.Engine {
 	auth.SetupAuthentication(r, map[string]interface{}{
 		"type": "jwt",
 		"key":  "secret",
-	})
+	}, nil)
 	return r
 }